@@ -6,11 +6,70 @@ package chain
 import "github.com/ava-labs/avalanchego/ids"
 
 type Activity struct {
-	Tmstmp int64  `serialize:"true" json:"timestamp"`
-	TxID   ids.ID `serialize:"true" json:"txId"`
-	Typ    string `serialize:"true" json:"type"`
-	Sender string `serialize:"true" json:"sender,omitempty"`
-	Key    string `serialize:"true" json:"key,omitempty"`
-	To     string `serialize:"true" json:"to,omitempty"` // common.Address will be 0x000 when not populated
-	Units  uint64 `serialize:"true" json:"units,omitempty"`
+	Tmstmp    int64    `serialize:"true" json:"timestamp"`
+	TxID      ids.ID   `serialize:"true" json:"txId"`
+	Typ       string   `serialize:"true" json:"type"`
+	Sender    string   `serialize:"true" json:"sender,omitempty"`
+	Key       string   `serialize:"true" json:"key,omitempty"`
+	Keys      []string `serialize:"true" json:"keys,omitempty"`
+	To        string   `serialize:"true" json:"to,omitempty"` // common.Address will be 0x000 when not populated
+	Units     uint64   `serialize:"true" json:"units,omitempty"`
+	Size      uint64   `serialize:"true" json:"size,omitempty"`
+	TTL       uint64   `serialize:"true" json:"ttl,omitempty"`
+	Extension uint64   `serialize:"true" json:"extension,omitempty"`
+}
+
+// ActivityField describes a field of [Activity] by its JSON name and the
+// shape of its value. [Optional] fields are omitted when zero (e.g. the TTL of
+// a value stored forever).
+type ActivityField struct {
+	Name     string `serialize:"true" json:"name"`
+	Type     string `serialize:"true" json:"type"`
+	Optional bool   `serialize:"true" json:"optional,omitempty"`
+}
+
+// ActivityType lists the fields populated in the [Activity] of a tx type.
+type ActivityType struct {
+	Typ    string           `serialize:"true" json:"type"`
+	Fields []*ActivityField `serialize:"true" json:"fields"`
+}
+
+// commonActivityFields are populated for every tx type once it is accepted.
+var commonActivityFields = []*ActivityField{
+	{Name: "timestamp", Type: "unix"},
+	{Name: "txId", Type: "ID"},
+	{Name: "type", Type: "string"},
+	{Name: "sender", Type: "address"},
+}
+
+// ActivityTypes returns the [ActivityType] of every tx type.
+func ActivityTypes() []*ActivityType {
+	withCommon := func(typ string, fields ...*ActivityField) *ActivityType {
+		all := make([]*ActivityField, 0, len(commonActivityFields)+len(fields))
+		for _, f := range commonActivityFields {
+			all = append(all, &ActivityField{Name: f.Name, Type: f.Type, Optional: f.Optional})
+		}
+		return &ActivityType{Typ: typ, Fields: append(all, fields...)}
+	}
+	return []*ActivityType{
+		withCommon(Set,
+			&ActivityField{Name: "key", Type: "hash"},
+			&ActivityField{Name: "size", Type: "uint64"},
+			&ActivityField{Name: "ttl", Type: "uint64", Optional: true},
+		),
+		withCommon(Transfer,
+			&ActivityField{Name: "to", Type: "address"},
+			&ActivityField{Name: "units", Type: "uint64"},
+		),
+		withCommon(Renew,
+			&ActivityField{Name: "key", Type: "hash"},
+			&ActivityField{Name: "extension", Type: "uint64"},
+		),
+		withCommon(Pin, &ActivityField{Name: "key", Type: "hash"}),
+		withCommon(Unpin, &ActivityField{Name: "key", Type: "hash"}),
+		withCommon(Batch,
+			&ActivityField{Name: "keys", Type: "[]hash"},
+			&ActivityField{Name: "size", Type: "uint64"},
+		),
+	}
 }
@@ -0,0 +1,117 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestActivity(t *testing.T) {
+	t.Parallel()
+
+	g := DefaultGenesis()
+	value := []byte("hello")
+	key := common.HexToHash("0x1234")
+	to := common.HexToAddress("0xabcd")
+	tt := []struct {
+		utx UnsignedTransaction
+		exp *Activity
+	}{
+		{
+			utx: &SetTx{BaseTx: &BaseTx{}, Value: value},
+			exp: &Activity{
+				Typ:  Set,
+				Key:  strings.ToLower(g.ValueHash(value).Hex()),
+				Size: uint64(len(value)),
+				TTL:  g.DefaultValueTTL,
+			},
+		},
+		{
+			utx: &SetTx{BaseTx: &BaseTx{}, Value: value, TTL: 10},
+			exp: &Activity{
+				Typ:  Set,
+				Key:  strings.ToLower(g.ValueHash(value).Hex()),
+				Size: uint64(len(value)),
+				TTL:  10,
+			},
+		},
+		{
+			utx: &TransferTx{BaseTx: &BaseTx{}, To: to, Units: 7},
+			exp: &Activity{Typ: Transfer, To: to.Hex(), Units: 7},
+		},
+		{
+			utx: &RenewTx{BaseTx: &BaseTx{}, Key: key, Extension: 60},
+			exp: &Activity{Typ: Renew, Key: key.Hex(), Extension: 60},
+		},
+		{
+			utx: &PinTx{BaseTx: &BaseTx{}, Key: key},
+			exp: &Activity{Typ: Pin, Key: key.Hex()},
+		},
+		{
+			utx: &UnpinTx{BaseTx: &BaseTx{}, Key: key},
+			exp: &Activity{Typ: Unpin, Key: key.Hex()},
+		},
+		{
+			utx: &BatchTx{BaseTx: &BaseTx{}, Values: []*BatchValue{{Value: value}, {Value: []byte("world")}}},
+			exp: &Activity{
+				Typ: Batch,
+				Keys: []string{
+					strings.ToLower(g.ValueHash(value).Hex()),
+					strings.ToLower(g.ValueHash([]byte("world")).Hex()),
+				},
+				Size: uint64(len(value) + len("world")),
+			},
+		},
+	}
+
+	schemas := make(map[string]map[string]bool) // name -> optional
+	for _, typ := range ActivityTypes() {
+		fields := make(map[string]bool)
+		for _, f := range typ.Fields {
+			fields[f.Name] = f.Optional
+		}
+		schemas[typ.Typ] = fields
+	}
+	if len(schemas) != len(tt)-1 {
+		t.Fatalf("expected %d activity types, got %d", len(tt)-1, len(schemas))
+	}
+
+	for i, tv := range tt {
+		act := tv.utx.Activity(g)
+		if !reflect.DeepEqual(act, tv.exp) {
+			t.Fatalf("#%d: activity expected %+v, got %+v", i, tv.exp, act)
+		}
+
+		// Every required field in the schema (and no unlisted field) must be
+		// populated once the common fields are set
+		act.Tmstmp, act.TxID, act.Sender = 1, [32]byte{1}, to.Hex()
+		b, err := json.Marshal(act)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var m map[string]interface{}
+		if err := json.Unmarshal(b, &m); err != nil {
+			t.Fatal(err)
+		}
+		schema, ok := schemas[act.Typ]
+		if !ok {
+			t.Fatalf("#%d: missing schema for %s", i, act.Typ)
+		}
+		for name := range m {
+			if _, ok := schema[name]; !ok {
+				t.Fatalf("#%d: %s populated unlisted field %s", i, act.Typ, name)
+			}
+		}
+		for name, optional := range schema {
+			if _, ok := m[name]; !ok && !optional {
+				t.Fatalf("#%d: %s missing field %s", i, act.Typ, name)
+			}
+		}
+	}
+}
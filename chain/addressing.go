@@ -0,0 +1,95 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base32"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Addressing is how the key a value is stored at is derived from the value.
+type Addressing string
+
+const (
+	// HashAddressing stores values at their [Genesis.ValueHash] (and is
+	// assumed for txs and [ValueMeta]s that don't record an [Addressing])
+	HashAddressing Addressing = ""
+
+	// CIDv1 stores values at the sha2-256 digest of their raw CIDv1 (the CID
+	// IPFS assigns to a single raw block), so they can be looked up by the
+	// same CID on both networks. [Genesis.ValueDomainSeparator] is not
+	// applied: the key must match the CID.
+	CIDv1 Addressing = "cidv1"
+)
+
+// The binary prefix of a CIDv1 of a raw block hashed with sha2-256: the CID
+// version, the raw multicodec, and the sha2-256 multihash code and length.
+var cidv1RawPrefix = []byte{0x01, 0x55, 0x12, 0x20}
+
+// cidBase32 is the multibase "b" encoding used for the string form of CIDv1s.
+var cidBase32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func (a Addressing) verify() error {
+	switch a {
+	case HashAddressing, CIDv1:
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrUnsupportedAddressing, a)
+	}
+}
+
+// Key returns the key [v] is stored at under [a], prepending [sep] (the
+// [Genesis.ValueDomainSeparator]) if [a] hashes with it.
+func (a Addressing) Key(sep []byte, v []byte) common.Hash {
+	if a == CIDv1 {
+		return sha256.Sum256(v)
+	}
+	return DomainValueHash(sep, v)
+}
+
+// AddressKey returns the key [v] is stored at under [a] on this chain.
+func (g *Genesis) AddressKey(a Addressing, v []byte) common.Hash {
+	return a.Key(g.ValueDomainSeparator, v)
+}
+
+// AddressingAllowed returns true if values can be set with [a].
+func (g *Genesis) AddressingAllowed(a Addressing) bool {
+	if a == HashAddressing {
+		return true
+	}
+	for _, allowed := range g.AllowedAddressing {
+		if allowed == a {
+			return true
+		}
+	}
+	return false
+}
+
+// CIDKey returns the key of the value addressed by [cid] (the base32 string
+// form of a raw, sha2-256 CIDv1) under [CIDv1].
+func CIDKey(cid string) (common.Hash, error) {
+	if !strings.HasPrefix(cid, "b") {
+		return common.Hash{}, fmt.Errorf("%w: unsupported multibase", ErrInvalidCID)
+	}
+	b, err := cidBase32.DecodeString(strings.ToUpper(cid[1:]))
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("%w: %v", ErrInvalidCID, err)
+	}
+	if len(b) != len(cidv1RawPrefix)+common.HashLength || !bytes.HasPrefix(b, cidv1RawPrefix) {
+		return common.Hash{}, fmt.Errorf("%w: not a raw sha2-256 CIDv1", ErrInvalidCID)
+	}
+	return common.BytesToHash(b[len(cidv1RawPrefix):]), nil
+}
+
+// KeyCID returns the CID (in base32 string form) of the value stored at [key]
+// under [CIDv1].
+func KeyCID(key common.Hash) string {
+	b := append(append([]byte{}, cidv1RawPrefix...), key[:]...)
+	return "b" + strings.ToLower(cidBase32.EncodeToString(b))
+}
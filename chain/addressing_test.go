@@ -0,0 +1,44 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"crypto/sha256"
+	"errors"
+	"testing"
+)
+
+func TestCIDKey(t *testing.T) {
+	t.Parallel()
+
+	// The CID "ipfs add --cid-version=1 --raw-leaves" assigns to "hello world"
+	const cid = "bafkreifzjut3te2nhyekklss27nh3k72ysco7y32koao5eei66wof36n5e"
+	k, err := CIDKey(cid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := []byte("hello world")
+	if expected := sha256.Sum256(v); k != expected {
+		t.Fatalf("unexpected key %x, expected %x", k, expected)
+	}
+	if k != CIDv1.Key([]byte("ignored"), v) {
+		t.Fatal("key should not depend on the domain separator")
+	}
+	if c := KeyCID(k); c != cid {
+		t.Fatalf("unexpected cid %s, expected %s", c, cid)
+	}
+
+	for _, invalid := range []string{
+		"",
+		"zb2rhe5P4gXftAwvA4eXQ5HJwsER2owDyS9sKaQRRVQPn93bA",            // base58btc
+		"bafybeifzjut3te2nhyekklss27nh3k72ysco7y32koao5eei66wof36n5e",  // dag-pb
+		"bafkreifzjut3te2nhyekklss27nh3k72ysco7y32koao5eei66wof36n",    // truncated
+		"bafkreifzjut3te2nhyekklss27nh3k72ysco7y32koao5eei66wof36n5e!", // not base32
+		"QmaozNR7DZHQK1ZcU9p7QdrshMvXqWK6gpu5rmrkPdT3L4",               // CIDv0
+	} {
+		if _, err := CIDKey(invalid); !errors.Is(err, ErrInvalidCID) {
+			t.Fatalf("%q: expected %v, got %v", invalid, ErrInvalidCID, err)
+		}
+	}
+}
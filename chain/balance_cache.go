@@ -0,0 +1,118 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"encoding/binary"
+
+	"github.com/ava-labs/avalanchego/database"
+	"github.com/ava-labs/avalanchego/database/nodb"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var _ database.Database = &balanceCache{}
+
+// balanceCache holds the balances written while executing a block in memory
+// (see [Context.BatchBalanceWrites]) and writes them to the underlying
+// database once, on [flush]. Every other key is read and written through.
+//
+// Iterators and batches would bypass the cache, so it is flushed before
+// either is created.
+type balanceCache struct {
+	database.Database
+
+	balances map[common.Address]uint64
+}
+
+func newBalanceCache(db database.Database) *balanceCache {
+	return &balanceCache{Database: db, balances: map[common.Address]uint64{}}
+}
+
+// balanceAddress returns the address of [k] if it is a balance key (see
+// [PrefixBalanceKey]).
+func balanceAddress(k []byte) (common.Address, bool) {
+	if len(k) != 2+common.AddressLength || k[0] != balancePrefix || k[1] != ByteDelimiter {
+		return common.Address{}, false
+	}
+	return common.BytesToAddress(k[2:]), true
+}
+
+func (c *balanceCache) getBalance(address common.Address) (uint64, error) {
+	if bal, ok := c.balances[address]; ok {
+		return bal, nil
+	}
+	return GetBalance(c.Database, address)
+}
+
+func (c *balanceCache) Has(k []byte) (bool, error) {
+	if address, ok := balanceAddress(k); ok {
+		if _, ok := c.balances[address]; ok {
+			return true, nil
+		}
+	}
+	return c.Database.Has(k)
+}
+
+func (c *balanceCache) Get(k []byte) ([]byte, error) {
+	if address, ok := balanceAddress(k); ok {
+		if bal, ok := c.balances[address]; ok {
+			v := make([]byte, 8)
+			binary.BigEndian.PutUint64(v, bal)
+			return v, nil
+		}
+	}
+	return c.Database.Get(k)
+}
+
+func (c *balanceCache) Put(k []byte, v []byte) error {
+	if address, ok := balanceAddress(k); ok && len(v) == 8 {
+		c.balances[address] = binary.BigEndian.Uint64(v)
+		return nil
+	}
+	return c.Database.Put(k, v)
+}
+
+func (c *balanceCache) Delete(k []byte) error {
+	if address, ok := balanceAddress(k); ok {
+		delete(c.balances, address)
+	}
+	return c.Database.Delete(k)
+}
+
+func (c *balanceCache) NewBatch() database.Batch {
+	// A failed flush means the underlying database is closed, so writing the
+	// batch fails too
+	_ = c.flush()
+	return c.Database.NewBatch()
+}
+
+func (c *balanceCache) NewIterator() database.Iterator {
+	return c.NewIteratorWithStartAndPrefix(nil, nil)
+}
+
+func (c *balanceCache) NewIteratorWithStart(start []byte) database.Iterator {
+	return c.NewIteratorWithStartAndPrefix(start, nil)
+}
+
+func (c *balanceCache) NewIteratorWithPrefix(prefix []byte) database.Iterator {
+	return c.NewIteratorWithStartAndPrefix(nil, prefix)
+}
+
+func (c *balanceCache) NewIteratorWithStartAndPrefix(start, prefix []byte) database.Iterator {
+	if err := c.flush(); err != nil {
+		return &nodb.Iterator{Err: err}
+	}
+	return c.Database.NewIteratorWithStartAndPrefix(start, prefix)
+}
+
+// flush writes the cached balances to the underlying database.
+func (c *balanceCache) flush() error {
+	for address, bal := range c.balances {
+		if err := SetBalance(c.Database, address, bal); err != nil {
+			return err
+		}
+		delete(c.balances, address)
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/database"
+	"github.com/ava-labs/avalanchego/database/memdb"
+	"github.com/ava-labs/avalanchego/database/versiondb"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var testBlockID = ids.ID{0, 1}
+
+func createTestTransferTx(tb testing.TB, g *Genesis, priv *ecdsa.PrivateKey, to common.Address, units uint64) *Transaction {
+	tb.Helper()
+
+	return createTestSignedTx(tb, g, priv, &TransferTx{
+		BaseTx: &BaseTx{BlockID: testBlockID, Price: 1},
+		To:     to,
+		Units:  units,
+	})
+}
+
+func createTestSignedTx(tb testing.TB, g *Genesis, priv *ecdsa.PrivateKey, utx UnsignedTransaction) *Transaction {
+	tb.Helper()
+
+	dh, err := DigestHash(utx)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	sig, err := Sign(dh, priv)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tx := NewTx(utx, sig)
+	if err := tx.Init(g); err != nil {
+		tb.Fatal(err)
+	}
+	return tx
+}
+
+func executeTestTxs(tb testing.TB, g *Genesis, db database.Database, txs []*Transaction) {
+	tb.Helper()
+
+	ctx := &Context{RecentBlockIDs: ids.Set{testBlockID: struct{}{}}}
+	blk := DummyBlock(1, nil)
+	for _, tx := range txs {
+		if err := tx.Execute(g, db, blk, ctx); err != nil {
+			tb.Fatal(err)
+		}
+	}
+}
+
+func TestBalanceCache(t *testing.T) {
+	t.Parallel()
+
+	privs := make([]*ecdsa.PrivateKey, 3)
+	addrs := make([]common.Address, 3)
+	for i := range privs {
+		priv, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		privs[i], addrs[i] = priv, crypto.PubkeyToAddress(priv.PublicKey)
+	}
+	g := DefaultGenesis()
+	g.CustomAllocation = []*CustomAllocation{{Address: addrs[0], Balance: 10000000}}
+
+	// addrs[1] and addrs[2] can only pay for their transfers with the units
+	// they receive earlier in the block
+	txs := []*Transaction{
+		createTestTransferTx(t, g, privs[0], addrs[1], 10000),
+		createTestTransferTx(t, g, privs[1], addrs[2], 100),
+		createTestTransferTx(t, g, privs[2], addrs[0], 10),
+	}
+
+	expected := make([]uint64, len(addrs))
+	for i, batch := range []bool{false, true} {
+		db := memdb.New()
+		if err := g.Load(db, nil); err != nil {
+			t.Fatal(err)
+		}
+		vdb := versiondb.New(db)
+		var exec database.Database = vdb
+		if batch {
+			exec = newBalanceCache(vdb)
+		}
+		executeTestTxs(t, g, exec, txs)
+		if batch {
+			// Balances are only written on flush
+			for _, addr := range addrs[1:] {
+				if bal, err := GetBalance(vdb, addr); err != nil || bal != 0 {
+					t.Fatalf("balance of %s written before flush (bal=%d err=%v)", addr, bal, err)
+				}
+			}
+			if err := exec.(*balanceCache).flush(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for j, addr := range addrs {
+			bal, err := GetBalance(vdb, addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if i == 0 {
+				expected[j] = bal
+				continue
+			}
+			if bal != expected[j] {
+				t.Fatalf("balance of %s expected %d, got %d", addr, expected[j], bal)
+			}
+		}
+		db.Close()
+	}
+}
+
+func BenchmarkTransferBlock(b *testing.B) {
+	const (
+		senders    = 16
+		recipients = 256
+		transfers  = 2048
+		ancestors  = 4
+	)
+	g := DefaultGenesis()
+	privs := make([]*ecdsa.PrivateKey, senders)
+	for i := range privs {
+		priv, err := crypto.GenerateKey()
+		if err != nil {
+			b.Fatal(err)
+		}
+		privs[i] = priv
+		g.CustomAllocation = append(g.CustomAllocation, &CustomAllocation{
+			Address: crypto.PubkeyToAddress(priv.PublicKey), Balance: 1_000_000_000,
+		})
+	}
+	txs := make([]*Transaction, transfers)
+	for i := range txs {
+		to := common.Address{1, byte(i % recipients)}
+		txs[i] = createTestTransferTx(b, g, privs[i%senders], to, 1)
+	}
+
+	db := memdb.New()
+	defer db.Close()
+	if err := g.Load(db, nil); err != nil {
+		b.Fatal(err)
+	}
+	// Execute on top of a chain of processing ancestors, as during verification
+	var parent database.Database = db
+	for i := 0; i < ancestors; i++ {
+		parent = versiondb.New(parent)
+	}
+
+	for _, batch := range []bool{false, true} {
+		b.Run(fmt.Sprintf("batch=%t", batch), func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				vdb := versiondb.New(parent)
+				var exec database.Database = vdb
+				if batch {
+					exec = newBalanceCache(vdb)
+				}
+				executeTestTxs(b, g, exec, txs)
+				if c, ok := exec.(*balanceCache); ok {
+					if err := c.flush(); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
@@ -9,14 +9,14 @@ import (
 
 type BaseTx struct {
 	// BlkID is the ID of a block in the [lookbackWindow].
-	BlockID ids.ID `serialize:"true" json:"blockId"`
+	BlockID ids.ID `serialize:"true" serializeV0:"true" json:"blockId"`
 
 	// Magic is a value defined in genesis to protect against replay attacks on
 	// different VMs.
-	Magic uint64 `serialize:"true" json:"magic"`
+	Magic uint64 `serialize:"true" serializeV0:"true" json:"magic"`
 
 	// Price is the value per unit to spend on this transaction.
-	Price uint64 `serialize:"true" json:"price"`
+	Price uint64 `serialize:"true" serializeV0:"true" json:"price"`
 }
 
 func (b *BaseTx) GetBlockID() ids.ID {
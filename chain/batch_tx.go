@@ -0,0 +1,138 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/ava-labs/blobvm/tdata"
+)
+
+var _ UnsignedTransaction = &BatchTx{}
+
+// BatchValue is a value written by a [BatchTx] (see [SetTx] for the meaning
+// of each field).
+type BatchValue struct {
+	Value []byte `serialize:"true" json:"value"`
+	TTL   uint64 `serialize:"true" json:"ttl"`
+	Tags  []*Tag `serialize:"true" json:"tags,omitempty"`
+}
+
+// BatchTx sets multiple values atomically: if any of [Values] can't be set,
+// none are. It is only charged [BaseTxUnits] once.
+type BatchTx struct {
+	*BaseTx `serialize:"true" json:"baseTx"`
+
+	Values []*BatchValue `serialize:"true" json:"values"`
+}
+
+func (b *BatchTx) Execute(t *TransactionContext) error {
+	g := t.Genesis
+	switch {
+	case len(b.Values) == 0:
+		return ErrBatchEmpty
+	case b.LoadUnits(g) > g.MaxBlockSize:
+		// Could never be included in a block
+		return ErrBatchTooBig
+	}
+	for _, v := range b.Values {
+		if err := setValue(t, g.ValueHash(v.Value), HashAddressing, v.Value, v.ttl(g), v.Tags, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ttl returns the number of seconds [Value] will be stored for (0 is
+// forever).
+func (v *BatchValue) ttl(g *Genesis) uint64 {
+	if v.TTL == 0 {
+		return g.DefaultValueTTL
+	}
+	return v.TTL
+}
+
+func (b *BatchTx) FeeUnits(g *Genesis) uint64 {
+	units := b.BaseTx.FeeUnits(g)
+	for _, v := range b.Values {
+		vunits := ttlUnits(g, valueUnits(g, v.size()), v.TTL)
+		if vunits > math.MaxUint64-units {
+			return math.MaxUint64
+		}
+		units += vunits
+	}
+	return units
+}
+
+func (b *BatchTx) LoadUnits(g *Genesis) uint64 {
+	units := b.BaseTx.FeeUnits(g)
+	for _, v := range b.Values {
+		units += valueUnits(g, v.size())
+	}
+	return units
+}
+
+// size returns the number of bytes stored for the value ([Value] and [Tags]).
+func (v *BatchValue) size() uint64 {
+	return uint64(len(v.Value)) + tagsSize(v.Tags)
+}
+
+func (b *BatchTx) Copy() UnsignedTransaction {
+	values := make([]*BatchValue, len(b.Values))
+	for i, v := range b.Values {
+		value := make([]byte, len(v.Value))
+		copy(value, v.Value)
+		values[i] = &BatchValue{Value: value, TTL: v.TTL, Tags: copyTags(v.Tags)}
+	}
+	return &BatchTx{
+		BaseTx: b.BaseTx.Copy(),
+		Values: values,
+	}
+}
+
+func (b *BatchTx) TypedData() *tdata.TypedData {
+	values := make([]interface{}, len(b.Values))
+	ttls := make([]interface{}, len(b.Values))
+	tags := make([]interface{}, len(b.Values))
+	for i, v := range b.Values {
+		values[i] = hexutil.Encode(v.Value)
+		ttls[i] = strconv.FormatUint(v.TTL, 10)
+		tags[i] = encodeTags(v.Tags)
+	}
+	return tdata.CreateTypedData(
+		b.Magic, Batch,
+		[]tdata.Type{
+			{Name: tdValues, Type: tdBytes + "[]"},
+			{Name: tdTTLs, Type: tdUint64 + "[]"},
+			{Name: tdTags, Type: tdString + "[]"},
+			{Name: tdPrice, Type: tdUint64},
+			{Name: tdBlockID, Type: tdString},
+		},
+		tdata.TypedDataMessage{
+			tdValues:  values,
+			tdTTLs:    ttls,
+			tdTags:    tags,
+			tdPrice:   strconv.FormatUint(b.Price, 10),
+			tdBlockID: b.BlockID.String(),
+		},
+	)
+}
+
+func (b *BatchTx) Activity(g *Genesis) *Activity {
+	keys := make([]string, len(b.Values))
+	size := uint64(0)
+	for i, v := range b.Values {
+		keys[i] = strings.ToLower(g.ValueHash(v.Value).Hex())
+		size += uint64(len(v.Value))
+	}
+	return &Activity{
+		Typ:  Batch,
+		Keys: keys,
+		Size: size,
+	}
+}
@@ -0,0 +1,274 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/database/memdb"
+	"github.com/ava-labs/avalanchego/database/versiondb"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/golang/mock/gomock"
+
+	"github.com/ava-labs/blobvm/tdata"
+)
+
+func TestBatchTx(t *testing.T) {
+	t.Parallel()
+
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := crypto.PubkeyToAddress(priv.PublicKey)
+
+	db := memdb.New()
+	defer db.Close()
+
+	g := DefaultGenesis()
+	g.DefaultValueTTL = 100
+	full := make([]byte, g.MaxValueSize)
+	tt := []struct {
+		utx    *BatchTx
+		stored [][]byte
+		err    error
+	}{
+		{ // no values
+			utx: &BatchTx{BaseTx: &BaseTx{}},
+			err: ErrBatchEmpty,
+		},
+		{ // can't fit in a block
+			utx: &BatchTx{BaseTx: &BaseTx{}, Values: []*BatchValue{{Value: full}, {Value: full[1:]}}},
+			err: ErrBatchTooBig,
+		},
+		{ // invalid value fails the whole batch
+			utx: &BatchTx{BaseTx: &BaseTx{}, Values: []*BatchValue{{Value: []byte("a")}, {}}},
+			err: ErrValueEmpty,
+		},
+		{ // duplicate values in the same batch
+			utx: &BatchTx{BaseTx: &BaseTx{}, Values: []*BatchValue{{Value: []byte("a")}, {Value: []byte("a")}}},
+			err: ErrKeyExists,
+		},
+		{ // valid
+			utx: &BatchTx{BaseTx: &BaseTx{}, Values: []*BatchValue{
+				{Value: []byte("a")},
+				{Value: []byte("b"), TTL: 10, Tags: []*Tag{{Key: "type", Value: "text/plain"}}},
+			}},
+			stored: [][]byte{[]byte("a"), []byte("b")},
+		},
+		{ // already stored values can't be set again
+			utx: &BatchTx{BaseTx: &BaseTx{}, Values: []*BatchValue{{Value: []byte("c")}, {Value: []byte("b")}}},
+			err: ErrKeyExists,
+		},
+	}
+	for i, tv := range tt {
+		txID := ids.GenerateTestID()
+		vdb := versiondb.New(db)
+		err := tv.utx.Execute(&TransactionContext{
+			Genesis:   g,
+			Database:  vdb,
+			BlockTime: 1,
+			TxID:      txID,
+			Sender:    sender,
+		})
+		if !errors.Is(err, tv.err) {
+			t.Fatalf("#%d: tx.Execute err expected %v, got %v", i, tv.err, err)
+		}
+		if err != nil {
+			// Failed txs are discarded along with any partial writes
+			vdb.Abort()
+			for _, v := range tv.utx.Values {
+				if len(v.Value) == 0 {
+					continue
+				}
+				vmeta, exists, err := GetValueMeta(db, g.ValueHash(v.Value))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if exists && vmeta.TxID == txID {
+					t.Fatalf("#%d: value %q of failed batch was stored", i, v.Value)
+				}
+			}
+			continue
+		}
+		if err := vdb.Commit(); err != nil {
+			t.Fatal(err)
+		}
+		for j, v := range tv.stored {
+			vmeta, exists, err := GetValueMeta(db, g.ValueHash(v))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !exists {
+				t.Fatalf("#%d: value %d not stored", i, j)
+			}
+			if !vmeta.Batched || vmeta.TxID != txID || vmeta.Size != uint64(len(v)) {
+				t.Fatalf("#%d: unexpected meta %+v for value %d", i, vmeta, j)
+			}
+			if exp := 1 + tv.utx.Values[j].ttl(g); vmeta.Expiry != exp {
+				t.Fatalf("#%d: expiry expected %d, got %d", i, exp, vmeta.Expiry)
+			}
+		}
+	}
+}
+
+func TestBatchTxFeeUnits(t *testing.T) {
+	t.Parallel()
+
+	g := DefaultGenesis()
+	g.DefaultValueTTL = 100
+	value := make([]byte, 10*g.ValueUnitSize) // 11 value units
+	values := []*BatchValue{{Value: value}, {Value: value, TTL: 300}, {Value: []byte("small")}}
+	batch := &BatchTx{BaseTx: &BaseTx{}, Values: values}
+
+	// A single base tx unit plus the value units of each value
+	var setUnits, setLoad uint64
+	for _, v := range values {
+		stx := &SetTx{BaseTx: &BaseTx{}, Value: v.Value, TTL: v.TTL}
+		setUnits += stx.FeeUnits(g) - g.BaseTxUnits
+		setLoad += stx.LoadUnits(g) - g.BaseTxUnits
+	}
+	if units := batch.FeeUnits(g); units != g.BaseTxUnits+setUnits {
+		t.Fatalf("fee units expected %d, got %d", g.BaseTxUnits+setUnits, units)
+	}
+	if units := batch.LoadUnits(g); units != g.BaseTxUnits+setLoad {
+		t.Fatalf("load units expected %d, got %d", g.BaseTxUnits+setLoad, units)
+	}
+}
+
+func TestBatchTxTypedData(t *testing.T) {
+	t.Parallel()
+
+	utx := &BatchTx{
+		BaseTx: &BaseTx{BlockID: ids.GenerateTestID(), Magic: 1, Price: 2},
+		Values: []*BatchValue{
+			{Value: []byte("a")},
+			{Value: []byte("b"), TTL: 10, Tags: []*Tag{{Key: "type", Value: "text/plain"}}},
+		},
+	}
+	dh, err := DigestHash(utx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Typed data is submitted as JSON
+	b, err := json.Marshal(utx.TypedData())
+	if err != nil {
+		t.Fatal(err)
+	}
+	td := new(tdata.TypedData)
+	if err := json.Unmarshal(b, td); err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseTypedData(td)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsed, utx) {
+		t.Fatalf("unexpected tx %+v, expected %+v", parsed, utx)
+	}
+	pdh, err := DigestHash(parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pdh, dh) {
+		t.Fatal("digest hash changed after parsing")
+	}
+
+	// Mismatched arrays are rejected
+	td.Message[tdTTLs] = []interface{}{"0"}
+	if _, err := ParseTypedData(td); !errors.Is(err, ErrInvalidBatch) {
+		t.Fatalf("unexpected error %v, expected %v", err, ErrInvalidBatch)
+	}
+}
+
+func TestBatchTxLinkValues(t *testing.T) {
+	t.Parallel()
+
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := DefaultGenesis()
+	values := [][]byte{[]byte("a"), []byte("b")}
+	utx := &BatchTx{
+		BaseTx: &BaseTx{BlockID: ids.GenerateTestID(), Price: 1},
+		Values: []*BatchValue{{Value: values[0]}, {Value: values[1]}},
+	}
+	dh, err := DigestHash(utx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := Sign(dh, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := NewTx(utx, sig)
+	if err := tx.Init(g); err != nil {
+		t.Fatal(err)
+	}
+
+	db := memdb.New()
+	defer db.Close()
+	if err := utx.Execute(&TransactionContext{
+		Genesis:   g,
+		Database:  db,
+		BlockTime: 1,
+		TxID:      tx.ID(),
+		Sender:    tx.Sender(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctrl := gomock.NewController(t)
+	vm := NewMockVM(ctrl)
+	vm.EXPECT().Genesis().Return(g).AnyTimes()
+	blk := &StatelessBlock{
+		StatefulBlock: &StatefulBlock{Hght: 1, Txs: []*Transaction{tx}},
+		vm:            vm,
+	}
+	if err := blk.init(); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetLastAccepted(db, blk, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// Values are resolvable by key and restored in the stored block
+	for i, v := range values {
+		stored, exists, err := GetValue(db, nil, g.ValueHash(v))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !exists || !bytes.Equal(stored, v) {
+			t.Fatalf("#%d: unexpected value %q (exists=%t)", i, stored, exists)
+		}
+	}
+	sblk, err := GetBlock(db, blk.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, ok := sblk.Txs[0].UnsignedTransaction.(*BatchTx)
+	if !ok {
+		t.Fatalf("unexpected tx type %T", sblk.Txs[0].UnsignedTransaction)
+	}
+	accepted, ok := blk.Txs[0].UnsignedTransaction.(*BatchTx)
+	if !ok {
+		t.Fatalf("unexpected tx type %T", blk.Txs[0].UnsignedTransaction)
+	}
+	for i, v := range values {
+		if !bytes.Equal(restored.Values[i].Value, v) {
+			t.Fatalf("#%d: unexpected restored value %q", i, restored.Values[i].Value)
+		}
+		// The accepted block keeps its values in case it is cached
+		if !bytes.Equal(accepted.Values[i].Value, v) {
+			t.Fatalf("#%d: unexpected accepted value %q", i, accepted.Values[i].Value)
+		}
+	}
+}
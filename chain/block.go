@@ -4,6 +4,7 @@
 package chain
 
 import (
+	"bytes"
 	"context"
 	"encoding/binary"
 	"fmt"
@@ -20,18 +21,24 @@ import (
 	log "github.com/inconshreveable/log15"
 )
 
-const futureBound = 10 * time.Second
+const (
+	defaultFutureBound = 10 * time.Second
+
+	// MaxBlockClockDriftLimit is the largest [Genesis.MaxBlockClockDrift]
+	// (1 day), which keeps the future bound well within a [time.Duration].
+	MaxBlockClockDriftLimit = int64(24 * time.Hour / time.Second)
+)
 
 var _ snowman.Block = &StatelessBlock{}
 
 type StatefulBlock struct {
-	Prnt        ids.ID         `serialize:"true" json:"parent"`
-	Tmstmp      int64          `serialize:"true" json:"timestamp"`
-	Hght        uint64         `serialize:"true" json:"height"`
-	Price       uint64         `serialize:"true" json:"price"`
-	Cost        uint64         `serialize:"true" json:"cost"`
-	AccessProof common.Hash    `serialize:"true" json:"accessProof"`
-	Txs         []*Transaction `serialize:"true" json:"txs"`
+	Prnt        ids.ID         `serialize:"true" serializeV0:"true" json:"parent"`
+	Tmstmp      int64          `serialize:"true" serializeV0:"true" json:"timestamp"`
+	Hght        uint64         `serialize:"true" serializeV0:"true" json:"height"`
+	Price       uint64         `serialize:"true" serializeV0:"true" json:"price"`
+	Cost        uint64         `serialize:"true" serializeV0:"true" json:"cost"`
+	AccessProof common.Hash    `serialize:"true" serializeV0:"true" json:"accessProof"`
+	Txs         []*Transaction `serialize:"true" serializeV0:"true" json:"txs"`
 }
 
 // Stateless is defined separately from "Block"
@@ -69,11 +76,52 @@ func ParseBlock(
 	status choices.Status,
 	vm VM,
 ) (*StatelessBlock, error) {
+	blk, err := unmarshalBlock(source)
+	if err != nil {
+		return nil, err
+	}
+	return ParseStatefulBlock(blk, source, status, vm)
+}
+
+// unmarshalBlock decodes [source], which must be the canonical encoding of the
+// block (see [Marshal]). The block ID is the hash of [source], so a block
+// re-encoded with another codec version would otherwise be accepted under an
+// ID that differs from the one it is loaded with.
+func unmarshalBlock(source []byte) (*StatefulBlock, error) {
 	blk := new(StatefulBlock)
 	if _, err := Unmarshal(source, blk); err != nil {
 		return nil, err
 	}
-	return ParseStatefulBlock(blk, source, status, vm)
+	b, err := Marshal(blk)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(b, source) {
+		return nil, ErrNonCanonicalBlock
+	}
+	return blk, nil
+}
+
+// DecodeBlock parses an encoded [StatefulBlock] (as returned by
+// "blobvm.getBlock") outside of the VM, deriving its ID and the ID and sender
+// of each of its txs (see [DecodeTx]). Like a tx, [b] must be the canonical
+// encoding of the block, so the ID is the one the VM assigns to [b].
+func DecodeBlock(b []byte) (ids.ID, *StatefulBlock, error) {
+	blk, err := unmarshalBlock(b)
+	if err != nil {
+		return ids.ID{}, nil, err
+	}
+	id, err := ids.ToID(crypto.Keccak256(b))
+	if err != nil {
+		return ids.ID{}, nil, err
+	}
+	for _, tx := range blk.Txs {
+		// The ID and sender of a tx don't depend on the genesis
+		if err := tx.Init(nil); err != nil {
+			return ids.ID{}, nil, err
+		}
+	}
+	return id, blk, nil
 }
 
 func ParseStatefulBlock(
@@ -135,7 +183,7 @@ func (b *StatelessBlock) init() error {
 // implements "snowman.Block.choices.Decidable"
 func (b *StatelessBlock) ID() ids.ID { return b.id }
 
-func generateAccessProof(db database.Database, pid ids.ID, hght uint64) common.Hash {
+func generateAccessProof(db database.Database, pid ids.ID, hght uint64, upgraded bool) common.Hash {
 	// This seed selection is gameable because the previous block producer could
 	// grind the block hash to bias value selection (by including different
 	// transactions). This could be improved with some form of a VRF.
@@ -143,7 +191,7 @@ func generateAccessProof(db database.Database, pid ids.ID, hght uint64) common.H
 	copy(seed, pid[:])       // copy hash to make sure not overwritten
 	binary.LittleEndian.PutUint64(seed[32:], hght)
 
-	v := SelectRandomValue(db, seed)
+	v := SelectRandomValue(db, seed, upgraded)
 	if len(v) == 0 {
 		log.Debug("no key found for access proof", "parent", hexutil.Encode(pid[:]), "height", hght)
 		return common.Hash{}
@@ -156,21 +204,21 @@ func generateAccessProof(db database.Database, pid ids.ID, hght uint64) common.H
 
 // verify checks the correctness of a block and then returns the
 // *versiondb.Database computed during execution.
-func (b *StatelessBlock) verify() (*StatelessBlock, *versiondb.Database, error) {
+func (b *StatelessBlock) verify() (*StatelessBlock, *Context, *versiondb.Database, error) {
 	g := b.vm.Genesis()
 
 	// Perform basic correctness checks before doing any expensive work
 	if len(b.Txs) == 0 {
-		return nil, nil, ErrNoTxs
+		return nil, nil, nil, ErrNoTxs
 	}
-	if b.Timestamp().Unix() >= time.Now().Add(futureBound).Unix() {
-		return nil, nil, ErrTimestampTooLate
+	if bound := g.futureBound(); b.Timestamp().Unix() >= b.vm.Now().Add(bound).Unix() {
+		return nil, nil, nil, fmt.Errorf("%w: more than %v ahead of the local clock", ErrFutureBlock, bound)
 	}
 	blockSize := uint64(0)
 	for _, tx := range b.Txs {
 		blockSize += tx.LoadUnits(g)
 		if blockSize > g.MaxBlockSize {
-			return nil, nil, ErrBlockTooBig
+			return nil, nil, nil, ErrBlockTooBig
 		}
 	}
 
@@ -178,55 +226,64 @@ func (b *StatelessBlock) verify() (*StatelessBlock, *versiondb.Database, error)
 	parent, err := b.vm.GetStatelessBlock(b.Prnt)
 	if err != nil {
 		log.Debug("could not get parent", "id", b.Prnt)
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	if b.Timestamp().Unix() < parent.Timestamp().Unix() {
-		return nil, nil, ErrTimestampTooEarly
+		return nil, nil, nil, ErrTimestampTooEarly
 	}
 
 	context, err := b.vm.ExecutionContext(b.Tmstmp, parent)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	if b.Cost != context.NextCost {
-		return nil, nil, ErrInvalidCost
+		return nil, nil, nil, ErrInvalidCost
 	}
 	if b.Price != context.NextPrice {
-		return nil, nil, ErrInvalidPrice
+		return nil, nil, nil, ErrInvalidPrice
 	}
 
 	parentState, err := parent.onAccept()
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	onAcceptDB := versiondb.New(parentState)
 
 	// Generate access proof from random value
-	accessProof := generateAccessProof(onAcceptDB, parent.ID(), b.Hght)
+	accessProof := generateAccessProof(onAcceptDB, parent.ID(), b.Hght, g.Upgraded(b.Tmstmp))
 	if b.AccessProof != accessProof {
-		return nil, nil, ErrInvalidAccessProof
+		return nil, nil, nil, ErrInvalidAccessProof
 	}
 
 	// Process new transactions
 	log.Debug("build context", "height", b.Hght, "price", b.Price, "cost", b.Cost)
+	var db database.Database = onAcceptDB
+	if context.BatchBalanceWrites {
+		db = newBalanceCache(onAcceptDB)
+	}
 	surplusFee := uint64(0)
 	for _, tx := range b.Txs {
-		if err := tx.Execute(g, onAcceptDB, b, context); err != nil {
-			return nil, nil, err
+		if err := tx.Execute(g, db, b, context); err != nil {
+			return nil, nil, nil, err
 		}
 		surplusFee += (tx.GetPrice() - b.Price) * tx.FeeUnits(g)
 	}
+	if c, ok := db.(*balanceCache); ok {
+		if err := c.flush(); err != nil {
+			return nil, nil, nil, err
+		}
+	}
 	// Ensure enough fee is paid to compensate for block production speed
 	requiredSurplus := b.Price * b.Cost
 	if surplusFee < requiredSurplus {
-		return nil, nil, fmt.Errorf("%w: required=%d found=%d", ErrInsufficientSurplus, requiredSurplus, surplusFee)
+		return nil, nil, nil, fmt.Errorf("%w: required=%d found=%d", ErrInsufficientSurplus, requiredSurplus, surplusFee)
 	}
-	return parent, onAcceptDB, nil
+	return parent, context, onAcceptDB, nil
 }
 
 // implements "snowman.Block"
 func (b *StatelessBlock) Verify(ctx context.Context) error {
-	parent, onAcceptDB, err := b.verify()
+	parent, context, onAcceptDB, err := b.verify()
 	if err != nil {
 		log.Debug("block verification failed", "blkID", b.ID(), "error", err)
 		return err
@@ -234,7 +291,7 @@ func (b *StatelessBlock) Verify(ctx context.Context) error {
 	b.onAcceptDB = onAcceptDB
 
 	// Set last accepted block and store
-	if err := SetLastAccepted(b.onAcceptDB, b); err != nil {
+	if err := SetLastAccepted(b.onAcceptDB, b, context.CompressBlocks); err != nil {
 		return err
 	}
 
@@ -303,6 +360,31 @@ func (b *StatelessBlock) addChild(c *StatelessBlock) {
 	b.children = append(b.children, c)
 }
 
+// BlockHeader contains the fields of a block excluding its transactions.
+type BlockHeader struct {
+	ID          ids.ID      `serialize:"true" json:"id"`
+	Prnt        ids.ID      `serialize:"true" json:"parent"`
+	Tmstmp      int64       `serialize:"true" json:"timestamp"`
+	Hght        uint64      `serialize:"true" json:"height"`
+	Price       uint64      `serialize:"true" json:"price"`
+	Cost        uint64      `serialize:"true" json:"cost"`
+	AccessProof common.Hash `serialize:"true" json:"accessProof"`
+	TxCount     int         `serialize:"true" json:"txCount"`
+}
+
+func (b *StatelessBlock) Header() *BlockHeader {
+	return &BlockHeader{
+		ID:          b.id,
+		Prnt:        b.Prnt,
+		Tmstmp:      b.Tmstmp,
+		Hght:        b.Hght,
+		Price:       b.Price,
+		Cost:        b.Cost,
+		AccessProof: b.AccessProof,
+		TxCount:     len(b.Txs),
+	}
+}
+
 // DummyBlock is used for validating new txs and some tests
 func DummyBlock(tmstp int64, tx *Transaction) *StatelessBlock {
 	return &StatelessBlock{
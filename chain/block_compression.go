@@ -0,0 +1,58 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// Stored blocks are either the codec encoding of a [StatefulBlock] (which
+// starts with its (2 byte, zero) codec version) or that encoding compressed
+// with gzip (which starts with these magic bytes), so they can't be confused.
+const (
+	gzipID1 = 0x1f
+	gzipID2 = 0x8b
+)
+
+// marshalStoredBlock encodes [blk] for storage, gzipped if [compress] is set
+// and that makes it smaller. [unmarshalStoredBlock] detects gzipped blocks by
+// their magic bytes, so blocks stored with and without compression can be
+// mixed in the same database.
+func marshalStoredBlock(blk *StatefulBlock, compress bool) ([]byte, error) {
+	b, err := Marshal(blk)
+	if err != nil || !compress {
+		return b, err
+	}
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	if buf.Len() >= len(b) {
+		// Signatures and hashes don't compress, so small blocks (or blocks of
+		// transfers) can grow
+		return b, nil
+	}
+	return buf.Bytes(), nil
+}
+
+func unmarshalStoredBlock(b []byte, blk *StatefulBlock) error {
+	if len(b) >= 2 && b[0] == gzipID1 && b[1] == gzipID2 {
+		r, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidStoredBlock, err)
+		}
+		if b, err = io.ReadAll(r); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidStoredBlock, err)
+		}
+	}
+	_, err := Unmarshal(b, blk)
+	return err
+}
@@ -0,0 +1,124 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/database/memdb"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	gomock "github.com/golang/mock/gomock"
+)
+
+func TestStoredBlockCompression(t *testing.T) {
+	t.Parallel()
+
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := DefaultGenesis()
+	text := func(i int) []byte {
+		return []byte(strings.Repeat(fmt.Sprintf("line %d of a text value\n", i), 64))
+	}
+	for _, tv := range []struct {
+		name string
+		txs  func() []*Transaction
+	}{
+		{
+			name: "transfers",
+			txs: func() []*Transaction {
+				txs := []*Transaction{}
+				for i := 0; i < 64; i++ {
+					txs = append(txs, createTestTransferTx(t, g, priv, common.Address{byte(i) + 1}, 1))
+				}
+				return txs
+			},
+		},
+		{
+			// Values are extracted before blocks are stored, so only their
+			// links remain
+			name: "text values",
+			txs: func() []*Transaction {
+				txs := []*Transaction{}
+				for i := 0; i < 64; i++ {
+					txs = append(txs, createTestSignedTx(t, g, priv, &SetTx{
+						BaseTx: &BaseTx{BlockID: testBlockID, Price: 1},
+						Value:  text(i),
+					}))
+				}
+				return txs
+			},
+		},
+		{
+			name: "tagged batches",
+			txs: func() []*Transaction {
+				txs := []*Transaction{}
+				for i := 0; i < 16; i++ {
+					values := []*BatchValue{}
+					for j := 0; j < 8; j++ {
+						values = append(values, &BatchValue{
+							Value: text(8*i + j),
+							Tags:  []*Tag{{Key: "content-type", Value: "text/plain"}, {Key: "app", Value: "notes"}},
+						})
+					}
+					txs = append(txs, createTestSignedTx(t, g, priv, &BatchTx{
+						BaseTx: &BaseTx{BlockID: testBlockID, Price: 1},
+						Values: values,
+					}))
+				}
+				return txs
+			},
+		},
+	} {
+		sizes := map[bool]int{}
+		for _, compress := range []bool{false, true} {
+			ctrl := gomock.NewController(t)
+			vm := NewMockVM(ctrl)
+			vm.EXPECT().Genesis().Return(g).AnyTimes()
+			blk := &StatelessBlock{
+				StatefulBlock: &StatefulBlock{Hght: 1, Txs: tv.txs()},
+				vm:            vm,
+			}
+			if err := blk.init(); err != nil {
+				t.Fatal(err)
+			}
+			db := memdb.New()
+			if err := SetLastAccepted(db, blk, compress); err != nil {
+				t.Fatal(err)
+			}
+			b, err := db.Get(PrefixBlockKey(blk.ID()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			sizes[compress] = len(b)
+
+			// Stored blocks are read whether or not they are compressed
+			sblk, err := GetBlock(db, blk.ID())
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected, err := Marshal(blk.StatefulBlock)
+			if err != nil {
+				t.Fatal(err)
+			}
+			restored, err := Marshal(sblk)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(restored, expected) {
+				t.Fatalf("%s (compress=%t): stored block doesn't round-trip", tv.name, compress)
+			}
+			db.Close()
+		}
+		if sizes[true] > sizes[false] {
+			t.Fatalf("%s: compressed block is %d bytes (uncompressed is %d)", tv.name, sizes[true], sizes[false])
+		}
+		t.Logf("%s: %d bytes stored (%d compressed)", tv.name, sizes[false], sizes[true])
+	}
+}
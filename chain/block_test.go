@@ -76,14 +76,14 @@ func TestBlock(t *testing.T) {
 						},
 						st: choices.Processing,
 					},
-					int64(futureBound+time.Hour),
+					int64(defaultFutureBound+time.Hour),
 					&Context{NextPrice: 1, NextCost: 1},
 					nil,
 					1,
 				)
 				return blk
 			},
-			expectedVerifyErr: ErrTimestampTooLate,
+			expectedVerifyErr: ErrFutureBlock,
 		},
 		{
 			createBlk: func() *StatelessBlock {
@@ -158,6 +158,50 @@ func TestBlock(t *testing.T) {
 	}
 }
 
+func TestBlockClockDrift(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now().Unix()
+	tt := []struct {
+		drift       int64
+		tmstmp      int64
+		expectedErr error
+	}{
+		{drift: 0, tmstmp: now + 60, expectedErr: ErrFutureBlock},
+		{drift: 120, tmstmp: now + 60, expectedErr: ErrInvalidCost},
+		{drift: 120, tmstmp: now + 180, expectedErr: ErrFutureBlock},
+		{drift: MaxBlockClockDriftLimit, tmstmp: now + 3600, expectedErr: ErrInvalidCost},
+	}
+	for i, tv := range tt {
+		g := DefaultGenesis()
+		g.MaxBlockClockDrift = tv.drift
+		var execCtx *Context
+		if !errors.Is(tv.expectedErr, ErrFutureBlock) {
+			// Fail right after the timestamp checks
+			execCtx = &Context{NextPrice: 1, NextCost: 2}
+		}
+		blk := createTestBlkWithGenesis(
+			t,
+			g,
+			&StatelessBlock{
+				StatefulBlock: &StatefulBlock{
+					Tmstmp: now,
+					Prnt:   ids.GenerateTestID(),
+					Hght:   1, Price: 1, Cost: 1,
+				},
+				st: choices.Accepted,
+			},
+			tv.tmstmp,
+			&Context{NextPrice: 1, NextCost: 1},
+			execCtx,
+			1,
+		)
+		if err := blk.Verify(context.Background()); !errors.Is(err, tv.expectedErr) {
+			t.Fatalf("#%d: block verify expected error %v, got %v", i, tv.expectedErr, err)
+		}
+	}
+}
+
 func createTestBlk(
 	t *testing.T,
 	parentBlk *StatelessBlock,
@@ -168,9 +212,24 @@ func createTestBlk(
 ) *StatelessBlock {
 	t.Helper()
 
+	return createTestBlkWithGenesis(t, DefaultGenesis(), parentBlk, blkTmpstp, blkCtx, execCtx, txsN)
+}
+
+func createTestBlkWithGenesis(
+	t *testing.T,
+	g *Genesis,
+	parentBlk *StatelessBlock,
+	blkTmpstp int64,
+	blkCtx *Context,
+	execCtx *Context,
+	txsN int,
+) *StatelessBlock {
+	t.Helper()
+
 	ctrl := gomock.NewController(t)
 	vm := NewMockVM(ctrl)
-	vm.EXPECT().Genesis().Return(DefaultGenesis()).AnyTimes()
+	vm.EXPECT().Genesis().Return(g).AnyTimes()
+	vm.EXPECT().Now().Return(time.Now()).AnyTimes()
 	parentBlk.vm = vm
 	if err := parentBlk.init(); err != nil {
 		t.Fatal(err)
@@ -4,11 +4,10 @@
 package chain
 
 import (
-	"time"
-
 	"github.com/ava-labs/avalanchego/database/versiondb"
 	"github.com/ava-labs/avalanchego/ids"
 	"github.com/ava-labs/avalanchego/snow/consensus/snowman"
+	"github.com/ethereum/go-ethereum/common"
 	log "github.com/inconshreveable/log15"
 )
 
@@ -16,7 +15,7 @@ func BuildBlock(vm VM, preferred ids.ID) (snowman.Block, error) {
 	g := vm.Genesis()
 
 	log.Debug("attempting block building")
-	nextTime := time.Now().Unix()
+	nextTime := vm.Now().Unix()
 	parent, err := vm.GetStatelessBlock(preferred)
 	if err != nil {
 		log.Debug("block building failed: couldn't get parent", "err", err)
@@ -41,10 +40,11 @@ func BuildBlock(vm VM, preferred ids.ID) (snowman.Block, error) {
 	vdb := versiondb.New(parentDB)
 
 	// Generate access proof from random value
-	b.AccessProof = generateAccessProof(vdb, parent.ID(), b.Hght)
+	b.AccessProof = generateAccessProof(vdb, parent.ID(), b.Hght, g.Upgraded(b.Tmstmp))
 
 	b.Txs = []*Transaction{}
 	units := uint64(0)
+	senderTxs := map[common.Address]uint64{}
 
 	// Restorable txs after block attempt finishes
 	unusableTxs := []*Transaction{}
@@ -61,6 +61,11 @@ func BuildBlock(vm VM, preferred ids.ID) (snowman.Block, error) {
 			log.Debug("skipping tx: too low price", "block price", b.Price, "tx price", price)
 			break
 		}
+		if g.MaxSenderTxsPerBlock > 0 && senderTxs[next.Sender()] >= g.MaxSenderTxsPerBlock {
+			unusableTxs = append(unusableTxs, next)
+			log.Debug("skipping tx: sender at block limit", "sender", next.Sender(), "limit", g.MaxSenderTxsPerBlock)
+			continue
+		}
 		nextLoad := next.LoadUnits(g)
 		if units+nextLoad > g.MaxBlockSize {
 			unusableTxs = append(unusableTxs, next)
@@ -78,6 +83,7 @@ func BuildBlock(vm VM, preferred ids.ID) (snowman.Block, error) {
 		}
 		b.Txs = append(b.Txs, next)
 		units += nextLoad
+		senderTxs[next.Sender()]++
 	}
 	vdb.Abort()
 
@@ -87,7 +93,7 @@ func BuildBlock(vm VM, preferred ids.ID) (snowman.Block, error) {
 	}
 
 	// Verify block to ensure it is formed correctly (don't save)
-	_, _, err = b.verify()
+	_, _, _, err = b.verify()
 	if err != nil {
 		log.Debug("block building failed: failed verification", "err", err)
 		return nil, err
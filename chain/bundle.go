@@ -0,0 +1,77 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Bundle is a self-contained copy of a stored value and the signed tx that set
+// it, so the value's provenance can be verified without the chain (see
+// [VerifyBundle]).
+type Bundle struct {
+	Key       common.Hash `serialize:"true" json:"key"`
+	Value     []byte      `serialize:"true" json:"value"`
+	ValueMeta *ValueMeta  `serialize:"true" json:"valueMeta"`
+
+	// Tx is the signed tx (including its signature) that set [Value]
+	Tx []byte `serialize:"true" json:"tx"`
+}
+
+// VerifyBundle checks that [b.Value] is addressed at [b.Key] under [g] and that
+// [b.Tx] is a validly signed tx (whose ID is [b.ValueMeta.TxID]) that set
+// [b.Value]. It returns the sender of [b.Tx].
+//
+// Only [ValueMeta.TxID], [ValueMeta.Size] and [ValueMeta.Addressing] are
+// covered by the signature: the
+// rest of [b.ValueMeta] (ex: [ValueMeta.Expiry]) is as reported by the node
+// that exported [b].
+func VerifyBundle(g *Genesis, b *Bundle) (common.Address, error) {
+	if b.ValueMeta == nil {
+		return common.Address{}, fmt.Errorf("%w: missing value meta", ErrInvalidBundle)
+	}
+	if k := g.AddressKey(b.ValueMeta.Addressing, b.Value); k != b.Key {
+		return common.Address{}, fmt.Errorf("%w: value is addressed at %s, expected %s", ErrInvalidBundle, k, b.Key)
+	}
+	if b.ValueMeta.Size != uint64(len(b.Value)) {
+		return common.Address{}, fmt.Errorf("%w: value is %d bytes, expected %d", ErrInvalidBundle, len(b.Value), b.ValueMeta.Size)
+	}
+
+	tx := new(Transaction)
+	if _, err := Unmarshal(b.Tx, tx); err != nil {
+		return common.Address{}, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+	// Derives the sender from the signature
+	if err := tx.Init(g); err != nil {
+		return common.Address{}, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+	if tx.ID() != b.ValueMeta.TxID {
+		return common.Address{}, fmt.Errorf("%w: tx is %s, expected %s", ErrInvalidBundle, tx.ID(), b.ValueMeta.TxID)
+	}
+	if !setsValue(tx.UnsignedTransaction, b.ValueMeta.Addressing, b.Value) {
+		return common.Address{}, fmt.Errorf("%w: tx does not set value", ErrInvalidBundle)
+	}
+	return tx.Sender(), nil
+}
+
+// setsValue returns true if [utx] stores [value] under [addressing].
+func setsValue(utx UnsignedTransaction, addressing Addressing, value []byte) bool {
+	switch t := utx.(type) {
+	case *SetTx:
+		return t.Addressing == addressing && bytes.Equal(t.Value, value)
+	case *BatchTx:
+		if addressing != HashAddressing {
+			return false
+		}
+		for _, v := range t.Values {
+			if bytes.Equal(v.Value, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestVerifyBundle(t *testing.T) {
+	t.Parallel()
+
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := DefaultGenesis()
+	tx := createTestTx(t, ids.GenerateTestID(), priv)
+	v := tx.UnsignedTransaction.(*SetTx).Value
+	other := createTestTx(t, ids.GenerateTestID(), priv)
+
+	valid := func() *Bundle {
+		return &Bundle{
+			Key:       g.ValueHash(v),
+			Value:     v,
+			ValueMeta: &ValueMeta{Size: uint64(len(v)), TxID: tx.ID()},
+			Tx:        tx.Bytes(),
+		}
+	}
+	tt := []struct {
+		modify func(b *Bundle)
+		err    error
+	}{
+		{modify: func(b *Bundle) {}},
+		{ // value doesn't match key
+			modify: func(b *Bundle) { b.Value = []byte("b") },
+			err:    ErrInvalidBundle,
+		},
+		{ // size doesn't match value
+			modify: func(b *Bundle) { b.ValueMeta.Size++ },
+			err:    ErrInvalidBundle,
+		},
+		{ // tx isn't the one in the value meta
+			modify: func(b *Bundle) { b.Tx = other.Bytes() },
+			err:    ErrInvalidBundle,
+		},
+		{ // tx doesn't set the value
+			modify: func(b *Bundle) {
+				b.Key, b.Value = g.ValueHash([]byte("b")), []byte("b")
+			},
+			err: ErrInvalidBundle,
+		},
+		{ // tampered signature
+			modify: func(b *Bundle) {
+				b.Tx = append([]byte{}, b.Tx...)
+				b.Tx[len(b.Tx)-2] ^= 0xff
+			},
+			err: ErrInvalidBundle,
+		},
+		{
+			modify: func(b *Bundle) { b.ValueMeta = nil },
+			err:    ErrInvalidBundle,
+		},
+	}
+	for i, tv := range tt {
+		b := valid()
+		tv.modify(b)
+		sender, err := VerifyBundle(g, b)
+		if !errors.Is(err, tv.err) {
+			t.Fatalf("#%d: expected error %v, got %v", i, tv.err, err)
+		}
+		if tv.err == nil && sender != crypto.PubkeyToAddress(priv.PublicKey) {
+			t.Fatalf("#%d: unexpected sender %s", i, sender)
+		}
+	}
+}
@@ -4,35 +4,68 @@
 package chain
 
 import (
+	"reflect"
+
 	"github.com/ava-labs/avalanchego/codec"
 	"github.com/ava-labs/avalanchego/codec/linearcodec"
+	"github.com/ava-labs/avalanchego/codec/reflectcodec"
 	"github.com/ava-labs/avalanchego/utils/units"
 	"github.com/ava-labs/avalanchego/utils/wrappers"
 )
 
 const (
 	// codecVersion is the current default codec version
-	codecVersion = 0
+	codecVersion = 1
+
+	// legacyCodecVersion is the codec used before [Genesis.UpgradeTime]. It
+	// only serializes the fields tagged with [legacyTagName] (the fields that
+	// existed when the chain launched), so data written before the upgrade
+	// keeps decoding (and re-encoding) to the same bytes.
+	legacyCodecVersion = 0
+	legacyTagName      = "serializeV0"
 
 	// maxSize is 4MB to support large values
 	maxSize = 4 * units.MiB
+
+	// maxSliceLen matches [linearcodec.NewDefault]
+	maxSliceLen = 256 * 1024
 )
 
-var codecManager codec.Manager
+var (
+	codecManager codec.Manager
+
+	// legacyTypes are the types registered in the legacy codec (the only
+	// types that can be encoded in an interface by it)
+	legacyTypes = map[reflect.Type]bool{}
+)
 
 func init() {
-	c := linearcodec.NewDefault()
 	codecManager = codec.NewManager(maxSize)
+
+	// The types registered in both codecs keep their (launch) type IDs, new
+	// types are only registered in the current codec
+	lc := linearcodec.New([]string{legacyTagName}, maxSliceLen)
+	c := linearcodec.New([]string{reflectcodec.DefaultTagName}, maxSliceLen)
 	errs := wrappers.Errs{}
+	for _, typ := range []interface{}{
+		&BaseTx{},
+		&SetTx{},
+		&TransferTx{},
+		&Transaction{},
+		&StatefulBlock{},
+		&CustomAllocation{},
+		&Airdrop{},
+		&Genesis{},
+	} {
+		errs.Add(lc.RegisterType(typ), c.RegisterType(typ))
+		legacyTypes[reflect.TypeOf(typ)] = true
+	}
 	errs.Add(
-		c.RegisterType(&BaseTx{}),
-		c.RegisterType(&SetTx{}),
-		c.RegisterType(&TransferTx{}),
-		c.RegisterType(&Transaction{}),
-		c.RegisterType(&StatefulBlock{}),
-		c.RegisterType(&CustomAllocation{}),
-		c.RegisterType(&Airdrop{}),
-		c.RegisterType(&Genesis{}),
+		c.RegisterType(&RenewTx{}),
+		c.RegisterType(&PinTx{}),
+		c.RegisterType(&UnpinTx{}),
+		c.RegisterType(&BatchTx{}),
+		codecManager.RegisterCodec(legacyCodecVersion, lc),
 		codecManager.RegisterCodec(codecVersion, c),
 	)
 	if errs.Errored() {
@@ -40,10 +73,76 @@ func init() {
 	}
 }
 
+// Marshal encodes [source] with the legacy codec if it can represent
+// [source] (see [legacyEncodable]), and with the current codec otherwise.
+// Decoding and re-encoding data therefore always yields the same bytes (and
+// the same IDs) regardless of when it was written.
 func Marshal(source interface{}) ([]byte, error) {
+	if legacyEncodable(reflect.ValueOf(source)) {
+		return codecManager.Marshal(legacyCodecVersion, source)
+	}
 	return codecManager.Marshal(codecVersion, source)
 }
 
+// marshalVersion encodes [source] with the codec [version], whether or not an
+// older codec could represent it.
+func marshalVersion(version uint16, source interface{}) ([]byte, error) {
+	return codecManager.Marshal(version, source)
+}
+
 func Unmarshal(source []byte, destination interface{}) (uint16, error) {
 	return codecManager.Unmarshal(source, destination)
 }
+
+// legacyEncodable returns true if the legacy codec encodes [v] without
+// dropping anything: every field only serialized by the current codec is zero
+// and every value held in an interface is of a [legacyTypes] type. Structs
+// without any [legacyTagName] fields didn't exist before the upgrade, so they
+// are never legacy encodable.
+func legacyEncodable(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return true
+		}
+		return legacyTypes[v.Elem().Type()] && legacyEncodable(v.Elem())
+	case reflect.Ptr:
+		if v.IsNil() {
+			return true
+		}
+		return legacyEncodable(v.Elem())
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return true
+		}
+		for i := 0; i < v.Len(); i++ {
+			if !legacyEncodable(v.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Struct:
+		t := v.Type()
+		legacyFields := 0
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			switch {
+			case f.Tag.Get(reflectcodec.DefaultTagName) != "true":
+				continue
+			case f.Tag.Get(legacyTagName) != "true":
+				// The codec doesn't distinguish nil and empty slices
+				if fv := v.Field(i); !fv.IsZero() && (fv.Kind() != reflect.Slice || fv.Len() > 0) {
+					return false
+				}
+			default:
+				legacyFields++
+				if !legacyEncodable(v.Field(i)) {
+					return false
+				}
+			}
+		}
+		return legacyFields > 0
+	default:
+		return v.IsValid()
+	}
+}
@@ -0,0 +1,395 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/database/memdb"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/snow/choices"
+	"github.com/ava-labs/avalanchego/utils/wrappers"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	gomock "github.com/golang/mock/gomock"
+)
+
+func TestLegacyValueMeta(t *testing.T) {
+	t.Parallel()
+
+	db := memdb.New()
+	defer db.Close()
+
+	// A [ValueMeta] as stored at launch
+	txID := ids.GenerateTestID()
+	p := wrappers.Packer{MaxSize: 64}
+	p.PackShort(legacyCodecVersion)
+	p.PackLong(10)
+	p.PackFixedBytes(txID[:])
+	p.PackLong(20)
+	if p.Errored() {
+		t.Fatal(p.Err)
+	}
+	launch := p.Bytes
+	// Selected by every seed
+	k := common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff")
+	if err := db.Put(ValueKey(k), launch); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &ValueMeta{Size: 10, TxID: txID, Created: 20}
+	check := func() {
+		t.Helper()
+		vmeta, exists, err := GetValueMeta(db, k)
+		if err != nil || !exists {
+			t.Fatalf("unexpected value meta (exists=%t): %v", exists, err)
+		}
+		if len(vmeta.Tags) == 0 {
+			// The codec doesn't distinguish nil and empty tags
+			vmeta.Tags = nil
+		}
+		if !reflect.DeepEqual(vmeta, expected) {
+			t.Fatalf("expected %+v, got %+v", expected, vmeta)
+		}
+		// Access proofs before the upgrade are computed over the launch bytes
+		if v := SelectRandomValue(db, []byte("seed"), false); !bytes.Equal(v, launch) {
+			t.Fatalf("expected launch encoding %x, got %x", launch, v)
+		}
+	}
+	check()
+
+	migrated, err := MigrateValueMetas(db, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if migrated != 1 {
+		t.Fatalf("expected 1 migrated value meta, got %d", migrated)
+	}
+	if b := mustGet(t, db, ValueKey(k)); !bytes.HasPrefix(b, []byte{0, codecVersion}) {
+		t.Fatalf("value meta not migrated to the current codec: %x", b)
+	}
+	check()
+	if migrated, err := MigrateValueMetas(db, false); err != nil || migrated != 0 {
+		t.Fatalf("expected nothing to migrate, got %d (err=%v)", migrated, err)
+	}
+
+	// Switching encodings migrates again
+	if migrated, err := MigrateValueMetas(db, true); err != nil || migrated != 1 {
+		t.Fatalf("expected 1 migrated value meta, got %d (err=%v)", migrated, err)
+	}
+	if b := mustGet(t, db, ValueKey(k)); b[0] != compactValueMetaVersion {
+		t.Fatalf("value meta not migrated to the compact encoding: %x", b)
+	}
+	check()
+}
+
+func TestValueMetaFormats(t *testing.T) {
+	t.Parallel()
+
+	txID := ids.GenerateTestID()
+	tt := []struct {
+		name  string
+		vmeta *ValueMeta
+	}{
+		{
+			name:  "tags",
+			vmeta: &ValueMeta{Size: 1, TxID: txID, Created: 2, Expiry: 3, Tags: []*Tag{{Key: "k", Value: "v"}}},
+		},
+		{
+			name:  "pinned",
+			vmeta: &ValueMeta{Size: 1, TxID: txID, Created: 2, Pinned: true, PinnedBy: common.Address{1}},
+		},
+		{
+			name:  "batched",
+			vmeta: &ValueMeta{Size: 1, TxID: txID, Created: 2, Batched: true},
+		},
+		{
+			name:  "addressing",
+			vmeta: &ValueMeta{Size: 1, TxID: txID, Created: 2, Addressing: CIDv1},
+		},
+	}
+	for _, tv := range tt {
+		// Metas using fields added after launch can't be encoded by the legacy
+		// codec
+		if legacyEncodable(reflect.ValueOf(tv.vmeta)) {
+			t.Fatalf("%s: expected %+v to need the current codec", tv.name, tv.vmeta)
+		}
+		b, err := Marshal(tv.vmeta)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(b, []byte{0, codecVersion}) {
+			t.Fatalf("%s: expected codec v%d, got %x", tv.name, codecVersion, b[:2])
+		}
+
+		db := memdb.New()
+		k := common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff")
+		if err := db.Put(ValueKey(k), b); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := MigrateValueMetas(db, false); err != nil {
+			t.Fatal(err)
+		}
+		vmeta, exists, err := GetValueMeta(db, k)
+		if err != nil || !exists {
+			t.Fatalf("%s: unexpected value meta (exists=%t): %v", tv.name, exists, err)
+		}
+		if len(vmeta.Tags) == 0 {
+			vmeta.Tags = tv.vmeta.Tags
+		}
+		if !reflect.DeepEqual(vmeta, tv.vmeta) {
+			t.Fatalf("%s: expected %+v, got %+v", tv.name, tv.vmeta, vmeta)
+		}
+
+		// Before the upgrade, access proofs only cover the launch fields
+		legacy, err := marshalVersion(legacyCodecVersion, &ValueMeta{Size: vmeta.Size, TxID: vmeta.TxID, Created: vmeta.Created})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v := SelectRandomValue(db, []byte("seed"), false); !bytes.Equal(v, legacy) {
+			t.Fatalf("%s: expected launch encoding %x, got %x", tv.name, legacy, v)
+		}
+		if v := SelectRandomValue(db, []byte("seed"), true); !bytes.Equal(v, b) {
+			t.Fatalf("%s: expected current encoding %x, got %x", tv.name, b, v)
+		}
+		db.Close()
+	}
+}
+
+func TestMarshalVersion(t *testing.T) {
+	t.Parallel()
+
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tt := []struct {
+		utx     UnsignedTransaction
+		version uint16
+	}{
+		{
+			utx:     &SetTx{BaseTx: &BaseTx{Price: 1}, Value: []byte("launch")},
+			version: legacyCodecVersion,
+		},
+		{
+			utx:     &SetTx{BaseTx: &BaseTx{Price: 1}, Value: []byte("launch"), Tags: []*Tag{}},
+			version: legacyCodecVersion,
+		},
+		{
+			utx:     &TransferTx{BaseTx: &BaseTx{Price: 1}, To: common.Address{1}, Units: 1},
+			version: legacyCodecVersion,
+		},
+		{
+			utx:     &SetTx{BaseTx: &BaseTx{Price: 1}, Value: []byte("tagged"), Tags: []*Tag{{Key: "k", Value: "v"}}},
+			version: codecVersion,
+		},
+		{
+			utx:     &RenewTx{BaseTx: &BaseTx{Price: 1}, Key: common.Hash{1}, Extension: 1},
+			version: codecVersion,
+		},
+	}
+	for i, tv := range tt {
+		tx := createTestSignedTx(t, DefaultGenesis(), priv, tv.utx)
+		if b := tx.Bytes(); !bytes.HasPrefix(b, []byte{0, byte(tv.version)}) {
+			t.Fatalf("#%d: expected codec v%d, got %x", i, tv.version, b[:2])
+		}
+		// Decoding and re-encoding yields the same bytes (and ID)
+		dtx, err := DecodeTx(tx.Bytes())
+		if err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+		if dtx.ID() != tx.ID() || dtx.Sender() != tx.Sender() {
+			t.Fatalf("#%d: decoded tx %s (sender %s), expected %s (sender %s)", i, dtx.ID(), dtx.Sender(), tx.ID(), tx.Sender())
+		}
+		// Legacy SetTxs are signed over their launch typed data
+		if stx, ok := tv.utx.(*SetTx); ok {
+			_, hasTTL := stx.TypedData().Message[tdTTL]
+			if legacy := tv.version == legacyCodecVersion; hasTTL == legacy {
+				t.Fatalf("#%d: typed data has ttl=%t (legacy=%t)", i, hasTTL, legacy)
+			}
+		}
+	}
+}
+
+func TestLegacyGossip(t *testing.T) {
+	t.Parallel()
+
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := createTestSignedTx(t, DefaultGenesis(), priv, &SetTx{
+		BaseTx: &BaseTx{BlockID: ids.ID{1}, Magic: 2, Price: 3},
+		Value:  []byte("gossip"),
+	})
+
+	// Txs as gossiped by a node that hasn't upgraded (SetTx is type 1)
+	p := wrappers.Packer{MaxSize: 1024}
+	p.PackShort(legacyCodecVersion)
+	p.PackInt(1)
+	p.PackInt(1)
+	blkID := ids.ID{1}
+	p.PackFixedBytes(blkID[:])
+	p.PackLong(2)
+	p.PackLong(3)
+	p.PackBytes([]byte("gossip"))
+	p.PackBytes(tx.Signature)
+	if p.Errored() {
+		t.Fatal(p.Err)
+	}
+	b, err := Marshal([]*Transaction{tx})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, p.Bytes) {
+		t.Fatalf("expected launch gossip encoding %x, got %x", p.Bytes, b)
+	}
+	txs := []*Transaction{}
+	if _, err := Unmarshal(p.Bytes, &txs); err != nil {
+		t.Fatal(err)
+	}
+	if len(txs) != 1 {
+		t.Fatalf("expected 1 tx, got %d", len(txs))
+	}
+	if err := txs[0].Init(DefaultGenesis()); err != nil {
+		t.Fatal(err)
+	}
+	if txs[0].ID() != tx.ID() || txs[0].Sender() != tx.Sender() {
+		t.Fatalf("decoded tx %s (sender %s), expected %s (sender %s)", txs[0].ID(), txs[0].Sender(), tx.ID(), tx.Sender())
+	}
+
+	// Txs added after launch can't be gossiped to nodes that haven't upgraded
+	batch := createTestSignedTx(t, DefaultGenesis(), priv, &BatchTx{
+		BaseTx: &BaseTx{Price: 1},
+		Values: []*BatchValue{{Value: []byte("batched")}},
+	})
+	if _, err := marshalVersion(legacyCodecVersion, []*Transaction{batch}); err == nil {
+		t.Fatal("expected the legacy codec to reject a BatchTx")
+	}
+	if b, err := Marshal([]*Transaction{tx, batch}); err != nil || !bytes.HasPrefix(b, []byte{0, codecVersion}) {
+		t.Fatalf("expected mixed gossip to use codec v%d (err=%v)", codecVersion, err)
+	}
+}
+
+func TestNonCanonicalBlock(t *testing.T) {
+	t.Parallel()
+
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := DefaultGenesis()
+	blk := &StatefulBlock{
+		Prnt:   ids.ID{1},
+		Tmstmp: 10,
+		Hght:   1,
+		Price:  1,
+		Cost:   1,
+		Txs: []*Transaction{createTestSignedTx(t, g, priv, &SetTx{
+			BaseTx: &BaseTx{BlockID: ids.ID{1}, Price: 1},
+			Value:  []byte("legacy"),
+		})},
+	}
+	canonical, err := Marshal(blk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(canonical, []byte{0, legacyCodecVersion}) {
+		t.Fatalf("expected codec v%d, got %x", legacyCodecVersion, canonical[:2])
+	}
+	id, _, err := DecodeBlock(canonical)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctrl := gomock.NewController(t)
+	vm := NewMockVM(ctrl)
+	vm.EXPECT().Genesis().Return(g).AnyTimes()
+	sblk, err := ParseBlock(canonical, choices.Processing, vm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sblk.ID() != id {
+		t.Fatalf("parsed block %s, decoded %s", sblk.ID(), id)
+	}
+
+	// The same block encoded with the current codec would hash to another ID
+	upgraded, err := marshalVersion(codecVersion, blk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := DecodeBlock(upgraded); !errors.Is(err, ErrNonCanonicalBlock) {
+		t.Fatalf("expected %v, got %v", ErrNonCanonicalBlock, err)
+	}
+	if _, err := ParseBlock(upgraded, choices.Processing, vm); !errors.Is(err, ErrNonCanonicalBlock) {
+		t.Fatalf("expected %v, got %v", ErrNonCanonicalBlock, err)
+	}
+}
+
+func TestUpgradeTime(t *testing.T) {
+	t.Parallel()
+
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := DefaultGenesis()
+	upgradeTime := uint64(10)
+	g.UpgradeTime = &upgradeTime
+	g.CustomAllocation = []*CustomAllocation{{Address: crypto.PubkeyToAddress(priv.PublicKey), Balance: 10000000}}
+
+	legacy := createTestSignedTx(t, DefaultGenesis(), priv, &SetTx{
+		BaseTx: &BaseTx{BlockID: ids.ID{0, 1}, Price: 10},
+		Value:  []byte("legacy"),
+	})
+	tagged := createTestSignedTx(t, DefaultGenesis(), priv, &SetTx{
+		BaseTx: &BaseTx{BlockID: ids.ID{0, 1}, Price: 10},
+		Value:  []byte("tagged"),
+		Tags:   []*Tag{{Key: "k", Value: "v"}},
+	})
+	pin := createTestSignedTx(t, DefaultGenesis(), priv, &PinTx{
+		BaseTx: &BaseTx{BlockID: ids.ID{0, 1}, Price: 10},
+		Key:    common.Hash{1},
+	})
+	batch := createTestSignedTx(t, DefaultGenesis(), priv, &BatchTx{
+		BaseTx: &BaseTx{BlockID: ids.ID{0, 1}, Price: 10},
+		Values: []*BatchValue{{Value: []byte("batched")}},
+	})
+	cid := createTestSignedTx(t, DefaultGenesis(), priv, &SetTx{
+		BaseTx:     &BaseTx{BlockID: ids.ID{0, 1}, Price: 10},
+		Value:      []byte("cid"),
+		Addressing: CIDv1,
+	})
+	tt := []struct {
+		genesisUpgrade *uint64
+		tx             *Transaction
+		blockTime      int64
+		executeErr     error
+	}{
+		{genesisUpgrade: &upgradeTime, tx: legacy, blockTime: 9},
+		{genesisUpgrade: &upgradeTime, tx: tagged, blockTime: 9, executeErr: ErrUpgradeNotActive},
+		{genesisUpgrade: &upgradeTime, tx: tagged, blockTime: 10},
+		{genesisUpgrade: &upgradeTime, tx: pin, blockTime: 9, executeErr: ErrUpgradeNotActive},
+		{genesisUpgrade: &upgradeTime, tx: batch, blockTime: 9, executeErr: ErrUpgradeNotActive},
+		{genesisUpgrade: &upgradeTime, tx: cid, blockTime: 9, executeErr: ErrUpgradeNotActive},
+		// Chains created before the upgrade existed stay on the launch formats
+		{tx: legacy, blockTime: 100},
+		{tx: tagged, blockTime: 100, executeErr: ErrUpgradeNotActive},
+	}
+	for i, tv := range tt {
+		db := memdb.New()
+		g.UpgradeTime = tv.genesisUpgrade
+		if err := g.Load(db, nil); err != nil {
+			t.Fatal(err)
+		}
+		ctx := &Context{RecentBlockIDs: ids.Set{{0, 1}: struct{}{}}}
+		err := tv.tx.Execute(g, db, DummyBlock(tv.blockTime, tv.tx), ctx)
+		if !errors.Is(err, tv.executeErr) {
+			t.Fatalf("#%d: unexpected tx.Execute error %v, expected %v", i, err, tv.executeErr)
+		}
+		db.Close()
+	}
+}
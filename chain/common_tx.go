@@ -4,6 +4,8 @@
 package chain
 
 import (
+	"math"
+	"math/bits"
 	"strings"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -16,10 +18,34 @@ func valueUnits(g *Genesis, size uint64) uint64 {
 	return size/g.ValueUnitSize + 1
 }
 
+// ttlUnits scales [units] by [ttl] relative to [DefaultValueTTL] (rounding
+// up). If [DefaultValueTTL] is 0, [units] is returned as-is.
+func ttlUnits(g *Genesis, units uint64, ttl uint64) uint64 {
+	if g.DefaultValueTTL == 0 || ttl == 0 {
+		return units
+	}
+	hi, lo := bits.Mul64(units, ttl)
+	if hi >= g.DefaultValueTTL {
+		// Quotient would overflow
+		return math.MaxUint64
+	}
+	quo, rem := bits.Div64(hi, lo, g.DefaultValueTTL)
+	if rem > 0 && quo < math.MaxUint64 {
+		quo++
+	}
+	return quo
+}
+
 func ValueHash(v []byte) common.Hash {
 	return common.BytesToHash(crypto.Keccak256(v))
 }
 
+// DomainValueHash returns keccak([sep] || [v]). An empty [sep] is equivalent
+// to [ValueHash].
+func DomainValueHash(sep []byte, v []byte) common.Hash {
+	return common.BytesToHash(crypto.Keccak256(sep, v))
+}
+
 func ValueHashString(v []byte) string {
 	return strings.ToLower(ValueHash(v).Hex())
 }
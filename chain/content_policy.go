@@ -0,0 +1,40 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"bytes"
+	"unicode/utf8"
+)
+
+// ContentPolicy restricts the values that can be stored. The zero value
+// allows everything. If any rule is set, a value must satisfy at least one of
+// them (i.e. a policy with [UTF8] and an image [AllowedPrefixes] allows text
+// and images).
+//
+// Note that file [Root]s uploaded by the tree package are JSON, so chunked
+// files can only be stored on chains where [UTF8] is set.
+type ContentPolicy struct {
+	// UTF8 allows values that are valid UTF-8
+	UTF8 bool `serialize:"true" json:"utf8,omitempty"`
+	// AllowedPrefixes allows values starting with any of these byte strings
+	// (ex: file magic numbers like "\x89PNG")
+	AllowedPrefixes [][]byte `serialize:"true" json:"allowedPrefixes,omitempty"`
+}
+
+// Allows returns true if [v] can be stored under [p].
+func (p *ContentPolicy) Allows(v []byte) bool {
+	if !p.UTF8 && len(p.AllowedPrefixes) == 0 {
+		return true
+	}
+	if p.UTF8 && utf8.Valid(v) {
+		return true
+	}
+	for _, prefix := range p.AllowedPrefixes {
+		if bytes.HasPrefix(v, prefix) {
+			return true
+		}
+	}
+	return false
+}
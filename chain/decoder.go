@@ -4,6 +4,7 @@
 package chain
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
 
@@ -17,29 +18,71 @@ import (
 const (
 	Set      = "set"
 	Transfer = "transfer"
+	Renew    = "renew"
+	Pin      = "pin"
+	Unpin    = "unpin"
+	Batch    = "batch"
 )
 
 type Input struct {
-	Typ   string         `json:"type"`
-	Key   string         `json:"key"`
-	Value []byte         `json:"value"`
-	To    common.Address `json:"to"`
-	Units uint64         `json:"units"`
+	Typ        string         `json:"type"`
+	Key        string         `json:"key"`
+	Value      []byte         `json:"value"`
+	TTL        uint64         `json:"ttl"`
+	Tags       []*Tag         `json:"tags"`
+	Addressing Addressing     `json:"addressing"`
+	To         common.Address `json:"to"`
+	Units      uint64         `json:"units"`
+
+	Extension uint64 `json:"extension"`
+
+	Values []*BatchValue `json:"values"`
 }
 
 func (i *Input) Decode() (UnsignedTransaction, error) {
 	switch i.Typ {
 	case Set:
-		return &SetTx{
+		tx := &SetTx{
 			BaseTx: &BaseTx{},
 			Value:  i.Value,
-		}, nil
+			TTL:    i.TTL,
+			Tags:   i.Tags,
+
+			Addressing: i.Addressing,
+		}
+		// Hash-addressed inputs used to send (and ignore) [Key], so it is only
+		// an override for other addressings
+		if i.Addressing != HashAddressing {
+			tx.Key = common.HexToHash(i.Key)
+		}
+		return tx, nil
 	case Transfer:
 		return &TransferTx{
 			BaseTx: &BaseTx{},
 			To:     i.To,
 			Units:  i.Units,
 		}, nil
+	case Renew:
+		return &RenewTx{
+			BaseTx:    &BaseTx{},
+			Key:       common.HexToHash(i.Key),
+			Extension: i.Extension,
+		}, nil
+	case Pin:
+		return &PinTx{
+			BaseTx: &BaseTx{},
+			Key:    common.HexToHash(i.Key),
+		}, nil
+	case Unpin:
+		return &UnpinTx{
+			BaseTx: &BaseTx{},
+			Key:    common.HexToHash(i.Key),
+		}, nil
+	case Batch:
+		return &BatchTx{
+			BaseTx: &BaseTx{},
+			Values: i.Values,
+		}, nil
 	default:
 		return nil, ErrInvalidType
 	}
@@ -55,8 +98,18 @@ const (
 	tdPrice   = "price"
 
 	tdValue = "value"
+	tdTTL   = "ttl"
+	tdTags  = "tags"
 	tdUnits = "units"
 	tdTo    = "to"
+
+	tdAddressing = "addressing"
+
+	tdKey       = "key"
+	tdExtension = "extension"
+
+	tdValues = "values"
+	tdTTLs   = "ttls"
 )
 
 func parseUint64Message(td *tdata.TypedData, k string) (uint64, error) {
@@ -67,6 +120,80 @@ func parseUint64Message(td *tdata.TypedData, k string) (uint64, error) {
 	return strconv.ParseUint(r, 10, 64)
 }
 
+// parseTagsMessage decodes the JSON-encoded tags stored at [k].
+func parseTagsMessage(td *tdata.TypedData, k string) ([]*Tag, error) {
+	r, ok := td.Message[k].(string)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTypedDataKeyMissing, k)
+	}
+	var tags []*Tag
+	if err := json.Unmarshal([]byte(r), &tags); err != nil {
+		return nil, err
+	}
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	return tags, nil
+}
+
+// parseArrayMessage returns the strings in the array stored at [k].
+func parseArrayMessage(td *tdata.TypedData, k string) ([]string, error) {
+	r, ok := td.Message[k].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTypedDataKeyMissing, k)
+	}
+	items := make([]string, len(r))
+	for i, ri := range r {
+		item, ok := ri.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s[%d] is not a string", ErrInvalidBatch, k, i)
+		}
+		items[i] = item
+	}
+	return items, nil
+}
+
+func parseBatchValues(td *tdata.TypedData) ([]*BatchValue, error) {
+	rvalues, err := parseArrayMessage(td, tdValues)
+	if err != nil {
+		return nil, err
+	}
+	rttls, err := parseArrayMessage(td, tdTTLs)
+	if err != nil {
+		return nil, err
+	}
+	rtags, err := parseArrayMessage(td, tdTags)
+	if err != nil {
+		return nil, err
+	}
+	if len(rttls) != len(rvalues) || len(rtags) != len(rvalues) {
+		return nil, fmt.Errorf(
+			"%w: %d values, %d ttls, and %d tags",
+			ErrInvalidBatch, len(rvalues), len(rttls), len(rtags),
+		)
+	}
+	values := make([]*BatchValue, len(rvalues))
+	for i := range rvalues {
+		value, err := hexutil.Decode(rvalues[i])
+		if err != nil {
+			return nil, err
+		}
+		ttl, err := strconv.ParseUint(rttls[i], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		var tags []*Tag
+		if err := json.Unmarshal([]byte(rtags[i]), &tags); err != nil {
+			return nil, err
+		}
+		if len(tags) == 0 {
+			tags = nil
+		}
+		values[i] = &BatchValue{Value: value, TTL: ttl, Tags: tags}
+	}
+	return values, nil
+}
+
 func parseBaseTx(td *tdata.TypedData) (*BaseTx, error) {
 	rblockID, ok := td.Message[tdBlockID].(string)
 	if !ok {
@@ -103,7 +230,35 @@ func ParseTypedData(td *tdata.TypedData) (UnsignedTransaction, error) {
 		if err != nil {
 			return nil, err
 		}
-		return &SetTx{BaseTx: bTx, Value: value}, nil
+		if _, ok := td.Message[tdTTL]; !ok {
+			// The typed data of legacy SetTxs only has their value (see
+			// [SetTx.TypedData])
+			return &SetTx{BaseTx: bTx, Value: value}, nil
+		}
+		ttl, err := parseUint64Message(td, tdTTL)
+		if err != nil {
+			return nil, err
+		}
+		tags, err := parseTagsMessage(td, tdTags)
+		if err != nil {
+			return nil, err
+		}
+		addressing, ok := td.Message[tdAddressing].(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrTypedDataKeyMissing, tdAddressing)
+		}
+		key, ok := td.Message[tdKey].(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrTypedDataKeyMissing, tdKey)
+		}
+		return &SetTx{
+			BaseTx:     bTx,
+			Value:      value,
+			TTL:        ttl,
+			Tags:       tags,
+			Addressing: Addressing(addressing),
+			Key:        common.HexToHash(key),
+		}, nil
 	case Transfer:
 		to, ok := td.Message[tdTo].(string)
 		if !ok {
@@ -114,6 +269,34 @@ func ParseTypedData(td *tdata.TypedData) (UnsignedTransaction, error) {
 			return nil, err
 		}
 		return &TransferTx{BaseTx: bTx, To: common.HexToAddress(to), Units: units}, nil
+	case Renew:
+		key, ok := td.Message[tdKey].(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrTypedDataKeyMissing, tdKey)
+		}
+		extension, err := parseUint64Message(td, tdExtension)
+		if err != nil {
+			return nil, err
+		}
+		return &RenewTx{BaseTx: bTx, Key: common.HexToHash(key), Extension: extension}, nil
+	case Pin:
+		key, ok := td.Message[tdKey].(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrTypedDataKeyMissing, tdKey)
+		}
+		return &PinTx{BaseTx: bTx, Key: common.HexToHash(key)}, nil
+	case Unpin:
+		key, ok := td.Message[tdKey].(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrTypedDataKeyMissing, tdKey)
+		}
+		return &UnpinTx{BaseTx: bTx, Key: common.HexToHash(key)}, nil
+	case Batch:
+		values, err := parseBatchValues(td)
+		if err != nil {
+			return nil, err
+		}
+		return &BatchTx{BaseTx: bTx, Values: values}, nil
 	default:
 		return nil, ErrInvalidType
 	}
@@ -5,39 +5,76 @@ package chain
 
 import (
 	"errors"
+	"fmt"
 )
 
 var (
 	// Genesis Correctness
-	ErrInvalidMagic     = errors.New("invalid magic")
-	ErrInvalidBlockRate = errors.New("invalid block rate")
+	ErrInvalidMagic       = errors.New("invalid magic")
+	ErrInvalidBlockRate   = errors.New("invalid block rate")
+	ErrInvalidClockDrift  = errors.New("invalid clock drift")
+	ErrTooManyAllocations = errors.New("too many allocations")
+	ErrInvalidUnitSize    = errors.New("invalid value unit size")
+	ErrInvalidValueSize   = errors.New("invalid max value size")
+	ErrInvalidBlockSize   = errors.New("invalid block size")
+	ErrInvalidMinPrice    = errors.New("invalid min price")
+	ErrInvalidLookback    = errors.New("invalid lookback window")
+
+	ErrUnsupportedAddressing = errors.New("unsupported addressing")
 
 	// Block Correctness
 	ErrTimestampTooEarly      = errors.New("block timestamp too early")
 	ErrTimestampTooLate       = errors.New("block timestamp too late")
+	ErrFutureBlock            = fmt.Errorf("%w: too far in the future", ErrTimestampTooLate)
 	ErrNoTxs                  = errors.New("no transactions")
 	ErrInvalidCost            = errors.New("invalid block cost")
 	ErrInvalidPrice           = errors.New("invalid price")
 	ErrInsufficientSurplus    = errors.New("insufficient surplus fee")
 	ErrParentBlockNotVerified = errors.New("parent block not verified or accepted")
 	ErrInvalidAccessProof     = errors.New("invalid access proof")
+	ErrNonCanonicalBlock      = errors.New("non-canonical block encoding")
 
 	// Tx Correctness
+	ErrInvalidTx           = errors.New("invalid tx")
 	ErrInvalidBlockID      = errors.New("invalid blockID")
 	ErrInvalidSignature    = errors.New("invalid signature")
 	ErrDuplicateTx         = errors.New("duplicate transaction")
 	ErrInsufficientPrice   = errors.New("insufficient price")
 	ErrInvalidType         = errors.New("invalid tx type")
 	ErrTypedDataKeyMissing = errors.New("typed data key missing")
+	ErrSenderNotAllowed    = errors.New("sender is not allowed")
+	ErrInvalidBatch        = errors.New("invalid batch")
 
 	// Execution Correctness
-	ErrValueEmpty     = errors.New("value empty")
-	ErrValueTooBig    = errors.New("value too big")
-	ErrKeyMissing     = errors.New("key missing")
-	ErrInvalidKey     = errors.New("key is invalid")
-	ErrKeyExists      = errors.New("key already exists")
-	ErrUnauthorized   = errors.New("sender is not authorized")
-	ErrInvalidBalance = errors.New("invalid balance")
-	ErrNonActionable  = errors.New("transaction doesn't do anything")
-	ErrBlockTooBig    = errors.New("block too big")
+	ErrValueEmpty      = errors.New("value empty")
+	ErrValueTooBig     = errors.New("value too big")
+	ErrContentRejected = errors.New("value rejected by content policy")
+	ErrKeyMissing      = errors.New("key missing")
+	ErrInvalidKey      = errors.New("key is invalid")
+	ErrKeyExists       = errors.New("key already exists")
+	ErrInvalidTTL      = errors.New("invalid ttl")
+	ErrExpired         = errors.New("value expired")
+	ErrTooManyTags     = errors.New("too many tags")
+	ErrTagsTooBig      = errors.New("tags too big")
+	ErrInvalidTag      = errors.New("invalid tag")
+	ErrUnauthorized    = errors.New("sender is not authorized")
+	ErrInvalidBalance  = errors.New("invalid balance")
+	ErrNonActionable   = errors.New("transaction doesn't do anything")
+	ErrBlockTooBig     = errors.New("block too big")
+	ErrBatchEmpty      = errors.New("batch empty")
+	ErrBatchTooBig     = errors.New("batch too big to fit in a block")
+
+	ErrAddressingNotAllowed = errors.New("addressing is not allowed")
+	ErrInvalidCID           = errors.New("invalid cid")
+
+	ErrTransferTooSmall = errors.New("transfer is less than the minimum")
+
+	ErrUpgradeNotActive = errors.New("tx needs the network upgrade")
+
+	// Bundle Correctness
+	ErrInvalidBundle = errors.New("invalid bundle")
+
+	// Storage Correctness
+	ErrInvalidValueMeta   = errors.New("invalid value meta")
+	ErrInvalidStoredBlock = errors.New("invalid stored block")
 )
@@ -6,6 +6,9 @@ package chain
 import (
 	"encoding/json"
 	"fmt"
+	"math"
+	"math/bits"
+	"sync"
 	"time"
 
 	"github.com/ava-labs/avalanchego/database"
@@ -20,22 +23,33 @@ const (
 	MinBlockCost          = 0
 	DefaultValueUnitSize  = 1 * units.KiB
 	DefaultLookbackWindow = 60
+	DefaultMaxAllocations = 5_000_000
 )
 
 type Airdrop struct {
 	// Address strings are hex-formatted common.Address
-	Address common.Address `serialize:"true" json:"address"`
+	Address common.Address `serialize:"true" serializeV0:"true" json:"address"`
 }
 
 type CustomAllocation struct {
 	// Address strings are hex-formatted common.Address
-	Address common.Address `serialize:"true" json:"address"`
-	Balance uint64         `serialize:"true" json:"balance"`
+	Address common.Address `serialize:"true" serializeV0:"true" json:"address"`
+	Balance uint64         `serialize:"true" serializeV0:"true" json:"balance"`
 }
 
 type Genesis struct {
 	Magic uint64 `serialize:"true" json:"magic"`
 
+	// UpgradeTime is the unix timestamp (seconds) from which blocks can
+	// include txs only the current codec can encode (ex: values with a TTL,
+	// tags, or pins) and [AccessProof]s cover the current encoding of a
+	// [ValueMeta]. Earlier blocks are encoded and verified as at launch.
+	// Chains created before it existed don't set it (their genesis can't
+	// change), so they keep the launch formats until the upgrade is scheduled
+	// for them in [UpgradeTimes]. Genesis is only ever JSON encoded, so it
+	// isn't tagged for the codec.
+	UpgradeTime *uint64 `json:"upgradeTime,omitempty"`
+
 	// Tx params
 	BaseTxUnits uint64 `serialize:"true" json:"baseTxUnits"`
 
@@ -43,6 +57,38 @@ type Genesis struct {
 	ValueUnitSize uint64 `serialize:"true" json:"valueUnitSize"`
 	MaxValueSize  uint64 `serialize:"true" json:"maxValueSize"`
 
+	// ValueDomainSeparator is prepended to each value before it is hashed to
+	// derive its key (see [Genesis.ValueHash]), namespacing this chain's content
+	// addresses. Empty by default.
+	ValueDomainSeparator []byte `serialize:"true" json:"valueDomainSeparator,omitempty"`
+
+	// AllowedAddressing are the [Addressing]s (besides [HashAddressing]) that
+	// SetTxs can store values with (empty only allows [HashAddressing]).
+	AllowedAddressing []Addressing `serialize:"true" json:"allowedAddressing,omitempty"`
+
+	// ContentPolicy restricts the values that can be set (allows everything by
+	// default)
+	ContentPolicy ContentPolicy `serialize:"true" json:"contentPolicy"`
+
+	// Bounds on the tags attached to each value ([MaxTagsSize] is the total
+	// size of all tag keys and values)
+	MaxTags     uint64 `serialize:"true" json:"maxTags"`
+	MaxTagsSize uint64 `serialize:"true" json:"maxTagsSize"`
+
+	// DefaultValueTTL is the number of seconds a value is stored for when a
+	// SetTx doesn't specify a TTL (0 means values never expire by default).
+	// Longer (or shorter) TTLs are charged proportionally more (or less).
+	DefaultValueTTL uint64 `serialize:"true" json:"defaultValueTTL"`
+
+	// PinUnitsMultiplier is the number of times the value units of a value
+	// are charged (once) to pin it.
+	PinUnitsMultiplier uint64 `serialize:"true" json:"pinUnitsMultiplier"`
+
+	// MinTransfer is the fewest units a TransferTx can send (0 allows any
+	// non-zero amount). Transfers to an address without a balance create it,
+	// so this bounds how cheaply dust transfers can grow the state.
+	MinTransfer uint64 `serialize:"true" json:"minTransfer"`
+
 	// Fee Mechanism Params
 	MinPrice         uint64 `serialize:"true" json:"minPrice"`
 	LookbackWindow   int64  `serialize:"true" json:"lookbackWindow"`
@@ -51,20 +97,56 @@ type Genesis struct {
 	MaxBlockSize     uint64 `serialize:"true" json:"maxBlockSize"`    // units
 	BlockCostEnabled bool   `serialize:"true" json:"blockCostEnabled"`
 
+	// MaxBlockClockDrift is the number of seconds a block timestamp can be
+	// ahead of the local clock before the block is rejected (0 uses a default
+	// of 10 seconds, and it can be at most [MaxBlockClockDriftLimit]).
+	MaxBlockClockDrift int64 `serialize:"true" json:"maxBlockClockDrift"`
+
+	// MaxPriceChangePercent bounds how far the price can fall between
+	// consecutive blocks, as a percentage of the parent price (0 is unbounded).
+	// The price can always move by at least 1. Rises are already limited to 1
+	// per block, so this only slows the drop back to [MinPrice] after a burst
+	// (which would otherwise make fees swing back up by the same amount).
+	MaxPriceChangePercent uint64 `serialize:"true" json:"maxPriceChangePercent"`
+
+	// MaxSenderTxsPerBlock is the number of txs from a single sender included
+	// in each block built by this VM (0 is unbounded), so one sender can't
+	// fill blocks. Excess txs stay in the mempool for later blocks. It only
+	// applies to block building: blocks with more txs from a sender are still
+	// valid.
+	MaxSenderTxsPerBlock uint64 `serialize:"true" json:"maxSenderTxsPerBlock"`
+
+	// AllowedSenders are the only addresses that can issue txs (empty allows
+	// any address). It is scanned for every tx, so it is meant for small
+	// permissioned deployments.
+	AllowedSenders []common.Address `serialize:"true" json:"allowedSenders"`
+
 	// Allocations
 	CustomAllocation []*CustomAllocation `serialize:"true" json:"customAllocation"`
 	AirdropHash      string              `serialize:"true" json:"airdropHash"`
 	AirdropUnits     uint64              `serialize:"true" json:"airdropUnits"`
+	MaxAllocations   uint64              `serialize:"true" json:"maxAllocations"` // 0 is unbounded
+
+	// Values stored at genesis (resolvable at [ValueHash] without a SetTx)
+	PreStoredValues [][]byte `serialize:"true" json:"preStoredValues"`
 }
 
 func DefaultGenesis() *Genesis {
 	return &Genesis{
+		// New chains start upgraded
+		UpgradeTime: new(uint64),
+
 		// Tx params
 		BaseTxUnits: 1,
 
 		// SetTx params
 		ValueUnitSize: DefaultValueUnitSize,
 		MaxValueSize:  200 * units.KiB,
+		MaxTags:       16,
+		MaxTagsSize:   1 * units.KiB,
+
+		// PinTx params
+		PinUnitsMultiplier: 100,
 
 		// Fee Mechanism Params
 		LookbackWindow:   DefaultLookbackWindow, // 60 Seconds
@@ -73,6 +155,9 @@ func DefaultGenesis() *Genesis {
 		MaxBlockSize:     246,                   // ~246KB -> Limited to 256KB by AvalancheGo (as of v1.7.3)
 		MinPrice:         1,
 		BlockCostEnabled: true,
+
+		// Allocations
+		MaxAllocations: DefaultMaxAllocations,
 	}
 }
 
@@ -83,6 +168,63 @@ func (g *Genesis) StatefulBlock() *StatefulBlock {
 	}
 }
 
+// Upgraded returns true if a block at [tmstmp] (unix seconds) is at or after
+// [UpgradeTime].
+func (g *Genesis) Upgraded(tmstmp int64) bool {
+	return g.UpgradeTime != nil && tmstmp >= 0 && uint64(tmstmp) >= *g.UpgradeTime
+}
+
+// futureBound returns how far ahead of the local clock a block timestamp can
+// be.
+func (g *Genesis) futureBound() time.Duration {
+	if g.MaxBlockClockDrift == 0 {
+		return defaultFutureBound
+	}
+	return time.Duration(g.MaxBlockClockDrift) * time.Second
+}
+
+// SenderAllowed returns true if [addr] can issue txs.
+func (g *Genesis) SenderAllowed(addr common.Address) bool {
+	if len(g.AllowedSenders) == 0 {
+		return true
+	}
+	for _, allowed := range g.AllowedSenders {
+		if allowed == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// ClampPrice limits the change from the [parent] price to the [next] price to
+// [MaxPriceChangePercent]. Since [next] is at most [parent]+1, only decreases
+// are limited in practice.
+func (g *Genesis) ClampPrice(parent uint64, next uint64) uint64 {
+	if g.MaxPriceChangePercent == 0 {
+		return next
+	}
+	maxDelta := uint64(math.MaxUint64)
+	if hi, lo := bits.Mul64(parent, g.MaxPriceChangePercent); hi < 100 {
+		maxDelta, _ = bits.Div64(hi, lo, 100)
+	}
+	if maxDelta == 0 {
+		maxDelta = 1
+	}
+	switch {
+	case next > parent && next-parent > maxDelta:
+		return parent + maxDelta
+	case next < parent && parent-next > maxDelta:
+		return parent - maxDelta
+	default:
+		return next
+	}
+}
+
+// ValueHash returns the key [v] is stored at on this chain.
+func (g *Genesis) ValueHash(v []byte) common.Hash {
+	return DomainValueHash(g.ValueDomainSeparator, v)
+}
+
 func (g *Genesis) Verify() error {
 	if g.Magic == 0 {
 		return ErrInvalidMagic
@@ -90,15 +232,91 @@ func (g *Genesis) Verify() error {
 	if g.TargetBlockRate == 0 {
 		return ErrInvalidBlockRate
 	}
+	if g.MaxBlockClockDrift < 0 || g.MaxBlockClockDrift > MaxBlockClockDriftLimit {
+		return fmt.Errorf(
+			"%w: %d is not in [0, %d]",
+			ErrInvalidClockDrift, g.MaxBlockClockDrift, MaxBlockClockDriftLimit,
+		)
+	}
+	if g.ValueUnitSize == 0 {
+		return ErrInvalidUnitSize
+	}
+	if g.MaxValueSize == 0 {
+		return ErrInvalidValueSize
+	}
+	if g.MaxBlockSize < g.TargetBlockSize {
+		return fmt.Errorf(
+			"%w: max=%d is less than target=%d",
+			ErrInvalidBlockSize, g.MaxBlockSize, g.TargetBlockSize,
+		)
+	}
+	// Block costs are charged in multiples of the block price, which falls to
+	// [MinPrice] when blocks aren't full
+	if g.BlockCostEnabled && g.MinPrice == 0 {
+		return ErrInvalidMinPrice
+	}
+	if g.LookbackWindow <= 0 {
+		return ErrInvalidLookback
+	}
+	if err := g.verifyAllocations(0); err != nil {
+		return err
+	}
+	for _, a := range g.AllowedAddressing {
+		if err := a.verify(); err != nil {
+			return err
+		}
+	}
+	for i, v := range g.PreStoredValues {
+		switch {
+		case len(v) == 0:
+			return fmt.Errorf("%w: pre-stored value %d", ErrValueEmpty, i)
+		case uint64(len(v)) > g.MaxValueSize:
+			return fmt.Errorf("%w: pre-stored value %d", ErrValueTooBig, i)
+		case !g.ContentPolicy.Allows(v):
+			return fmt.Errorf("%w: pre-stored value %d", ErrContentRejected, i)
+		}
+	}
+	return nil
+}
+
+// verifyAllocations ensures the number of custom allocations plus [airdrops]
+// does not exceed [MaxAllocations] (which would cause a very slow [Load]).
+func (g *Genesis) verifyAllocations(airdrops int) error {
+	if g.MaxAllocations == 0 {
+		return nil
+	}
+	if total := uint64(len(g.CustomAllocation) + airdrops); total > g.MaxAllocations {
+		return fmt.Errorf(
+			"%w: custom=%d airdrop=%d max=%d",
+			ErrTooManyAllocations, len(g.CustomAllocation), airdrops, g.MaxAllocations,
+		)
+	}
 	return nil
 }
 
 func (g *Genesis) Load(db database.Database, airdropData []byte) error {
+	return g.LoadWithWorkers(db, airdropData, 1)
+}
+
+// LoadWithWorkers is [Load] with balances written by up to [workers]
+// goroutines. The resulting state does not depend on [workers].
+func (g *Genesis) LoadWithWorkers(db database.Database, airdropData []byte, workers int) error {
 	start := time.Now()
 	defer func() {
 		log.Debug("loaded genesis allocations", "t", time.Since(start))
 	}()
 
+	// Allocations are only applied once, so re-running [Load] can't reset
+	// balances spent since the first commit
+	applied, err := HasGenesisApplied(db)
+	if err != nil {
+		return err
+	}
+	if applied {
+		log.Debug("genesis already applied")
+		return nil
+	}
+
 	vdb := versiondb.New(db)
 	if len(g.AirdropHash) > 0 {
 		h := common.BytesToHash(crypto.Keccak256(airdropData)).Hex()
@@ -110,11 +328,14 @@ func (g *Genesis) Load(db database.Database, airdropData []byte) error {
 		if err := json.Unmarshal(airdropData, &airdrop); err != nil {
 			return err
 		}
+		if err := g.verifyAllocations(len(airdrop)); err != nil {
+			return err
+		}
 
-		for _, alloc := range airdrop {
-			if err := SetBalance(vdb, alloc.Address, g.AirdropUnits); err != nil {
-				return fmt.Errorf("%w: addr=%s, bal=%d", err, alloc.Address, g.AirdropUnits)
-			}
+		if err := setBalances(vdb, len(airdrop), workers, func(i int) (common.Address, uint64) {
+			return airdrop[i].Address, g.AirdropUnits
+		}); err != nil {
+			return err
 		}
 		log.Debug(
 			"applied airdrop allocation",
@@ -124,13 +345,86 @@ func (g *Genesis) Load(db database.Database, airdropData []byte) error {
 
 	// Do custom allocation last in case an address shows up in standard
 	// allocation
-	for _, alloc := range g.CustomAllocation {
-		if err := SetBalance(vdb, alloc.Address, alloc.Balance); err != nil {
-			return fmt.Errorf("%w: addr=%s, bal=%d", err, alloc.Address, alloc.Balance)
+	if err := setBalances(vdb, len(g.CustomAllocation), workers, func(i int) (common.Address, uint64) {
+		alloc := g.CustomAllocation[i]
+		return alloc.Address, alloc.Balance
+	}); err != nil {
+		return err
+	}
+	log.Debug("applied custom allocation", "addrs", len(g.CustomAllocation))
+
+	// Pre-stored values are not linked to any tx, so they are written to a
+	// dedicated prefix and marked with an empty TxID
+	created := uint64(g.StatefulBlock().Tmstmp)
+	for _, v := range g.PreStoredValues {
+		k := g.ValueHash(v)
+		if err := PutGenesisValue(vdb, k, v, created); err != nil {
+			return fmt.Errorf("%w: key=%s", err, k)
 		}
-		log.Debug("applied custom allocation", "addr", alloc.Address, "balance", alloc.Balance)
+		log.Debug("applied pre-stored value", "key", k, "size", len(v))
+	}
+
+	if err := vdb.Put(genesisApplied, nil); err != nil {
+		return err
 	}
 
 	// Commit as a batch to improve speed
 	return vdb.Commit()
 }
+
+// setBalances writes [n] balances (returned by [alloc]) to [db] using up to
+// [workers] goroutines.
+//
+// Each worker writes a contiguous range of allocations to its own batch and
+// the batches are written to [db] in order, so later allocations of the same
+// address overwrite earlier ones (just like writing them sequentially).
+func setBalances(
+	db database.Batcher, n int, workers int,
+	alloc func(int) (common.Address, uint64),
+) error {
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > n {
+		workers = n
+	}
+	if workers == 0 {
+		return nil
+	}
+
+	var (
+		size    = (n + workers - 1) / workers
+		batches = make([]database.Batch, workers)
+		errs    = make([]error, workers)
+		wg      sync.WaitGroup
+	)
+	for w := range batches {
+		batches[w] = db.NewBatch()
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			end := (w + 1) * size
+			if end > n {
+				end = n
+			}
+			for i := w * size; i < end; i++ {
+				addr, bal := alloc(i)
+				if err := SetBalance(batches[w], addr, bal); err != nil {
+					errs[w] = fmt.Errorf("%w: addr=%s, bal=%d", err, addr, bal)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	for w, b := range batches {
+		if errs[w] != nil {
+			return errs[w]
+		}
+		if err := b.Write(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
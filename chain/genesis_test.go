@@ -0,0 +1,309 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/database/memdb"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestGenesisPreStoredValues(t *testing.T) {
+	t.Parallel()
+
+	db := memdb.New()
+	defer db.Close()
+
+	g := DefaultGenesis()
+	g.Magic = 1
+	g.PreStoredValues = [][]byte{[]byte("terms of service"), []byte("privacy policy")}
+	if err := g.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Load(db, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, v := range g.PreStoredValues {
+		k := ValueHash(v)
+		vmeta, exists, err := GetValueMeta(db, k)
+		if err != nil {
+			t.Fatalf("#%d: failed to get meta info %v", i, err)
+		}
+		if !exists {
+			t.Fatalf("#%d: pre-stored value not found", i)
+		}
+		if vmeta.TxID != ids.Empty {
+			t.Fatalf("#%d: unexpected txID %q, expected empty", i, vmeta.TxID)
+		}
+		if vmeta.Size != uint64(len(v)) {
+			t.Fatalf("#%d: unexpected size %d, expected %d", i, vmeta.Size, len(v))
+		}
+
+		val, exists, err := GetValue(db, nil, k)
+		if err != nil {
+			t.Fatalf("#%d: failed to get value %v", i, err)
+		}
+		if !exists {
+			t.Fatalf("#%d: pre-stored value not found", i)
+		}
+		if !bytes.Equal(v, val) {
+			t.Fatalf("#%d: unexpected value %q, expected %q", i, val, v)
+		}
+	}
+}
+
+func TestGenesisVerifyPreStoredValues(t *testing.T) {
+	t.Parallel()
+
+	g := DefaultGenesis()
+	g.Magic = 1
+	g.PreStoredValues = [][]byte{{}}
+	if err := g.Verify(); !errors.Is(err, ErrValueEmpty) {
+		t.Fatalf("unexpected error %v, expected %v", err, ErrValueEmpty)
+	}
+	g.PreStoredValues = [][]byte{make([]byte, g.MaxValueSize+1)}
+	if err := g.Verify(); !errors.Is(err, ErrValueTooBig) {
+		t.Fatalf("unexpected error %v, expected %v", err, ErrValueTooBig)
+	}
+	g.ContentPolicy.UTF8 = true
+	g.PreStoredValues = [][]byte{{0xff}}
+	if err := g.Verify(); !errors.Is(err, ErrContentRejected) {
+		t.Fatalf("unexpected error %v, expected %v", err, ErrContentRejected)
+	}
+}
+
+func TestGenesisVerify(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		modify func(g *Genesis)
+		err    error
+	}{
+		{modify: func(g *Genesis) {}},
+		{modify: func(g *Genesis) { g.Magic = 0 }, err: ErrInvalidMagic},
+		{modify: func(g *Genesis) { g.TargetBlockRate = 0 }, err: ErrInvalidBlockRate},
+		{modify: func(g *Genesis) { g.MaxBlockClockDrift = -1 }, err: ErrInvalidClockDrift},
+		{modify: func(g *Genesis) { g.MaxBlockClockDrift = MaxBlockClockDriftLimit }},
+		{modify: func(g *Genesis) { g.MaxBlockClockDrift = MaxBlockClockDriftLimit + 1 }, err: ErrInvalidClockDrift},
+		{modify: func(g *Genesis) { g.MaxBlockClockDrift = math.MaxInt64 }, err: ErrInvalidClockDrift},
+		{modify: func(g *Genesis) { g.ValueUnitSize = 0 }, err: ErrInvalidUnitSize},
+		{modify: func(g *Genesis) { g.MaxValueSize = 0 }, err: ErrInvalidValueSize},
+		{modify: func(g *Genesis) { g.MaxBlockSize = g.TargetBlockSize - 1 }, err: ErrInvalidBlockSize},
+		{modify: func(g *Genesis) { g.MaxBlockSize = g.TargetBlockSize }},
+		{modify: func(g *Genesis) { g.MinPrice = 0 }, err: ErrInvalidMinPrice},
+		{ // free chains don't charge block costs
+			modify: func(g *Genesis) {
+				g.MinPrice = 0
+				g.BlockCostEnabled = false
+			},
+		},
+		{modify: func(g *Genesis) { g.LookbackWindow = 0 }, err: ErrInvalidLookback},
+		{modify: func(g *Genesis) { g.LookbackWindow = -1 }, err: ErrInvalidLookback},
+	}
+	for i, tv := range tt {
+		g := DefaultGenesis()
+		g.Magic = 1
+		tv.modify(g)
+		if err := g.Verify(); !errors.Is(err, tv.err) {
+			t.Fatalf("#%d: unexpected error %v, expected %v", i, err, tv.err)
+		}
+	}
+}
+
+func TestGenesisMaxAllocations(t *testing.T) {
+	t.Parallel()
+
+	g := DefaultGenesis()
+	g.Magic = 1
+	g.MaxAllocations = 2
+	g.CustomAllocation = []*CustomAllocation{
+		{Address: common.Address{1}, Balance: 1},
+		{Address: common.Address{2}, Balance: 1},
+	}
+	if err := g.Verify(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// Exceeding the bound with custom allocations is caught by [Verify]
+	g.CustomAllocation = append(g.CustomAllocation, &CustomAllocation{Address: common.Address{3}, Balance: 1})
+	if err := g.Verify(); !errors.Is(err, ErrTooManyAllocations) {
+		t.Fatalf("unexpected error %v, expected %v", err, ErrTooManyAllocations)
+	}
+
+	// Exceeding the bound with airdrops is caught by [Load]
+	g.CustomAllocation = g.CustomAllocation[:2]
+	airdropData := []byte(`[{"address":"0x0000000000000000000000000000000000000004"}]`)
+	g.AirdropHash = crypto.Keccak256Hash(airdropData).Hex()
+	g.AirdropUnits = 1
+	db := memdb.New()
+	defer db.Close()
+	if err := g.Load(db, airdropData); !errors.Is(err, ErrTooManyAllocations) {
+		t.Fatalf("unexpected error %v, expected %v", err, ErrTooManyAllocations)
+	}
+
+	g.MaxAllocations = 3
+	if err := g.Load(db, airdropData); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestGenesisLoadOnce(t *testing.T) {
+	t.Parallel()
+
+	db := memdb.New()
+	defer db.Close()
+
+	addr := common.Address{1}
+	g := DefaultGenesis()
+	g.Magic = 1
+	g.CustomAllocation = []*CustomAllocation{{Address: addr, Balance: 10}}
+	if err := g.Load(db, nil); err != nil {
+		t.Fatal(err)
+	}
+	applied, err := HasGenesisApplied(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !applied {
+		t.Fatal("genesis not marked as applied")
+	}
+
+	// Spend some of the allocation before loading again
+	if _, err := ModifyBalance(db, addr, false, 4); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Load(db, nil); err != nil {
+		t.Fatal(err)
+	}
+	bal, err := GetBalance(db, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bal != 6 {
+		t.Fatalf("unexpected balance %d, expected %d", bal, 6)
+	}
+}
+
+func createTestAirdrop(tb testing.TB, n int) []byte {
+	tb.Helper()
+
+	airdrop := make([]*Airdrop, n)
+	for i := range airdrop {
+		airdrop[i] = &Airdrop{Address: common.BigToAddress(big.NewInt(int64(i % (n - n/10))))} // ~10% duplicates
+	}
+	b, err := json.Marshal(airdrop)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return b
+}
+
+func TestGenesisLoadWithWorkers(t *testing.T) {
+	t.Parallel()
+
+	airdropData := createTestAirdrop(t, 1000)
+	g := DefaultGenesis()
+	g.Magic = 1
+	g.AirdropHash = crypto.Keccak256Hash(airdropData).Hex()
+	g.AirdropUnits = 10
+	g.CustomAllocation = []*CustomAllocation{
+		{Address: common.BigToAddress(big.NewInt(1)), Balance: 1}, // overrides airdrop
+		{Address: common.Address{1}, Balance: 2},
+		{Address: common.Address{1}, Balance: 3}, // overrides earlier custom allocation
+	}
+
+	serial := memdb.New()
+	defer serial.Close()
+	if err := g.LoadWithWorkers(serial, airdropData, 1); err != nil {
+		t.Fatal(err)
+	}
+	for _, workers := range []int{2, 3, 8, 2000} {
+		parallel := memdb.New()
+		if err := g.LoadWithWorkers(parallel, airdropData, workers); err != nil {
+			t.Fatal(err)
+		}
+		si, pi := serial.NewIterator(), parallel.NewIterator()
+		for si.Next() {
+			if !pi.Next() {
+				t.Fatalf("workers=%d: missing key %x", workers, si.Key())
+			}
+			if !bytes.Equal(si.Key(), pi.Key()) || !bytes.Equal(si.Value(), pi.Value()) {
+				t.Fatalf("workers=%d: entry expected %x=%x, got %x=%x", workers, si.Key(), si.Value(), pi.Key(), pi.Value())
+			}
+		}
+		if pi.Next() {
+			t.Fatalf("workers=%d: unexpected key %x", workers, pi.Key())
+		}
+		si.Release()
+		pi.Release()
+		parallel.Close()
+	}
+
+	for addr, bal := range map[common.Address]uint64{
+		common.BigToAddress(big.NewInt(0)): 10,
+		common.BigToAddress(big.NewInt(1)): 1,
+		{1}:                                3,
+	} {
+		b, err := GetBalance(serial, addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b != bal {
+			t.Fatalf("balance of %s expected %d, got %d", addr, bal, b)
+		}
+	}
+}
+
+func BenchmarkGenesisLoadWithWorkers(b *testing.B) {
+	airdropData := createTestAirdrop(b, 100_000)
+	g := DefaultGenesis()
+	g.Magic = 1
+	g.AirdropHash = crypto.Keccak256Hash(airdropData).Hex()
+	g.AirdropUnits = 10
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				db := memdb.New()
+				if err := g.LoadWithWorkers(db, airdropData, workers); err != nil {
+					b.Fatal(err)
+				}
+				db.Close()
+			}
+		})
+	}
+}
+
+func TestScheduleUpgrade(t *testing.T) {
+	scheduled, unscheduled := ids.GenerateTestID(), ids.GenerateTestID()
+	UpgradeTimes[scheduled] = 10
+	defer delete(UpgradeTimes, scheduled)
+
+	g := DefaultGenesis()
+	g.UpgradeTime = nil
+	g.ScheduleUpgrade(unscheduled)
+	if g.UpgradeTime != nil {
+		t.Fatalf("unexpected upgrade time %d", *g.UpgradeTime)
+	}
+	g.ScheduleUpgrade(scheduled)
+	if g.UpgradeTime == nil || *g.UpgradeTime != 10 {
+		t.Fatalf("upgrade time expected 10, got %v", g.UpgradeTime)
+	}
+
+	// A genesis upgrade time is never overridden
+	g = DefaultGenesis()
+	g.ScheduleUpgrade(scheduled)
+	if *g.UpgradeTime != 0 {
+		t.Fatalf("upgrade time expected 0, got %d", *g.UpgradeTime)
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	smath "github.com/ethereum/go-ethereum/common/math"
+
+	"github.com/ava-labs/blobvm/tdata"
+)
+
+var _ UnsignedTransaction = &PinTx{}
+
+// PinTx exempts a stored value from expiry until it is unpinned (with an
+// UnpinTx) by the sender. Pinning charges a one-time fee for permanent
+// storage.
+type PinTx struct {
+	*BaseTx `serialize:"true" json:"baseTx"`
+
+	// Key is the [ValueHash] of the value to pin.
+	Key common.Hash `serialize:"true" json:"key"`
+}
+
+func (p *PinTx) Execute(t *TransactionContext) error {
+	g := t.Genesis
+	vmeta, exists, err := GetValueMeta(t.Database, p.Key)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrKeyMissing
+	}
+	if vmeta.Pinned || vmeta.Expiry == 0 {
+		// Value will never expire
+		return ErrNonActionable
+	}
+	if vmeta.Expired(t.BlockTime) {
+		return ErrExpired
+	}
+
+	// Pinning is charged once, for good: [Genesis.PinUnitsMultiplier] times
+	// the value units of the stored value (a PinTx only carries the key).
+	fee, xflow := smath.SafeMul(p.StorageUnits(g, vmeta.Size), p.Price)
+	if xflow {
+		return ErrInvalidBalance
+	}
+	if _, err := ModifyBalance(t.Database, t.Sender, false, fee); err != nil {
+		return err
+	}
+
+	vmeta.Pinned = true
+	vmeta.PinnedBy = t.Sender
+	return t.putKey(p.Key, vmeta)
+}
+
+// StorageUnits returns the units charged to pin a value of [size] bytes (in
+// addition to [FeeUnits]).
+func (p *PinTx) StorageUnits(g *Genesis, size uint64) uint64 {
+	units, xflow := smath.SafeMul(valueUnits(g, size), g.PinUnitsMultiplier)
+	if xflow {
+		return math.MaxUint64
+	}
+	return units
+}
+
+func (p *PinTx) Copy() UnsignedTransaction {
+	key := make([]byte, common.HashLength)
+	copy(key, p.Key[:])
+	return &PinTx{
+		BaseTx: p.BaseTx.Copy(),
+		Key:    common.BytesToHash(key),
+	}
+}
+
+func (p *PinTx) TypedData() *tdata.TypedData {
+	return tdata.CreateTypedData(
+		p.Magic, Pin,
+		[]tdata.Type{
+			{Name: tdKey, Type: tdString},
+			{Name: tdPrice, Type: tdUint64},
+			{Name: tdBlockID, Type: tdString},
+		},
+		tdata.TypedDataMessage{
+			tdKey:     p.Key.Hex(),
+			tdPrice:   strconv.FormatUint(p.Price, 10),
+			tdBlockID: p.BlockID.String(),
+		},
+	)
+}
+
+func (p *PinTx) Activity(*Genesis) *Activity {
+	return &Activity{
+		Typ: Pin,
+		Key: p.Key.Hex(),
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestPinTx(t *testing.T) {
+	t.Parallel()
+
+	f := newStorageFixture(t, 110)
+	sender, sender2 := f.sender, f.unfunded
+	expiring, permanent := f.expiring, f.permanent
+
+	key := ValueHash(expiring)
+	tt := []struct {
+		utx       UnsignedTransaction
+		blockTime uint64
+		sender    common.Address
+		fee       uint64
+		pinned    bool
+		expired   bool
+		err       error
+	}{
+		{ // invalid when key is missing
+			utx:       &PinTx{BaseTx: &BaseTx{Price: 1}, Key: ValueHash([]byte("missing"))},
+			blockTime: 50,
+			sender:    sender,
+			err:       ErrKeyMissing,
+		},
+		{ // invalid when value never expires
+			utx:       &PinTx{BaseTx: &BaseTx{Price: 1}, Key: ValueHash(permanent)},
+			blockTime: 50,
+			sender:    sender,
+			err:       ErrNonActionable,
+		},
+		{ // invalid when no funds
+			utx:       &PinTx{BaseTx: &BaseTx{Price: 1}, Key: key},
+			blockTime: 50,
+			sender:    sender2,
+			err:       ErrInvalidBalance,
+		},
+		{ // invalid unpin when not pinned
+			utx:       &UnpinTx{BaseTx: &BaseTx{Price: 1}, Key: key},
+			blockTime: 50,
+			sender:    sender,
+			err:       ErrNonActionable,
+		},
+		{ // valid pin (charged 100x the value units)
+			utx:       &PinTx{BaseTx: &BaseTx{Price: 2}, Key: key},
+			blockTime: 50,
+			sender:    sender,
+			fee:       11 * 100 * 2,
+			pinned:    true,
+		},
+		{ // invalid when already pinned
+			utx:       &PinTx{BaseTx: &BaseTx{Price: 1}, Key: key},
+			blockTime: 60,
+			sender:    sender,
+			err:       ErrNonActionable,
+		},
+		{ // pinned value survives past its expiry
+			utx:       &RenewTx{BaseTx: &BaseTx{Price: 1}, Key: key, Extension: 100},
+			blockTime: 200,
+			sender:    sender,
+			fee:       11,
+			pinned:    true,
+		},
+		{ // only the pinner can unpin
+			utx:       &UnpinTx{BaseTx: &BaseTx{Price: 1}, Key: key},
+			blockTime: 300,
+			sender:    sender2,
+			err:       ErrUnauthorized,
+		},
+		{ // unpinning restores the (renewed) expiry
+			utx:       &UnpinTx{BaseTx: &BaseTx{Price: 1}, Key: key},
+			blockTime: 300,
+			sender:    sender,
+			expired:   true,
+		},
+	}
+	for i, tv := range tt {
+		fee, err := f.execute(t, tv.utx, tv.blockTime, tv.sender)
+		if !errors.Is(err, tv.err) {
+			t.Fatalf("#%d: tx.Execute err expected %v, got %v", i, tv.err, err)
+		}
+		if tv.err != nil {
+			continue
+		}
+		if fee != tv.fee {
+			t.Fatalf("#%d: fee expected %d, got %d", i, tv.fee, fee)
+		}
+		vmeta, exists, err := GetValueMeta(f.db, key)
+		if err != nil {
+			t.Fatalf("#%d: failed to get meta info %v", i, err)
+		}
+		if !exists {
+			t.Fatalf("#%d: value not found", i)
+		}
+		if vmeta.Pinned != tv.pinned {
+			t.Fatalf("#%d: pinned expected %t, got %t", i, tv.pinned, vmeta.Pinned)
+		}
+		if vmeta.Expired(tv.blockTime) != tv.expired {
+			t.Fatalf("#%d: expired expected %t, got %t", i, tv.expired, vmeta.Expired(tv.blockTime))
+		}
+		if vmeta.Expired(tv.blockTime) {
+			continue
+		}
+		// Unexpired values can't be overwritten
+		utx := &SetTx{BaseTx: &BaseTx{}, Value: expiring}
+		if _, err := f.execute(t, utx, tv.blockTime, tv.sender); !errors.Is(err, ErrKeyExists) {
+			t.Fatalf("#%d: overwrite err expected %v, got %v", i, ErrKeyExists, err)
+		}
+	}
+}
+
+func TestPinTreeRoot(t *testing.T) {
+	t.Parallel()
+
+	f := newStorageFixture(t, 110)
+	chunks := make([]common.Hash, 3)
+	for i := range chunks {
+		chunks[i] = putTestValue(t, f.db, []byte(fmt.Sprintf("chunk %d", i)), 10, 110)
+	}
+	b, err := json.Marshal(&TreeRoot{Children: chunks, Size: 21})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := putTestValue(t, f.db, b, 10, 110)
+
+	check := func(now uint64, live bool) {
+		t.Helper()
+		for _, k := range append([]common.Hash{root}, chunks...) {
+			_, l, err := GetLiveValueMeta(f.db, k, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if l != live {
+				t.Fatalf("now=%d: %s live=%t, expected live=%t", now, k, l, live)
+			}
+		}
+	}
+
+	// Pinning the root keeps its chunks resolvable past their own expiry
+	if _, err := f.execute(t, &PinTx{BaseTx: &BaseTx{Price: 1}, Key: root}, 50, f.sender); err != nil {
+		t.Fatal(err)
+	}
+	check(500, true)
+	if _, err := f.execute(t, &UnpinTx{BaseTx: &BaseTx{Price: 1}, Key: root}, 500, f.sender); err != nil {
+		t.Fatal(err)
+	}
+	check(500, false)
+}
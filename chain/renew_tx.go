@@ -0,0 +1,107 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	smath "github.com/ethereum/go-ethereum/common/math"
+
+	"github.com/ava-labs/blobvm/tdata"
+)
+
+var _ UnsignedTransaction = &RenewTx{}
+
+// RenewTx extends the expiry of a stored value. Because values are
+// content-addressed (and not owned), anyone willing to pay can renew them.
+type RenewTx struct {
+	*BaseTx `serialize:"true" json:"baseTx"`
+
+	// Key is the [ValueHash] of the value to renew.
+	Key common.Hash `serialize:"true" json:"key"`
+
+	// Extension is the number of seconds added to the expiry of [Key].
+	Extension uint64 `serialize:"true" json:"extension"`
+}
+
+func (r *RenewTx) Execute(t *TransactionContext) error {
+	g := t.Genesis
+	if r.Extension == 0 {
+		return ErrNonActionable
+	}
+	vmeta, exists, err := GetValueMeta(t.Database, r.Key)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrKeyMissing
+	}
+	if vmeta.Expiry == 0 {
+		// Value will never expire
+		return ErrNonActionable
+	}
+	if vmeta.Expired(t.BlockTime) {
+		return ErrExpired
+	}
+	expiry, xflow := smath.SafeAdd(vmeta.Expiry, r.Extension)
+	if xflow {
+		return ErrInvalidTTL
+	}
+
+	// A RenewTx only carries the key, so the extension is priced off the
+	// stored [ValueMeta.Size] (like the [SetTx] TTL it extends).
+	fee, xflow := smath.SafeMul(r.StorageUnits(g, vmeta.Size), r.Price)
+	if xflow {
+		return ErrInvalidBalance
+	}
+	if _, err := ModifyBalance(t.Database, t.Sender, false, fee); err != nil {
+		return err
+	}
+
+	vmeta.Expiry = expiry
+	return t.putKey(r.Key, vmeta)
+}
+
+// StorageUnits returns the units charged to extend a value of [size] bytes by
+// [Extension] (in addition to [FeeUnits]).
+func (r *RenewTx) StorageUnits(g *Genesis, size uint64) uint64 {
+	return ttlUnits(g, valueUnits(g, size), r.Extension)
+}
+
+func (r *RenewTx) Copy() UnsignedTransaction {
+	key := make([]byte, common.HashLength)
+	copy(key, r.Key[:])
+	return &RenewTx{
+		BaseTx:    r.BaseTx.Copy(),
+		Key:       common.BytesToHash(key),
+		Extension: r.Extension,
+	}
+}
+
+func (r *RenewTx) TypedData() *tdata.TypedData {
+	return tdata.CreateTypedData(
+		r.Magic, Renew,
+		[]tdata.Type{
+			{Name: tdKey, Type: tdString},
+			{Name: tdExtension, Type: tdUint64},
+			{Name: tdPrice, Type: tdUint64},
+			{Name: tdBlockID, Type: tdString},
+		},
+		tdata.TypedDataMessage{
+			tdKey:       r.Key.Hex(),
+			tdExtension: strconv.FormatUint(r.Extension, 10),
+			tdPrice:     strconv.FormatUint(r.Price, 10),
+			tdBlockID:   r.BlockID.String(),
+		},
+	)
+}
+
+func (r *RenewTx) Activity(*Genesis) *Activity {
+	return &Activity{
+		Typ:       Renew,
+		Key:       r.Key.Hex(),
+		Extension: r.Extension,
+	}
+}
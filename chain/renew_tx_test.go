@@ -0,0 +1,138 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestRenewTx(t *testing.T) {
+	t.Parallel()
+
+	f := newStorageFixture(t, 110)
+	sender, sender2 := f.sender, f.unfunded
+	expiring, permanent := f.expiring, f.permanent
+
+	tt := []struct {
+		utx       *RenewTx
+		blockTime uint64
+		sender    common.Address
+		expiry    uint64
+		fee       uint64
+		err       error
+	}{
+		{ // invalid when no extension is given
+			utx:       &RenewTx{BaseTx: &BaseTx{Price: 1}, Key: ValueHash(expiring)},
+			blockTime: 50,
+			sender:    sender,
+			err:       ErrNonActionable,
+		},
+		{ // invalid when key is missing
+			utx:       &RenewTx{BaseTx: &BaseTx{Price: 1}, Key: ValueHash([]byte("missing")), Extension: 100},
+			blockTime: 50,
+			sender:    sender,
+			err:       ErrKeyMissing,
+		},
+		{ // invalid when value never expires
+			utx:       &RenewTx{BaseTx: &BaseTx{Price: 1}, Key: ValueHash(permanent), Extension: 100},
+			blockTime: 50,
+			sender:    sender,
+			err:       ErrNonActionable,
+		},
+		{ // invalid when no funds
+			utx:       &RenewTx{BaseTx: &BaseTx{Price: 1}, Key: ValueHash(expiring), Extension: 100},
+			blockTime: 50,
+			sender:    sender2,
+			err:       ErrInvalidBalance,
+		},
+		{ // valid renewal by anyone (charged for 2x default ttl)
+			utx:       &RenewTx{BaseTx: &BaseTx{Price: 3}, Key: ValueHash(expiring), Extension: 200},
+			blockTime: 50,
+			sender:    sender,
+			expiry:    310,
+			fee:       22 * 3,
+		},
+		{ // valid renewal (charged for 0.5x default ttl, rounded up)
+			utx:       &RenewTx{BaseTx: &BaseTx{Price: 1}, Key: ValueHash(expiring), Extension: 50},
+			blockTime: 309,
+			sender:    sender,
+			expiry:    360,
+			fee:       6,
+		},
+		{ // invalid after expiry
+			utx:       &RenewTx{BaseTx: &BaseTx{Price: 1}, Key: ValueHash(expiring), Extension: 100},
+			blockTime: 360,
+			sender:    sender,
+			err:       ErrExpired,
+		},
+	}
+	for i, tv := range tt {
+		fee, err := f.execute(t, tv.utx, tv.blockTime, tv.sender)
+		if !errors.Is(err, tv.err) {
+			t.Fatalf("#%d: tx.Execute err expected %v, got %v", i, tv.err, err)
+		}
+		if tv.err != nil {
+			continue
+		}
+		if fee != tv.fee {
+			t.Fatalf("#%d: fee expected %d, got %d", i, tv.fee, fee)
+		}
+		vmeta, exists, err := GetValueMeta(f.db, tv.utx.Key)
+		if err != nil {
+			t.Fatalf("#%d: failed to get meta info %v", i, err)
+		}
+		if !exists {
+			t.Fatalf("#%d: renewed value not found", i)
+		}
+		if vmeta.Expiry != tv.expiry {
+			t.Fatalf("#%d: unexpected expiry %d, expected %d", i, vmeta.Expiry, tv.expiry)
+		}
+	}
+}
+
+func TestRenewTreeRoot(t *testing.T) {
+	t.Parallel()
+
+	f := newStorageFixture(t, 110)
+	putRoot := func(r *TreeRoot) common.Hash {
+		b, err := json.Marshal(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return putTestValue(t, f.db, b, 10, 110)
+	}
+	chunks := []common.Hash{
+		putTestValue(t, f.db, []byte("chunk 0"), 10, 110),
+		putTestValue(t, f.db, []byte("chunk 1"), 10, 110),
+	}
+	sub := putRoot(&TreeRoot{Children: chunks})
+	root := putRoot(&TreeRoot{Children: []common.Hash{sub}, Height: 1})
+
+	// Only the root is renewed, but what it references stays live with it
+	if _, err := f.execute(t, &RenewTx{BaseTx: &BaseTx{Price: 1}, Key: root, Extension: 100}, 50, f.sender); err != nil {
+		t.Fatal(err)
+	}
+	for _, tv := range []struct {
+		now  uint64
+		live bool
+	}{
+		{now: 109, live: true},
+		{now: 150, live: true},
+		{now: 210, live: false},
+	} {
+		for _, k := range append([]common.Hash{root, sub}, chunks...) {
+			_, live, err := GetLiveValueMeta(f.db, k, tv.now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if live != tv.live {
+				t.Fatalf("now=%d: %s live expected %t, got %t", tv.now, k, tv.live, live)
+			}
+		}
+	}
+}
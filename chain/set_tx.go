@@ -4,55 +4,163 @@
 package chain
 
 import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
 	"strconv"
+	"strings"
 
 	"github.com/ava-labs/blobvm/tdata"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 )
 
 var _ UnsignedTransaction = &SetTx{}
 
 type SetTx struct {
-	*BaseTx `serialize:"true" json:"baseTx"`
+	*BaseTx `serialize:"true" serializeV0:"true" json:"baseTx"`
 
-	Value []byte `serialize:"true" json:"value"`
+	Value []byte `serialize:"true" serializeV0:"true" json:"value"`
+
+	// TTL is the number of seconds [Value] is stored for (0 uses
+	// [Genesis.DefaultValueTTL]).
+	TTL uint64 `serialize:"true" json:"ttl"`
+
+	// Tags are stored alongside [Value] in its [ValueMeta].
+	Tags []*Tag `serialize:"true" json:"tags,omitempty"`
+
+	// Addressing derives the key [Value] is stored at. Values set with an
+	// [Addressing] other than [HashAddressing] (which must be in
+	// [Genesis.AllowedAddressing]) are stored at [Key], which must be the key
+	// of [Value] under that [Addressing].
+	Addressing Addressing  `serialize:"true" json:"addressing,omitempty"`
+	Key        common.Hash `serialize:"true" json:"key"`
 }
 
 func (s *SetTx) Execute(t *TransactionContext) error {
+	g := t.Genesis
+	if err := s.verifyKey(g); err != nil {
+		return err
+	}
+	return setValue(t, s.key(g), s.Addressing, s.Value, s.ttl(g), s.Tags, false)
+}
+
+// verifyKey ensures [Key] is the key of [Value] under an allowed [Addressing].
+func (s *SetTx) verifyKey(g *Genesis) error {
+	if s.Addressing == HashAddressing {
+		if s.Key != (common.Hash{}) {
+			return fmt.Errorf("%w: key is derived from the value", ErrInvalidKey)
+		}
+		return nil
+	}
+	if err := s.Addressing.verify(); err != nil {
+		return err
+	}
+	if !g.AddressingAllowed(s.Addressing) {
+		return fmt.Errorf("%w: %q", ErrAddressingNotAllowed, s.Addressing)
+	}
+	if k := g.AddressKey(s.Addressing, s.Value); k != s.Key {
+		return fmt.Errorf("%w: value is addressed at %s", ErrInvalidKey, k)
+	}
+	return nil
+}
+
+// key returns the key [Value] is stored at.
+func (s *SetTx) key(g *Genesis) common.Hash {
+	return g.AddressKey(s.Addressing, s.Value)
+}
+
+// setValue stores [value] at [k] (its key under [addressing]) for [ttl]
+// seconds (0 is forever) with [tags]. [batched] values are linked to the
+// [BatchTx] that set them.
+func setValue(
+	t *TransactionContext,
+	k common.Hash,
+	addressing Addressing,
+	value []byte,
+	ttl uint64,
+	tags []*Tag,
+	batched bool,
+) error {
 	g := t.Genesis
 	switch {
-	case len(s.Value) == 0:
+	case len(value) == 0:
 		return ErrValueEmpty
-	case uint64(len(s.Value)) > g.MaxValueSize:
+	case uint64(len(value)) > g.MaxValueSize:
 		return ErrValueTooBig
+	case !g.ContentPolicy.Allows(value):
+		return ErrContentRejected
+	}
+	if err := verifyTags(g, tags); err != nil {
+		return err
 	}
 
-	k := ValueHash(s.Value)
-
-	// Do not allow duplicate value setting
-	_, exists, err := GetValueMeta(t.Database, k)
+	// Do not allow duplicate value setting (unless the existing value expired)
+	vmeta, exists, err := GetValueMeta(t.Database, k)
 	if err != nil {
 		return err
 	}
-	if exists {
+	if exists && !vmeta.Expired(t.BlockTime) {
 		return ErrKeyExists
 	}
 
-	return PutKey(t.Database, k, &ValueMeta{
-		Size:    uint64(len(s.Value)),
-		TxID:    t.TxID,
-		Created: t.BlockTime,
-	})
+	var expiry uint64
+	if ttl > 0 {
+		expiry = t.BlockTime + ttl
+		if expiry < t.BlockTime {
+			return ErrInvalidTTL
+		}
+	}
+	if exists {
+		// Remove the expired value from the tag index before it is replaced
+		if err := DeleteTags(t.Database, k, vmeta.Tags); err != nil {
+			return err
+		}
+	}
+	if err := t.putKey(k, &ValueMeta{
+		Size:       uint64(len(value)),
+		TxID:       t.TxID,
+		Created:    t.BlockTime,
+		Expiry:     expiry,
+		Tags:       tags,
+		Batched:    batched,
+		Addressing: addressing,
+	}); err != nil {
+		return err
+	}
+	return PutTags(t.Database, k, tags)
+}
+
+// ttl returns the number of seconds [Value] will be stored for (0 is
+// forever).
+func (s *SetTx) ttl(g *Genesis) uint64 {
+	if s.TTL == 0 {
+		return g.DefaultValueTTL
+	}
+	return s.TTL
 }
 
 func (s *SetTx) FeeUnits(g *Genesis) uint64 {
 	// We don't subtract by 1 here because we want to charge extra for any
 	// value-based interaction (even if it is small or a delete).
-	return s.BaseTx.FeeUnits(g) + valueUnits(g, uint64(len(s.Value)))
+	base := s.BaseTx.FeeUnits(g)
+	units := ttlUnits(g, valueUnits(g, s.size()), s.TTL)
+	if units > math.MaxUint64-base {
+		return math.MaxUint64
+	}
+	return base + units
 }
 
 func (s *SetTx) LoadUnits(g *Genesis) uint64 {
-	return s.FeeUnits(g)
+	// Storing a value for longer doesn't make the block that includes it any
+	// bigger, so [TTL] is not considered.
+	return s.BaseTx.FeeUnits(g) + valueUnits(g, s.size())
+}
+
+// size returns the number of bytes stored by the tx ([Value] and [Tags]).
+func (s *SetTx) size() uint64 {
+	return uint64(len(s.Value)) + tagsSize(s.Tags)
 }
 
 func (s *SetTx) Copy() UnsignedTransaction {
@@ -61,28 +169,75 @@ func (s *SetTx) Copy() UnsignedTransaction {
 	return &SetTx{
 		BaseTx: s.BaseTx.Copy(),
 		Value:  value,
+		TTL:    s.TTL,
+		Tags:   copyTags(s.Tags),
+
+		Addressing: s.Addressing,
+		Key:        s.Key,
 	}
 }
 
 func (s *SetTx) TypedData() *tdata.TypedData {
+	if legacyEncodable(reflect.ValueOf(s)) {
+		// SetTxs signed before the upgrade only covered these fields, so
+		// their signatures must keep recovering the same sender
+		return tdata.CreateTypedData(
+			s.Magic, Set,
+			[]tdata.Type{
+				{Name: tdValue, Type: tdBytes},
+				{Name: tdPrice, Type: tdUint64},
+				{Name: tdBlockID, Type: tdString},
+			},
+			tdata.TypedDataMessage{
+				tdValue:   hexutil.Encode(s.Value),
+				tdPrice:   strconv.FormatUint(s.Price, 10),
+				tdBlockID: s.BlockID.String(),
+			},
+		)
+	}
 	return tdata.CreateTypedData(
 		s.Magic, Set,
 		[]tdata.Type{
 			{Name: tdValue, Type: tdBytes},
+			{Name: tdTTL, Type: tdUint64},
+			{Name: tdTags, Type: tdString},
+			{Name: tdAddressing, Type: tdString},
+			{Name: tdKey, Type: tdString},
 			{Name: tdPrice, Type: tdUint64},
 			{Name: tdBlockID, Type: tdString},
 		},
 		tdata.TypedDataMessage{
-			tdValue:   hexutil.Encode(s.Value),
-			tdPrice:   strconv.FormatUint(s.Price, 10),
-			tdBlockID: s.BlockID.String(),
+			tdValue:      hexutil.Encode(s.Value),
+			tdTTL:        strconv.FormatUint(s.TTL, 10),
+			tdTags:       encodeTags(s.Tags),
+			tdAddressing: string(s.Addressing),
+			tdKey:        s.Key.Hex(),
+			tdPrice:      strconv.FormatUint(s.Price, 10),
+			tdBlockID:    s.BlockID.String(),
 		},
 	)
 }
 
-func (s *SetTx) Activity() *Activity {
+func (s *SetTx) Activity(g *Genesis) *Activity {
 	return &Activity{
-		Typ: Set,
-		Key: ValueHashString(s.Value),
+		Typ:  Set,
+		Key:  strings.ToLower(s.key(g).Hex()),
+		Size: uint64(len(s.Value)),
+		TTL:  s.ttl(g),
+	}
+}
+
+// encodeTags returns the JSON encoding of [tags] used in the typed data. nil
+// and empty [tags] are encoded the same way because the codec doesn't
+// distinguish between them.
+func encodeTags(tags []*Tag) string {
+	if len(tags) == 0 {
+		return "[]"
+	}
+	b, err := json.Marshal(tags)
+	if err != nil {
+		// [Tag] only contains strings, so this should never happen
+		panic(err)
 	}
+	return string(b)
 }
@@ -6,6 +6,9 @@ package chain
 import (
 	"bytes"
 	"errors"
+	"math"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/ava-labs/avalanchego/database/memdb"
@@ -98,7 +101,7 @@ func TestSetTx(t *testing.T) {
 				}
 			}
 
-			val, exists, err := GetValue(db, k)
+			val, exists, err := GetValue(db, nil, k)
 			if err != nil {
 				t.Fatalf("#%d: failed to get key info %v", i, err)
 			}
@@ -115,3 +118,390 @@ func TestSetTx(t *testing.T) {
 		}
 	}
 }
+
+func TestSetTxTTL(t *testing.T) {
+	t.Parallel()
+
+	db := memdb.New()
+	defer db.Close()
+
+	g := DefaultGenesis()
+	g.DefaultValueTTL = 100
+	tt := []struct {
+		value     []byte
+		ttl       uint64
+		blockTime uint64
+		expiry    uint64
+		err       error
+	}{
+		{ // default ttl is applied
+			value:     []byte("default"),
+			blockTime: 10,
+			expiry:    110,
+		},
+		{ // longer ttl overrides default
+			value:     []byte("longer"),
+			ttl:       1000,
+			blockTime: 10,
+			expiry:    1010,
+		},
+		{ // shorter ttl overrides default
+			value:     []byte("shorter"),
+			ttl:       5,
+			blockTime: 10,
+			expiry:    15,
+		},
+		{ // can't overwrite unexpired value
+			value:     []byte("shorter"),
+			blockTime: 14,
+			err:       ErrKeyExists,
+		},
+		{ // can overwrite expired value
+			value:     []byte("shorter"),
+			blockTime: 15,
+			expiry:    115,
+		},
+		{ // expiry overflow
+			value:     []byte("overflow"),
+			ttl:       math.MaxUint64,
+			blockTime: 10,
+			err:       ErrInvalidTTL,
+		},
+	}
+	for i, tv := range tt {
+		id := ids.GenerateTestID()
+		if err := db.Put(PrefixTxValueKey(id), tv.value); err != nil {
+			t.Fatal(err)
+		}
+		utx := &SetTx{BaseTx: &BaseTx{}, Value: tv.value, TTL: tv.ttl}
+		tc := &TransactionContext{
+			Genesis:   g,
+			Database:  db,
+			BlockTime: tv.blockTime,
+			TxID:      id,
+		}
+		err := utx.Execute(tc)
+		if !errors.Is(err, tv.err) {
+			t.Fatalf("#%d: tx.Execute err expected %v, got %v", i, tv.err, err)
+		}
+		if tv.err != nil {
+			continue
+		}
+		vmeta, exists, err := GetValueMeta(db, ValueHash(tv.value))
+		if err != nil {
+			t.Fatalf("#%d: failed to get meta info %v", i, err)
+		}
+		if !exists {
+			t.Fatalf("#%d: value should have been persisted but not found", i)
+		}
+		if vmeta.TxID != id {
+			t.Fatalf("#%d: unexpected txID %q, expected %q", i, vmeta.TxID, id)
+		}
+		if vmeta.Expiry != tv.expiry {
+			t.Fatalf("#%d: unexpected expiry %d, expected %d", i, vmeta.Expiry, tv.expiry)
+		}
+	}
+}
+
+func TestSetTxTags(t *testing.T) {
+	t.Parallel()
+
+	db := memdb.New()
+	defer db.Close()
+
+	g := DefaultGenesis()
+	g.MaxTags = 2
+	g.MaxTagsSize = 10
+	tt := []struct {
+		value []byte
+		tags  []*Tag
+		err   error
+	}{
+		{ // no tags
+			value: []byte("untagged"),
+		},
+		{ // tags are persisted
+			value: []byte("tagged"),
+			tags:  []*Tag{{Key: "a", Value: "1"}, {Key: "b", Value: "2"}},
+		},
+		{ // too many tags
+			value: []byte("many"),
+			tags:  []*Tag{{Key: "a"}, {Key: "b"}, {Key: "c"}},
+			err:   ErrTooManyTags,
+		},
+		{ // tags too big
+			value: []byte("big"),
+			tags:  []*Tag{{Key: "abcde", Value: "fghijk"}},
+			err:   ErrTagsTooBig,
+		},
+		{ // empty key
+			value: []byte("empty"),
+			tags:  []*Tag{{Value: "1"}},
+			err:   ErrInvalidTag,
+		},
+		{ // duplicate key
+			value: []byte("duplicate"),
+			tags:  []*Tag{{Key: "a", Value: "1"}, {Key: "a", Value: "2"}},
+			err:   ErrInvalidTag,
+		},
+	}
+	for i, tv := range tt {
+		id := ids.GenerateTestID()
+		if err := db.Put(PrefixTxValueKey(id), tv.value); err != nil {
+			t.Fatal(err)
+		}
+		utx := &SetTx{BaseTx: &BaseTx{}, Value: tv.value, Tags: tv.tags}
+		tc := &TransactionContext{
+			Genesis:   g,
+			Database:  db,
+			BlockTime: 1,
+			TxID:      id,
+		}
+		err := utx.Execute(tc)
+		if !errors.Is(err, tv.err) {
+			t.Fatalf("#%d: tx.Execute err expected %v, got %v", i, tv.err, err)
+		}
+		vmeta, exists, err := GetValueMeta(db, ValueHash(tv.value))
+		if err != nil {
+			t.Fatalf("#%d: failed to get meta info %v", i, err)
+		}
+		if tv.err != nil {
+			if exists {
+				t.Fatalf("#%d: value should not have been persisted", i)
+			}
+			continue
+		}
+		if !exists {
+			t.Fatalf("#%d: value should have been persisted but not found", i)
+		}
+		if len(vmeta.Tags) != len(tv.tags) || (len(tv.tags) > 0 && !reflect.DeepEqual(vmeta.Tags, tv.tags)) {
+			t.Fatalf("#%d: unexpected tags %v, expected %v", i, vmeta.Tags, tv.tags)
+		}
+	}
+}
+
+func TestSetTxTagsTypedData(t *testing.T) {
+	t.Parallel()
+
+	utx := &SetTx{
+		BaseTx: &BaseTx{BlockID: ids.GenerateTestID(), Magic: 1, Price: 2},
+		Value:  []byte("tagged"),
+		Tags:   []*Tag{{Key: "type", Value: "text/plain"}, {Key: "a", Value: `"}]`}},
+	}
+	parsed, err := ParseTypedData(utx.TypedData())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsed, utx) {
+		t.Fatalf("unexpected tx %+v, expected %+v", parsed, utx)
+	}
+
+	// Tags are charged for like value bytes
+	untagged := &SetTx{BaseTx: &BaseTx{}, Value: utx.Value}
+	g := DefaultGenesis()
+	g.ValueUnitSize = 1
+	if utx.FeeUnits(g) <= untagged.FeeUnits(g) {
+		t.Fatalf("tagged fee units %d should exceed untagged %d", utx.FeeUnits(g), untagged.FeeUnits(g))
+	}
+}
+
+func TestSetTxFeeUnitsTTL(t *testing.T) {
+	t.Parallel()
+
+	g := DefaultGenesis()
+	value := make([]byte, 10*g.ValueUnitSize) // 11 value units
+	base := g.BaseTxUnits
+	tt := []struct {
+		defaultTTL uint64
+		ttl        uint64
+		units      uint64
+	}{
+		{defaultTTL: 0, ttl: 0, units: base + 11},                   // no expiry
+		{defaultTTL: 0, ttl: 1000, units: base + 11},                // no scaling without default
+		{defaultTTL: 100, ttl: 0, units: base + 11},                 // default ttl
+		{defaultTTL: 100, ttl: 100, units: base + 11},               // explicit default ttl
+		{defaultTTL: 100, ttl: 300, units: base + 33},               // 3x ttl
+		{defaultTTL: 100, ttl: 50, units: base + 6},                 // 0.5x ttl (rounded up)
+		{defaultTTL: 100, ttl: 1, units: base + 1},                  // minimum ttl
+		{defaultTTL: 1, ttl: math.MaxUint64, units: math.MaxUint64}, // overflow
+	}
+	for i, tv := range tt {
+		g.DefaultValueTTL = tv.defaultTTL
+		utx := &SetTx{BaseTx: &BaseTx{}, Value: value, TTL: tv.ttl}
+		if units := utx.FeeUnits(g); units != tv.units {
+			t.Fatalf("#%d: fee units expected %d, got %d", i, tv.units, units)
+		}
+		if units := utx.LoadUnits(g); units != base+11 {
+			t.Fatalf("#%d: load units expected %d, got %d", i, base+11, units)
+		}
+	}
+}
+
+func TestSetTxDomainSeparator(t *testing.T) {
+	t.Parallel()
+
+	db := memdb.New()
+	defer db.Close()
+
+	g := DefaultGenesis()
+	g.ValueDomainSeparator = []byte("blobvm-test")
+	v := []byte("namespaced")
+	k := g.ValueHash(v)
+	if k == ValueHash(v) {
+		t.Fatal("separator should change the key")
+	}
+	if k != ValueHash(append([]byte("blobvm-test"), v...)) {
+		t.Fatalf("key should be keccak(sep || value), got %s", k)
+	}
+
+	id := ids.GenerateTestID()
+	if err := db.Put(PrefixTxValueKey(id), v); err != nil {
+		t.Fatal(err)
+	}
+	utx := &SetTx{BaseTx: &BaseTx{}, Value: v}
+	if err := utx.Execute(&TransactionContext{Genesis: g, Database: db, TxID: id}); err != nil {
+		t.Fatal(err)
+	}
+	if _, exists, err := GetValueMeta(db, ValueHash(v)); err != nil || exists {
+		t.Fatalf("value should not be stored at the unseparated key (exists=%t, err=%v)", exists, err)
+	}
+	rv, exists, err := GetValue(db, nil, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !exists || !bytes.Equal(rv, v) {
+		t.Fatalf("value expected %q at %s, got %q (exists=%t)", v, k, rv, exists)
+	}
+	if key := utx.Activity(g).Key; key != strings.ToLower(k.Hex()) {
+		t.Fatalf("activity key expected %s, got %s", k.Hex(), key)
+	}
+	if err := utx.Execute(&TransactionContext{Genesis: g, Database: db, TxID: id}); !errors.Is(err, ErrKeyExists) {
+		t.Fatalf("duplicate set err expected %v, got %v", ErrKeyExists, err)
+	}
+}
+
+func TestSetTxAddressing(t *testing.T) {
+	t.Parallel()
+
+	db := memdb.New()
+	defer db.Close()
+
+	g := DefaultGenesis()
+	g.ValueDomainSeparator = []byte("blobvm-test")
+	v := []byte("hello world")
+	k, err := CIDKey("bafkreifzjut3te2nhyekklss27nh3k72ysco7y32koao5eei66wof36n5e")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tt := []struct {
+		utx     *SetTx
+		allowed []Addressing
+		err     error
+	}{
+		{ // not allowed by default
+			utx: &SetTx{BaseTx: &BaseTx{}, Value: v, Addressing: CIDv1, Key: k},
+			err: ErrAddressingNotAllowed,
+		},
+		{ // key doesn't match the value
+			utx:     &SetTx{BaseTx: &BaseTx{}, Value: v, Addressing: CIDv1, Key: g.ValueHash(v)},
+			allowed: []Addressing{CIDv1},
+			err:     ErrInvalidKey,
+		},
+		{ // hashed keys can't be overridden
+			utx:     &SetTx{BaseTx: &BaseTx{}, Value: v, Key: k},
+			allowed: []Addressing{CIDv1},
+			err:     ErrInvalidKey,
+		},
+		{
+			utx:     &SetTx{BaseTx: &BaseTx{}, Value: v, Addressing: "cidv0", Key: k},
+			allowed: []Addressing{CIDv1},
+			err:     ErrUnsupportedAddressing,
+		},
+		{
+			utx:     &SetTx{BaseTx: &BaseTx{}, Value: v, Addressing: CIDv1, Key: k},
+			allowed: []Addressing{CIDv1},
+		},
+	}
+	for i, tv := range tt {
+		g.AllowedAddressing = tv.allowed
+		id := ids.GenerateTestID()
+		if err := db.Put(PrefixTxValueKey(id), v); err != nil {
+			t.Fatal(err)
+		}
+		err := tv.utx.Execute(&TransactionContext{Genesis: g, Database: db, TxID: id})
+		if !errors.Is(err, tv.err) {
+			t.Fatalf("#%d: tx.Execute err expected %v, got %v", i, tv.err, err)
+		}
+	}
+
+	vmeta, exists, err := GetValueMeta(db, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !exists || vmeta.Addressing != CIDv1 {
+		t.Fatalf("value meta expected at %s with %q addressing, got %+v", k, CIDv1, vmeta)
+	}
+	rv, exists, err := GetValue(db, nil, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !exists || !bytes.Equal(rv, v) {
+		t.Fatalf("value expected %q at %s, got %q (exists=%t)", v, k, rv, exists)
+	}
+	if _, exists, err := GetValueMeta(db, g.ValueHash(v)); err != nil || exists {
+		t.Fatalf("value should not be stored at its hash (exists=%t, err=%v)", exists, err)
+	}
+	if err := RestoreValue(db, nil, g, k, v); err != nil {
+		t.Fatal(err)
+	}
+
+	// The addressing is signed
+	utx := tt[len(tt)-1].utx
+	parsed, err := ParseTypedData(utx.TypedData())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsed, utx) {
+		t.Fatalf("unexpected tx %+v, expected %+v", parsed, utx)
+	}
+
+	g.Magic = 1
+	g.AllowedAddressing = []Addressing{"cidv0"}
+	if err := g.Verify(); !errors.Is(err, ErrUnsupportedAddressing) {
+		t.Fatalf("genesis err expected %v, got %v", ErrUnsupportedAddressing, err)
+	}
+}
+
+func TestSetTxContentPolicy(t *testing.T) {
+	t.Parallel()
+
+	db := memdb.New()
+	defer db.Close()
+
+	g := DefaultGenesis()
+	g.ContentPolicy = ContentPolicy{
+		UTF8:            true,
+		AllowedPrefixes: [][]byte{[]byte("\x89PNG")},
+	}
+	tt := []struct {
+		value []byte
+		err   error
+	}{
+		{value: []byte("hello, 世界")},
+		{value: []byte("\x89PNG\r\n\x1a\n\xff\xfe")},
+		{value: []byte{0xff, 0xfe, 0xfd}, err: ErrContentRejected},
+		{value: []byte("GIF89a\xff"), err: ErrContentRejected},
+	}
+	for i, tv := range tt {
+		utx := &SetTx{BaseTx: &BaseTx{}, Value: tv.value}
+		err := utx.Execute(&TransactionContext{Genesis: g, Database: db, TxID: ids.GenerateTestID()})
+		if !errors.Is(err, tv.err) {
+			t.Fatalf("#%d: tx.Execute err expected %v, got %v", i, tv.err, err)
+		}
+	}
+
+	// Everything is allowed by default
+	if p := (&ContentPolicy{}); !p.Allows([]byte{0xff, 0xfe, 0xfd}) {
+		t.Fatal("empty policy should allow all values")
+	}
+}
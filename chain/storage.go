@@ -6,25 +6,39 @@ package chain
 import (
 	"bytes"
 	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"fmt"
 
-	"github.com/ava-labs/avalanchego/cache"
 	"github.com/ava-labs/avalanchego/database"
 	"github.com/ava-labs/avalanchego/ids"
 	"github.com/ethereum/go-ethereum/common"
 	smath "github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
 )
 
 // 0x0/ (block hashes)
 // 0x1/ (tx hashes)
-//   -> [tx hash]=>nil
+//   -> [tx hash]=>block hash (nil for txs accepted before blocks were indexed)
 // 0x2/ (tx values)
 //   -> [tx hash]=>value
+//   -> [tx hash]/[key]=>value (batched values)
 // 0x3/ (item keys)
 //   -> [key]
 // 0x4/ (balance)
 //   -> [owner]=> balance
+// 0x5/ (genesis values)
+//   -> [key]=>value
+// 0x6/ (tag index)
+//   -> [tag hash]/[key]=>nil
+// 0x7/ (sender index)
+//   -> [sender]/[^height][^tx index]=>tx hash
+// 0x8/ (banned senders, node-local)
+//   -> [sender]=>nil
+// 0x9/ (accepted block heights)
+//   -> [height]=>block hash
+// 0xa/ (tree references, node-local)
+//   -> [child]/[root]=>nil
 
 const (
 	blockPrefix   = 0x0
@@ -32,15 +46,19 @@ const (
 	txValuePrefix = 0x2
 	keyPrefix     = 0x3
 	balancePrefix = 0x4
-
-	linkedTxLRUSize = 512
+	genesisPrefix = 0x5
+	tagPrefix     = 0x6
+	senderPrefix  = 0x7
+	bannedPrefix  = 0x8
+	heightPrefix  = 0x9
+	treeRefPrefix = 0xa
 
 	ByteDelimiter byte = '/'
 )
 
 var (
-	lastAccepted  = []byte("last_accepted")
-	linkedTxCache = &cache.LRU{Size: linkedTxLRUSize}
+	lastAccepted   = []byte("last_accepted")
+	genesisApplied = []byte("genesis_applied")
 )
 
 // [blockPrefix] + [delimiter] + [blockID]
@@ -61,6 +79,17 @@ func PrefixTxKey(txID ids.ID) (k []byte) {
 	return k
 }
 
+// [txValuePrefix] + [delimiter] + [txID] + [delimiter] + [key]
+func PrefixBatchValueKey(txID ids.ID, key common.Hash) (k []byte) {
+	k = make([]byte, 2+len(txID)+1+common.HashLength)
+	k[0] = txValuePrefix
+	k[1] = ByteDelimiter
+	copy(k[2:], txID[:])
+	k[2+len(txID)] = ByteDelimiter
+	copy(k[2+len(txID)+1:], key[:])
+	return k
+}
+
 // [txValuePrefix] + [delimiter] + [txID]
 func PrefixTxValueKey(txID ids.ID) (k []byte) {
 	k = make([]byte, 2+len(txID))
@@ -89,6 +118,99 @@ func PrefixBalanceKey(address common.Address) (k []byte) {
 	return
 }
 
+// [genesisPrefix] + [delimiter] + [key]
+func PrefixGenesisValueKey(key common.Hash) (k []byte) {
+	k = make([]byte, 2+common.HashLength)
+	k[0] = genesisPrefix
+	k[1] = ByteDelimiter
+	copy(k[2:], key.Bytes())
+	return k
+}
+
+// TagHash returns the hash used to index values tagged with [key]=[value].
+func TagHash(key string, value string) common.Hash {
+	b := make([]byte, 8+len(key)+len(value))
+	binary.BigEndian.PutUint64(b, uint64(len(key)))
+	copy(b[8:], key)
+	copy(b[8+len(key):], value)
+	return crypto.Keccak256Hash(b)
+}
+
+// [tagPrefix] + [delimiter] + [tagHash] + [delimiter]
+func prefixTag(tagHash common.Hash) (k []byte) {
+	k = make([]byte, 3+common.HashLength)
+	k[0] = tagPrefix
+	k[1] = ByteDelimiter
+	copy(k[2:], tagHash.Bytes())
+	k[2+common.HashLength] = ByteDelimiter
+	return k
+}
+
+// [tagPrefix] + [delimiter] + [tagHash] + [delimiter] + [key]
+func PrefixTagKey(tagHash common.Hash, key common.Hash) (k []byte) {
+	k = make([]byte, 3+2*common.HashLength)
+	copy(k, prefixTag(tagHash))
+	copy(k[3+common.HashLength:], key.Bytes())
+	return k
+}
+
+// [senderPrefix] + [delimiter] + [address] + [delimiter]
+func prefixSender(address common.Address) (k []byte) {
+	k = make([]byte, 3+common.AddressLength)
+	k[0] = senderPrefix
+	k[1] = ByteDelimiter
+	copy(k[2:], address[:])
+	k[2+common.AddressLength] = ByteDelimiter
+	return k
+}
+
+// [senderPrefix] + [delimiter] + [address] + [delimiter] + [^height] + [^index]
+//
+// [height] and [index] are inverted so newer txs sort first.
+func PrefixSenderTxKey(address common.Address, height uint64, index uint32) (k []byte) {
+	k = make([]byte, 3+common.AddressLength+8+4)
+	copy(k, prefixSender(address))
+	binary.BigEndian.PutUint64(k[3+common.AddressLength:], ^height)
+	binary.BigEndian.PutUint32(k[3+common.AddressLength+8:], ^index)
+	return k
+}
+
+// [bannedPrefix] + [delimiter] + [address]
+func PrefixBannedKey(address common.Address) (k []byte) {
+	k = make([]byte, 2+common.AddressLength)
+	k[0] = bannedPrefix
+	k[1] = ByteDelimiter
+	copy(k[2:], address[:])
+	return k
+}
+
+// [heightPrefix] + [delimiter] + [height]
+func PrefixHeightKey(height uint64) (k []byte) {
+	k = make([]byte, 2+8)
+	k[0] = heightPrefix
+	k[1] = ByteDelimiter
+	binary.BigEndian.PutUint64(k[2:], height)
+	return k
+}
+
+// [treeRefPrefix] + [delimiter] + [child] + [delimiter]
+func prefixTreeRef(child common.Hash) (k []byte) {
+	k = make([]byte, 3+common.HashLength)
+	k[0] = treeRefPrefix
+	k[1] = ByteDelimiter
+	copy(k[2:], child.Bytes())
+	k[2+common.HashLength] = ByteDelimiter
+	return k
+}
+
+// [treeRefPrefix] + [delimiter] + [child] + [delimiter] + [root]
+func PrefixTreeRefKey(child common.Hash, root common.Hash) (k []byte) {
+	k = make([]byte, 3+2*common.HashLength)
+	copy(k, prefixTreeRef(child))
+	copy(k[3+common.HashLength:], root.Bytes())
+	return k
+}
+
 var ErrInvalidKeyFormat = errors.New("invalid key format")
 
 func GetValueMeta(db database.KeyValueReader, key common.Hash) (*ValueMeta, bool, error) {
@@ -102,13 +224,15 @@ func GetValueMeta(db database.KeyValueReader, key common.Hash) (*ValueMeta, bool
 		return nil, false, err
 	}
 	vmeta := new(ValueMeta)
-	if _, err := Unmarshal(rvmeta, vmeta); err != nil {
+	if err := unmarshalValueMeta(rvmeta, vmeta); err != nil {
 		return nil, false, err
 	}
 	return vmeta, true, nil
 }
 
-func GetValue(db database.KeyValueReader, key common.Hash) ([]byte, bool, error) {
+// GetValue returns the value stored at [key], reading values linked to txs
+// through [vc] (which may be nil).
+func GetValue(db database.KeyValueReader, vc *ValueCache, key common.Hash) ([]byte, bool, error) {
 	// [keyPrefix] + [delimiter] + [key]
 	k := ValueKey(key)
 	rvmeta, err := db.Get(k)
@@ -119,12 +243,20 @@ func GetValue(db database.KeyValueReader, key common.Hash) ([]byte, bool, error)
 		return nil, false, err
 	}
 	vmeta := new(ValueMeta)
-	if _, err := Unmarshal(rvmeta, vmeta); err != nil {
+	if err := unmarshalValueMeta(rvmeta, vmeta); err != nil {
 		return nil, false, err
 	}
 
 	// Lookup stored value
-	v, err := getLinkedValue(db, vmeta.TxID[:])
+	var v []byte
+	if vmeta.TxID == ids.Empty {
+		// Values pre-stored at genesis are not linked to a tx
+		v, err = db.Get(PrefixGenesisValueKey(key))
+	} else if vmeta.Batched {
+		v, err = vc.get(db, batchLink(vmeta.TxID, key))
+	} else {
+		v, err = vc.get(db, vmeta.TxID[:])
+	}
 	if err != nil {
 		return nil, false, err
 	}
@@ -136,7 +268,7 @@ type KeyValueMeta struct {
 	ValueMeta *ValueMeta `serialize:"true" json:"valueMeta"`
 }
 
-// linkValues extracts all *SetTx.Value in [block] and replaces them with the
+// linkValues extracts all *SetTx.Value (and *BatchTx.Values) in [block] and replaces them with the
 // corresponding txID where they were found. The extracted value is then
 // written to disk.
 func linkValues(db database.KeyValueWriter, block *StatelessBlock) ([]*Transaction, error) {
@@ -160,7 +292,27 @@ func linkValues(db database.KeyValueWriter, block *StatelessBlock) ([]*Transacti
 			if err := db.Put(PrefixTxValueKey(tx.ID()), t.Value); err != nil {
 				return nil, err
 			}
+			if err := putTreeRefs(db, t.key(g), t.Value); err != nil {
+				return nil, err
+			}
 			t.Value = tx.id[:] // used to properly parse on restore
+		case *BatchTx:
+			cptx := tx.Copy()
+			if err := cptx.Init(g); err != nil {
+				return nil, err
+			}
+			ogTxs[i] = cptx
+
+			for _, v := range t.Values {
+				k := g.ValueHash(v.Value)
+				if err := db.Put(PrefixBatchValueKey(tx.ID(), k), v.Value); err != nil {
+					return nil, err
+				}
+				if err := putTreeRefs(db, k, v.Value); err != nil {
+					return nil, err
+				}
+				v.Value = batchLink(tx.ID(), k) // used to properly parse on restore
+			}
 		default:
 			ogTxs[i] = tx
 		}
@@ -169,37 +321,64 @@ func linkValues(db database.KeyValueWriter, block *StatelessBlock) ([]*Transacti
 }
 
 // restoreValues restores the unlinked values associated with all *SetTx.Value
-// in [block].
+// (and *BatchTx.Values) in [block].
 func restoreValues(db database.KeyValueReader, block *StatefulBlock) error {
 	for _, tx := range block.Txs {
-		if t, ok := tx.UnsignedTransaction.(*SetTx); ok {
+		switch t := tx.UnsignedTransaction.(type) {
+		case *SetTx:
 			if len(t.Value) == 0 {
 				continue
 			}
-			txID, err := ids.ToID(t.Value)
-			if err != nil {
-				return err
-			}
-			b, err := db.Get(PrefixTxValueKey(txID))
+			b, err := restoreValue(db, t.Value)
 			if err != nil {
 				return err
 			}
 			t.Value = b
+		case *BatchTx:
+			for _, v := range t.Values {
+				b, err := restoreValue(db, v.Value)
+				if err != nil {
+					return err
+				}
+				v.Value = b
+			}
 		}
 	}
 	return nil
 }
 
-func SetLastAccepted(db database.KeyValueWriter, block *StatelessBlock) error {
+func restoreValue(db database.KeyValueReader, link []byte) ([]byte, error) {
+	vk, err := linkedValueKey(link)
+	if err != nil {
+		return nil, err
+	}
+	return db.Get(vk)
+}
+
+// SetLastAccepted stores [block] (compressed if [compress] is set, see
+// [Context.CompressBlocks]) and indexes it as the last accepted block.
+func SetLastAccepted(db database.KeyValueWriter, block *StatelessBlock, compress bool) error {
 	bid := block.ID()
 	if err := db.Put(lastAccepted, bid[:]); err != nil {
 		return err
 	}
+	if err := db.Put(PrefixHeightKey(block.Hght), bid[:]); err != nil {
+		return err
+	}
 	ogTxs, err := linkValues(db, block)
 	if err != nil {
 		return err
 	}
-	sbytes, err := Marshal(block.StatefulBlock)
+	for i, tx := range block.Txs {
+		txID := tx.ID()
+		if err := db.Put(PrefixTxKey(txID), bid[:]); err != nil {
+			return err
+		}
+		if err := db.Put(PrefixSenderTxKey(tx.Sender(), block.Hght, uint32(i)), txID[:]); err != nil {
+			return err
+		}
+	}
+	sbytes, err := marshalStoredBlock(block.StatefulBlock, compress)
 	if err != nil {
 		return err
 	}
@@ -216,6 +395,11 @@ func HasLastAccepted(db database.Database) (bool, error) {
 	return db.Has(lastAccepted)
 }
 
+// HasGenesisApplied returns true if [Genesis.Load] has committed to [db].
+func HasGenesisApplied(db database.KeyValueReader) (bool, error) {
+	return db.Has(genesisApplied)
+}
+
 func GetLastAccepted(db database.KeyValueReader) (ids.ID, error) {
 	v, err := db.Get(lastAccepted)
 	if errors.Is(err, database.ErrNotFound) {
@@ -228,20 +412,78 @@ func GetLastAccepted(db database.KeyValueReader) (ids.ID, error) {
 }
 
 func GetBlock(db database.KeyValueReader, bid ids.ID) (*StatefulBlock, error) {
-	b, err := db.Get(PrefixBlockKey(bid))
+	blk, err := getStoredBlock(db, bid)
 	if err != nil {
 		return nil, err
 	}
-	blk := new(StatefulBlock)
-	if _, err := Unmarshal(b, blk); err != nil {
+	if err := restoreValues(db, blk); err != nil {
 		return nil, err
 	}
-	if err := restoreValues(db, blk); err != nil {
+	return blk, nil
+}
+
+// getStoredBlock returns the accepted block [bid] without restoring its values.
+func getStoredBlock(db database.KeyValueReader, bid ids.ID) (*StatefulBlock, error) {
+	b, err := db.Get(PrefixBlockKey(bid))
+	if err != nil {
+		return nil, err
+	}
+	blk := new(StatefulBlock)
+	if err := unmarshalStoredBlock(b, blk); err != nil {
 		return nil, err
 	}
 	return blk, nil
 }
 
+// GetBlockIDAtHeight returns the ID of the accepted block at [height] (false
+// if there is none).
+func GetBlockIDAtHeight(db database.KeyValueReader, height uint64) (ids.ID, bool, error) {
+	v, err := db.Get(PrefixHeightKey(height))
+	if errors.Is(err, database.ErrNotFound) {
+		return ids.ID{}, false, nil
+	}
+	if err != nil {
+		return ids.ID{}, false, err
+	}
+	id, err := ids.ToID(v)
+	return id, err == nil, err
+}
+
+// IndexBlockHeights indexes the heights of [bid] (an accepted block) and its
+// ancestors that were accepted before heights were indexed by
+// [SetLastAccepted], walking back until it finds an indexed ancestor. The index
+// is written at once, so an interrupted walk is started over. It returns the
+// number of heights indexed.
+func IndexBlockHeights(db database.Database, bid ids.ID) (int, error) {
+	batch := db.NewBatch()
+	indexed := 0
+	for {
+		blk, err := getStoredBlock(db, bid)
+		if err != nil {
+			return 0, err
+		}
+		id, ok, err := GetBlockIDAtHeight(db, blk.Hght)
+		if err != nil {
+			return 0, err
+		}
+		if ok && id == bid {
+			break
+		}
+		if err := batch.Put(PrefixHeightKey(blk.Hght), bid[:]); err != nil {
+			return 0, err
+		}
+		indexed++
+		if blk.Hght == 0 {
+			break
+		}
+		bid = blk.Prnt
+	}
+	if err := batch.Write(); err != nil {
+		return 0, err
+	}
+	return indexed, nil
+}
+
 // DB
 func HasKey(db database.KeyValueReader, key common.Hash) (bool, error) {
 	// [keyPrefix] + [delimiter] + [key]
@@ -250,21 +492,257 @@ func HasKey(db database.KeyValueReader, key common.Hash) (bool, error) {
 }
 
 type ValueMeta struct {
-	Size    uint64 `serialize:"true" json:"size"`
-	TxID    ids.ID `serialize:"true" json:"txId"`
-	Created uint64 `serialize:"true" json:"created"`
+	Size    uint64 `serialize:"true" serializeV0:"true" json:"size"`
+	TxID    ids.ID `serialize:"true" serializeV0:"true" json:"txId"`
+	Created uint64 `serialize:"true" serializeV0:"true" json:"created"`
+	Expiry  uint64 `serialize:"true" json:"expiry"` // 0 never expires
+	Tags    []*Tag `serialize:"true" json:"tags,omitempty"`
+
+	// Batched values were set by the [BatchTx] [TxID] (which may have set
+	// other values too).
+	Batched bool `serialize:"true" json:"batched,omitempty"`
+
+	// Pinned values never expire (regardless of [Expiry]) until they are
+	// unpinned by [PinnedBy].
+	Pinned   bool           `serialize:"true" json:"pinned,omitempty"`
+	PinnedBy common.Address `serialize:"true" json:"pinnedBy"`
+
+	// Addressing is how the key of the value was derived from it.
+	Addressing Addressing `serialize:"true" json:"addressing,omitempty"`
 }
 
+// Expired returns true if the value is no longer stored at [now] (unix
+// seconds).
+func (v *ValueMeta) Expired(now uint64) bool {
+	return !v.Pinned && v.Expiry != 0 && now >= v.Expiry
+}
+
+// PutKey stores [vmeta] for [key] in the codec encoding.
 func PutKey(db database.KeyValueWriter, key common.Hash, vmeta *ValueMeta) error {
+	return putValueMeta(db, key, vmeta, false)
+}
+
+// putValueMeta stores [vmeta] for [key], in the compact encoding if [compact]
+// is set (see [Context.CompactValueMeta]).
+func putValueMeta(db database.KeyValueWriter, key common.Hash, vmeta *ValueMeta, compact bool) error {
 	// [keyPrefix] + [delimiter] + [key]
 	k := ValueKey(key)
-	rvmeta, err := Marshal(vmeta)
+	rvmeta, err := marshalValueMeta(vmeta, compact)
 	if err != nil {
 		return err
 	}
 	return db.Put(k, rvmeta)
 }
 
+// PutGenesisValue stores [value] at [key] without linking it to a tx.
+func PutGenesisValue(db database.KeyValueWriter, key common.Hash, value []byte, created uint64) error {
+	if err := db.Put(PrefixGenesisValueKey(key), value); err != nil {
+		return err
+	}
+	return PutKey(db, key, &ValueMeta{
+		Size:    uint64(len(value)),
+		TxID:    ids.Empty,
+		Created: created,
+	})
+}
+
+// PutTags indexes [key] under each of [tags].
+func PutTags(db database.KeyValueWriter, key common.Hash, tags []*Tag) error {
+	for _, tag := range tags {
+		if err := db.Put(PrefixTagKey(TagHash(tag.Key, tag.Value), key), nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteTags removes [key] from the index of each of [tags].
+func DeleteTags(db database.KeyValueDeleter, key common.Hash, tags []*Tag) error {
+	for _, tag := range tags {
+		if err := db.Delete(PrefixTagKey(TagHash(tag.Key, tag.Value), key)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TreeRoot mirrors the JSON encoding of a tree.Root (which can't be imported
+// here without an import cycle). The tree package tests that both stay in
+// sync.
+type TreeRoot struct {
+	Contents    []byte        `json:"contents"`
+	Children    []common.Hash `json:"children"`
+	Height      uint64        `json:"height,omitempty"`
+	Size        uint64        `json:"size,omitempty"`
+	Compression string        `json:"compression,omitempty"`
+	Chunking    string        `json:"chunking,omitempty"`
+}
+
+// ParseTreeRoot returns the [TreeRoot] encoded in [v], if [v] is one.
+func ParseTreeRoot(v []byte) (*TreeRoot, bool) {
+	d := json.NewDecoder(bytes.NewReader(v))
+	d.DisallowUnknownFields()
+	r := new(TreeRoot)
+	if err := d.Decode(r); err != nil || d.More() {
+		return nil, false
+	}
+	// Exactly one of [Contents] or [Children] is set for a valid root
+	if (len(r.Contents) > 0) == (len(r.Children) > 0) {
+		return nil, false
+	}
+	return r, true
+}
+
+// putTreeRefs records that [root] references each of its children, if [v] (the
+// value of [root]) is a [TreeRoot].
+func putTreeRefs(db database.KeyValueWriter, root common.Hash, v []byte) error {
+	r, ok := ParseTreeRoot(v)
+	if !ok {
+		return nil
+	}
+	for _, child := range r.Children {
+		if err := db.Put(PrefixTreeRefKey(child, root), nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isReferenced returns true if [key] is a child of a [TreeRoot] that is
+// unexpired at [now] (unix seconds) or is itself referenced, so the chunks of
+// multi-level trees are protected by their top root. [visited] holds the roots
+// already checked.
+func isReferenced(db database.Database, key common.Hash, now uint64, visited map[common.Hash]struct{}) (bool, error) {
+	prefix := prefixTreeRef(key)
+	cursor := db.NewIteratorWithPrefix(prefix)
+	defer cursor.Release()
+	for cursor.Next() {
+		root := common.BytesToHash(cursor.Key()[len(prefix):])
+		if _, ok := visited[root]; ok {
+			continue
+		}
+		visited[root] = struct{}{}
+		vmeta, exists, err := GetValueMeta(db, root)
+		if err != nil {
+			return false, err
+		}
+		if !exists {
+			continue
+		}
+		if !vmeta.Expired(now) {
+			return true, nil
+		}
+		referenced, err := isReferenced(db, root, now, visited)
+		if err != nil || referenced {
+			return referenced, err
+		}
+	}
+	return false, cursor.Error()
+}
+
+// GetLiveValueMeta returns the [ValueMeta] of [key] and whether its value is
+// still served at [now] (unix seconds): it is unexpired, or it is referenced
+// by a live [TreeRoot] (see [isReferenced]), so the chunks of a renewed or
+// pinned root stay resolvable after their own expiry.
+func GetLiveValueMeta(db database.Database, key common.Hash, now uint64) (*ValueMeta, bool, error) {
+	vmeta, exists, err := GetValueMeta(db, key)
+	if err != nil || !exists {
+		return nil, false, err
+	}
+	if !vmeta.Expired(now) {
+		return vmeta, true, nil
+	}
+	referenced, err := isReferenced(db, key, now, map[common.Hash]struct{}{})
+	if err != nil {
+		return nil, false, err
+	}
+	return vmeta, referenced, nil
+}
+
+// Inconsistency is a [ValueMeta] found by [CheckIntegrity] that doesn't match
+// its stored value.
+type Inconsistency struct {
+	Key    common.Hash
+	Reason string
+}
+
+// CheckIntegrity cross-checks every [ValueMeta] against its stored value
+// (linked from its tx or pre-stored at genesis), returning those whose value
+// is missing or doesn't match [ValueMeta.Size].
+func CheckIntegrity(db database.Database) (checked int, inconsistent []*Inconsistency, err error) {
+	prefix := []byte{keyPrefix, ByteDelimiter}
+	cursor := db.NewIteratorWithPrefix(prefix)
+	defer cursor.Release()
+	for cursor.Next() {
+		checked++
+		key := common.BytesToHash(cursor.Key()[len(prefix):])
+		vmeta := new(ValueMeta)
+		if err := unmarshalValueMeta(cursor.Value(), vmeta); err != nil {
+			inconsistent = append(inconsistent, &Inconsistency{Key: key, Reason: fmt.Sprintf("invalid value meta: %v", err)})
+			continue
+		}
+		vk := PrefixGenesisValueKey(key)
+		if vmeta.TxID != ids.Empty {
+			link := vmeta.TxID[:]
+			if vmeta.Batched {
+				link = batchLink(vmeta.TxID, key)
+			}
+			vk, err = linkedValueKey(link)
+			if err != nil {
+				return 0, nil, err
+			}
+		}
+		v, err := db.Get(vk)
+		switch {
+		case errors.Is(err, database.ErrNotFound):
+			inconsistent = append(inconsistent, &Inconsistency{Key: key, Reason: "missing value"})
+		case err != nil:
+			return 0, nil, err
+		case uint64(len(v)) != vmeta.Size:
+			inconsistent = append(inconsistent, &Inconsistency{
+				Key:    key,
+				Reason: fmt.Sprintf("value is %d bytes, expected %d", len(v), vmeta.Size),
+			})
+		}
+	}
+	return checked, inconsistent, cursor.Error()
+}
+
+// RestoreValue stores [v] where the [ValueMeta] at [key] links it (ex: after
+// the value went missing from disk), once [v] is checked against [key] (under
+// its [ValueMeta.Addressing]) and the [ValueMeta].
+func RestoreValue(db database.Database, vc *ValueCache, g *Genesis, key common.Hash, v []byte) error {
+	vmeta, exists, err := GetValueMeta(db, key)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrKeyMissing
+	}
+	if k := g.AddressKey(vmeta.Addressing, v); k != key {
+		return fmt.Errorf("%w: value is addressed at %s", ErrInvalidKey, k)
+	}
+	if uint64(len(v)) != vmeta.Size {
+		return fmt.Errorf("%w: value is %d bytes, expected %d", ErrInvalidKey, len(v), vmeta.Size)
+	}
+	if vmeta.TxID == ids.Empty {
+		return db.Put(PrefixGenesisValueKey(key), v)
+	}
+	link := vmeta.TxID[:]
+	if vmeta.Batched {
+		link = batchLink(vmeta.TxID, key)
+	}
+	vk, err := linkedValueKey(link)
+	if err != nil {
+		return err
+	}
+	if err := db.Put(vk, v); err != nil {
+		return err
+	}
+	vc.evict(link)
+	return nil
+}
+
 func SetTransaction(db database.KeyValueWriter, tx *Transaction) error {
 	k := PrefixTxKey(tx.ID())
 	return db.Put(k, nil)
@@ -275,29 +753,98 @@ func HasTransaction(db database.KeyValueReader, txID ids.ID) (bool, error) {
 	return db.Has(k)
 }
 
-func getLinkedValue(db database.KeyValueReader, b []byte) ([]byte, error) {
-	bh := string(b)
-	if v, ok := linkedTxCache.Get(bh); ok {
-		bytes, ok := v.([]byte)
-		if !ok {
-			return nil, fmt.Errorf("expected []byte but got %T", v)
+// GetTransactionHeight returns the height of the block that accepted [txID]
+// (false if [txID] wasn't accepted, or was accepted before its block was
+// indexed).
+func GetTransactionHeight(db database.KeyValueReader, txID ids.ID) (uint64, bool, error) {
+	bid, err := db.Get(PrefixTxKey(txID))
+	if errors.Is(err, database.ErrNotFound) || (err == nil && len(bid) == 0) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	blkID, err := ids.ToID(bid)
+	if err != nil {
+		return 0, false, err
+	}
+	blk, err := getStoredBlock(db, blkID)
+	if err != nil {
+		return 0, false, err
+	}
+	return blk.Hght, true, nil
+}
+
+// GetTransaction returns the accepted tx [txID] (with its values restored).
+// Txs accepted before their block was indexed are reported as missing.
+func GetTransaction(db database.KeyValueReader, g *Genesis, txID ids.ID) (*Transaction, bool, error) {
+	bid, err := db.Get(PrefixTxKey(txID))
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if len(bid) == 0 {
+		return nil, false, nil
+	}
+	blkID, err := ids.ToID(bid)
+	if err != nil {
+		return nil, false, err
+	}
+	blk, err := GetBlock(db, blkID)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, tx := range blk.Txs {
+		if err := tx.Init(g); err != nil {
+			return nil, false, err
+		}
+		if tx.ID() == txID {
+			return tx, true, nil
 		}
-		return bytes, nil
 	}
-	txID, err := ids.ToID(b)
+	return nil, false, fmt.Errorf("tx %s not found in block %s", txID, blkID)
+}
+
+func readLinkedValue(db database.KeyValueReader, b []byte) ([]byte, error) {
+	vk, err := linkedValueKey(b)
 	if err != nil {
 		return nil, err
 	}
-	vk := PrefixTxValueKey(txID)
-	v, err := db.Get(vk)
+	return db.Get(vk)
+}
+
+// batchLink returns the link to the value at [key] set by the [BatchTx]
+// [txID].
+func batchLink(txID ids.ID, key common.Hash) []byte {
+	b := make([]byte, len(txID)+common.HashLength)
+	copy(b, txID[:])
+	copy(b[len(txID):], key[:])
+	return b
+}
+
+// linkedValueKey returns the database key of the value linked by [b] (a txID
+// or a [batchLink]).
+func linkedValueKey(b []byte) ([]byte, error) {
+	if len(b) == len(ids.Empty)+common.HashLength {
+		txID, err := ids.ToID(b[:len(ids.Empty)])
+		if err != nil {
+			return nil, err
+		}
+		return PrefixBatchValueKey(txID, common.BytesToHash(b[len(ids.Empty):])), nil
+	}
+	txID, err := ids.ToID(b)
 	if err != nil {
 		return nil, err
 	}
-	linkedTxCache.Put(bh, v)
-	return v, nil
+	return PrefixTxValueKey(txID), nil
 }
 
 func GetBalance(db database.KeyValueReader, address common.Address) (uint64, error) {
+	if c, ok := db.(*balanceCache); ok {
+		return c.getBalance(address)
+	}
 	k := PrefixBalanceKey(address)
 	v, err := db.Get(k)
 	if errors.Is(err, database.ErrNotFound) {
@@ -310,6 +857,10 @@ func GetBalance(db database.KeyValueReader, address common.Address) (uint64, err
 }
 
 func SetBalance(db database.KeyValueWriter, address common.Address, bal uint64) error {
+	if c, ok := db.(*balanceCache); ok {
+		c.balances[address] = bal
+		return nil
+	}
 	k := PrefixBalanceKey(address)
 	b := make([]byte, 8)
 	binary.BigEndian.PutUint64(b, bal)
@@ -336,23 +887,136 @@ func ModifyBalance(db database.KeyValueReaderWriter, address common.Address, add
 	return n, SetBalance(db, address, n)
 }
 
-func SelectRandomValue(db database.Database, seed []byte) []byte {
-	iterator := ValueHash(seed)
-	startKey := ValueKey(iterator)
-	baseKey := []byte{keyPrefix, ByteDelimiter} // don't add empty hash with ValueKey
-	cursor := db.NewIteratorWithStart(startKey)
+// ListKeys returns up to [limit] unexpired keys (in ascending order) starting
+// at [start] (inclusive). If there are more keys, [next] is the key to start
+// the next page at.
+func ListKeys(
+	db database.Iteratee, start common.Hash, limit int, now uint64,
+) (keys []common.Hash, next *common.Hash, err error) {
+	cursor := db.NewIteratorWithStartAndPrefix(ValueKey(start), []byte{keyPrefix, ByteDelimiter})
 	defer cursor.Release()
+	keys = []common.Hash{}
 	for cursor.Next() {
-		curKey := cursor.Key()
-		if bytes.Compare(baseKey, curKey) < -1 { // startKey < curKey; continue search
+		vmeta := new(ValueMeta)
+		if err := unmarshalValueMeta(cursor.Value(), vmeta); err != nil {
+			return nil, nil, err
+		}
+		if vmeta.Expired(now) {
 			continue
 		}
-		if !bytes.HasPrefix(curKey, baseKey) { // curKey does not have prefix base key; end search
-			break
+		key := common.BytesToHash(cursor.Key()[2:])
+		if len(keys) == limit {
+			return keys, &key, nil
 		}
-		return cursor.Value()
+		keys = append(keys, key)
 	}
+	return keys, nil, cursor.Error()
+}
 
-	// No value selected
-	return nil
+// QueryByTag returns up to [limit] unexpired keys (in ascending order) tagged
+// with [key]=[value].
+func QueryByTag(
+	db database.Database, key string, value string, limit int, now uint64,
+) ([]common.Hash, error) {
+	prefix := prefixTag(TagHash(key, value))
+	cursor := db.NewIteratorWithPrefix(prefix)
+	defer cursor.Release()
+	keys := []common.Hash{}
+	for len(keys) < limit && cursor.Next() {
+		k := common.BytesToHash(cursor.Key()[len(prefix):])
+		vmeta, exists, err := GetValueMeta(db, k)
+		if err != nil {
+			return nil, err
+		}
+		if !exists || vmeta.Expired(now) {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	return keys, cursor.Error()
+}
+
+// GetTxsBySender returns the IDs of up to [limit] accepted txs issued by
+// [address] (from newest to oldest).
+func GetTxsBySender(db database.Database, address common.Address, limit int) ([]ids.ID, error) {
+	cursor := db.NewIteratorWithPrefix(prefixSender(address))
+	defer cursor.Release()
+	txIDs := []ids.ID{}
+	for len(txIDs) < limit && cursor.Next() {
+		txID, err := ids.ToID(cursor.Value())
+		if err != nil {
+			return nil, err
+		}
+		txIDs = append(txIDs, txID)
+	}
+	return txIDs, cursor.Error()
+}
+
+// SetBanned persists whether txs from [address] are rejected by this node.
+// Bans are not part of consensus (blocks from other nodes may still include
+// txs from [address]).
+func SetBanned(db database.KeyValueWriterDeleter, address common.Address, banned bool) error {
+	if banned {
+		return db.Put(PrefixBannedKey(address), nil)
+	}
+	return db.Delete(PrefixBannedKey(address))
+}
+
+// GetBanned returns all addresses banned with [SetBanned].
+func GetBanned(db database.Iteratee) ([]common.Address, error) {
+	prefix := []byte{bannedPrefix, ByteDelimiter}
+	cursor := db.NewIteratorWithPrefix(prefix)
+	defer cursor.Release()
+	addrs := []common.Address{}
+	for cursor.Next() {
+		addrs = append(addrs, common.BytesToAddress(cursor.Key()[len(prefix):]))
+	}
+	return addrs, cursor.Error()
+}
+
+// SelectRandomValueKey deterministically maps [seed] to a stored key: the first
+// key (in byte order) at or after keccak256([seed]), so the same [seed] always
+// selects the same key given the same set of stored keys. Keys don't wrap
+// around, so nothing is selected if every stored key is before
+// keccak256([seed]).
+func SelectRandomValueKey(db database.Database, seed []byte) (common.Hash, bool, error) {
+	startKey := ValueKey(ValueHash(seed))
+	baseKey := []byte{keyPrefix, ByteDelimiter} // don't add empty hash with ValueKey
+	cursor := db.NewIteratorWithStart(startKey)
+	defer cursor.Release()
+
+	// The iterator starts at the first key >= [startKey], so only that key
+	// needs to be checked
+	if cursor.Next() {
+		if curKey := cursor.Key(); bytes.HasPrefix(curKey, baseKey) {
+			return common.BytesToHash(curKey[len(baseKey):]), true, nil
+		}
+	}
+	return common.Hash{}, false, cursor.Error()
+}
+
+// SelectRandomValue returns the [ValueMeta] of the key selected by
+// [SelectRandomValueKey] (not the value itself), which [AccessProof]s are
+// computed over, or nil if no key is selected. It is encoded with the legacy
+// codec (as it was stored at launch) unless the block is [upgraded], so the
+// result doesn't depend on how this node stores it.
+func SelectRandomValue(db database.Database, seed []byte, upgraded bool) []byte {
+	k, ok, err := SelectRandomValueKey(db, seed)
+	if err != nil || !ok {
+		// No value selected
+		return nil
+	}
+	vmeta, exists, err := GetValueMeta(db, k)
+	if err != nil || !exists {
+		return nil
+	}
+	version := uint16(legacyCodecVersion)
+	if upgraded {
+		version = codecVersion
+	}
+	v, err := marshalVersion(version, vmeta)
+	if err != nil {
+		return nil
+	}
+	return v
 }
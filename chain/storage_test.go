@@ -5,8 +5,14 @@ package chain
 
 import (
 	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
 	"testing"
 
+	"github.com/ava-labs/avalanchego/database/memdb"
 	"github.com/ava-labs/avalanchego/ids"
 	"github.com/ethereum/go-ethereum/common"
 )
@@ -74,3 +80,412 @@ func TestPrefixBlockKey(t *testing.T) {
 		}
 	}
 }
+
+func TestListKeys(t *testing.T) {
+	t.Parallel()
+
+	db := memdb.New()
+	defer db.Close()
+
+	keys := []common.Hash{{0x1}, {0x2}, {0x3}, {0x4}, {0x5}}
+	for i, k := range keys {
+		vmeta := &ValueMeta{Size: 1, TxID: ids.GenerateTestID()}
+		if i == 2 {
+			vmeta.Expiry = 10
+		}
+		if err := PutKey(db, k, vmeta); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Keys under other prefixes should not be listed
+	if err := SetBalance(db, common.Address{0x1}, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		start common.Hash
+		limit int
+		now   uint64
+		keys  []common.Hash
+		next  *common.Hash
+	}{
+		{start: common.Hash{}, limit: 10, now: 1, keys: keys},
+		{start: common.Hash{}, limit: 2, now: 1, keys: keys[:2], next: &keys[2]},
+		{start: keys[2], limit: 2, now: 1, keys: keys[2:4], next: &keys[4]},
+		{start: keys[4], limit: 2, now: 1, keys: keys[4:]},
+		{start: common.Hash{}, limit: 2, now: 10, keys: keys[:2], next: &keys[3]}, // skips expired
+		{start: common.Hash{0x6}, limit: 2, now: 1, keys: []common.Hash{}},
+	}
+	for i, tv := range tt {
+		keys, next, err := ListKeys(db, tv.start, tv.limit, tv.now)
+		if err != nil {
+			t.Fatalf("#%d: failed to list keys %v", i, err)
+		}
+		if !reflect.DeepEqual(keys, tv.keys) {
+			t.Fatalf("#%d: keys expected %v, got %v", i, tv.keys, keys)
+		}
+		if !reflect.DeepEqual(next, tv.next) {
+			t.Fatalf("#%d: next expected %v, got %v", i, tv.next, next)
+		}
+	}
+}
+
+func TestQueryByTag(t *testing.T) {
+	t.Parallel()
+
+	db := memdb.New()
+	defer db.Close()
+
+	g := DefaultGenesis()
+	doc := []*Tag{{Key: "type", Value: "doc"}}
+	image := []*Tag{{Key: "type", Value: "image"}}
+	set := func(v string, tags []*Tag, ttl uint64, blockTime uint64) common.Hash {
+		id := ids.GenerateTestID()
+		if err := db.Put(PrefixTxValueKey(id), []byte(v)); err != nil {
+			t.Fatal(err)
+		}
+		utx := &SetTx{BaseTx: &BaseTx{}, Value: []byte(v), TTL: ttl, Tags: tags}
+		tc := &TransactionContext{Genesis: g, Database: db, BlockTime: blockTime, TxID: id}
+		if err := utx.Execute(tc); err != nil {
+			t.Fatal(err)
+		}
+		return ValueHash([]byte(v))
+	}
+	a := set("a", doc, 0, 10)
+	b := set("b", append([]*Tag{{Key: "lang", Value: "en"}}, doc...), 0, 10)
+	c := set("c", image, 5, 10)
+	d := set("d", doc, 5, 10)
+	set("e", nil, 0, 10)
+	sorted := func(keys ...common.Hash) []common.Hash {
+		sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i][:], keys[j][:]) < 0 })
+		return keys
+	}
+
+	check := func(i int, tag *Tag, limit int, now uint64, expected []common.Hash) {
+		keys, err := QueryByTag(db, tag.Key, tag.Value, limit, now)
+		if err != nil {
+			t.Fatalf("#%d: failed to query by tag %v", i, err)
+		}
+		if !reflect.DeepEqual(keys, expected) {
+			t.Fatalf("#%d: keys expected %v, got %v", i, expected, keys)
+		}
+	}
+	check(0, doc[0], 10, 11, sorted(a, b, d))
+	check(1, doc[0], 1, 11, sorted(a, b, d)[:1])
+	check(2, image[0], 10, 11, []common.Hash{c})
+	check(3, &Tag{Key: "lang", Value: "en"}, 10, 11, []common.Hash{b})
+	check(4, &Tag{Key: "type", Value: "video"}, 10, 11, []common.Hash{})
+	check(5, doc[0], 10, 15, sorted(a, b)) // skips expired
+
+	// Replacing an expired value removes it from its old tags
+	if k := set("d", image, 0, 15); k != d {
+		t.Fatalf("unexpected key %v", k)
+	}
+	check(6, doc[0], 10, 15, sorted(a, b))
+	check(7, image[0], 10, 15, []common.Hash{d})
+	check(8, doc[0], 10, 11, sorted(a, b))
+}
+
+func TestGetLiveValueMeta(t *testing.T) {
+	t.Parallel()
+
+	db := memdb.New()
+	defer db.Close()
+
+	g := DefaultGenesis()
+	set := func(v []byte, ttl uint64) common.Hash {
+		id := ids.GenerateTestID()
+		if err := db.Put(PrefixTxValueKey(id), v); err != nil {
+			t.Fatal(err)
+		}
+		utx := &SetTx{BaseTx: &BaseTx{}, Value: v, TTL: ttl}
+		tc := &TransactionContext{Genesis: g, Database: db, BlockTime: 10, TxID: id}
+		if err := utx.Execute(tc); err != nil {
+			t.Fatal(err)
+		}
+		k := ValueHash(v)
+		if err := putTreeRefs(db, k, v); err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+	setRoot := func(ttl uint64, children ...common.Hash) common.Hash {
+		b, err := json.Marshal(&TreeRoot{Children: children})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return set(b, ttl)
+	}
+	live := set([]byte("live"), 0)
+	expired := set([]byte("expired"), 5)
+
+	// A chunk referenced by an unexpired root is live (even if it expired)
+	referenced := set([]byte("referenced"), 5)
+	setRoot(0, referenced)
+
+	// Chunks under the sub-roots of an unexpired multi-level root are live
+	// (even if the sub-roots expired)
+	deepChunk := set([]byte("deep chunk"), 5)
+	setRoot(0, setRoot(5, deepChunk))
+
+	// Once a root expires, its chunks are no longer referenced
+	orphan := set([]byte("orphan"), 5)
+	expiredRoot := setRoot(5, orphan)
+
+	for k, exp := range map[common.Hash]bool{
+		live:        true,
+		expired:     false,
+		referenced:  true,
+		deepChunk:   true,
+		orphan:      false,
+		expiredRoot: false,
+	} {
+		vmeta, l, err := GetLiveValueMeta(db, k, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if vmeta == nil || l != exp {
+			t.Fatalf("%s live expected %t, got %t (vmeta=%+v)", k, exp, l, vmeta)
+		}
+	}
+	if vmeta, l, err := GetLiveValueMeta(db, common.Hash{1}, 100); err != nil || vmeta != nil || l {
+		t.Fatalf("unexpected unknown value meta %+v (live=%t, err=%v)", vmeta, l, err)
+	}
+}
+
+func TestSelectRandomValueKey(t *testing.T) {
+	t.Parallel()
+
+	// Store the same keys in two databases (in different orders)
+	keys := make([]common.Hash, 64)
+	for i := range keys {
+		keys[i] = ValueHash([]byte(fmt.Sprintf("value-%d", i)))
+	}
+	dbs := []*memdb.Database{memdb.New(), memdb.New()}
+	for i := range keys {
+		for j, db := range dbs {
+			k := keys[i]
+			if j == 1 {
+				k = keys[len(keys)-1-i]
+			}
+			if err := PutKey(db, k, &ValueMeta{Size: uint64(i)}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	// Other prefixes are never selected
+	if err := SetBalance(dbs[0], common.Address{0xff}, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	selected := map[common.Hash]int{}
+	for i := uint64(0); i < 256; i++ {
+		seed := make([]byte, 8)
+		binary.LittleEndian.PutUint64(seed, i)
+
+		k, ok, err := SelectRandomValueKey(dbs[0], seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for j := 0; j < 2; j++ {
+			k2, ok2, err := SelectRandomValueKey(dbs[j], seed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if k != k2 || ok != ok2 {
+				t.Fatalf("seed %d: selection is not deterministic (%s, %s)", i, k, k2)
+			}
+		}
+		if !ok {
+			continue
+		}
+		// The first key >= keccak256(seed) is selected
+		start := ValueHash(seed)
+		for _, other := range keys {
+			if bytes.Compare(other[:], start[:]) >= 0 && bytes.Compare(other[:], k[:]) < 0 {
+				t.Fatalf("seed %d: selected %s but %s is closer", i, k, other)
+			}
+		}
+		if v := SelectRandomValue(dbs[0], seed, true); !bytes.Equal(v, mustGet(t, dbs[0], ValueKey(k))) {
+			t.Fatalf("seed %d: unexpected value %x", i, v)
+		}
+		selected[k]++
+	}
+	if len(selected) < len(keys)/2 {
+		t.Fatalf("selection should spread across the keyspace, only selected %d of %d keys", len(selected), len(keys))
+	}
+
+	if _, ok, err := SelectRandomValueKey(memdb.New(), []byte("seed")); err != nil || ok {
+		t.Fatalf("nothing should be selected from an empty db (ok=%t, err=%v)", ok, err)
+	}
+}
+
+// storageFixture is the state shared by the tests of txs that pay for
+// storage (see [newStorageFixture]).
+type storageFixture struct {
+	g  *Genesis
+	db *memdb.Database
+
+	// [sender] is funded, [unfunded] isn't
+	sender   common.Address
+	unfunded common.Address
+
+	// [expiring] is 11 value units, [permanent] never expires
+	expiring  []byte
+	permanent []byte
+}
+
+// newStorageFixture loads a genesis with a DefaultValueTTL of 100 and stores
+// [storageFixture.expiring] (expiring at [expiry]) and
+// [storageFixture.permanent] as if they were set at time 10.
+func newStorageFixture(t *testing.T, expiry uint64) *storageFixture {
+	t.Helper()
+
+	f := &storageFixture{
+		db:        memdb.New(),
+		sender:    common.Address{1},
+		unfunded:  common.Address{2},
+		permanent: []byte("permanent"),
+	}
+	t.Cleanup(func() { f.db.Close() })
+
+	f.g = DefaultGenesis()
+	f.g.DefaultValueTTL = 100
+	f.g.CustomAllocation = []*CustomAllocation{{Address: f.sender, Balance: 10000000}}
+	if err := f.g.Load(f.db, nil); err != nil {
+		t.Fatal(err)
+	}
+	f.expiring = make([]byte, 10*f.g.ValueUnitSize)
+	putTestValue(t, f.db, f.expiring, 10, expiry)
+	putTestValue(t, f.db, f.permanent, 10, 0)
+	return f
+}
+
+// execute runs [utx] at [blockTime] as [sender], returning the fee it was
+// charged.
+func (f *storageFixture) execute(t *testing.T, utx UnsignedTransaction, blockTime uint64, sender common.Address) (uint64, error) {
+	t.Helper()
+
+	bal, err := GetBalance(f.db, sender)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc := &TransactionContext{
+		Genesis:   f.g,
+		Database:  f.db,
+		BlockTime: blockTime,
+		TxID:      ids.Empty,
+		Sender:    sender,
+	}
+	if err := utx.Execute(tc); err != nil {
+		return 0, err
+	}
+	newBal, err := GetBalance(f.db, sender)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bal - newBal, nil
+}
+
+// putTestValue stores [v] as if it was set by a tx at [created] (expiring at
+// [expiry], 0 never expires), indexing its children if it is a [TreeRoot].
+func putTestValue(t *testing.T, db *memdb.Database, v []byte, created uint64, expiry uint64) common.Hash {
+	t.Helper()
+
+	id := ids.GenerateTestID()
+	if err := db.Put(PrefixTxValueKey(id), v); err != nil {
+		t.Fatal(err)
+	}
+	k := ValueHash(v)
+	if err := PutKey(db, k, &ValueMeta{
+		Size:    uint64(len(v)),
+		TxID:    id,
+		Created: created,
+		Expiry:  expiry,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := putTreeRefs(db, k, v); err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func mustGet(t *testing.T, db *memdb.Database, k []byte) []byte {
+	t.Helper()
+
+	v, err := db.Get(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestCheckIntegrity(t *testing.T) {
+	t.Parallel()
+
+	db := memdb.New()
+	defer db.Close()
+
+	g := DefaultGenesis()
+	set := func(v []byte, ttl uint64) (common.Hash, ids.ID) {
+		id := ids.GenerateTestID()
+		if err := db.Put(PrefixTxValueKey(id), v); err != nil {
+			t.Fatal(err)
+		}
+		utx := &SetTx{BaseTx: &BaseTx{}, Value: v, TTL: ttl}
+		tc := &TransactionContext{Genesis: g, Database: db, BlockTime: 10, TxID: id}
+		if err := utx.Execute(tc); err != nil {
+			t.Fatal(err)
+		}
+		return ValueHash(v), id
+	}
+	set([]byte("valid"), 0)
+	gk := ValueHash([]byte("genesis"))
+	if err := PutGenesisValue(db, gk, []byte("genesis"), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	set([]byte("expired"), 5)
+
+	checked, inconsistent, err := CheckIntegrity(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checked != 3 || len(inconsistent) != 0 {
+		t.Fatalf("expected 3 consistent values, got %d (inconsistent=%v)", checked, inconsistent)
+	}
+
+	// Expired values are checked too
+	missing, id := set([]byte("missing"), 5)
+	if err := db.Delete(PrefixTxValueKey(id)); err != nil {
+		t.Fatal(err)
+	}
+	truncated, id := set([]byte("truncated"), 0)
+	if err := db.Put(PrefixTxValueKey(id), []byte("trunc")); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Delete(PrefixGenesisValueKey(gk)); err != nil {
+		t.Fatal(err)
+	}
+
+	checked, inconsistent, err = CheckIntegrity(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checked != 5 {
+		t.Fatalf("checked expected 5, got %d", checked)
+	}
+	found := map[common.Hash]string{}
+	for _, c := range inconsistent {
+		found[c.Key] = c.Reason
+	}
+	exp := map[common.Hash]string{
+		missing:   "missing value",
+		truncated: "value is 5 bytes, expected 9",
+		gk:        "missing value",
+	}
+	if !reflect.DeepEqual(found, exp) {
+		t.Fatalf("inconsistencies expected %v, got %v", exp, found)
+	}
+}
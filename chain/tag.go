@@ -0,0 +1,54 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import "fmt"
+
+// Tag is a searchable label (i.e. "type=image") attached to a value.
+type Tag struct {
+	Key   string `serialize:"true" json:"key"`
+	Value string `serialize:"true" json:"value"`
+}
+
+// tagsSize returns the number of bytes used by the keys and values of [tags].
+func tagsSize(tags []*Tag) uint64 {
+	size := uint64(0)
+	for _, tag := range tags {
+		size += uint64(len(tag.Key) + len(tag.Value))
+	}
+	return size
+}
+
+// verifyTags ensures [tags] are within the bounds set by [g] and that each key
+// is non-empty and unique.
+func verifyTags(g *Genesis, tags []*Tag) error {
+	if uint64(len(tags)) > g.MaxTags {
+		return fmt.Errorf("%w: %d (max=%d)", ErrTooManyTags, len(tags), g.MaxTags)
+	}
+	if size := tagsSize(tags); size > g.MaxTagsSize {
+		return fmt.Errorf("%w: %d bytes (max=%d)", ErrTagsTooBig, size, g.MaxTagsSize)
+	}
+	keys := make(map[string]struct{}, len(tags))
+	for _, tag := range tags {
+		if tag == nil || len(tag.Key) == 0 {
+			return ErrInvalidTag
+		}
+		if _, ok := keys[tag.Key]; ok {
+			return fmt.Errorf("%w: duplicate key %q", ErrInvalidTag, tag.Key)
+		}
+		keys[tag.Key] = struct{}{}
+	}
+	return nil
+}
+
+func copyTags(tags []*Tag) []*Tag {
+	if tags == nil {
+		return nil
+	}
+	c := make([]*Tag, len(tags))
+	for i, tag := range tags {
+		c[i] = &Tag{Key: tag.Key, Value: tag.Value}
+	}
+	return c
+}
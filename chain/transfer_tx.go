@@ -5,6 +5,7 @@ package chain
 
 import (
 	"bytes"
+	"fmt"
 	"strconv"
 
 	"github.com/ava-labs/blobvm/tdata"
@@ -14,13 +15,14 @@ import (
 var _ UnsignedTransaction = &TransferTx{}
 
 type TransferTx struct {
-	*BaseTx `serialize:"true" json:"baseTx"`
+	*BaseTx `serialize:"true" serializeV0:"true" json:"baseTx"`
 
-	// To is the recipient of the [Units].
-	To common.Address `serialize:"true" json:"to"`
+	// To is the recipient of the [Units]. There is no notion of account
+	// creation: an address without a balance is credited like any other.
+	To common.Address `serialize:"true" serializeV0:"true" json:"to"`
 
-	// Units are transferred to [To].
-	Units uint64 `serialize:"true" json:"units"`
+	// Units are transferred to [To] (at least [Genesis.MinTransfer]).
+	Units uint64 `serialize:"true" serializeV0:"true" json:"units"`
 }
 
 func (t *TransferTx) Execute(c *TransactionContext) error {
@@ -36,6 +38,9 @@ func (t *TransferTx) Execute(c *TransactionContext) error {
 	if t.Units == 0 {
 		return ErrNonActionable
 	}
+	if t.Units < c.Genesis.MinTransfer {
+		return fmt.Errorf("%w: %d units (min=%d)", ErrTransferTooSmall, t.Units, c.Genesis.MinTransfer)
+	}
 	if _, err := ModifyBalance(c.Database, c.Sender, false, t.Units); err != nil {
 		return err
 	}
@@ -73,7 +78,7 @@ func (t *TransferTx) TypedData() *tdata.TypedData {
 	)
 }
 
-func (t *TransferTx) Activity() *Activity {
+func (t *TransferTx) Activity(*Genesis) *Activity {
 	return &Activity{
 		Typ:   Transfer,
 		To:    t.To.Hex(),
@@ -124,3 +124,49 @@ func TestTransferTx(t *testing.T) {
 		}
 	}
 }
+
+func TestTransferTxMinTransfer(t *testing.T) {
+	t.Parallel()
+
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := crypto.PubkeyToAddress(priv.PublicKey)
+
+	db := memdb.New()
+	defer db.Close()
+
+	g := DefaultGenesis()
+	g.MinTransfer = 100
+	g.CustomAllocation = []*CustomAllocation{{Address: sender, Balance: 10000000}}
+	if err := g.Load(db, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	to := common.Address{1}
+	for i, tv := range []struct {
+		units uint64
+		err   error
+	}{
+		{units: 1, err: ErrTransferTooSmall},
+		{units: 99, err: ErrTransferTooSmall},
+		{units: 100},
+		{units: 101},
+	} {
+		utx := &TransferTx{BaseTx: &BaseTx{}, To: to, Units: tv.units}
+		err := utx.Execute(&TransactionContext{Genesis: g, Database: db, Sender: sender})
+		if !errors.Is(err, tv.err) {
+			t.Fatalf("#%d: tx.Execute err expected %v, got %v", i, tv.err, err)
+		}
+	}
+
+	// Rejected transfers don't create a balance for the recipient
+	bal, err := GetBalance(db, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bal != 201 {
+		t.Fatalf("balance expected 201, got %d", bal)
+	}
+}
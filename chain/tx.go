@@ -4,16 +4,21 @@
 package chain
 
 import (
+	"bytes"
+	"fmt"
+	"reflect"
+
 	"github.com/ava-labs/avalanchego/database"
 	"github.com/ava-labs/avalanchego/ids"
 	"github.com/ava-labs/blobvm/tdata"
 	"github.com/ethereum/go-ethereum/common"
+	smath "github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/crypto"
 )
 
 type Transaction struct {
-	UnsignedTransaction `serialize:"true" json:"unsignedTransaction"`
-	Signature           []byte `serialize:"true" json:"signature"`
+	UnsignedTransaction `serialize:"true" serializeV0:"true" json:"unsignedTransaction"`
+	Signature           []byte `serialize:"true" serializeV0:"true" json:"signature"`
 
 	digestHash []byte
 	bytes      []byte
@@ -73,8 +78,32 @@ func (t *Transaction) Init(g *Genesis) error {
 	return nil
 }
 
+// DecodeTx parses a signed tx from its wire format (ex: the bytes passed to
+// "blobvm.issueRawTx" or returned by [Transaction.Bytes]) and derives its ID
+// and sender. The signature must recover a sender and [b] must be the
+// canonical encoding of the tx, so [Transaction.ID] is the ID the VM assigns
+// to [b].
+func DecodeTx(b []byte) (*Transaction, error) {
+	tx := new(Transaction)
+	if _, err := Unmarshal(b, tx); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTx, err)
+	}
+	// The ID and sender of a tx don't depend on the genesis
+	if err := tx.Init(nil); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTx, err)
+	}
+	if !bytes.Equal(tx.Bytes(), b) {
+		return nil, fmt.Errorf("%w: non-canonical encoding", ErrInvalidTx)
+	}
+	return tx, nil
+}
+
 func (t *Transaction) Bytes() []byte { return t.bytes }
 
+// legacy returns true if [t] could be issued before [Genesis.UpgradeTime] (it
+// is encoded with the legacy codec).
+func (t *Transaction) legacy() bool { return legacyEncodable(reflect.ValueOf(t)) }
+
 func (t *Transaction) Size() uint64 { return t.size }
 
 func (t *Transaction) ID() ids.ID { return t.id }
@@ -87,6 +116,13 @@ func (t *Transaction) Execute(g *Genesis, db database.Database, blk *StatelessBl
 	if err := t.UnsignedTransaction.ExecuteBase(g); err != nil {
 		return err
 	}
+	// Nodes that haven't upgraded can't decode the tx (or blocks including it)
+	if !g.Upgraded(blk.Tmstmp) && !t.legacy() {
+		return ErrUpgradeNotActive
+	}
+	if !g.SenderAllowed(t.sender) {
+		return ErrSenderNotAllowed
+	}
 	if !context.RecentBlockIDs.Contains(t.GetBlockID()) {
 		// Hash must be recent to be any good
 		// Should not happen beause of mempool cleanup
@@ -101,7 +137,11 @@ func (t *Transaction) Execute(g *Genesis, db database.Database, blk *StatelessBl
 	}
 
 	// Ensure sender has balance
-	if _, err := ModifyBalance(db, t.sender, false, t.FeeUnits(g)*t.GetPrice()); err != nil {
+	fee, xflow := smath.SafeMul(t.FeeUnits(g), t.GetPrice())
+	if xflow {
+		return fmt.Errorf("%w: fee overflow", ErrInvalidBalance)
+	}
+	if _, err := ModifyBalance(db, t.sender, false, fee); err != nil {
 		return err
 	}
 	if t.GetPrice() < context.NextPrice {
@@ -113,6 +153,8 @@ func (t *Transaction) Execute(g *Genesis, db database.Database, blk *StatelessBl
 		BlockTime: uint64(blk.Tmstmp),
 		TxID:      t.id,
 		Sender:    t.sender,
+
+		CompactValueMeta: context.CompactValueMeta,
 	}); err != nil {
 		return err
 	}
@@ -122,8 +164,8 @@ func (t *Transaction) Execute(g *Genesis, db database.Database, blk *StatelessBl
 	return nil
 }
 
-func (t *Transaction) Activity() *Activity {
-	activity := t.UnsignedTransaction.Activity()
+func (t *Transaction) Activity(g *Genesis) *Activity {
+	activity := t.UnsignedTransaction.Activity(g)
 	activity.Sender = t.sender.Hex()
 	activity.TxID = t.id
 	return activity
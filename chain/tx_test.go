@@ -4,6 +4,7 @@
 package chain
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"errors"
 	"fmt"
@@ -113,6 +114,48 @@ func TestTransactionErrInvalidSignature(t *testing.T) {
 	}
 }
 
+func TestTransactionFeeOverflow(t *testing.T) {
+	t.Parallel()
+
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := crypto.PubkeyToAddress(priv.PublicKey)
+
+	g := DefaultGenesis()
+	g.DefaultValueTTL = 1
+	g.CustomAllocation = []*CustomAllocation{{Address: sender, Balance: 10000000}}
+	db := memdb.New()
+	defer db.Close()
+	if err := g.Load(db, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// (2^63 + 1) fee units * 2 price wraps to 2 without overflow checks
+	utx := &SetTx{
+		BaseTx: &BaseTx{BlockID: ids.ID{0, 1}, Price: 2},
+		Value:  []byte("a"),
+		TTL:    1 << 63,
+	}
+	dh, err := DigestHash(utx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := Sign(dh, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := NewTx(utx, sig)
+	if err := tx.Init(g); err != nil {
+		t.Fatal(err)
+	}
+	ctx := &Context{RecentBlockIDs: ids.Set{{0, 1}: struct{}{}}}
+	if err := tx.Execute(g, db, DummyBlock(1, tx), ctx); !errors.Is(err, ErrInvalidBalance) {
+		t.Fatalf("unexpected tx.Execute error %v, expected %v", err, ErrInvalidBalance)
+	}
+}
+
 func createTestTx(t *testing.T, blockID ids.ID, priv *ecdsa.PrivateKey) *Transaction {
 	t.Helper()
 
@@ -143,3 +186,48 @@ func createTestTx(t *testing.T, blockID ids.ID, priv *ecdsa.PrivateKey) *Transac
 
 	return tx
 }
+
+func TestDecodeTx(t *testing.T) {
+	t.Parallel()
+
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := DefaultGenesis()
+	tx := createTestSignedTx(t, g, priv, &SetTx{
+		BaseTx: &BaseTx{BlockID: ids.GenerateTestID(), Magic: g.Magic, Price: 1},
+		Value:  []byte("decoded"),
+	})
+
+	decoded, err := DecodeTx(tx.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.ID() != tx.ID() || decoded.Sender() != crypto.PubkeyToAddress(priv.PublicKey) {
+		t.Fatalf("decoded tx %s from %s, expected %s from %s", decoded.ID(), decoded.Sender(), tx.ID(), tx.Sender())
+	}
+	if !bytes.Equal(decoded.Bytes(), tx.Bytes()) || decoded.Size() != tx.Size() {
+		t.Fatal("decoded tx does not round-trip")
+	}
+	if stx, ok := decoded.UnsignedTransaction.(*SetTx); !ok || string(stx.Value) != "decoded" {
+		t.Fatalf("unexpected decoded tx %+v", decoded.UnsignedTransaction)
+	}
+
+	badSig := tx.Copy()
+	badSig.Signature = badSig.Signature[1:]
+	badSigBytes, err := Marshal(badSig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, b := range map[string][]byte{
+		"empty":         nil,
+		"truncated":     tx.Bytes()[:len(tx.Bytes())-1],
+		"trailing":      append(append([]byte{}, tx.Bytes()...), 0),
+		"bad signature": badSigBytes,
+	} {
+		if _, err := DecodeTx(b); !errors.Is(err, ErrInvalidTx) {
+			t.Fatalf("%s: unexpected error %v, expected %v", name, err, ErrInvalidTx)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/ava-labs/blobvm/tdata"
+)
+
+var _ UnsignedTransaction = &UnpinTx{}
+
+// UnpinTx reverses a PinTx issued by the sender. The value then expires at its
+// original expiry (immediately, if that has already passed). No fees are
+// refunded.
+type UnpinTx struct {
+	*BaseTx `serialize:"true" json:"baseTx"`
+
+	// Key is the [ValueHash] of the value to unpin.
+	Key common.Hash `serialize:"true" json:"key"`
+}
+
+func (u *UnpinTx) Execute(t *TransactionContext) error {
+	vmeta, exists, err := GetValueMeta(t.Database, u.Key)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrKeyMissing
+	}
+	if !vmeta.Pinned {
+		return ErrNonActionable
+	}
+	if vmeta.PinnedBy != t.Sender {
+		return ErrUnauthorized
+	}
+
+	vmeta.Pinned = false
+	vmeta.PinnedBy = common.Address{}
+	return t.putKey(u.Key, vmeta)
+}
+
+func (u *UnpinTx) Copy() UnsignedTransaction {
+	key := make([]byte, common.HashLength)
+	copy(key, u.Key[:])
+	return &UnpinTx{
+		BaseTx: u.BaseTx.Copy(),
+		Key:    common.BytesToHash(key),
+	}
+}
+
+func (u *UnpinTx) TypedData() *tdata.TypedData {
+	return tdata.CreateTypedData(
+		u.Magic, Unpin,
+		[]tdata.Type{
+			{Name: tdKey, Type: tdString},
+			{Name: tdPrice, Type: tdUint64},
+			{Name: tdBlockID, Type: tdString},
+		},
+		tdata.TypedDataMessage{
+			tdKey:     u.Key.Hex(),
+			tdPrice:   strconv.FormatUint(u.Price, 10),
+			tdBlockID: u.BlockID.String(),
+		},
+	)
+}
+
+func (u *UnpinTx) Activity(*Genesis) *Activity {
+	return &Activity{
+		Typ: Unpin,
+		Key: u.Key.Hex(),
+	}
+}
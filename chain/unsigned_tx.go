@@ -17,6 +17,16 @@ type TransactionContext struct {
 	BlockTime uint64
 	TxID      ids.ID
 	Sender    common.Address
+
+	// CompactValueMeta is [Context.CompactValueMeta] of the block executing
+	// the tx
+	CompactValueMeta bool
+}
+
+// putKey stores [vmeta] for [key] in the encoding selected by the block
+// context.
+func (t *TransactionContext) putKey(key common.Hash, vmeta *ValueMeta) error {
+	return putValueMeta(t.Database, key, vmeta, t.CompactValueMeta)
 }
 
 type UnsignedTransaction interface {
@@ -33,5 +43,5 @@ type UnsignedTransaction interface {
 	ExecuteBase(*Genesis) error
 	Execute(*TransactionContext) error
 	TypedData() *tdata.TypedData
-	Activity() *Activity
+	Activity(*Genesis) *Activity
 }
@@ -39,17 +39,17 @@ func (m *MockUnsignedTransaction) EXPECT() *MockUnsignedTransactionMockRecorder
 }
 
 // Activity mocks base method.
-func (m *MockUnsignedTransaction) Activity() *Activity {
+func (m *MockUnsignedTransaction) Activity(arg0 *Genesis) *Activity {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Activity")
+	ret := m.ctrl.Call(m, "Activity", arg0)
 	ret0, _ := ret[0].(*Activity)
 	return ret0
 }
 
 // Activity indicates an expected call of Activity.
-func (mr *MockUnsignedTransactionMockRecorder) Activity() *gomock.Call {
+func (mr *MockUnsignedTransactionMockRecorder) Activity(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Activity", reflect.TypeOf((*MockUnsignedTransaction)(nil).Activity))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Activity", reflect.TypeOf((*MockUnsignedTransaction)(nil).Activity), arg0)
 }
 
 // Copy mocks base method.
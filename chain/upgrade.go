@@ -0,0 +1,26 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"github.com/ava-labs/avalanchego/ids"
+)
+
+// UpgradeTimes schedules the network upgrade (unix seconds) of chains whose
+// genesis predates [Genesis.UpgradeTime], by chainID. Like avalanchego's
+// network upgrade times, the schedule ships with the VM so every node of a
+// chain activates the upgrade at the same time: node config never changes
+// which blocks are valid.
+var UpgradeTimes = map[ids.ID]uint64{}
+
+// ScheduleUpgrade sets [UpgradeTime] from [UpgradeTimes] if the genesis of
+// [chainID] doesn't set it. Chains in neither keep the launch formats.
+func (g *Genesis) ScheduleUpgrade(chainID ids.ID) {
+	if g.UpgradeTime != nil {
+		return
+	}
+	if upgradeTime, ok := UpgradeTimes[chainID]; ok {
+		g.UpgradeTime = &upgradeTime
+	}
+}
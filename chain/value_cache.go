@@ -0,0 +1,101 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/ava-labs/avalanchego/cache"
+	"github.com/ava-labs/avalanchego/database"
+)
+
+// ValueCache holds recently read linked values (see [GetValue]) in memory. A
+// nil [ValueCache] caches nothing, so every read goes to disk.
+type ValueCache struct {
+	l    sync.Mutex
+	lru  *cache.LRU
+	size int
+	len  int
+
+	stats ValueCacheStats
+}
+
+// ValueCacheStats count the lookups made in a [ValueCache].
+type ValueCacheStats struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+
+	// Evictions are values dropped to make room for others (values removed
+	// because they changed on disk aren't counted)
+	Evictions uint64 `json:"evictions"`
+}
+
+// NewValueCache returns a [ValueCache] holding up to [size] values (nil if
+// [size] is not positive).
+func NewValueCache(size int) *ValueCache {
+	if size <= 0 {
+		return nil
+	}
+	return &ValueCache{lru: &cache.LRU{Size: size}, size: size}
+}
+
+// Stats returns the lookups made so far.
+func (c *ValueCache) Stats() ValueCacheStats {
+	if c == nil {
+		return ValueCacheStats{}
+	}
+	c.l.Lock()
+	defer c.l.Unlock()
+	return c.stats
+}
+
+// get returns the linked value [b] from the cache, reading (and caching) it
+// from [db] on a miss.
+func (c *ValueCache) get(db database.KeyValueReader, b []byte) ([]byte, error) {
+	if c == nil {
+		return readLinkedValue(db, b)
+	}
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	bh := string(b)
+	if v, ok := c.lru.Get(bh); ok {
+		bytes, ok := v.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("expected []byte but got %T", v)
+		}
+		c.stats.Hits++
+		return bytes, nil
+	}
+	c.stats.Misses++
+	v, err := readLinkedValue(db, b)
+	if err != nil {
+		return nil, err
+	}
+	// The LRU drops its oldest value when full
+	if c.len == c.size {
+		c.stats.Evictions++
+	} else {
+		c.len++
+	}
+	c.lru.Put(bh, v)
+	return v, nil
+}
+
+// evict removes the linked value [b] (once it changed on disk).
+func (c *ValueCache) evict(b []byte) {
+	if c == nil {
+		return
+	}
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	bh := string(b)
+	if _, ok := c.lru.Get(bh); !ok {
+		return
+	}
+	c.lru.Evict(bh)
+	c.len--
+}
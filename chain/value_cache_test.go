@@ -0,0 +1,69 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/database/memdb"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestValueCache(t *testing.T) {
+	t.Parallel()
+
+	db := memdb.New()
+	defer db.Close()
+	g := DefaultGenesis()
+	values := [][]byte{[]byte("a"), []byte("b")}
+	keys := make([]common.Hash, len(values))
+	for i, v := range values {
+		id := ids.GenerateTestID()
+		if err := db.Put(PrefixTxValueKey(id), v); err != nil {
+			t.Fatal(err)
+		}
+		utx := &SetTx{BaseTx: &BaseTx{}, Value: v}
+		if err := utx.Execute(&TransactionContext{Genesis: g, Database: db, TxID: id}); err != nil {
+			t.Fatal(err)
+		}
+		keys[i] = g.ValueHash(v)
+	}
+
+	vc := NewValueCache(1)
+	get := func(i int) {
+		t.Helper()
+		v, exists, err := GetValue(db, vc, keys[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !exists || !bytes.Equal(v, values[i]) {
+			t.Fatalf("value expected %q, got %q (exists=%t)", values[i], v, exists)
+		}
+	}
+	for _, i := range []int{0, 0, 1, 0} {
+		get(i)
+	}
+	if stats, expected := vc.Stats(), (ValueCacheStats{Hits: 1, Misses: 3, Evictions: 2}); stats != expected {
+		t.Fatalf("stats expected %+v, got %+v", expected, stats)
+	}
+
+	// Restored values replace the cached ones (without counting as evictions)
+	if err := RestoreValue(db, vc, g, keys[0], values[0]); err != nil {
+		t.Fatal(err)
+	}
+	get(1)
+	if stats, expected := vc.Stats(), (ValueCacheStats{Hits: 1, Misses: 4, Evictions: 2}); stats != expected {
+		t.Fatalf("stats expected %+v, got %+v", expected, stats)
+	}
+
+	// A nil cache reads every value from disk
+	if vc := NewValueCache(0); vc != nil {
+		t.Fatalf("expected nil cache, got %+v", vc)
+	}
+	if v, _, err := GetValue(db, nil, keys[0]); err != nil || !bytes.Equal(v, values[0]) {
+		t.Fatalf("value expected %q, got %q (err=%v)", values[0], v, err)
+	}
+}
@@ -0,0 +1,266 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/ava-labs/avalanchego/database"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/units"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// compactValueMetaVersion prefixes the compact encoding of a [ValueMeta]. The
+// codec encoding always starts with its 2 byte codec version (whose first
+// byte is zero), so the encodings can't be confused.
+const compactValueMetaVersion = 0x01
+
+// valueMetaFormatKey records the encoding stored [ValueMeta]s were last
+// migrated to (see [MigrateValueMetas]).
+var valueMetaFormatKey = []byte("value_meta_format")
+
+// migrateBatchSize bounds the writes buffered by [MigrateValueMetas]
+const migrateBatchSize = units.MiB
+
+// Flags of the compact encoding, marking which optional fields follow
+const (
+	flagBatched byte = 1 << iota
+	flagPinned
+	flagTxID
+	flagPinnedBy
+	flagTags
+	flagAddressing
+
+	knownFlags = flagBatched | flagPinned | flagTxID | flagPinnedBy | flagTags | flagAddressing
+)
+
+// valueMetaFormat identifies the encoding written by [marshalValueMeta].
+func valueMetaFormat(compact bool) []byte {
+	if !compact {
+		return []byte{0, codecVersion}
+	}
+	return []byte{compactValueMetaVersion}
+}
+
+// marshalValueMeta encodes [vmeta] for storage, in a compact binary layout if
+// [compact] is set and with the current codec otherwise. Stored metas are
+// only read by this node, so they needn't be encoded as they were at launch
+// (see [SelectRandomValue]).
+func marshalValueMeta(vmeta *ValueMeta, compact bool) ([]byte, error) {
+	if !compact {
+		return marshalVersion(codecVersion, vmeta)
+	}
+	return vmeta.marshalCompact(), nil
+}
+
+// MigrateValueMetas rewrites every stored [ValueMeta] written in another
+// encoding (ex: by the legacy codec before the upgrade) in the compact
+// encoding if [compact] is set and the current codec encoding otherwise,
+// returning how many were rewritten. [db] is only scanned if the encoding
+// changed since the last migration. Metas in any encoding stay readable, so
+// an interrupted migration is finished by the next one.
+func MigrateValueMetas(db database.Database, compact bool) (int, error) {
+	format := valueMetaFormat(compact)
+	last, err := db.Get(valueMetaFormatKey)
+	switch {
+	case err == nil && bytes.Equal(last, format):
+		return 0, nil
+	case err != nil && !errors.Is(err, database.ErrNotFound):
+		return 0, err
+	}
+
+	cursor := db.NewIteratorWithPrefix([]byte{keyPrefix, ByteDelimiter})
+	defer cursor.Release()
+	batch := db.NewBatch()
+	migrated := 0
+	for cursor.Next() {
+		v := cursor.Value()
+		vmeta := new(ValueMeta)
+		if err := unmarshalValueMeta(v, vmeta); err != nil {
+			return migrated, fmt.Errorf("%w: key %x: %v", ErrInvalidValueMeta, cursor.Key(), err)
+		}
+		mv, err := marshalValueMeta(vmeta, compact)
+		if err != nil {
+			return migrated, err
+		}
+		if bytes.Equal(mv, v) {
+			continue
+		}
+		if err := batch.Put(cursor.Key(), mv); err != nil {
+			return migrated, err
+		}
+		migrated++
+		if batch.Size() >= migrateBatchSize {
+			if err := batch.Write(); err != nil {
+				return migrated, err
+			}
+			batch.Reset()
+		}
+	}
+	if err := cursor.Error(); err != nil {
+		return migrated, err
+	}
+	if err := batch.Put(valueMetaFormatKey, format); err != nil {
+		return migrated, err
+	}
+	return migrated, batch.Write()
+}
+
+func unmarshalValueMeta(b []byte, vmeta *ValueMeta) error {
+	if len(b) > 0 && b[0] == compactValueMetaVersion {
+		return vmeta.unmarshalCompact(b)
+	}
+	_, err := Unmarshal(b, vmeta)
+	return err
+}
+
+// marshalCompact encodes [v] as a version byte and a flags byte, followed by
+// [Size], [TxID] (if set), [Created], [Expiry], [PinnedBy] (if set), [Tags]
+// (if any), and [Addressing] (if set). Integers are uvarints and strings are prefixed by their
+// uvarint length.
+func (v *ValueMeta) marshalCompact() []byte {
+	var flags byte
+	if v.Batched {
+		flags |= flagBatched
+	}
+	if v.Pinned {
+		flags |= flagPinned
+	}
+	if v.TxID != ids.Empty {
+		flags |= flagTxID
+	}
+	if v.PinnedBy != (common.Address{}) {
+		flags |= flagPinnedBy
+	}
+	if len(v.Tags) > 0 {
+		flags |= flagTags
+	}
+	if v.Addressing != HashAddressing {
+		flags |= flagAddressing
+	}
+
+	b := make([]byte, 0, 2+3*binary.MaxVarintLen64+len(ids.Empty)+common.AddressLength)
+	b = append(b, compactValueMetaVersion, flags)
+	b = appendUvarint(b, v.Size)
+	if flags&flagTxID != 0 {
+		b = append(b, v.TxID[:]...)
+	}
+	b = appendUvarint(b, v.Created)
+	b = appendUvarint(b, v.Expiry)
+	if flags&flagPinnedBy != 0 {
+		b = append(b, v.PinnedBy[:]...)
+	}
+	if flags&flagTags != 0 {
+		b = appendUvarint(b, uint64(len(v.Tags)))
+		for _, t := range v.Tags {
+			b = appendUvarint(b, uint64(len(t.Key)))
+			b = append(b, t.Key...)
+			b = appendUvarint(b, uint64(len(t.Value)))
+			b = append(b, t.Value...)
+		}
+	}
+	if flags&flagAddressing != 0 {
+		b = appendUvarint(b, uint64(len(v.Addressing)))
+		b = append(b, v.Addressing...)
+	}
+	return b
+}
+
+// unmarshalCompact decodes the output of [marshalCompact].
+func (v *ValueMeta) unmarshalCompact(b []byte) error {
+	r := &compactReader{b: b}
+	if version := r.byte(); version != compactValueMetaVersion {
+		return fmt.Errorf("%w: unknown version %d", ErrInvalidValueMeta, version)
+	}
+	flags := r.byte()
+	if flags&^knownFlags != 0 {
+		return fmt.Errorf("%w: unknown flags %08b", ErrInvalidValueMeta, flags)
+	}
+	*v = ValueMeta{
+		Batched: flags&flagBatched != 0,
+		Pinned:  flags&flagPinned != 0,
+	}
+	v.Size = r.uvarint()
+	if flags&flagTxID != 0 {
+		copy(v.TxID[:], r.bytes(len(ids.Empty)))
+	}
+	v.Created = r.uvarint()
+	v.Expiry = r.uvarint()
+	if flags&flagPinnedBy != 0 {
+		copy(v.PinnedBy[:], r.bytes(common.AddressLength))
+	}
+	if flags&flagTags != 0 {
+		n := r.uvarint()
+		// Each tag takes at least 2 bytes
+		if n > uint64(len(r.b))/2 {
+			return fmt.Errorf("%w: %d tags in %d bytes", ErrInvalidValueMeta, n, len(r.b))
+		}
+		v.Tags = make([]*Tag, n)
+		for i := range v.Tags {
+			key := r.bytes(int(r.uvarint()))
+			value := r.bytes(int(r.uvarint()))
+			v.Tags[i] = &Tag{Key: string(key), Value: string(value)}
+		}
+	}
+	if flags&flagAddressing != 0 {
+		v.Addressing = Addressing(r.bytes(int(r.uvarint())))
+	}
+	if r.err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidValueMeta, r.err)
+	}
+	if len(r.b) > 0 {
+		return fmt.Errorf("%w: %d trailing bytes", ErrInvalidValueMeta, len(r.b))
+	}
+	return nil
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	return append(b, buf[:n]...)
+}
+
+// compactReader consumes [b], recording the first read past its end in [err]
+// (after which reads return zero values).
+type compactReader struct {
+	b   []byte
+	err error
+}
+
+func (r *compactReader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.b) {
+		r.err = fmt.Errorf("need %d bytes, %d left", n, len(r.b))
+		return nil
+	}
+	out := r.b[:n]
+	r.b = r.b[n:]
+	return out
+}
+
+func (r *compactReader) byte() byte {
+	if b := r.bytes(1); len(b) == 1 {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *compactReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.b)
+	if n <= 0 {
+		r.err = errors.New("invalid uvarint")
+		return 0
+	}
+	r.b = r.b[n:]
+	return v
+}
@@ -0,0 +1,106 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/database/memdb"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestCompactValueMeta(t *testing.T) {
+	t.Parallel()
+
+	for i, vmeta := range []*ValueMeta{
+		{},
+		{Size: 1, TxID: ids.GenerateTestID(), Created: 1_650_000_000, Expiry: 1_650_086_400},
+		{Size: 200 * 1024, TxID: ids.GenerateTestID(), Created: 1_650_000_000, Batched: true},
+		{Size: 11, TxID: ids.GenerateTestID(), Created: 1_650_000_000, Addressing: CIDv1},
+		{
+			Size:     64,
+			TxID:     ids.GenerateTestID(),
+			Created:  1_650_000_000,
+			Expiry:   1_650_086_400,
+			Tags:     []*Tag{{Key: "type", Value: "image/png"}, {Key: "empty", Value: ""}},
+			Pinned:   true,
+			PinnedBy: common.Address{0x01},
+		},
+	} {
+		b := vmeta.marshalCompact()
+		codec, err := Marshal(vmeta)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(b) >= len(codec) {
+			t.Fatalf("#%d: compact encoding is %d bytes (codec is %d)", i, len(b), len(codec))
+		}
+		for _, enc := range [][]byte{b, codec} {
+			dvmeta := new(ValueMeta)
+			if err := unmarshalValueMeta(enc, dvmeta); err != nil {
+				t.Fatalf("#%d: %v", i, err)
+			}
+			if len(vmeta.Tags) == 0 {
+				// nil and empty tags are equivalent
+				dvmeta.Tags = vmeta.Tags
+			}
+			if !reflect.DeepEqual(dvmeta, vmeta) {
+				t.Fatalf("#%d: expected %+v, got %+v", i, vmeta, dvmeta)
+			}
+		}
+
+		// Truncated and extended encodings are rejected
+		for _, enc := range [][]byte{b[:len(b)-1], append(append([]byte{}, b...), 0)} {
+			if err := unmarshalValueMeta(enc, new(ValueMeta)); !errors.Is(err, ErrInvalidValueMeta) {
+				t.Fatalf("#%d: expected %v, got %v", i, ErrInvalidValueMeta, err)
+			}
+		}
+	}
+
+	if err := unmarshalValueMeta([]byte{compactValueMetaVersion, 0x80, 0, 0, 0}, new(ValueMeta)); !errors.Is(err, ErrInvalidValueMeta) {
+		t.Fatalf("expected %v, got %v", ErrInvalidValueMeta, err)
+	}
+}
+
+func TestPutKeyCompact(t *testing.T) {
+	t.Parallel()
+
+	db := memdb.New()
+	defer db.Close()
+	vmeta := &ValueMeta{Size: 5, TxID: ids.GenerateTestID(), Created: 1, Tags: []*Tag{{Key: "k", Value: "v"}}}
+	k := ValueHash([]byte("hello"))
+	tc := &TransactionContext{Database: db, CompactValueMeta: true}
+	if err := tc.putKey(k, vmeta); err != nil {
+		t.Fatal(err)
+	}
+	b, err := db.Get(ValueKey(k))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, vmeta.marshalCompact()) {
+		t.Fatal("value meta not stored compactly")
+	}
+	dvmeta, exists, err := GetValueMeta(db, k)
+	if err != nil || !exists {
+		t.Fatalf("unexpected value meta (exists=%t): %v", exists, err)
+	}
+	if !reflect.DeepEqual(dvmeta, vmeta) {
+		t.Fatalf("expected %+v, got %+v", vmeta, dvmeta)
+	}
+
+	// Access proofs are still computed over the codec encoding
+	for _, version := range []uint16{legacyCodecVersion, codecVersion} {
+		codec, err := marshalVersion(version, vmeta)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v := SelectRandomValue(db, []byte("hello"), version == codecVersion); !bytes.Equal(v, codec) {
+			t.Fatalf("expected codec v%d encoding %x, got %x", version, codec, v)
+		}
+	}
+}
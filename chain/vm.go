@@ -4,6 +4,8 @@
 package chain
 
 import (
+	"time"
+
 	"github.com/ava-labs/avalanchego/database"
 	"github.com/ava-labs/avalanchego/ids"
 )
@@ -18,6 +20,18 @@ type Context struct {
 
 	NextCost  uint64
 	NextPrice uint64
+
+	// Hold the balances written by a block's txs in memory while verifying it
+	// and write them once all txs have executed (node-local: doesn't change
+	// the result of execution)
+	BatchBalanceWrites bool
+
+	// Store value metadata in the compact encoding (node-local: both
+	// encodings are read, see [MigrateValueMetas])
+	CompactValueMeta bool
+	// Compress blocks before they are stored (node-local: stored blocks are
+	// read whether or not they are compressed)
+	CompressBlocks bool
 }
 
 type VM interface {
@@ -25,6 +39,8 @@ type VM interface {
 	IsBootstrapped() bool
 	State() database.Database
 	Mempool() Mempool
+	// Now is the time used to build blocks and bound their timestamps.
+	Now() time.Time
 	GetStatelessBlock(ids.ID) (*StatelessBlock, error)
 	ExecutionContext(currentTime int64, parent *StatelessBlock) (*Context, error)
 	Verified(*StatelessBlock)
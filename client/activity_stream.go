@@ -0,0 +1,77 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/ava-labs/avalanchego/utils/units"
+
+	"github.com/ava-labs/blobvm/chain"
+	"github.com/ava-labs/blobvm/vm"
+)
+
+// maxActivityEventSize bounds a single line of the activity stream.
+const maxActivityEventSize = units.MiB
+
+func (cli *client) StreamActivity(ctx context.Context, f func(*chain.Activity) error) error {
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodGet,
+		fmt.Sprintf("%s%s", cli.uri, vm.ActivityStreamEndpoint),
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: received status code: %d", ErrInvalidResponse, resp.StatusCode)
+	}
+
+	// Events are separated by blank lines. Lines starting with ":" are
+	// comments (the keepalives sent on idle streams) and only "data" fields
+	// are sent.
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(nil, maxActivityEventSize)
+	var data []byte
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		switch {
+		case len(line) == 0:
+			if len(data) == 0 {
+				continue
+			}
+			activity := new(chain.Activity)
+			if err := json.Unmarshal(data, activity); err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+			}
+			data = data[:0]
+			if err := f(activity); err != nil {
+				return err
+			}
+		case line[0] == ':':
+			// Keepalive
+		case bytes.HasPrefix(line, []byte("data:")):
+			data = append(data, bytes.TrimPrefix(line[len("data:"):], []byte(" "))...)
+		}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return ErrStreamClosed
+}
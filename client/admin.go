@@ -0,0 +1,93 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ava-labs/avalanchego/utils/rpc"
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/ava-labs/blobvm/vm"
+)
+
+// AdminClient defines blobvm admin operations. The admin endpoint only serves
+// requests that send the adminToken of the node's VM config.
+type AdminClient interface {
+	// Ban rejects txs from [addr] at mempool admission on this node.
+	Ban(ctx context.Context, addr common.Address) error
+	// Unban reverts [Ban].
+	Unban(ctx context.Context, addr common.Address) error
+	// Banned returns all banned addresses.
+	Banned(ctx context.Context) ([]common.Address, error)
+	// Repair restores the values missing from the node's disk by fetching them
+	// from its peers, returning the keys that were (and weren't) restored.
+	Repair(ctx context.Context) (repaired []common.Hash, unrepairable []common.Hash, err error)
+}
+
+// NewAdmin creates a new admin client object that authenticates with [token]
+// (see [vm.Config.AdminToken]).
+func NewAdmin(uri string, token string, reqTimeout time.Duration) AdminClient {
+	req := rpc.NewEndpointRequester(
+		fmt.Sprintf("%s%s", uri, vm.AdminEndpoint),
+	)
+	return &adminClient{req: req, token: rpc.WithHeader(vm.AdminTokenHeader, token)}
+}
+
+type adminClient struct {
+	req   rpc.EndpointRequester
+	token rpc.Option
+}
+
+func (cli *adminClient) Ban(ctx context.Context, addr common.Address) error {
+	resp := new(vm.BanReply)
+	return cli.req.SendRequest(
+		ctx,
+		"blobvm.ban",
+		&vm.BanArgs{Address: addr},
+		resp,
+		cli.token,
+	)
+}
+
+func (cli *adminClient) Unban(ctx context.Context, addr common.Address) error {
+	resp := new(vm.BanReply)
+	return cli.req.SendRequest(
+		ctx,
+		"blobvm.unban",
+		&vm.BanArgs{Address: addr},
+		resp,
+		cli.token,
+	)
+}
+
+func (cli *adminClient) Banned(ctx context.Context) ([]common.Address, error) {
+	resp := new(vm.BannedReply)
+	if err := cli.req.SendRequest(
+		ctx,
+		"blobvm.banned",
+		nil,
+		resp,
+		cli.token,
+	); err != nil {
+		return nil, err
+	}
+	return resp.Addresses, nil
+}
+
+func (cli *adminClient) Repair(ctx context.Context) ([]common.Hash, []common.Hash, error) {
+	resp := new(vm.RepairReply)
+	if err := cli.req.SendRequest(
+		ctx,
+		"blobvm.repair",
+		nil,
+		resp,
+		cli.token,
+	); err != nil {
+		return nil, nil, err
+	}
+	return resp.Repaired, resp.Unrepairable, nil
+}
@@ -0,0 +1,114 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"sync"
+	"time"
+
+	"github.com/ava-labs/blobvm/chain"
+)
+
+// BenchValueSize is the size of the random values set by [Bench].
+const BenchValueSize = 64
+
+// BenchStats summarizes a call to [Bench].
+type BenchStats struct {
+	Issued  int           `json:"issued"`
+	Failed  int           `json:"failed"`
+	Cost    uint64        `json:"cost"`
+	Elapsed time.Duration `json:"elapsed"`
+
+	// Latency is the total time spent issuing (and confirming, if
+	// [WithPollTx] is provided) successful txs.
+	Latency time.Duration `json:"latency"`
+}
+
+// TPS returns the number of successfully issued txs per second.
+func (s *BenchStats) TPS() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Issued) / s.Elapsed.Seconds()
+}
+
+// AvgLatency returns the average latency of successfully issued txs.
+func (s *BenchStats) AvgLatency() time.Duration {
+	if s.Issued == 0 {
+		return 0
+	}
+	return s.Latency / time.Duration(s.Issued)
+}
+
+// ErrorRate returns the fraction of attempted txs that failed.
+func (s *BenchStats) ErrorRate() float64 {
+	if s.Issued+s.Failed == 0 {
+		return 0
+	}
+	return float64(s.Failed) / float64(s.Issued+s.Failed)
+}
+
+// Bench issues [SetTx]s with random values from [concurrency] workers for
+// [duration], paying fees with [priv].
+//
+// Errors returned by individual txs are counted in [BenchStats.Failed]
+// instead of stopping the benchmark.
+func Bench(
+	ctx context.Context, cli Client, priv *ecdsa.PrivateKey,
+	duration time.Duration, concurrency int, opts ...OpOption,
+) (*BenchStats, error) {
+	if duration <= 0 {
+		return nil, ErrInvalidDuration
+	}
+	if concurrency <= 0 {
+		return nil, ErrInvalidWorkers
+	}
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+
+	var (
+		stats = &BenchStats{}
+		l     sync.Mutex
+		wg    sync.WaitGroup
+	)
+	start := time.Now()
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				v := make([]byte, BenchValueSize)
+				if _, err := rand.Read(v); err != nil {
+					l.Lock()
+					stats.Failed++
+					l.Unlock()
+					continue
+				}
+				utx := &chain.SetTx{BaseTx: &chain.BaseTx{}, Value: v}
+				issued := time.Now()
+				_, cost, err := SignIssueRawTx(ctx, cli, utx, priv, opts...)
+				latency := time.Since(issued)
+				if ctx.Err() != nil {
+					// Interrupted by the end of the benchmark
+					return
+				}
+				l.Lock()
+				if err != nil {
+					stats.Failed++
+				} else {
+					stats.Issued++
+					stats.Cost += cost
+					stats.Latency += latency
+				}
+				l.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	stats.Elapsed = time.Since(start)
+	return stats, nil
+}
@@ -6,7 +6,9 @@ package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/ava-labs/avalanchego/ids"
@@ -25,21 +27,99 @@ type Client interface {
 	Ping(ctx context.Context) (bool, error)
 	// Network information about this instance of the VM
 	Network(ctx context.Context) (uint32, ids.ID, ids.ID, error)
+	// Returns "true" if the node is bootstrapped and has recently accepted a
+	// block.
+	IsCaughtUp(ctx context.Context) (bool, error)
 
 	// Returns the VM genesis.
 	Genesis(ctx context.Context) (*chain.Genesis, error)
+	// CachedGenesis returns the VM genesis, only fetching it the first time
+	// (the genesis never changes). The result is shared and must not be
+	// modified.
+	CachedGenesis(ctx context.Context) (*chain.Genesis, error)
 	// Accepted fetches the ID of the last accepted block.
 	Accepted(ctx context.Context) (ids.ID, error)
+	// Tip fetches the header of the last accepted block.
+	Tip(ctx context.Context) (*chain.BlockHeader, error)
+	// GetBlock fetches the accepted block [blkID] (with its values restored)
+	// and checks it against [blkID]. [blk] is nil if the block is bigger than
+	// the node's response size limit (only its [header] is returned).
+	GetBlock(ctx context.Context, blkID ids.ID) (exists bool, header *chain.BlockHeader, blk *chain.StatefulBlock, err error)
+	// GetBlockByHeight is [GetBlock] for the accepted block at [height].
+	GetBlockByHeight(
+		ctx context.Context,
+		height uint64,
+	) (exists bool, header *chain.BlockHeader, blk *chain.StatefulBlock, err error)
 
+	// HasKeys returns whether each of [keys] is stored (and unexpired)
+	HasKeys(ctx context.Context, keys []common.Hash) ([]bool, error)
+	// WouldStore returns the key of [value] and whether a SetTx for it would
+	// store a new value (instead of failing because it already exists)
+	WouldStore(ctx context.Context, value []byte) (isNew bool, key common.Hash, err error)
 	// Balance returns the balance of an account
 	Balance(ctx context.Context, addr common.Address) (bal uint64, err error)
+	// ValueHash returns the key [v] is stored at (using the chain's value
+	// domain separator)
+	ValueHash(ctx context.Context, v []byte) (common.Hash, error)
 	// Resolve returns the value associated with a path
 	Resolve(ctx context.Context, key common.Hash) (exists bool, value []byte, valueMeta *chain.ValueMeta, err error)
+	// ResolveIfModified returns the value associated with a path only if the
+	// TxID of the current value differs from [lastTxID]
+	ResolveIfModified(
+		ctx context.Context,
+		key common.Hash,
+		lastTxID ids.ID,
+	) (exists bool, modified bool, value []byte, valueMeta *chain.ValueMeta, err error)
+	// ResolvePending returns the value associated with a path, falling back to
+	// a pending SetTx in the mempool if it has not been accepted yet
+	ResolvePending(
+		ctx context.Context,
+		key common.Hash,
+	) (exists bool, pending bool, value []byte, valueMeta *chain.ValueMeta, err error)
+	// ResolveMaxBytes returns the value associated with a path only if it is
+	// at most [maxBytes] long (otherwise [tooLarge] is set and only
+	// [valueMeta] is returned)
+	ResolveMaxBytes(
+		ctx context.Context,
+		key common.Hash,
+		maxBytes uint64,
+	) (exists bool, tooLarge bool, value []byte, valueMeta *chain.ValueMeta, err error)
+	// ResolveWithOptions returns the value associated with a path, applying
+	// any combination of [ResolveOption]s (ex: to skip values the caller
+	// doesn't need or to include pending values)
+	ResolveWithOptions(ctx context.Context, key common.Hash, opts ...ResolveOption) (*ResolveResult, error)
+	// ExportValue returns the value associated with a path bundled with the
+	// signed tx that set it, so its provenance can be verified offline with
+	// [chain.VerifyBundle] (nil if the value is not stored).
+	ExportValue(ctx context.Context, key common.Hash) (*chain.Bundle, error)
+	// ResolveRange returns bytes [start, end) (capped at the end of the value)
+	// of the value associated with a path. If the value is a tree root, its
+	// file is read instead, fetching only the chunks that cover the range
+	// (each of which is checked against its key).
+	ResolveRange(ctx context.Context, key common.Hash, start uint64, end uint64) (exists bool, value []byte, err error)
 
 	// Requests the suggested price and cost from VM.
 	SuggestedRawFee(ctx context.Context) (uint64, uint64, error)
+	// Requests the genesis magic, last accepted blockID, and suggested price
+	// and cost from VM in a single call.
+	PrepareTx(ctx context.Context) (magic uint64, blkID ids.ID, price uint64, cost uint64, err error)
 	// Issues the transaction and returns the transaction ID.
 	IssueRawTx(ctx context.Context, d []byte) (ids.ID, error)
+	// IssueRawTxWithFee is [IssueRawTx] but also returns the fee charged for
+	// the transaction and the sender's balance after executing it (as reported
+	// by the VM).
+	IssueRawTxWithFee(ctx context.Context, d []byte) (txID ids.ID, fee uint64, balance uint64, err error)
+	// IssueRawTxBatch issues many signed transactions (up to
+	// [vm.MaxIssueRawTxBatchLimit]) in a single request. [txIDs] and [errs]
+	// are in the same order as [txs]: errs[i] is nil if txs[i] was issued as
+	// txIDs[i]. A failed transaction does not prevent the others from being
+	// issued.
+	IssueRawTxBatch(ctx context.Context, txs [][]byte) (txIDs []ids.ID, errs []error, err error)
+	// SimulateRawTx executes the signed transaction as [IssueRawTx] would,
+	// without issuing it, and returns [fee] and [balance] as
+	// [IssueRawTxWithFee] would. If the transaction would be rejected, the
+	// error is a [*SimulationError].
+	SimulateRawTx(ctx context.Context, d []byte) (fee uint64, balance uint64, err error)
 
 	// Requests the suggested price and cost from VM, returns the input as
 	// TypedData.
@@ -49,23 +129,189 @@ type Client interface {
 
 	// Checks the status of the transaction, and returns "true" if confirmed.
 	HasTx(ctx context.Context, id ids.ID) (bool, error)
-	// Polls the transactions until its status is confirmed.
+	// Checks the status of many transactions at once (up to
+	// [vm.MaxHasTxsLimit]), returning "true" for each that is confirmed.
+	HasTxs(ctx context.Context, ids []ids.ID) ([]bool, error)
+	// Polls the transactions until its status is confirmed (see
+	// [WithConfirmations]).
 	PollTx(ctx context.Context, txID ids.ID) (confirmed bool, err error)
+	// WaitForBalance polls the balance of [addr] until it is at least
+	// [atLeast], returning the last balance observed.
+	WaitForBalance(ctx context.Context, addr common.Address, atLeast uint64) (bal uint64, err error)
 
 	// Recent actions on the network (sorted from recent to oldest)
 	RecentActivity(ctx context.Context) ([]*chain.Activity, error)
+	// RecentActivityWithMore is [RecentActivity] but also returns whether
+	// older activity was left out to keep the response within the node's
+	// size limit.
+	RecentActivityWithMore(ctx context.Context) (activity []*chain.Activity, more bool, err error)
+	// ActivityTypes returns the fields populated in the activity of each tx
+	// type.
+	ActivityTypes(ctx context.Context) ([]*chain.ActivityType, error)
+	// StreamActivity calls [f] with the activity of each tx accepted from now
+	// on (see [vm.ActivityStreamEndpoint]) until [ctx] is done, [f] returns an
+	// error, or the stream is closed by the node ([ErrStreamClosed]).
+	// Keepalives sent by the node on idle streams are skipped.
+	StreamActivity(ctx context.Context, f func(*chain.Activity) error) error
+	// ListKeys returns up to [limit] unexpired keys starting at [start] (0
+	// uses the max limit). If there are more keys, [next] is the start of the
+	// next page.
+	ListKeys(ctx context.Context, start common.Hash, limit int) (keys []common.Hash, next *common.Hash, err error)
+	// QueryByTag returns up to [limit] unexpired keys tagged with
+	// [key]=[value] (0 uses the max limit).
+	QueryByTag(ctx context.Context, key string, value string, limit int) ([]common.Hash, error)
+	// TxsBySender returns the IDs of up to [limit] accepted txs issued by
+	// [addr], from newest to oldest (0 uses the max limit).
+	TxsBySender(ctx context.Context, addr common.Address, limit int) ([]ids.ID, error)
+	// MempoolStats returns a summary of the txs in the node's mempool.
+	MempoolStats(ctx context.Context) (*vm.MempoolStatsReply, error)
+	// PendingTxIDs returns the IDs of up to [limit] of the highest paying txs
+	// in the node's mempool, from highest to lowest price (0 uses the max
+	// limit).
+	PendingTxIDs(ctx context.Context, limit int) ([]ids.ID, error)
 }
 
+// pollTxFinalCheckTimeout bounds the status check made by PollTx after its
+// context expires.
+const pollTxFinalCheckTimeout = 5 * time.Second
+
 // New creates a new client object.
-func New(uri string, reqTimeout time.Duration) Client {
+func New(uri string, reqTimeout time.Duration, opts ...Option) Client {
+	op := &clientOp{basePath: vm.PublicEndpoint}
+	for _, opt := range opts {
+		opt(op)
+	}
 	req := rpc.NewEndpointRequester(
-		fmt.Sprintf("%s%s", uri, vm.PublicEndpoint),
+		fmt.Sprintf("%s%s", uri, op.basePath),
 	)
-	return &client{req: req}
+	return &client{uri: uri, req: req, op: op}
+}
+
+type clientOp struct {
+	basePath string
+
+	// Verified before the first tx is issued (if non-zero)
+	networkID uint32
+	chainID   ids.ID
+
+	// Fetched from the genesis on first use (if not set)
+	domainSep    []byte
+	domainSepSet bool
+
+	// Blocks deep a tx must be for PollTx to report it as confirmed
+	confirmations uint64
+}
+
+type Option func(*clientOp)
+
+// WithBasePath overrides the path appended to the uri passed to [New]
+// (defaults to [vm.PublicEndpoint]). Use an empty path if the uri already
+// points at the public endpoint (ex: when behind a path-rewriting proxy).
+func WithBasePath(p string) Option {
+	return func(op *clientOp) { op.basePath = p }
+}
+
+// WithNetworkID requires the VM to report [networkID] from Network before any
+// tx is issued.
+func WithNetworkID(networkID uint32) Option {
+	return func(op *clientOp) { op.networkID = networkID }
+}
+
+// WithChainID requires the VM to report [chainID] from Network before any tx
+// is issued.
+func WithChainID(chainID ids.ID) Option {
+	return func(op *clientOp) { op.chainID = chainID }
+}
+
+// WithValueDomainSeparator sets the [chain.Genesis.ValueDomainSeparator] used
+// to compute keys instead of fetching it from the genesis.
+func WithValueDomainSeparator(sep []byte) Option {
+	return func(op *clientOp) {
+		op.domainSep = sep
+		op.domainSepSet = true
+	}
+}
+
+// WithConfirmations makes PollTx wait until the block that accepted a tx is at
+// least [n] blocks deep (counting the block itself, so 0 and 1 report txs as
+// confirmed once they are accepted). Txs accepted before the node indexed
+// their block are reported as confirmed.
+func WithConfirmations(n uint64) Option {
+	return func(op *clientOp) { op.confirmations = n }
 }
 
 type client struct {
+	uri string
 	req rpc.EndpointRequester
+	op  *clientOp
+
+	verifiedLock sync.Mutex
+	verified     bool
+
+	genesisLock sync.Mutex
+	genesis     *chain.Genesis
+}
+
+// verifyNetwork ensures the VM is on the network expected by [op]. The result
+// is cached once verification succeeds.
+func (cli *client) verifyNetwork(ctx context.Context) error {
+	if cli.op.networkID == 0 && cli.op.chainID == ids.Empty {
+		return nil
+	}
+	cli.verifiedLock.Lock()
+	defer cli.verifiedLock.Unlock()
+	if cli.verified {
+		return nil
+	}
+	networkID, _, chainID, err := cli.Network(ctx)
+	if err != nil {
+		return err
+	}
+	if cli.op.networkID != 0 && networkID != cli.op.networkID {
+		return fmt.Errorf("%w: networkID %d (expected %d)", ErrWrongNetwork, networkID, cli.op.networkID)
+	}
+	if cli.op.chainID != ids.Empty && chainID != cli.op.chainID {
+		return fmt.Errorf("%w: chainID %s (expected %s)", ErrWrongNetwork, chainID, cli.op.chainID)
+	}
+	cli.verified = true
+	return nil
+}
+
+// domainSeparator returns the [chain.Genesis.ValueDomainSeparator] of the VM.
+func (cli *client) domainSeparator(ctx context.Context) ([]byte, error) {
+	if cli.op.domainSepSet {
+		return cli.op.domainSep, nil
+	}
+	g, err := cli.CachedGenesis(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return g.ValueDomainSeparator, nil
+}
+
+func (cli *client) ValueHash(ctx context.Context, v []byte) (common.Hash, error) {
+	sep, err := cli.domainSeparator(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return chain.DomainValueHash(sep, v), nil
+}
+
+// checkIntegrity ensures [v] is stored at [key] under the addressing of
+// [vmeta] (if any).
+func (cli *client) checkIntegrity(ctx context.Context, key common.Hash, v []byte, vmeta *chain.ValueMeta) error {
+	sep, err := cli.domainSeparator(ctx)
+	if err != nil {
+		return err
+	}
+	addressing := chain.HashAddressing
+	if vmeta != nil {
+		addressing = vmeta.Addressing
+	}
+	if addressing.Key(sep, v) != key {
+		return ErrIntegrityFailure
+	}
+	return nil
 }
 
 func (cli *client) Ping(ctx context.Context) (bool, error) {
@@ -95,6 +341,20 @@ func (cli *client) Network(ctx context.Context) (uint32, ids.ID, ids.ID, error)
 	return resp.NetworkID, resp.SubnetID, resp.ChainID, nil
 }
 
+func (cli *client) IsCaughtUp(ctx context.Context) (bool, error) {
+	resp := new(vm.IsCaughtUpReply)
+	err := cli.req.SendRequest(
+		ctx,
+		"blobvm.isCaughtUp",
+		nil,
+		resp,
+	)
+	if err != nil {
+		return false, err
+	}
+	return resp.CaughtUp, nil
+}
+
 func (cli *client) Genesis(ctx context.Context) (*chain.Genesis, error) {
 	resp := new(vm.GenesisReply)
 	err := cli.req.SendRequest(
@@ -106,6 +366,24 @@ func (cli *client) Genesis(ctx context.Context) (*chain.Genesis, error) {
 	return resp.Genesis, err
 }
 
+func (cli *client) CachedGenesis(ctx context.Context) (*chain.Genesis, error) {
+	cli.genesisLock.Lock()
+	defer cli.genesisLock.Unlock()
+
+	if cli.genesis != nil {
+		return cli.genesis, nil
+	}
+	g, err := cli.Genesis(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if g == nil {
+		return nil, fmt.Errorf("%w: missing genesis", ErrInvalidResponse)
+	}
+	cli.genesis = g
+	return g, nil
+}
+
 func (cli *client) Accepted(ctx context.Context) (ids.ID, error) {
 	resp := new(vm.LastAcceptedReply)
 	if err := cli.req.SendRequest(
@@ -120,6 +398,74 @@ func (cli *client) Accepted(ctx context.Context) (ids.ID, error) {
 	return resp.BlockID, nil
 }
 
+func (cli *client) Tip(ctx context.Context) (*chain.BlockHeader, error) {
+	resp := new(vm.TipReply)
+	if err := cli.req.SendRequest(
+		ctx,
+		"blobvm.tip",
+		nil,
+		resp,
+	); err != nil {
+		return nil, err
+	}
+	return resp.Header, nil
+}
+
+func (cli *client) GetBlock(ctx context.Context, blkID ids.ID) (bool, *chain.BlockHeader, *chain.StatefulBlock, error) {
+	resp := new(vm.GetBlockReply)
+	if err := cli.req.SendRequest(
+		ctx,
+		"blobvm.getBlock",
+		&vm.GetBlockArgs{BlockID: blkID},
+		resp,
+	); err != nil {
+		return false, nil, nil, err
+	}
+	return parseBlockReply(resp, &blkID)
+}
+
+func (cli *client) GetBlockByHeight(ctx context.Context, height uint64) (bool, *chain.BlockHeader, *chain.StatefulBlock, error) {
+	resp := new(vm.GetBlockReply)
+	if err := cli.req.SendRequest(
+		ctx,
+		"blobvm.getBlockByHeight",
+		&vm.GetBlockByHeightArgs{Height: height},
+		resp,
+	); err != nil {
+		return false, nil, nil, err
+	}
+	exists, header, blk, err := parseBlockReply(resp, nil)
+	if err == nil && exists && header.Hght != height {
+		return false, nil, nil, fmt.Errorf("%w: block at height %d, expected %d", ErrInvalidResponse, header.Hght, height)
+	}
+	return exists, header, blk, err
+}
+
+// parseBlockReply decodes the block in [resp] (if any) and checks it against
+// its header (and [blkID], if not nil).
+func parseBlockReply(resp *vm.GetBlockReply, blkID *ids.ID) (bool, *chain.BlockHeader, *chain.StatefulBlock, error) {
+	if !resp.Exists {
+		return false, nil, nil, nil
+	}
+	if resp.Header == nil {
+		return false, nil, nil, fmt.Errorf("%w: missing block header", ErrInvalidResponse)
+	}
+	if blkID != nil && resp.Header.ID != *blkID {
+		return false, nil, nil, fmt.Errorf("%w: block %s, expected %s", ErrInvalidResponse, resp.Header.ID, *blkID)
+	}
+	if resp.TooLarge {
+		return true, resp.Header, nil, nil
+	}
+	id, blk, err := chain.DecodeBlock(resp.Block)
+	if err != nil {
+		return false, nil, nil, fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+	}
+	if id != resp.Header.ID || blk.Hght != resp.Header.Hght {
+		return false, nil, nil, ErrIntegrityFailure
+	}
+	return true, resp.Header, blk, nil
+}
+
 func (cli *client) SuggestedRawFee(ctx context.Context) (uint64, uint64, error) {
 	resp := new(vm.SuggestedRawFeeReply)
 	if err := cli.req.SendRequest(
@@ -133,7 +479,28 @@ func (cli *client) SuggestedRawFee(ctx context.Context) (uint64, uint64, error)
 	return resp.Price, resp.Cost, nil
 }
 
+func (cli *client) PrepareTx(ctx context.Context) (uint64, ids.ID, uint64, uint64, error) {
+	resp := new(vm.PrepareTxReply)
+	if err := cli.req.SendRequest(
+		ctx,
+		"blobvm.prepareTx",
+		nil,
+		resp,
+	); err != nil {
+		return 0, ids.Empty, 0, 0, err
+	}
+	return resp.Magic, resp.BlockID, resp.Price, resp.Cost, nil
+}
+
 func (cli *client) IssueRawTx(ctx context.Context, d []byte) (ids.ID, error) {
+	txID, _, _, err := cli.IssueRawTxWithFee(ctx, d)
+	return txID, err
+}
+
+func (cli *client) IssueRawTxWithFee(ctx context.Context, d []byte) (ids.ID, uint64, uint64, error) {
+	if err := cli.verifyNetwork(ctx); err != nil {
+		return ids.Empty, 0, 0, err
+	}
 	resp := new(vm.IssueRawTxReply)
 	if err := cli.req.SendRequest(
 		ctx,
@@ -141,12 +508,64 @@ func (cli *client) IssueRawTx(ctx context.Context, d []byte) (ids.ID, error) {
 		&vm.IssueRawTxArgs{Tx: d},
 		resp,
 	); err != nil {
-		return ids.Empty, err
+		return ids.Empty, 0, 0, err
 	}
-	return resp.TxID, nil
+	return resp.TxID, resp.Fee, resp.Balance, nil
+}
+
+func (cli *client) IssueRawTxBatch(ctx context.Context, txs [][]byte) ([]ids.ID, []error, error) {
+	if err := cli.verifyNetwork(ctx); err != nil {
+		return nil, nil, err
+	}
+	resp := new(vm.IssueRawTxBatchReply)
+	if err := cli.req.SendRequest(
+		ctx,
+		"blobvm.issueRawTxBatch",
+		&vm.IssueRawTxBatchArgs{Txs: txs},
+		resp,
+	); err != nil {
+		return nil, nil, err
+	}
+	if len(resp.TxIDs) != len(txs) || len(resp.Errors) != len(txs) {
+		return nil, nil, fmt.Errorf("%w: expected %d results, got %d", ErrInvalidResponse, len(txs), len(resp.TxIDs))
+	}
+	errs := make([]error, len(txs))
+	for i, msg := range resp.Errors {
+		if len(msg) > 0 {
+			errs[i] = errors.New(msg)
+		}
+	}
+	return resp.TxIDs, errs, nil
+}
+
+func (cli *client) SimulateRawTx(ctx context.Context, d []byte) (uint64, uint64, error) {
+	if err := cli.verifyNetwork(ctx); err != nil {
+		return 0, 0, err
+	}
+	resp := new(vm.SimulateTxReply)
+	if err := cli.req.SendRequest(
+		ctx,
+		"blobvm.simulateTx",
+		&vm.SimulateTxArgs{Tx: d},
+		resp,
+	); err != nil {
+		return 0, 0, err
+	}
+	if !resp.Success {
+		return 0, 0, &SimulationError{TxID: resp.TxID, Reason: resp.Error}
+	}
+	return resp.Fee, resp.Balance, nil
 }
 
 func (cli *client) HasTx(ctx context.Context, txID ids.ID) (bool, error) {
+	resp, err := cli.hasTx(ctx, txID)
+	if err != nil {
+		return false, err
+	}
+	return resp.Accepted, nil
+}
+
+func (cli *client) hasTx(ctx context.Context, txID ids.ID) (*vm.HasTxReply, error) {
 	resp := new(vm.HasTxReply)
 	if err := cli.req.SendRequest(
 		ctx,
@@ -154,8 +573,44 @@ func (cli *client) HasTx(ctx context.Context, txID ids.ID) (bool, error) {
 		&vm.HasTxArgs{TxID: txID},
 		resp,
 	); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// confirmed returns true if [txID] was accepted at least
+// [clientOp.confirmations] blocks deep.
+func (cli *client) confirmed(ctx context.Context, txID ids.ID) (bool, error) {
+	resp, err := cli.hasTx(ctx, txID)
+	if err != nil || !resp.Accepted {
 		return false, err
 	}
+	if cli.op.confirmations <= 1 || resp.Height == nil {
+		return true, nil
+	}
+	tip, err := cli.Tip(ctx)
+	if err != nil {
+		return false, err
+	}
+	if tip == nil || tip.Hght < *resp.Height {
+		return false, fmt.Errorf("%w: tip is below the tx", ErrInvalidResponse)
+	}
+	return tip.Hght-*resp.Height+1 >= cli.op.confirmations, nil
+}
+
+func (cli *client) HasTxs(ctx context.Context, txIDs []ids.ID) ([]bool, error) {
+	resp := new(vm.HasTxsReply)
+	if err := cli.req.SendRequest(
+		ctx,
+		"blobvm.hasTxs",
+		&vm.HasTxsArgs{TxIDs: txIDs},
+		resp,
+	); err != nil {
+		return nil, err
+	}
+	if len(resp.Accepted) != len(txIDs) {
+		return nil, fmt.Errorf("%w: expected %d results, got %d", ErrInvalidResponse, len(txIDs), len(resp.Accepted))
+	}
 	return resp.Accepted, nil
 }
 
@@ -173,6 +628,9 @@ func (cli *client) SuggestedFee(ctx context.Context, i *chain.Input) (*tdata.Typ
 }
 
 func (cli *client) IssueTx(ctx context.Context, td *tdata.TypedData, sig []byte) (ids.ID, error) {
+	if err := cli.verifyNetwork(ctx); err != nil {
+		return ids.Empty, err
+	}
 	resp := new(vm.IssueTxReply)
 	if err := cli.req.SendRequest(
 		ctx,
@@ -195,7 +653,7 @@ done:
 			break done
 		}
 
-		confirmed, err := cli.HasTx(ctx, txID)
+		confirmed, err := cli.confirmed(ctx, txID)
 		if err != nil {
 			color.Red("polling transaction failed %v", err)
 			continue
@@ -205,30 +663,216 @@ done:
 			return true, nil
 		}
 	}
+
+	// The tx may have been confirmed right as [ctx] expired, so check once more
+	// (with a fresh context) before reporting it as unconfirmed.
+	fctx, cancel := context.WithTimeout(context.Background(), pollTxFinalCheckTimeout)
+	defer cancel()
+	if confirmed, err := cli.confirmed(fctx, txID); err == nil && confirmed {
+		color.Green("confirmed transaction %v", txID)
+		return true, nil
+	}
 	return false, ctx.Err()
 }
 
+func (cli *client) WaitForBalance(ctx context.Context, addr common.Address, atLeast uint64) (uint64, error) {
+	var bal uint64
+	for {
+		b, err := cli.Balance(ctx, addr)
+		switch {
+		case err != nil:
+			color.Red("polling balance failed %v", err)
+		case b >= atLeast:
+			return b, nil
+		default:
+			bal = b
+		}
+
+		select {
+		case <-time.After(time.Second):
+		case <-ctx.Done():
+			return bal, fmt.Errorf("%w: balance of %s is %d (waiting for %d)", ctx.Err(), addr, bal, atLeast)
+		}
+	}
+}
+
 func (cli *client) Resolve(ctx context.Context, key common.Hash) (bool, []byte, *chain.ValueMeta, error) {
+	r, err := cli.ResolveWithOptions(ctx, key)
+	if err != nil {
+		return false, nil, nil, err
+	}
+	return r.Exists, r.Value, r.ValueMeta, nil
+}
+
+func (cli *client) ResolveWithOptions(ctx context.Context, key common.Hash, opts ...ResolveOption) (*ResolveResult, error) {
+	op := &resolveOp{}
+	op.applyOpts(opts)
+
 	resp := new(vm.ResolveReply)
 	if err := cli.req.SendRequest(
 		ctx,
 		"blobvm.resolve",
 		&vm.ResolveArgs{
-			Key: key,
+			Key:            key,
+			LastTxID:       op.lastTxID,
+			MaxBytes:       op.maxBytes,
+			IncludePending: op.pending,
 		},
 		resp,
 	); err != nil {
-		return false, nil, nil, err
+		return nil, err
 	}
 
 	if !resp.Exists {
-		return false, nil, nil, nil
+		return &ResolveResult{}, nil
+	}
+	r := &ResolveResult{
+		Exists:      true,
+		ValueMeta:   resp.ValueMeta,
+		NotModified: resp.NotModified,
+		TooLarge:    resp.TooLarge,
+		Pending:     resp.Pending,
+	}
+	if r.NotModified || r.TooLarge {
+		return r, nil
 	}
 
-	if key != chain.ValueHash(resp.Value) {
-		return false, nil, nil, ErrIntegrityFailure
+	v, err := vm.DecodeValue(resp.Encoding, resp.Value)
+	if err != nil {
+		return nil, err
+	}
+	if err := cli.checkIntegrity(ctx, key, v, resp.ValueMeta); err != nil {
+		return nil, err
+	}
+	r.Value = v
+	return r, nil
+}
+
+func (cli *client) ExportValue(ctx context.Context, key common.Hash) (*chain.Bundle, error) {
+	resp := new(vm.ExportValueReply)
+	if err := cli.req.SendRequest(
+		ctx,
+		"blobvm.exportValue",
+		&vm.ExportValueArgs{Key: key},
+		resp,
+	); err != nil {
+		return nil, err
+	}
+	if !resp.Exists {
+		return nil, nil
+	}
+	if resp.Bundle == nil || resp.Bundle.Key != key {
+		return nil, fmt.Errorf("%w: missing bundle for %s", ErrInvalidResponse, key)
+	}
+	if err := cli.checkIntegrity(ctx, key, resp.Bundle.Value, resp.Bundle.ValueMeta); err != nil {
+		return nil, err
+	}
+	return resp.Bundle, nil
+}
+
+func (cli *client) ResolveRange(ctx context.Context, key common.Hash, start uint64, end uint64) (bool, []byte, error) {
+	resp := new(vm.ResolveRangeReply)
+	if err := cli.req.SendRequest(
+		ctx,
+		"blobvm.resolveRange",
+		&vm.ResolveRangeArgs{Key: key, Start: start, End: end},
+		resp,
+	); err != nil {
+		return false, nil, err
 	}
-	return true, resp.Value, resp.ValueMeta, nil
+	if !resp.Exists {
+		return false, nil, nil
+	}
+	if len(resp.Chunks) == 0 {
+		return true, resp.Value, nil
+	}
+	if len(resp.Keys) != len(resp.Chunks) || resp.Offset > start {
+		return false, nil, fmt.Errorf("%w: invalid chunks for range", ErrInvalidResponse)
+	}
+	var b []byte
+	for i, chunk := range resp.Chunks {
+		if err := cli.checkIntegrity(ctx, resp.Keys[i], chunk, nil); err != nil {
+			return false, nil, err
+		}
+		b = append(b, chunk...)
+	}
+	// Trim the chunks to the requested range
+	l := resp.Offset + uint64(len(b))
+	if start >= l {
+		return true, nil, nil
+	}
+	if end > l {
+		end = l
+	}
+	return true, b[start-resp.Offset : end-resp.Offset], nil
+}
+
+func (cli *client) ResolveIfModified(
+	ctx context.Context,
+	key common.Hash,
+	lastTxID ids.ID,
+) (bool, bool, []byte, *chain.ValueMeta, error) {
+	r, err := cli.ResolveWithOptions(ctx, key, WithLastTxID(lastTxID))
+	if err != nil {
+		return false, false, nil, nil, err
+	}
+	if !r.Exists {
+		return false, false, nil, nil, nil
+	}
+	return true, !r.NotModified, r.Value, r.ValueMeta, nil
+}
+
+func (cli *client) ResolveMaxBytes(
+	ctx context.Context,
+	key common.Hash,
+	maxBytes uint64,
+) (bool, bool, []byte, *chain.ValueMeta, error) {
+	r, err := cli.ResolveWithOptions(ctx, key, WithMaxBytes(maxBytes))
+	if err != nil {
+		return false, false, nil, nil, err
+	}
+	return r.Exists, r.TooLarge, r.Value, r.ValueMeta, nil
+}
+
+func (cli *client) ResolvePending(
+	ctx context.Context,
+	key common.Hash,
+) (bool, bool, []byte, *chain.ValueMeta, error) {
+	r, err := cli.ResolveWithOptions(ctx, key, WithPending())
+	if err != nil {
+		return false, false, nil, nil, err
+	}
+	return r.Exists, r.Pending, r.Value, r.ValueMeta, nil
+}
+
+func (cli *client) HasKeys(ctx context.Context, keys []common.Hash) ([]bool, error) {
+	resp := new(vm.HasKeysReply)
+	if err := cli.req.SendRequest(
+		ctx,
+		"blobvm.hasKeys",
+		&vm.HasKeysArgs{
+			Keys: keys,
+		},
+		resp,
+	); err != nil {
+		return nil, err
+	}
+	if len(resp.Exists) != len(keys) {
+		return nil, fmt.Errorf("%w: expected %d results, got %d", ErrInvalidResponse, len(keys), len(resp.Exists))
+	}
+	return resp.Exists, nil
+}
+
+func (cli *client) WouldStore(ctx context.Context, value []byte) (bool, common.Hash, error) {
+	k, err := cli.ValueHash(ctx, value)
+	if err != nil {
+		return false, common.Hash{}, err
+	}
+	exists, err := cli.HasKeys(ctx, []common.Hash{k})
+	if err != nil {
+		return false, common.Hash{}, err
+	}
+	return !exists[0], k, nil
 }
 
 func (cli *client) Balance(ctx context.Context, addr common.Address) (bal uint64, err error) {
@@ -246,15 +890,110 @@ func (cli *client) Balance(ctx context.Context, addr common.Address) (bal uint64
 	return resp.Balance, nil
 }
 
-func (cli *client) RecentActivity(ctx context.Context) (activity []*chain.Activity, err error) {
+func (cli *client) RecentActivity(ctx context.Context) ([]*chain.Activity, error) {
+	activity, _, err := cli.RecentActivityWithMore(ctx)
+	return activity, err
+}
+
+func (cli *client) RecentActivityWithMore(ctx context.Context) ([]*chain.Activity, bool, error) {
 	resp := new(vm.RecentActivityReply)
-	if err = cli.req.SendRequest(
+	if err := cli.req.SendRequest(
 		ctx,
 		"blobvm.recentActivity",
 		nil,
 		resp,
+	); err != nil {
+		return nil, false, err
+	}
+	return resp.Activity, resp.More, nil
+}
+
+func (cli *client) ActivityTypes(ctx context.Context) ([]*chain.ActivityType, error) {
+	resp := new(vm.ActivityTypesReply)
+	if err := cli.req.SendRequest(
+		ctx,
+		"blobvm.activityTypes",
+		nil,
+		resp,
+	); err != nil {
+		return nil, err
+	}
+	return resp.Types, nil
+}
+
+func (cli *client) ListKeys(ctx context.Context, start common.Hash, limit int) ([]common.Hash, *common.Hash, error) {
+	resp := new(vm.ListKeysReply)
+	if err := cli.req.SendRequest(
+		ctx,
+		"blobvm.listKeys",
+		&vm.ListKeysArgs{
+			Start: start,
+			Limit: limit,
+		},
+		resp,
+	); err != nil {
+		return nil, nil, err
+	}
+	return resp.Keys, resp.Next, nil
+}
+
+func (cli *client) QueryByTag(ctx context.Context, key string, value string, limit int) ([]common.Hash, error) {
+	resp := new(vm.QueryByTagReply)
+	if err := cli.req.SendRequest(
+		ctx,
+		"blobvm.queryByTag",
+		&vm.QueryByTagArgs{
+			Key:   key,
+			Value: value,
+			Limit: limit,
+		},
+		resp,
+	); err != nil {
+		return nil, err
+	}
+	return resp.Keys, nil
+}
+
+func (cli *client) TxsBySender(ctx context.Context, addr common.Address, limit int) ([]ids.ID, error) {
+	resp := new(vm.TxsBySenderReply)
+	if err := cli.req.SendRequest(
+		ctx,
+		"blobvm.txsBySender",
+		&vm.TxsBySenderArgs{
+			Address: addr,
+			Limit:   limit,
+		},
+		resp,
+	); err != nil {
+		return nil, err
+	}
+	return resp.TxIDs, nil
+}
+
+func (cli *client) MempoolStats(ctx context.Context) (*vm.MempoolStatsReply, error) {
+	resp := new(vm.MempoolStatsReply)
+	if err := cli.req.SendRequest(
+		ctx,
+		"blobvm.mempoolStats",
+		nil,
+		resp,
+	); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (cli *client) PendingTxIDs(ctx context.Context, limit int) ([]ids.ID, error) {
+	resp := new(vm.PendingTxIDsReply)
+	if err := cli.req.SendRequest(
+		ctx,
+		"blobvm.pendingTxIDs",
+		&vm.PendingTxIDsArgs{
+			Limit: limit,
+		},
+		resp,
 	); err != nil {
 		return nil, err
 	}
-	return resp.Activity, nil
+	return resp.TxIDs, nil
 }
@@ -0,0 +1,674 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/rpc"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/ava-labs/blobvm/chain"
+	"github.com/ava-labs/blobvm/vm"
+)
+
+var _ rpc.EndpointRequester = &networkRequester{}
+
+// networkRequester reports [networkID] and [chainID] from "blobvm.network" and
+// records all methods called.
+type networkRequester struct {
+	networkID uint32
+	chainID   ids.ID
+	methods   []string
+}
+
+func (r *networkRequester) SendRequest(_ context.Context, method string, _ interface{}, reply interface{}, _ ...rpc.Option) error {
+	r.methods = append(r.methods, method)
+	switch method {
+	case "blobvm.network":
+		resp := reply.(*vm.NetworkReply)
+		resp.NetworkID = r.networkID
+		resp.ChainID = r.chainID
+	case "blobvm.issueRawTx":
+		reply.(*vm.IssueRawTxReply).TxID = ids.GenerateTestID()
+	}
+	return nil
+}
+
+func TestVerifyNetwork(t *testing.T) {
+	chainID := ids.GenerateTestID()
+	tt := []struct {
+		opts    []Option
+		methods []string
+		err     error
+	}{
+		{ // no expectations
+			methods: []string{"blobvm.issueRawTx", "blobvm.issueRawTx"},
+		},
+		{ // network is only verified once
+			opts:    []Option{WithNetworkID(1), WithChainID(chainID)},
+			methods: []string{"blobvm.network", "blobvm.issueRawTx", "blobvm.issueRawTx"},
+		},
+		{ // wrong networkID
+			opts:    []Option{WithNetworkID(2)},
+			methods: []string{"blobvm.network", "blobvm.network"},
+			err:     ErrWrongNetwork,
+		},
+		{ // wrong chainID
+			opts:    []Option{WithNetworkID(1), WithChainID(ids.GenerateTestID())},
+			methods: []string{"blobvm.network", "blobvm.network"},
+			err:     ErrWrongNetwork,
+		},
+	}
+	for i, tv := range tt {
+		op := &clientOp{}
+		for _, opt := range tv.opts {
+			opt(op)
+		}
+		r := &networkRequester{networkID: 1, chainID: chainID}
+		cli := &client{req: r, op: op}
+		for j := 0; j < 2; j++ {
+			if _, err := cli.IssueRawTx(context.Background(), nil); !errors.Is(err, tv.err) {
+				t.Fatalf("#%d: expected %v, got %v", i, tv.err, err)
+			}
+		}
+		if !reflect.DeepEqual(r.methods, tv.methods) {
+			t.Fatalf("#%d: methods expected %v, got %v", i, tv.methods, r.methods)
+		}
+	}
+}
+
+var _ rpc.EndpointRequester = &deadlineRequester{}
+
+// deadlineRequester reports a tx as accepted once [confirmAt] has passed.
+type deadlineRequester struct {
+	confirmAt time.Time
+}
+
+func (r *deadlineRequester) SendRequest(ctx context.Context, method string, _ interface{}, reply interface{}, _ ...rpc.Option) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if method != "blobvm.hasTx" {
+		return errors.New("unexpected method " + method)
+	}
+	reply.(*vm.HasTxReply).Accepted = !time.Now().Before(r.confirmAt)
+	return nil
+}
+
+func TestPollTxConfirmedAtDeadline(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+	defer cancel()
+	deadline, _ := ctx.Deadline()
+
+	cli := &client{req: &deadlineRequester{confirmAt: deadline}, op: &clientOp{}}
+	confirmed, err := cli.PollTx(ctx, ids.GenerateTestID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !confirmed {
+		t.Fatal("tx confirmed at the deadline should be reported as confirmed")
+	}
+}
+
+var _ rpc.EndpointRequester = &depthRequester{}
+
+// depthRequester reports a tx as accepted at [height] (if not nil), and
+// advances the tip by a block on every tip request (starting at the tx).
+type depthRequester struct {
+	height *uint64
+	tips   int
+}
+
+func (r *depthRequester) SendRequest(ctx context.Context, method string, _ interface{}, reply interface{}, _ ...rpc.Option) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	switch method {
+	case "blobvm.hasTx":
+		reply.(*vm.HasTxReply).Accepted = true
+		reply.(*vm.HasTxReply).Height = r.height
+	case "blobvm.tip":
+		reply.(*vm.TipReply).Header = &chain.BlockHeader{Hght: *r.height + uint64(r.tips)}
+		r.tips++
+	default:
+		return errors.New("unexpected method " + method)
+	}
+	return nil
+}
+
+func TestPollTxConfirmations(t *testing.T) {
+	t.Parallel()
+
+	height := uint64(10)
+	tt := []struct {
+		height        *uint64
+		confirmations uint64
+		tips          int
+	}{
+		{height: &height, confirmations: 0},
+		{height: &height, confirmations: 1},
+		// Confirmed once the tip is 2 blocks past the tx's block
+		{height: &height, confirmations: 3, tips: 3},
+		// The depth of txs accepted before their block was indexed is unknown
+		{confirmations: 3},
+	}
+	for i, tv := range tt {
+		r := &depthRequester{height: tv.height}
+		cli := &client{req: r, op: &clientOp{confirmations: tv.confirmations}}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		confirmed, err := cli.PollTx(ctx, ids.GenerateTestID())
+		cancel()
+		if err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+		if !confirmed {
+			t.Fatalf("#%d: expected tx to be confirmed", i)
+		}
+		if r.tips != tv.tips {
+			t.Fatalf("#%d: expected confirmation after %d tips, got %d", i, tv.tips, r.tips)
+		}
+	}
+
+	// Not confirmed before the tx is deep enough
+	r := &depthRequester{height: &height}
+	cli := &client{req: r, op: &clientOp{confirmations: 100}}
+	ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+	defer cancel()
+	if confirmed, err := cli.PollTx(ctx, ids.GenerateTestID()); confirmed || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected tx not to be confirmed by the deadline, got confirmed=%t err=%v", confirmed, err)
+	}
+}
+
+var _ rpc.EndpointRequester = &balanceRequester{}
+
+// balanceRequester returns the next of [balances] for each balance request
+// (repeating the last).
+type balanceRequester struct {
+	balances []uint64
+	calls    int
+}
+
+func (r *balanceRequester) SendRequest(ctx context.Context, method string, _ interface{}, reply interface{}, _ ...rpc.Option) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if method != "blobvm.balance" {
+		return errors.New("unexpected method " + method)
+	}
+	i := r.calls
+	if i >= len(r.balances) {
+		i = len(r.balances) - 1
+	}
+	r.calls++
+	reply.(*vm.BalanceReply).Balance = r.balances[i]
+	return nil
+}
+
+func TestWaitForBalance(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		balances []uint64
+		atLeast  uint64
+		bal      uint64
+		err      error
+	}{
+		{balances: []uint64{10}, atLeast: 10, bal: 10},
+		{balances: []uint64{0, 5, 20}, atLeast: 10, bal: 20},
+		{balances: []uint64{0, 5}, atLeast: 10, bal: 5, err: context.DeadlineExceeded},
+	}
+	for i, tv := range tt {
+		ctx, cancel := context.WithTimeout(context.Background(), 3500*time.Millisecond)
+		cli := &client{req: &balanceRequester{balances: tv.balances}, op: &clientOp{}}
+		bal, err := cli.WaitForBalance(ctx, common.Address{1}, tv.atLeast)
+		cancel()
+		if !errors.Is(err, tv.err) {
+			t.Fatalf("#%d: unexpected error %v, expected %v", i, err, tv.err)
+		}
+		if bal != tv.bal {
+			t.Fatalf("#%d: balance expected %d, got %d", i, tv.bal, bal)
+		}
+	}
+}
+
+var _ rpc.EndpointRequester = &domainRequester{}
+
+// domainRequester serves a genesis with [sep] and resolves every key to
+// [value] (stored with [addressing]).
+type domainRequester struct {
+	sep        []byte
+	value      []byte
+	addressing chain.Addressing
+	methods    []string
+}
+
+func (r *domainRequester) SendRequest(_ context.Context, method string, _ interface{}, reply interface{}, _ ...rpc.Option) error {
+	r.methods = append(r.methods, method)
+	switch method {
+	case "blobvm.genesis":
+		g := chain.DefaultGenesis()
+		g.ValueDomainSeparator = r.sep
+		reply.(*vm.GenesisReply).Genesis = g
+	case "blobvm.resolve":
+		ev, err := vm.EncodeValue(vm.Base64Encoding, r.value)
+		if err != nil {
+			return err
+		}
+		resp := reply.(*vm.ResolveReply)
+		resp.Exists = true
+		resp.Encoding = vm.Base64Encoding
+		resp.Value = ev
+		resp.ValueMeta = &chain.ValueMeta{Size: uint64(len(r.value)), Addressing: r.addressing}
+	default:
+		return errors.New("unexpected method " + method)
+	}
+	return nil
+}
+
+func TestResolveDomainSeparator(t *testing.T) {
+	t.Parallel()
+
+	sep := []byte("blobvm-test")
+	v := []byte("namespaced")
+	k := chain.DomainValueHash(sep, v)
+
+	req := &domainRequester{sep: sep, value: v}
+	cli := &client{req: req, op: &clientOp{}}
+	if vk, err := cli.ValueHash(context.Background(), v); err != nil || vk != k {
+		t.Fatalf("key expected %s, got %s (err=%v)", k, vk, err)
+	}
+	for i := 0; i < 2; i++ {
+		exists, rv, _, err := cli.Resolve(context.Background(), k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !exists || !reflect.DeepEqual(rv, v) {
+			t.Fatalf("value expected %q, got %q (exists=%t)", v, rv, exists)
+		}
+	}
+	// The separator is only fetched once
+	expected := []string{"blobvm.genesis", "blobvm.resolve", "blobvm.resolve"}
+	if !reflect.DeepEqual(req.methods, expected) {
+		t.Fatalf("methods expected %v, got %v", expected, req.methods)
+	}
+
+	// Values stored without the separator fail the integrity check
+	if _, _, _, err := cli.Resolve(context.Background(), chain.ValueHash(v)); !errors.Is(err, ErrIntegrityFailure) {
+		t.Fatalf("err expected %v, got %v", ErrIntegrityFailure, err)
+	}
+
+	// A configured separator is used without fetching the genesis
+	req = &domainRequester{sep: sep, value: v}
+	cli = &client{req: req, op: &clientOp{domainSep: sep, domainSepSet: true}}
+	if _, _, _, err := cli.Resolve(context.Background(), k); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(req.methods, []string{"blobvm.resolve"}) {
+		t.Fatalf("unexpected methods %v", req.methods)
+	}
+
+	// Values stored with another addressing are checked under it (without
+	// the separator)
+	req.addressing = chain.CIDv1
+	if _, _, _, err := cli.Resolve(context.Background(), chain.CIDv1.Key(nil, v)); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := cli.Resolve(context.Background(), k); !errors.Is(err, ErrIntegrityFailure) {
+		t.Fatalf("err expected %v, got %v", ErrIntegrityFailure, err)
+	}
+}
+
+func TestCachedGenesis(t *testing.T) {
+	t.Parallel()
+
+	req := &domainRequester{sep: []byte("blobvm-test")}
+	cli := &client{req: req, op: &clientOp{}}
+	for i := 0; i < 2; i++ {
+		g, err := cli.CachedGenesis(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(g.ValueDomainSeparator, req.sep) {
+			t.Fatalf("unexpected genesis %+v", g)
+		}
+	}
+	// Shared with the domain separator
+	if _, err := cli.ValueHash(context.Background(), []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(req.methods, []string{"blobvm.genesis"}) {
+		t.Fatalf("genesis should only be fetched once, got %v", req.methods)
+	}
+
+	// [Genesis] always fetches it
+	if _, err := cli.Genesis(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(req.methods) != 2 {
+		t.Fatalf("genesis should be fetched again, got %v", req.methods)
+	}
+}
+
+var _ rpc.EndpointRequester = &hasKeysRequester{}
+
+// hasKeysRequester reports the keys in [stored] as existing.
+type hasKeysRequester struct {
+	stored map[common.Hash]bool
+}
+
+func (r *hasKeysRequester) SendRequest(_ context.Context, method string, args interface{}, reply interface{}, _ ...rpc.Option) error {
+	if method != "blobvm.hasKeys" {
+		return errors.New("unexpected method " + method)
+	}
+	keys := args.(*vm.HasKeysArgs).Keys
+	resp := reply.(*vm.HasKeysReply)
+	for _, k := range keys {
+		resp.Exists = append(resp.Exists, r.stored[k])
+	}
+	return nil
+}
+
+func TestWouldStore(t *testing.T) {
+	t.Parallel()
+
+	stored := []byte("stored")
+	req := &hasKeysRequester{stored: map[common.Hash]bool{chain.ValueHash(stored): true}}
+	cli := &client{req: req, op: &clientOp{domainSepSet: true}}
+	for i, tv := range []struct {
+		value []byte
+		isNew bool
+	}{
+		{value: stored, isNew: false},
+		{value: []byte("new"), isNew: true},
+	} {
+		isNew, k, err := cli.WouldStore(context.Background(), tv.value)
+		if err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+		if isNew != tv.isNew {
+			t.Fatalf("#%d: isNew expected %t, got %t", i, tv.isNew, isNew)
+		}
+		if k != chain.ValueHash(tv.value) {
+			t.Fatalf("#%d: key expected %s, got %s", i, chain.ValueHash(tv.value), k)
+		}
+	}
+}
+
+var _ rpc.EndpointRequester = &rangeRequester{}
+
+// rangeRequester replies to "blobvm.resolveRange" with [reply].
+type rangeRequester struct {
+	reply *vm.ResolveRangeReply
+}
+
+func (r *rangeRequester) SendRequest(_ context.Context, method string, _ interface{}, reply interface{}, _ ...rpc.Option) error {
+	if method != "blobvm.resolveRange" {
+		return errors.New("unexpected method " + method)
+	}
+	*reply.(*vm.ResolveRangeReply) = *r.reply
+	return nil
+}
+
+func TestResolveRange(t *testing.T) {
+	t.Parallel()
+
+	chunks := [][]byte{[]byte("bbbb"), []byte("cc")}
+	keys := []common.Hash{chain.ValueHash(chunks[0]), chain.ValueHash(chunks[1])}
+	for i, tv := range []struct {
+		reply      *vm.ResolveRangeReply
+		start, end uint64
+
+		value []byte
+		err   error
+	}{
+		{
+			reply: &vm.ResolveRangeReply{Exists: true, Value: []byte("lo")},
+			start: 3, end: 5,
+			value: []byte("lo"),
+		},
+		{
+			reply: &vm.ResolveRangeReply{Exists: true, Keys: keys, Chunks: chunks, Offset: 4},
+			start: 5, end: 9,
+			value: []byte("bbbc"),
+		},
+		{ // end is capped at the last chunk
+			reply: &vm.ResolveRangeReply{Exists: true, Keys: keys, Chunks: chunks, Offset: 4},
+			start: 7, end: 100,
+			value: []byte("bcc"),
+		},
+		{
+			reply: &vm.ResolveRangeReply{Exists: true, Keys: keys, Chunks: [][]byte{chunks[1], chunks[0]}, Offset: 4},
+			start: 5, end: 9,
+			err: ErrIntegrityFailure,
+		},
+		{ // chunks don't cover [start]
+			reply: &vm.ResolveRangeReply{Exists: true, Keys: keys, Chunks: chunks, Offset: 6},
+			start: 5, end: 9,
+			err: ErrInvalidResponse,
+		},
+	} {
+		cli := &client{req: &rangeRequester{reply: tv.reply}, op: &clientOp{domainSepSet: true}}
+		exists, v, err := cli.ResolveRange(context.Background(), common.Hash{}, tv.start, tv.end)
+		if !errors.Is(err, tv.err) {
+			t.Fatalf("#%d: err expected %v, got %v", i, tv.err, err)
+		}
+		if tv.err != nil {
+			continue
+		}
+		if !exists || !reflect.DeepEqual(v, tv.value) {
+			t.Fatalf("#%d: value expected %q, got %q (exists=%t)", i, tv.value, v, exists)
+		}
+	}
+}
+
+func TestStreamActivity(t *testing.T) {
+	t.Parallel()
+
+	expected := []*chain.Activity{
+		{Tmstmp: 1, TxID: ids.GenerateTestID(), Typ: chain.Set, Key: "0x01"},
+		{Tmstmp: 2, TxID: ids.GenerateTestID(), Typ: chain.Transfer, Units: 10},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != vm.ActivityStreamEndpoint {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": keepalive\n\n")
+		for _, a := range expected {
+			b, err := json.Marshal(a)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			fmt.Fprintf(w, "data: %s\n\n: keepalive\n\n: keepalive\n\n", b)
+		}
+	}))
+	defer server.Close()
+
+	cli := New(server.URL, time.Second)
+	activity := []*chain.Activity{}
+	err := cli.StreamActivity(context.Background(), func(a *chain.Activity) error {
+		activity = append(activity, a)
+		return nil
+	})
+	if !errors.Is(err, ErrStreamClosed) {
+		t.Fatalf("unexpected error %v, expected %v", err, ErrStreamClosed)
+	}
+	if !reflect.DeepEqual(activity, expected) {
+		t.Fatalf("unexpected activity %+v", activity)
+	}
+
+	// Returning an error from [f] stops the stream
+	errStop := errors.New("stop")
+	calls := 0
+	if err := cli.StreamActivity(context.Background(), func(*chain.Activity) error {
+		calls++
+		return errStop
+	}); !errors.Is(err, errStop) || calls != 1 {
+		t.Fatalf("unexpected error %v after %d calls, expected %v", err, calls, errStop)
+	}
+}
+
+var _ rpc.EndpointRequester = &blockRequester{}
+
+// blockRequester replies to "blobvm.getBlock" with [reply].
+type blockRequester struct {
+	reply *vm.GetBlockReply
+}
+
+func (r *blockRequester) SendRequest(_ context.Context, method string, _ interface{}, reply interface{}, _ ...rpc.Option) error {
+	if method != "blobvm.getBlock" {
+		return errors.New("unexpected method " + method)
+	}
+	*reply.(*vm.GetBlockReply) = *r.reply
+	return nil
+}
+
+func TestGetBlock(t *testing.T) {
+	t.Parallel()
+
+	b, err := chain.Marshal(&chain.StatefulBlock{Hght: 1, Txs: []*chain.Transaction{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	blkID := ids.ID(crypto.Keccak256Hash(b))
+	otherID := ids.GenerateTestID()
+	for i, tv := range []struct {
+		reply *vm.GetBlockReply
+		id    ids.ID
+
+		exists bool
+		blk    bool
+		err    error
+	}{
+		{
+			reply: &vm.GetBlockReply{},
+			id:    blkID,
+		},
+		{
+			reply:  &vm.GetBlockReply{Exists: true, Header: &chain.BlockHeader{ID: blkID, Hght: 1}, Block: b},
+			id:     blkID,
+			exists: true, blk: true,
+		},
+		{
+			reply:  &vm.GetBlockReply{Exists: true, Header: &chain.BlockHeader{ID: blkID, Hght: 1}, TooLarge: true},
+			id:     blkID,
+			exists: true,
+		},
+		{ // the block doesn't hash to its header
+			reply: &vm.GetBlockReply{Exists: true, Header: &chain.BlockHeader{ID: otherID, Hght: 1}, Block: b},
+			id:    otherID,
+			err:   ErrIntegrityFailure,
+		},
+		{ // the node returned another block
+			reply: &vm.GetBlockReply{Exists: true, Header: &chain.BlockHeader{ID: blkID, Hght: 1}, Block: b},
+			id:    otherID,
+			err:   ErrInvalidResponse,
+		},
+	} {
+		cli := &client{req: &blockRequester{reply: tv.reply}, op: &clientOp{}}
+		exists, _, blk, err := cli.GetBlock(context.Background(), tv.id)
+		if !errors.Is(err, tv.err) {
+			t.Fatalf("#%d: unexpected error %v, expected %v", i, err, tv.err)
+		}
+		if exists != tv.exists || (blk != nil) != tv.blk {
+			t.Fatalf("#%d: unexpected block (exists=%t blk=%v)", i, exists, blk)
+		}
+	}
+}
+
+var _ rpc.EndpointRequester = &simulateRequester{}
+
+type simulateRequester struct {
+	reply *vm.SimulateTxReply
+}
+
+func (r *simulateRequester) SendRequest(_ context.Context, method string, _ interface{}, reply interface{}, _ ...rpc.Option) error {
+	if method != "blobvm.simulateTx" {
+		return errors.New("unexpected method " + method)
+	}
+	*reply.(*vm.SimulateTxReply) = *r.reply
+	return nil
+}
+
+func TestSimulateRawTx(t *testing.T) {
+	t.Parallel()
+
+	txID := ids.GenerateTestID()
+	cli := &client{req: &simulateRequester{reply: &vm.SimulateTxReply{TxID: txID, Success: true, Fee: 2, Balance: 3}}, op: &clientOp{}}
+	fee, balance, err := cli.SimulateRawTx(context.Background(), nil)
+	if err != nil || fee != 2 || balance != 3 {
+		t.Fatalf("unexpected simulation (fee=%d balance=%d): %v", fee, balance, err)
+	}
+
+	// Rejected txs are reported as errors
+	cli = &client{req: &simulateRequester{reply: &vm.SimulateTxReply{TxID: txID, Error: "insufficient balance"}}, op: &clientOp{}}
+	_, _, err = cli.SimulateRawTx(context.Background(), nil)
+	var serr *SimulationError
+	if !errors.As(err, &serr) {
+		t.Fatalf("expected a simulation error, got %v", err)
+	}
+	if serr.TxID != txID || serr.Reason != "insufficient balance" {
+		t.Fatalf("unexpected simulation error %+v", serr)
+	}
+}
+
+var _ rpc.EndpointRequester = &resolveArgsRequester{}
+
+// resolveArgsRequester records the args of "blobvm.resolve" and replies with
+// [reply].
+type resolveArgsRequester struct {
+	args  *vm.ResolveArgs
+	reply *vm.ResolveReply
+}
+
+func (r *resolveArgsRequester) SendRequest(_ context.Context, method string, args interface{}, reply interface{}, _ ...rpc.Option) error {
+	if method != "blobvm.resolve" {
+		return errors.New("unexpected method " + method)
+	}
+	r.args = args.(*vm.ResolveArgs)
+	*reply.(*vm.ResolveReply) = *r.reply
+	return nil
+}
+
+func TestResolveOptions(t *testing.T) {
+	t.Parallel()
+
+	txID := ids.GenerateTestID()
+	vmeta := &chain.ValueMeta{Size: 10, TxID: txID}
+	req := &resolveArgsRequester{reply: &vm.ResolveReply{Exists: true, TooLarge: true, ValueMeta: vmeta}}
+	cli := &client{req: req, op: &clientOp{domainSepSet: true}}
+	k := chain.ValueHash([]byte("value"))
+	r, err := cli.ResolveWithOptions(context.Background(), k, WithLastTxID(txID), WithMaxBytes(5), WithPending())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &vm.ResolveArgs{Key: k, LastTxID: txID, MaxBytes: 5, IncludePending: true}
+	if !reflect.DeepEqual(req.args, expected) {
+		t.Fatalf("expected args %+v, got %+v", expected, req.args)
+	}
+	// Omitted values aren't checked against the key
+	if !r.Exists || !r.TooLarge || r.Value != nil || r.ValueMeta != vmeta {
+		t.Fatalf("unexpected result %+v", r)
+	}
+
+	req.reply = &vm.ResolveReply{}
+	if r, err := cli.ResolveWithOptions(context.Background(), k); err != nil || r.Exists {
+		t.Fatalf("unexpected result %+v (err=%v)", r, err)
+	}
+}
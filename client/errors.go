@@ -3,6 +3,42 @@
 
 package client
 
-import "errors"
+import (
+	"errors"
+	"strings"
 
-var ErrIntegrityFailure = errors.New("received file that does not match hash")
+	"github.com/ava-labs/avalanchego/ids"
+)
+
+var (
+	ErrIntegrityFailure = errors.New("received file that does not match hash")
+	ErrWrongNetwork     = errors.New("connected to unexpected network")
+	ErrInvalidResponse  = errors.New("invalid response")
+	ErrInvalidDuration  = errors.New("duration must be positive")
+	ErrInvalidWorkers   = errors.New("concurrency must be positive")
+	ErrStreamClosed     = errors.New("activity stream closed")
+	ErrBalanceTooLow    = errors.New("balance does not exceed the fee")
+)
+
+// SimulationError is returned by [Client.SimulateRawTx] if the simulated tx
+// would be rejected. Other errors mean the simulation itself failed.
+type SimulationError struct {
+	TxID ids.ID
+	// Reason is why the tx would be rejected
+	Reason string
+}
+
+func (e *SimulationError) Error() string {
+	return "tx " + e.TxID.String() + " would be rejected: " + e.Reason
+}
+
+// IsThrottled returns true if [err] was caused by the server rejecting a
+// request because it is overloaded (HTTP 429 or 503).
+func IsThrottled(err error) bool {
+	if err == nil {
+		return false
+	}
+	s := err.Error()
+	return strings.Contains(s, "received status code: 429") ||
+		strings.Contains(s, "received status code: 503")
+}
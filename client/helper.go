@@ -8,8 +8,13 @@ import (
 	"crypto/ecdsa"
 	"encoding/json"
 	"fmt"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/common"
+	smath "github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/fatih/color"
 
@@ -31,6 +36,86 @@ func PPActivity(a []*chain.Activity) error {
 	return nil
 }
 
+// GatewayFilePath is the path a gateway serves files at ("GET
+// <GatewayFilePath><root>").
+const GatewayFilePath = "/file/"
+
+// ShareURL returns the URL of the file at [root] on the gateway at
+// [gatewayBase] (ex: "https://gateway.example.com").
+func ShareURL(root common.Hash, gatewayBase string) string {
+	return strings.TrimRight(gatewayBase, "/") + GatewayFilePath + root.Hex()
+}
+
+// ResolveWithAge resolves [key] and returns how long ago (in block time) its
+// value was set. The age is measured against the current tip, so it only
+// advances as blocks are accepted.
+func ResolveWithAge(
+	ctx context.Context, cli Client, key common.Hash,
+) (exists bool, value []byte, vmeta *chain.ValueMeta, age time.Duration, err error) {
+	exists, value, vmeta, err = cli.Resolve(ctx, key)
+	if err != nil || !exists {
+		return false, nil, nil, 0, err
+	}
+	tip, err := cli.Tip(ctx)
+	if err != nil {
+		return false, nil, nil, 0, err
+	}
+	if now := uint64(tip.Tmstmp); now > vmeta.Created {
+		age = time.Duration(now-vmeta.Created) * time.Second
+	}
+	return true, value, vmeta, age, nil
+}
+
+// ResolveResult is the reply of [Client.ResolveWithOptions].
+type ResolveResult struct {
+	Exists bool
+	// Value is nil if [NotModified] or [TooLarge] is set
+	Value     []byte
+	ValueMeta *chain.ValueMeta
+
+	// NotModified is set if the value was set by the tx passed to
+	// [WithLastTxID]
+	NotModified bool
+	// TooLarge is set if the value is bigger than the limit passed to
+	// [WithMaxBytes]
+	TooLarge bool
+	// Pending is set if the value was found in the mempool (see [WithPending])
+	Pending bool
+}
+
+type resolveOp struct {
+	lastTxID ids.ID
+	maxBytes uint64
+	pending  bool
+}
+
+// ResolveOption configures [Client.ResolveWithOptions]. Options can be combined.
+type ResolveOption func(*resolveOp)
+
+func (op *resolveOp) applyOpts(opts []ResolveOption) {
+	for _, opt := range opts {
+		opt(op)
+	}
+}
+
+// WithLastTxID omits the value (setting [ResolveResult.NotModified]) if it
+// was set by [txID], ex: because the caller already has it.
+func WithLastTxID(txID ids.ID) ResolveOption {
+	return func(op *resolveOp) { op.lastTxID = txID }
+}
+
+// WithMaxBytes omits the value (setting [ResolveResult.TooLarge]) if it is
+// bigger than [maxBytes], ex: to read only its metadata.
+func WithMaxBytes(maxBytes uint64) ResolveOption {
+	return func(op *resolveOp) { op.maxBytes = maxBytes }
+}
+
+// WithPending falls back to a pending SetTx in the mempool if the value has
+// not been accepted yet (setting [ResolveResult.Pending]).
+func WithPending() ResolveOption {
+	return func(op *resolveOp) { op.pending = true }
+}
+
 // Signs and issues the transaction (node construction).
 func SignIssueTx(
 	ctx context.Context,
@@ -79,53 +164,148 @@ func SignIssueRawTx(
 	ret := &Op{}
 	ret.applyOpts(opts)
 
-	g, err := cli.Genesis(ctx)
+	g, err := cli.CachedGenesis(ctx)
 	if err != nil {
 		return ids.Empty, 0, err
 	}
 
-	la, err := cli.Accepted(ctx)
-	if err != nil {
+	for attempt := 0; ; attempt++ {
+		tx, err := ret.signTx(ctx, cli, g, utx, priv)
+		if err != nil {
+			return ids.Empty, 0, err
+		}
+		la := tx.GetBlockID()
+
+		color.Yellow(
+			"issuing tx %s (fee units=%d, load units=%d, price=%d, blkID=%s)",
+			tx.ID(), tx.FeeUnits(g), tx.LoadUnits(g), tx.GetPrice(), tx.GetBlockID(),
+		)
+		txID, cost, _, err = cli.IssueRawTxWithFee(ctx, tx.Bytes())
+		if err == nil {
+			break
+		}
+		if !isStaleBlockID(err) {
+			return ids.Empty, 0, err
+		}
+		if ret.pin != nil {
+			ret.pin.expire(la)
+		}
+		if attempt >= ret.staleBlockRetries {
+			return ids.Empty, 0, err
+		}
+		color.Yellow("tx %s rejected with stale blkID=%s (retrying)", tx.ID(), tx.GetBlockID())
+	}
+
+	if err := handleConfirmation(ctx, ret, cli, txID, priv); err != nil {
 		return ids.Empty, 0, err
 	}
+	return txID, cost, nil
+}
 
-	price, blockCost, err := cli.SuggestedRawFee(ctx)
+// SignIssueSweepTx transfers the entire balance of [priv] to [to], leaving a
+// zero balance. The units transferred are the balance (fetched when the tx is
+// issued) minus the fee of the transfer at the price it is signed with, so the
+// sweep fails with [ErrBalanceTooLow] if the fee is at least the balance (or
+// leaves less than [chain.Genesis.MinTransfer]). Txs
+// from [priv] that are still pending when the balance is fetched aren't
+// accounted for (and will make the transfer fail).
+func SignIssueSweepTx(
+	ctx context.Context,
+	cli Client,
+	to common.Address,
+	priv *ecdsa.PrivateKey,
+	opts ...OpOption,
+) (txID ids.ID, units uint64, err error) {
+	bal, err := cli.Balance(ctx, crypto.PubkeyToAddress(priv.PublicKey))
+	if err != nil {
+		return ids.Empty, 0, err
+	}
+	utx := &chain.TransferTx{BaseTx: &chain.BaseTx{}, To: to}
+	// Don't modify the caller's options
+	opts = append(append([]OpOption{}, opts...), func(op *Op) {
+		op.beforeSign = func(g *chain.Genesis, utx chain.UnsignedTransaction) error {
+			fee, xflow := smath.SafeMul(utx.FeeUnits(g), utx.GetPrice())
+			if xflow || fee >= bal || bal-fee < g.MinTransfer {
+				return fmt.Errorf("%w: balance=%d fee=%d minTransfer=%d", ErrBalanceTooLow, bal, fee, g.MinTransfer)
+			}
+			utx.(*chain.TransferTx).Units = bal - fee
+			return nil
+		}
+	})
+	txID, _, err = SignIssueRawTx(ctx, cli, utx, priv, opts...)
 	if err != nil {
 		return ids.Empty, 0, err
 	}
+	return txID, utx.Units, nil
+}
+
+// SignSimulateRawTx signs [utx] as [SignIssueRawTx] would and simulates it
+// (see [Client.SimulateRawTx]) instead of issuing it. If [utx] would be
+// rejected, the error is a [*SimulationError].
+func SignSimulateRawTx(
+	ctx context.Context,
+	cli Client,
+	utx chain.UnsignedTransaction,
+	priv *ecdsa.PrivateKey,
+	opts ...OpOption,
+) (fee uint64, balance uint64, err error) {
+	ret := &Op{}
+	ret.applyOpts(opts)
+
+	g, err := cli.CachedGenesis(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	tx, err := ret.signTx(ctx, cli, g, utx, priv)
+	if err != nil {
+		return 0, 0, err
+	}
+	return cli.SimulateRawTx(ctx, tx.Bytes())
+}
+
+// signTx sets the magic, blockID, and price of [utx] (as suggested by [cli])
+// and signs it.
+func (op *Op) signTx(
+	ctx context.Context, cli Client, g *chain.Genesis,
+	utx chain.UnsignedTransaction, priv *ecdsa.PrivateKey,
+) (*chain.Transaction, error) {
+	magic, la, price, blockCost, err := op.prepareTx(ctx, cli)
+	if err != nil {
+		return nil, err
+	}
 
 	utx.SetBlockID(la)
-	utx.SetMagic(g.Magic)
+	utx.SetMagic(magic)
 	utx.SetPrice(price + blockCost/utx.FeeUnits(g))
+	if op.beforeSign != nil {
+		if err := op.beforeSign(g, utx); err != nil {
+			return nil, err
+		}
+	}
 
 	dh, err := chain.DigestHash(utx)
 	if err != nil {
-		return ids.Empty, 0, err
+		return nil, err
 	}
 
 	sig, err := chain.Sign(dh, priv)
 	if err != nil {
-		return ids.Empty, 0, err
+		return nil, err
 	}
 
 	tx := chain.NewTx(utx, sig)
 	if err := tx.Init(g); err != nil {
-		return ids.Empty, 0, err
-	}
-
-	color.Yellow(
-		"issuing tx %s (fee units=%d, load units=%d, price=%d, blkID=%s)",
-		tx.ID(), tx.FeeUnits(g), tx.LoadUnits(g), tx.GetPrice(), tx.GetBlockID(),
-	)
-	txID, err = cli.IssueRawTx(ctx, tx.Bytes())
-	if err != nil {
-		return ids.Empty, 0, err
+		return nil, err
 	}
+	return tx, nil
+}
 
-	if err := handleConfirmation(ctx, ret, cli, txID, priv); err != nil {
-		return ids.Empty, 0, err
-	}
-	return txID, utx.GetPrice() * utx.FeeUnits(g), nil
+// isStaleBlockID returns true if [err] indicates a tx referenced a block that
+// is no longer (or not yet) in the lookback window of the node.
+//
+// Errors are returned as strings over RPC, so [errors.Is] can't be used.
+func isStaleBlockID(err error) bool {
+	return strings.Contains(err.Error(), chain.ErrInvalidBlockID.Error())
 }
 
 func handleConfirmation(
@@ -160,6 +340,13 @@ func handleConfirmation(
 type Op struct {
 	pollTx  bool
 	balance bool
+
+	staleBlockRetries int
+	pin               *BlockPin
+
+	// [beforeSign] completes [utx] once its price is set (ex: to transfer
+	// everything but the fee)
+	beforeSign func(g *chain.Genesis, utx chain.UnsignedTransaction) error
 }
 
 type OpOption func(*Op)
@@ -170,6 +357,13 @@ func (op *Op) applyOpts(opts []OpOption) {
 	}
 }
 
+func (op *Op) prepareTx(ctx context.Context, cli Client) (uint64, ids.ID, uint64, uint64, error) {
+	if op.pin != nil {
+		return op.pin.prepare(ctx, cli)
+	}
+	return cli.PrepareTx(ctx)
+}
+
 // "true" to poll transaction for its confirmation.
 func WithPollTx() OpOption {
 	return func(op *Op) { op.pollTx = true }
@@ -178,3 +372,74 @@ func WithPollTx() OpOption {
 func WithBalance() OpOption {
 	return func(op *Op) { op.balance = true }
 }
+
+// WithStaleBlockRetries re-signs and re-issues a transaction (with the latest
+// accepted blockID) up to [retries] times if it is rejected for referencing a
+// stale blockID. Only applies to [SignIssueRawTx].
+func WithStaleBlockRetries(retries int) OpOption {
+	return func(op *Op) { op.staleBlockRetries = retries }
+}
+
+// WithBlockPin references the blockID (and uses the fee) pinned by [pin]
+// instead of fetching them for each transaction. Only applies to
+// [SignIssueRawTx].
+func WithBlockPin(pin *BlockPin) OpOption {
+	return func(op *Op) { op.pin = pin }
+}
+
+// DefaultBlockPinMaxAge is well within the default lookback window of
+// [chain.Genesis], so a pinned blockID is refreshed before txs referencing it
+// are rejected.
+const DefaultBlockPinMaxAge = 10 * time.Second
+
+// BlockPin shares the result of [Client.PrepareTx] (the magic, a recently
+// accepted blockID, and the fee) across the transactions issued with
+// [WithBlockPin], so a sequence of transactions (ex: an upload) doesn't fetch
+// it for every transaction. It is refreshed once it is older than [maxAge] or a
+// transaction is rejected for referencing the pinned blockID, and is safe for
+// concurrent use.
+type BlockPin struct {
+	maxAge time.Duration
+
+	l         sync.Mutex
+	fetched   time.Time
+	magic     uint64
+	blkID     ids.ID
+	price     uint64
+	blockCost uint64
+}
+
+func NewBlockPin(maxAge time.Duration) *BlockPin {
+	return &BlockPin{maxAge: maxAge}
+}
+
+// Refresh forces the next transaction to fetch a new blockID.
+func (p *BlockPin) Refresh() {
+	p.l.Lock()
+	defer p.l.Unlock()
+	p.fetched = time.Time{}
+}
+
+func (p *BlockPin) prepare(ctx context.Context, cli Client) (uint64, ids.ID, uint64, uint64, error) {
+	p.l.Lock()
+	defer p.l.Unlock()
+	if p.fetched.IsZero() || time.Since(p.fetched) >= p.maxAge {
+		magic, blkID, price, blockCost, err := cli.PrepareTx(ctx)
+		if err != nil {
+			return 0, ids.Empty, 0, 0, err
+		}
+		p.fetched = time.Now()
+		p.magic, p.blkID, p.price, p.blockCost = magic, blkID, price, blockCost
+	}
+	return p.magic, p.blkID, p.price, p.blockCost, nil
+}
+
+// expire refreshes the pin if [blkID] is still pinned (it may already have been
+// refreshed by another transaction).
+func (p *BlockPin) expire(blkID ids.ID) {
+	p.l.Lock()
+	defer p.l.Unlock()
+	if p.blkID == blkID {
+		p.fetched = time.Time{}
+	}
+}
@@ -0,0 +1,280 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/ava-labs/blobvm/chain"
+)
+
+var _ Client = &staleClient{}
+
+// staleClient rejects any tx that doesn't reference the most recent block
+// returned by [PrepareTx].
+type staleClient struct {
+	Client
+
+	g        *chain.Genesis
+	accepted []ids.ID
+	calls    int
+	prepares int
+	issued   []ids.ID // blockIDs of issued txs
+	txs      []*chain.Transaction
+	balance  uint64
+}
+
+func (c *staleClient) Genesis(context.Context) (*chain.Genesis, error) { return c.g, nil }
+
+func (c *staleClient) CachedGenesis(context.Context) (*chain.Genesis, error) { return c.g, nil }
+
+func (c *staleClient) Balance(context.Context, common.Address) (uint64, error) {
+	return c.balance, nil
+}
+
+func (c *staleClient) PrepareTx(context.Context) (uint64, ids.ID, uint64, uint64, error) {
+	c.prepares++
+	blkID := c.accepted[c.calls]
+	if c.calls < len(c.accepted)-1 {
+		c.calls++
+	}
+	return c.g.Magic, blkID, 1, 0, nil
+}
+
+func (c *staleClient) IssueRawTxWithFee(_ context.Context, d []byte) (ids.ID, uint64, uint64, error) {
+	tx := new(chain.Transaction)
+	if _, err := chain.Unmarshal(d, tx); err != nil {
+		return ids.Empty, 0, 0, err
+	}
+	if err := tx.Init(c.g); err != nil {
+		return ids.Empty, 0, 0, err
+	}
+	c.issued = append(c.issued, tx.GetBlockID())
+	c.txs = append(c.txs, tx)
+	if tx.GetBlockID() != c.accepted[len(c.accepted)-1] {
+		// Mimic error string returned over RPC
+		return ids.Empty, 0, 0, fmt.Errorf("problem issuing tx: %s", chain.ErrInvalidBlockID.Error())
+	}
+	return tx.ID(), tx.FeeUnits(c.g) * tx.GetPrice(), 0, nil
+}
+
+func TestSignIssueRawTxStaleBlockRetries(t *testing.T) {
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := chain.DefaultGenesis()
+	g.Magic = 1
+	stale, fresh := ids.GenerateTestID(), ids.GenerateTestID()
+
+	// Fails without retries
+	cli := &staleClient{g: g, accepted: []ids.ID{stale, fresh}}
+	utx := &chain.SetTx{BaseTx: &chain.BaseTx{}, Value: []byte("value")}
+	if _, _, err := SignIssueRawTx(context.Background(), cli, utx, priv); err == nil {
+		t.Fatal("expected stale blockID error")
+	}
+
+	// Re-signs with fresh blockID when retries are enabled
+	cli = &staleClient{g: g, accepted: []ids.ID{stale, fresh}}
+	utx = &chain.SetTx{BaseTx: &chain.BaseTx{}, Value: []byte("value")}
+	txID, _, err := SignIssueRawTx(context.Background(), cli, utx, priv, WithStaleBlockRetries(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txID == ids.Empty {
+		t.Fatal("unexpected empty txID")
+	}
+	if len(cli.issued) != 2 || cli.issued[0] != stale || cli.issued[1] != fresh {
+		t.Fatalf("issued blockIDs expected [%s %s], got %v", stale, fresh, cli.issued)
+	}
+
+	// Other errors are not retried
+	bcli := &badClient{staleClient: &staleClient{g: g, accepted: []ids.ID{fresh}}}
+	utx = &chain.SetTx{BaseTx: &chain.BaseTx{}, Value: []byte("value")}
+	if _, _, err := SignIssueRawTx(context.Background(), bcli, utx, priv, WithStaleBlockRetries(3)); !errors.Is(err, errBad) {
+		t.Fatalf("unexpected error %v, expected %v", err, errBad)
+	}
+	if bcli.issues != 1 {
+		t.Fatalf("issues expected 1, got %d", bcli.issues)
+	}
+}
+
+func TestSignIssueRawTxBlockPin(t *testing.T) {
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := chain.DefaultGenesis()
+	g.Magic = 1
+	first, second := ids.GenerateTestID(), ids.GenerateTestID()
+
+	cli := &staleClient{g: g, accepted: []ids.ID{first}}
+	pin := NewBlockPin(time.Minute)
+	issue := func(v string) {
+		utx := &chain.SetTx{BaseTx: &chain.BaseTx{}, Value: []byte(v)}
+		if _, _, err := SignIssueRawTx(
+			context.Background(), cli, utx, priv, WithBlockPin(pin), WithStaleBlockRetries(1),
+		); err != nil {
+			t.Fatal(err)
+		}
+	}
+	check := func(prepares int, issued ...ids.ID) {
+		t.Helper()
+		if cli.prepares != prepares {
+			t.Fatalf("prepares expected %d, got %d", prepares, cli.prepares)
+		}
+		if len(cli.issued) != len(issued) {
+			t.Fatalf("issued blockIDs expected %v, got %v", issued, cli.issued)
+		}
+		for i, blkID := range issued {
+			if cli.issued[i] != blkID {
+				t.Fatalf("issued blockIDs expected %v, got %v", issued, cli.issued)
+			}
+		}
+	}
+
+	// Reuses the pinned blockID
+	for _, v := range []string{"a", "b", "c"} {
+		issue(v)
+	}
+	check(1, first, first, first)
+
+	// Refreshes once the pinned blockID is stale
+	cli.accepted = []ids.ID{second}
+	issue("d")
+	check(2, first, first, first, first, second)
+	issue("e")
+	check(2, first, first, first, first, second, second)
+
+	// Refreshes when asked
+	pin.Refresh()
+	issue("f")
+	check(3, first, first, first, first, second, second, second)
+}
+
+func TestSignIssueSweepTx(t *testing.T) {
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := chain.DefaultGenesis()
+	g.Magic = 1
+	stale, fresh := ids.GenerateTestID(), ids.GenerateTestID()
+	to := common.Address{1}
+
+	// The fee is deducted from the balance (at the price of the re-signed tx)
+	fee := g.BaseTxUnits // price of 1
+	cli := &staleClient{g: g, accepted: []ids.ID{stale, fresh}, balance: 1000}
+	_, units, err := SignIssueSweepTx(context.Background(), cli, to, priv, WithStaleBlockRetries(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if units != 1000-fee {
+		t.Fatalf("units expected %d, got %d", 1000-fee, units)
+	}
+	tx := cli.txs[len(cli.txs)-1]
+	utx, ok := tx.UnsignedTransaction.(*chain.TransferTx)
+	if !ok || utx.To != to || utx.Units != units {
+		t.Fatalf("unexpected tx %+v", tx.UnsignedTransaction)
+	}
+	if spent := utx.Units + tx.FeeUnits(g)*tx.GetPrice(); spent != 1000 {
+		t.Fatalf("expected to spend the entire balance, spent %d", spent)
+	}
+
+	// Nothing (or less than the minimum transfer) is left once the fee is
+	// paid
+	g.MinTransfer = 10
+	for _, bal := range []uint64{0, fee, fee + 9} {
+		cli = &staleClient{g: g, accepted: []ids.ID{fresh}, balance: bal}
+		if _, _, err := SignIssueSweepTx(context.Background(), cli, to, priv); !errors.Is(err, ErrBalanceTooLow) {
+			t.Fatalf("balance %d: expected %v, got %v", bal, ErrBalanceTooLow, err)
+		}
+		if len(cli.issued) != 0 {
+			t.Fatalf("balance %d: expected no tx to be issued, got %d", bal, len(cli.issued))
+		}
+	}
+}
+
+var errBad = errors.New("bad")
+
+type badClient struct {
+	*staleClient
+	issues int
+}
+
+func (c *badClient) IssueRawTxWithFee(context.Context, []byte) (ids.ID, uint64, uint64, error) {
+	c.issues++
+	return ids.Empty, 0, 0, errBad
+}
+
+func TestShareURL(t *testing.T) {
+	t.Parallel()
+
+	root := common.HexToHash("0x6fe5a52f52b34fb1e07ba90bad47811c645176d0d49ef0c7a7b4b22013f676c8")
+	exp := "https://gateway.example.com/file/0x6fe5a52f52b34fb1e07ba90bad47811c645176d0d49ef0c7a7b4b22013f676c8"
+	for i, base := range []string{"https://gateway.example.com", "https://gateway.example.com/"} {
+		if u := ShareURL(root, base); u != exp {
+			t.Fatalf("#%d: unexpected URL %q, expected %q", i, u, exp)
+		}
+	}
+}
+
+// ageClient serves a single value created at [created] and a tip at [now].
+type ageClient struct {
+	Client
+
+	key     common.Hash
+	created uint64
+	now     int64
+}
+
+func (c *ageClient) Resolve(_ context.Context, k common.Hash) (bool, []byte, *chain.ValueMeta, error) {
+	if k != c.key {
+		return false, nil, nil, nil
+	}
+	return true, []byte("value"), &chain.ValueMeta{Created: c.created}, nil
+}
+
+func (c *ageClient) Tip(context.Context) (*chain.BlockHeader, error) {
+	return &chain.BlockHeader{Tmstmp: c.now}, nil
+}
+
+func TestResolveWithAge(t *testing.T) {
+	t.Parallel()
+
+	cli := &ageClient{key: common.Hash{1}, created: 1000}
+	for i, tv := range []struct {
+		now int64
+		age time.Duration
+	}{
+		{now: 1000, age: 0},
+		{now: 1060, age: time.Minute},
+		{now: 1000 + 3*24*60*60, age: 3 * 24 * time.Hour},
+	} {
+		cli.now = tv.now
+		exists, v, vmeta, age, err := ResolveWithAge(context.Background(), cli, cli.key)
+		if err != nil {
+			t.Fatalf("#%d: unexpected error %v", i, err)
+		}
+		if !exists || string(v) != "value" || vmeta.Created != cli.created {
+			t.Fatalf("#%d: unexpected value %q (exists=%t)", i, v, exists)
+		}
+		if age != tv.age {
+			t.Fatalf("#%d: unexpected age %s, expected %s", i, age, tv.age)
+		}
+	}
+
+	exists, _, _, age, err := ResolveWithAge(context.Background(), cli, common.Hash{2})
+	if err != nil || exists || age != 0 {
+		t.Fatalf("unexpected result for missing key (exists=%t age=%s err=%v)", exists, age, err)
+	}
+}
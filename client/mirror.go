@@ -0,0 +1,114 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package client
+
+import (
+	"context"
+	"crypto/ecdsa"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/fatih/color"
+
+	"github.com/ava-labs/blobvm/chain"
+)
+
+// MirrorStats summarizes a call to [Mirror].
+type MirrorStats struct {
+	Copied  int    `json:"copied"`
+	Skipped int    `json:"skipped"`
+	Cost    uint64 `json:"cost"`
+
+	// Next is the key to resume mirroring from (nil once all keys of the source
+	// have been visited).
+	Next *common.Hash `json:"next"`
+}
+
+// Mirror sets every key on [src] (starting at [start]) that is missing on
+// [dst], paying fees with [priv]. Values that expire on [src] are set with
+// their remaining TTL.
+//
+// If an error is returned, [MirrorStats.Next] can be passed as [start] to
+// resume.
+func Mirror(
+	ctx context.Context, src Client, dst Client, priv *ecdsa.PrivateKey,
+	start common.Hash, opts ...OpOption,
+) (*MirrorStats, error) {
+	stats := &MirrorStats{Next: &start}
+	for stats.Next != nil {
+		keys, next, err := src.ListKeys(ctx, *stats.Next, 0)
+		if err != nil {
+			return stats, err
+		}
+		tip, err := src.Tip(ctx)
+		if err != nil {
+			return stats, err
+		}
+		for i, k := range keys {
+			stats.Next = &keys[i]
+			copied, cost, err := mirrorKey(ctx, src, dst, priv, k, uint64(tip.Tmstmp), opts)
+			if err != nil {
+				return stats, err
+			}
+			if !copied {
+				stats.Skipped++
+				continue
+			}
+			stats.Copied++
+			stats.Cost += cost
+		}
+		stats.Next = next
+	}
+	return stats, nil
+}
+
+func mirrorKey(
+	ctx context.Context, src Client, dst Client, priv *ecdsa.PrivateKey,
+	k common.Hash, now uint64, opts []OpOption,
+) (bool, uint64, error) {
+	exists, _, _, err := dst.Resolve(ctx, k)
+	if err != nil {
+		return false, 0, err
+	}
+	if exists {
+		return false, 0, nil
+	}
+	exists, v, vmeta, err := src.Resolve(ctx, k)
+	if err != nil {
+		return false, 0, err
+	}
+	if !exists || vmeta.Expired(now) {
+		// Expired after it was listed
+		return false, 0, nil
+	}
+	// [v] is stored at a different key on [dst] if the chains use different
+	// value domain separators
+	dk, err := dst.ValueHash(ctx, v)
+	if err != nil {
+		return false, 0, err
+	}
+	if dk != k {
+		exists, _, _, err := dst.Resolve(ctx, dk)
+		if err != nil {
+			return false, 0, err
+		}
+		if exists {
+			return false, 0, nil
+		}
+	}
+
+	utx := &chain.SetTx{
+		BaseTx: &chain.BaseTx{},
+		Value:  v,
+		Tags:   vmeta.Tags,
+	}
+	if vmeta.Expiry > 0 {
+		utx.TTL = vmeta.Expiry - now
+	}
+	_, cost, err := SignIssueRawTx(ctx, dst, utx, priv, opts...)
+	if err != nil {
+		return false, 0, err
+	}
+	color.Yellow("mirrored k=%s cost=%d", k, cost)
+	return true, cost, nil
+}
@@ -0,0 +1,104 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/ava-labs/blobvm/client"
+)
+
+var adminToken string
+
+func init() {
+	adminCmd.AddCommand(
+		banCmd,
+		unbanCmd,
+		bannedCmd,
+	)
+	for _, cmd := range []*cobra.Command{adminCmd, repairCmd} {
+		cmd.PersistentFlags().StringVar(
+			&adminToken,
+			"admin-token",
+			"",
+			"adminToken of the VM config",
+		)
+	}
+}
+
+var adminCmd = &cobra.Command{
+	Use:   "admin",
+	Short: "Manages node-local state (requires the node's admin token)",
+}
+
+var banCmd = &cobra.Command{
+	Use:   "ban [options] <address>",
+	Short: "Rejects txs from an address at mempool admission",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setBannedFunc(args, true)
+	},
+}
+
+var unbanCmd = &cobra.Command{
+	Use:   "unban [options] <address>",
+	Short: "Accepts txs from a previously banned address",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setBannedFunc(args, false)
+	},
+}
+
+var bannedCmd = &cobra.Command{
+	Use:   "banned [options]",
+	Short: "Lists banned addresses",
+	RunE:  bannedFunc,
+}
+
+func setBannedFunc(args []string, banned bool) error {
+	addr, err := getAddressOp(args)
+	if err != nil {
+		return err
+	}
+
+	cli := client.NewAdmin(uri, adminToken, requestTimeout)
+	if banned {
+		if err := cli.Ban(context.Background(), addr); err != nil {
+			return err
+		}
+		color.Green("banned %s", addr)
+		return nil
+	}
+	if err := cli.Unban(context.Background(), addr); err != nil {
+		return err
+	}
+	color.Green("unbanned %s", addr)
+	return nil
+}
+
+func bannedFunc(cmd *cobra.Command, args []string) error {
+	cli := client.NewAdmin(uri, adminToken, requestTimeout)
+	addrs, err := cli.Banned(context.Background())
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		color.Yellow("%s", addr)
+	}
+	color.Green("%d banned addresses", len(addrs))
+	return nil
+}
+
+func getAddressOp(args []string) (common.Address, error) {
+	if len(args) != 1 {
+		return common.Address{}, fmt.Errorf("expected exactly 1 argument, got %d", len(args))
+	}
+	if !common.IsHexAddress(args[0]) {
+		return common.Address{}, fmt.Errorf("invalid address %q", args[0])
+	}
+	return common.HexToAddress(args[0]), nil
+}
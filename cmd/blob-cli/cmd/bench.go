@@ -0,0 +1,62 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package cmd
+
+import (
+	"context"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/ava-labs/blobvm/client"
+)
+
+var (
+	benchDuration    time.Duration
+	benchConcurrency int
+)
+
+func init() {
+	benchCmd.PersistentFlags().DurationVar(
+		&benchDuration,
+		"duration",
+		30*time.Second,
+		"time to issue txs for",
+	)
+	benchCmd.PersistentFlags().IntVar(
+		&benchConcurrency,
+		"concurrency",
+		4,
+		"number of txs to issue concurrently",
+	)
+}
+
+var benchCmd = &cobra.Command{
+	Use:   "bench [options]",
+	Short: "Measures write throughput by issuing random values",
+	RunE:  benchFunc,
+}
+
+func benchFunc(cmd *cobra.Command, args []string) error {
+	priv, err := loadPrivateKey()
+	if err != nil {
+		return err
+	}
+
+	cli := client.New(uri, requestTimeout)
+	stats, err := client.Bench(
+		context.Background(), cli, priv, benchDuration, benchConcurrency,
+		client.WithPollTx(), client.WithStaleBlockRetries(3),
+	)
+	if err != nil {
+		return err
+	}
+	color.Green(
+		"issued %d txs in %s (tps=%.2f avg latency=%s error rate=%.2f%% cost=%d)",
+		stats.Issued, stats.Elapsed.Round(time.Millisecond), stats.TPS(),
+		stats.AvgLatency().Round(time.Millisecond), 100*stats.ErrorRate(), stats.Cost,
+	)
+	return nil
+}
@@ -4,14 +4,27 @@
 package cmd
 
 import (
+	"encoding/json"
 	"errors"
 	"os"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 )
 
+var encrypt bool
+
+func init() {
+	createCmd.PersistentFlags().BoolVar(
+		&encrypt,
+		"encrypt",
+		true,
+		"encrypt the key with a passphrase (read from $"+passphraseEnv+" or prompted)",
+	)
+}
+
 var createCmd = &cobra.Command{
 	Use:   "create [options]",
 	Short: "Creates a new key in the default location",
@@ -19,6 +32,9 @@ var createCmd = &cobra.Command{
 Creates a new key in the default location.
 It will error if the key file already exists.
 
+The key is encrypted with a passphrase (read from $` + passphraseEnv + ` or
+prompted). Pass --encrypt=false to save it in plaintext instead.
+
 $ blob-cli create
 
 `,
@@ -28,25 +44,51 @@ $ blob-cli create
 func createFunc(cmd *cobra.Command, args []string) error {
 	if _, err := os.Stat(privateKeyFile); err == nil {
 		// Already found, remind the user they have it
-		priv, err := crypto.LoadECDSA(privateKeyFile)
+		addr, err := keyAddress(privateKeyFile)
 		if err != nil {
 			return err
 		}
-		color.Green("ABORTING!!! key for %s already exists at %s", crypto.PubkeyToAddress(priv.PublicKey), privateKeyFile)
+		color.Green("ABORTING!!! key for %s already exists at %s", addr, privateKeyFile)
 		return os.ErrExist
 	} else if !errors.Is(err, os.ErrNotExist) {
 		return err
 	}
 
 	// Generate new key and save to disk
-	// TODO: encrypt key
 	priv, err := crypto.GenerateKey()
 	if err != nil {
 		return err
 	}
-	if err := crypto.SaveECDSA(privateKeyFile, priv); err != nil {
+	if encrypt {
+		err = saveEncryptedKey(privateKeyFile, priv)
+	} else {
+		err = crypto.SaveECDSA(privateKeyFile, priv)
+	}
+	if err != nil {
 		return err
 	}
 	color.Green("created address %s and saved to %s", crypto.PubkeyToAddress(priv.PublicKey), privateKeyFile)
 	return nil
 }
+
+// keyAddress returns the address of the key at [path] (without decrypting it).
+func keyAddress(path string) (common.Address, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if isEncryptedKey(b) {
+		var k struct {
+			Address string `json:"address"`
+		}
+		if err := json.Unmarshal(b, &k); err != nil {
+			return common.Address{}, err
+		}
+		return common.HexToAddress(k.Address), nil
+	}
+	priv, err := crypto.LoadECDSA(path)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(priv.PublicKey), nil
+}
@@ -0,0 +1,59 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var encryptKeyCmd = &cobra.Command{
+	Use:   "encrypt-key [options] [plaintext key file]",
+	Short: "Encrypts a plaintext key and saves it in the default location",
+	Long: `
+Encrypts a plaintext key (defaults to the private key file itself) with a
+passphrase and saves it to the private key file.
+
+$ blob-cli encrypt-key
+
+`,
+	RunE: encryptKeyFunc,
+}
+
+func encryptKeyFunc(cmd *cobra.Command, args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("expected at most 1 argument, got %d", len(args))
+	}
+	src := privateKeyFile
+	if len(args) == 1 {
+		src = args[0]
+	}
+
+	b, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if isEncryptedKey(b) {
+		return fmt.Errorf("%w: %s", ErrKeyEncrypted, src)
+	}
+	if src != privateKeyFile {
+		// Don't replace a different key when importing
+		if _, err := os.Stat(privateKeyFile); err == nil {
+			return fmt.Errorf("%w: %s", os.ErrExist, privateKeyFile)
+		}
+	}
+	priv, err := crypto.LoadECDSA(src)
+	if err != nil {
+		return err
+	}
+	if err := saveEncryptedKey(privateKeyFile, priv); err != nil {
+		return err
+	}
+	color.Green("encrypted key for %s and saved to %s", crypto.PubkeyToAddress(priv.PublicKey), privateKeyFile)
+	return nil
+}
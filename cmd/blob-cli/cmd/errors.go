@@ -0,0 +1,29 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+var (
+	ErrKeyFileMissing      = errors.New("no private key found")
+	ErrEndpointMissing     = errors.New("no endpoint set")
+	ErrEndpointUnreachable = errors.New("could not reach endpoint")
+)
+
+// friendlyError adds actionable guidance to errors caused by common
+// misconfigurations (currently an unset or unreachable endpoint).
+func friendlyError(err error) error {
+	var uerr *url.Error
+	if !errors.As(err, &uerr) {
+		return err
+	}
+	if len(uri) == 0 {
+		return fmt.Errorf("%w (pass the VM's RPC endpoint with --endpoint): %v", ErrEndpointMissing, err)
+	}
+	return fmt.Errorf("%w %q (check --endpoint and that the node is running): %v", ErrEndpointUnreachable, uri, err)
+}
@@ -0,0 +1,70 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ava-labs/blobvm/client"
+)
+
+func TestLoadPrivateKeyMissing(t *testing.T) {
+	orig := privateKeyFile
+	privateKeyFile = filepath.Join(t.TempDir(), ".blob-cli-pk")
+	defer func() { privateKeyFile = orig }()
+
+	_, err := loadPrivateKey()
+	if !errors.Is(err, ErrKeyFileMissing) {
+		t.Fatalf("expected %v, got %v", ErrKeyFileMissing, err)
+	}
+	for _, s := range []string{privateKeyFile, "blob-cli create"} {
+		if !strings.Contains(err.Error(), s) {
+			t.Fatalf("error %q should mention %q", err, s)
+		}
+	}
+}
+
+func TestFriendlyErrorEndpoint(t *testing.T) {
+	// Closing the server leaves an address nothing is listening on
+	srv := httptest.NewServer(nil)
+	unreachable := srv.URL
+	srv.Close()
+
+	orig := uri
+	defer func() { uri = orig }()
+
+	tt := []struct {
+		uri      string
+		err      error
+		contains string
+	}{
+		{uri: unreachable, err: ErrEndpointUnreachable, contains: "check --endpoint"},
+		{uri: "", err: ErrEndpointMissing, contains: "--endpoint"},
+	}
+	for i, tv := range tt {
+		uri = tv.uri
+		_, err := client.New(uri, requestTimeout).Ping(context.Background())
+		if err == nil {
+			t.Fatalf("#%d: ping should fail", i)
+		}
+		err = friendlyError(err)
+		if !errors.Is(err, tv.err) {
+			t.Fatalf("#%d: expected %v, got %v", i, tv.err, err)
+		}
+		if !strings.Contains(err.Error(), tv.contains) || !strings.Contains(err.Error(), tv.uri) {
+			t.Fatalf("#%d: error %q should mention %q and %q", i, err, tv.contains, tv.uri)
+		}
+	}
+
+	// Other errors are returned as-is
+	other := errors.New("other")
+	if err := friendlyError(other); err != other {
+		t.Fatalf("expected %v, got %v", other, err)
+	}
+}
@@ -0,0 +1,44 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+var ErrKeyFileOverwrite = errors.New("refusing to overwrite private key file")
+
+// checkNotKeyFile returns an error if writing to [path] would overwrite the
+// configured private key file (including through a different relative path or
+// link).
+func checkNotKeyFile(path string) error {
+	keyPath, err := filepath.Abs(privateKeyFile)
+	if err != nil {
+		return err
+	}
+	outPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if keyPath == outPath {
+		return fmt.Errorf("%w: %s", ErrKeyFileOverwrite, path)
+	}
+
+	keyInfo, err := os.Stat(keyPath)
+	if err != nil {
+		// Nothing to clobber if the key file doesn't exist
+		return nil
+	}
+	outInfo, err := os.Stat(outPath)
+	if err != nil {
+		return nil
+	}
+	if os.SameFile(keyInfo, outInfo) {
+		return fmt.Errorf("%w: %s", ErrKeyFileOverwrite, path)
+	}
+	return nil
+}
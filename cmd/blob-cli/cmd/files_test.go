@@ -0,0 +1,54 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckNotKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, ".blob-cli-pk")
+	if err := os.WriteFile(keyFile, []byte("key"), fsModeWrite); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(keyFile, link); err != nil {
+		t.Fatal(err)
+	}
+
+	orig := privateKeyFile
+	privateKeyFile = keyFile
+	defer func() { privateKeyFile = orig }()
+
+	tt := []struct {
+		path string
+		err  error
+	}{
+		{path: keyFile, err: ErrKeyFileOverwrite},
+		{path: filepath.Join(dir, "sub", "..", ".blob-cli-pk"), err: ErrKeyFileOverwrite},
+		{path: link, err: ErrKeyFileOverwrite},
+		{path: filepath.Join(dir, "out.gif")},
+	}
+	for i, tv := range tt {
+		if err := checkNotKeyFile(tv.path); !errors.Is(err, tv.err) {
+			t.Fatalf("#%d: expected %v, got %v", i, tv.err, err)
+		}
+	}
+
+	// resolve-file refuses before touching the network or the key file
+	if err := resolveFileFunc(resolveFileCmd, []string{"0x01", keyFile}); !errors.Is(err, ErrKeyFileOverwrite) {
+		t.Fatalf("expected %v, got %v", ErrKeyFileOverwrite, err)
+	}
+	b, err := os.ReadFile(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "key" {
+		t.Fatalf("key file was modified: %q", b)
+	}
+}
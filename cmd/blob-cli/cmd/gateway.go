@@ -0,0 +1,71 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package cmd
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ava-labs/avalanchego/utils/units"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/ava-labs/blobvm/client"
+	"github.com/ava-labs/blobvm/tree"
+)
+
+var (
+	gatewayAddr      string
+	gatewayAuthToken string
+	gatewayMaxSize   int64
+)
+
+func init() {
+	gatewayCmd.PersistentFlags().StringVar(
+		&gatewayAddr,
+		"listen",
+		"127.0.0.1:8080",
+		"address to serve file uploads and downloads on",
+	)
+	gatewayCmd.PersistentFlags().StringVar(
+		&gatewayAuthToken,
+		"auth-token",
+		"",
+		"bearer token required to upload files",
+	)
+	gatewayCmd.PersistentFlags().Int64Var(
+		&gatewayMaxSize,
+		"max-size",
+		10*units.MiB,
+		"maximum size of an uploaded file (in bytes)",
+	)
+}
+
+var gatewayCmd = &cobra.Command{
+	Use:   "gateway [options]",
+	Short: "Serves file uploads (POST /file) and downloads (GET /file/<root>) over HTTP",
+	RunE:  gatewayFunc,
+}
+
+func gatewayFunc(cmd *cobra.Command, args []string) error {
+	priv, err := loadPrivateKey()
+	if err != nil {
+		return err
+	}
+
+	cli := client.New(uri, requestTimeout)
+	h, err := tree.NewHandler(context.Background(), cli, priv, gatewayAuthToken, gatewayMaxSize)
+	if err != nil {
+		return err
+	}
+
+	rh := tree.NewResolveHandler(cli)
+
+	mux := http.NewServeMux()
+	mux.Handle("/file", h)
+	mux.Handle("/file/", rh)
+	mux.Handle("/value/", rh)
+	color.Green("serving file uploads on http://%s/file", gatewayAddr)
+	return http.ListenAndServe(gatewayAddr, mux)
+}
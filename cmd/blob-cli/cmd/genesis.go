@@ -24,6 +24,14 @@ var (
 
 	airdropHash  string
 	airdropUnits uint64
+
+	defaultValueTTL uint64
+
+	maxSenderTxsPerBlock uint64
+
+	minTransfer uint64
+
+	allowedAddressing []string
 )
 
 func init() {
@@ -51,6 +59,30 @@ func init() {
 		0,
 		"units to allocate to each airdrop address",
 	)
+	genesisCmd.PersistentFlags().Uint64Var(
+		&defaultValueTTL,
+		"default-value-ttl",
+		0,
+		"seconds values are stored for by default (0 is forever)",
+	)
+	genesisCmd.PersistentFlags().Uint64Var(
+		&maxSenderTxsPerBlock,
+		"max-sender-txs-per-block",
+		0,
+		"max txs from a single sender in each built block (0 is unbounded)",
+	)
+	genesisCmd.PersistentFlags().Uint64Var(
+		&minTransfer,
+		"min-transfer",
+		0,
+		"fewest units a transfer can send (0 allows any amount)",
+	)
+	genesisCmd.PersistentFlags().StringSliceVar(
+		&allowedAddressing,
+		"allowed-addressing",
+		nil,
+		"addressing schemes (besides value hashes) values can be set with (ex: cidv1)",
+	)
 }
 
 var genesisCmd = &cobra.Command{
@@ -78,6 +110,12 @@ var genesisCmd = &cobra.Command{
 func genesisFunc(cmd *cobra.Command, args []string) error {
 	genesis := chain.DefaultGenesis()
 	genesis.Magic = magic
+	genesis.DefaultValueTTL = defaultValueTTL
+	genesis.MaxSenderTxsPerBlock = maxSenderTxsPerBlock
+	genesis.MinTransfer = minTransfer
+	for _, a := range allowedAddressing {
+		genesis.AllowedAddressing = append(genesis.AllowedAddressing, chain.Addressing(a))
+	}
 	if minPrice >= 0 {
 		genesis.MinPrice = uint64(minPrice)
 	}
@@ -103,6 +141,9 @@ func genesisFunc(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	if err := checkNotKeyFile(genesisFile); err != nil {
+		return err
+	}
 	if err := os.WriteFile(genesisFile, b, fsModeWrite); err != nil {
 		return err
 	}
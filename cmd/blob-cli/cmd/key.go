@@ -0,0 +1,136 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var exportOut string
+
+func init() {
+	keyCmd.AddCommand(
+		keyExportCmd,
+		keyImportCmd,
+		keyAddressCmd,
+	)
+
+	keyExportCmd.PersistentFlags().StringVar(
+		&exportOut,
+		"out",
+		"",
+		"keystore file to write the exported key to",
+	)
+}
+
+var keyCmd = &cobra.Command{
+	Use:   "key",
+	Short: "Exports, imports, and inspects the private key",
+}
+
+var keyExportCmd = &cobra.Command{
+	Use:   "export [options]",
+	Short: "Exports the private key to a passphrase-encrypted keystore file",
+	Long: `
+Exports the private key (decrypting it first if it is encrypted) to a keystore
+file encrypted with a new passphrase. It will error if the file already exists.
+
+$ blob-cli key export --out wallet.json
+
+`,
+	RunE: keyExportFunc,
+}
+
+var keyImportCmd = &cobra.Command{
+	Use:   "import [options] <keystore file>",
+	Short: "Imports a passphrase-encrypted keystore file as the private key",
+	Long: `
+Checks the passphrase of a keystore file and saves it (still encrypted) to the
+private key file. It will error if the private key file already exists.
+
+$ blob-cli key import wallet.json
+
+`,
+	RunE: keyImportFunc,
+}
+
+var keyAddressCmd = &cobra.Command{
+	Use:   "address [options]",
+	Short: "Prints the address of the private key",
+	RunE:  keyAddressFunc,
+}
+
+func keyExportFunc(cmd *cobra.Command, args []string) error {
+	if len(exportOut) == 0 {
+		return errors.New("--out is required")
+	}
+	if err := checkNotKeyFile(exportOut); err != nil {
+		return err
+	}
+	if _, err := os.Stat(exportOut); err == nil {
+		return fmt.Errorf("%w: %s", os.ErrExist, exportOut)
+	}
+	priv, err := loadPrivateKey()
+	if err != nil {
+		return err
+	}
+	if err := saveEncryptedKey(exportOut, priv); err != nil {
+		return err
+	}
+	color.Green("exported key for %s to %s", crypto.PubkeyToAddress(priv.PublicKey), exportOut)
+	return nil
+}
+
+func keyImportFunc(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected exactly 1 argument, got %d", len(args))
+	}
+	src := args[0]
+	if _, err := os.Stat(privateKeyFile); err == nil {
+		return fmt.Errorf("%w: %s", os.ErrExist, privateKeyFile)
+	}
+
+	b, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if !isEncryptedKey(b) {
+		return fmt.Errorf("%w: %s (run \"blob-cli encrypt-key %s\" to import it)", ErrKeyNotEncrypted, src, src)
+	}
+	passphrase, err := readPassphrase(false)
+	if err != nil {
+		return err
+	}
+	priv, err := decryptKey(b, passphrase)
+	if err != nil {
+		return err
+	}
+	if err := writeKeyFile(privateKeyFile, b); err != nil {
+		return err
+	}
+	color.Green("imported key for %s and saved to %s", crypto.PubkeyToAddress(priv.PublicKey), privateKeyFile)
+	return nil
+}
+
+func keyAddressFunc(cmd *cobra.Command, args []string) error {
+	addr, err := keyAddress(privateKeyFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf(
+			"%w at %s (run \"blob-cli create\" to generate one or set --private-key-file)",
+			ErrKeyFileMissing, privateKeyFile,
+		)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println(addr)
+	return nil
+}
@@ -0,0 +1,84 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestKeyExportImport(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, ".blob-cli-pk")
+	wallet := filepath.Join(dir, "wallet.json")
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := crypto.SaveECDSA(keyFile, priv); err != nil {
+		t.Fatal(err)
+	}
+
+	origFile, origN, origOut := privateKeyFile, scryptN, exportOut
+	privateKeyFile, scryptN = keyFile, testScryptN
+	defer func() { privateKeyFile, scryptN, exportOut = origFile, origN, origOut }()
+	t.Setenv(passphraseEnv, "passphrase")
+
+	exportOut = keyFile
+	if err := keyExportFunc(keyExportCmd, nil); !errors.Is(err, ErrKeyFileOverwrite) {
+		t.Fatalf("expected %v, got %v", ErrKeyFileOverwrite, err)
+	}
+	exportOut = wallet
+	if err := keyExportFunc(keyExportCmd, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := keyExportFunc(keyExportCmd, nil); !errors.Is(err, os.ErrExist) {
+		t.Fatalf("expected %v, got %v", os.ErrExist, err)
+	}
+	b, err := os.ReadFile(wallet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isEncryptedKey(b) {
+		t.Fatal("exported key was not encrypted")
+	}
+
+	// Importing requires the key file to be absent and the keystore passphrase
+	if err := keyImportFunc(keyImportCmd, []string{wallet}); !errors.Is(err, os.ErrExist) {
+		t.Fatalf("expected %v, got %v", os.ErrExist, err)
+	}
+	if err := os.Remove(keyFile); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(passphraseEnv, "wrong")
+	if err := keyImportFunc(keyImportCmd, []string{wallet}); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("expected %v, got %v", ErrWrongPassphrase, err)
+	}
+	if _, err := os.Stat(keyFile); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("key file written despite wrong passphrase: %v", err)
+	}
+	t.Setenv(passphraseEnv, "passphrase")
+	if err := keyImportFunc(keyImportCmd, []string{wallet}); err != nil {
+		t.Fatal(err)
+	}
+
+	lpriv, err := loadPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !lpriv.Equal(priv) {
+		t.Fatal("imported key does not match")
+	}
+	addr, err := keyAddress(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr != crypto.PubkeyToAddress(priv.PublicKey) {
+		t.Fatalf("unexpected address %s", addr)
+	}
+}
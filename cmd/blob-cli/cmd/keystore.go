@@ -0,0 +1,187 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package cmd
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/fatih/color"
+	"github.com/google/uuid"
+	"golang.org/x/term"
+)
+
+// scryptN is the scrypt cost used for new keys.
+var scryptN = keystore.StandardScryptN
+
+const (
+	// maxScryptN bounds the scrypt cost of the keys that are decrypted, so a
+	// crafted key file can't exhaust memory (the cost of new keys is never
+	// higher).
+	maxScryptN = keystore.StandardScryptN
+
+	// passphraseEnv can be set to avoid the passphrase prompt (ex: in scripts)
+	passphraseEnv = "BLOB_CLI_PASSPHRASE"
+)
+
+var (
+	ErrWrongPassphrase    = errors.New("wrong passphrase")
+	ErrPassphraseEmpty    = errors.New("passphrase is empty")
+	ErrPassphraseMismatch = errors.New("passphrases do not match")
+	ErrKeyEncrypted       = errors.New("key file is already encrypted")
+	ErrKeyNotEncrypted    = errors.New("key file is not encrypted")
+
+	ErrScryptCostTooHigh = errors.New("scrypt cost too high")
+)
+
+// encryptKey encrypts [priv] with [passphrase] in the go-ethereum keystore
+// (Web3 Secret Storage v3) format, using scrypt with cost [n].
+func encryptKey(priv *ecdsa.PrivateKey, passphrase string, n int) ([]byte, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, err
+	}
+	return keystore.EncryptKey(&keystore.Key{
+		Id:         id,
+		Address:    crypto.PubkeyToAddress(priv.PublicKey),
+		PrivateKey: priv,
+	}, passphrase, n, keystore.StandardScryptP)
+}
+
+// decryptKey decrypts a go-ethereum keystore key (ex: written by
+// [encryptKey]).
+func decryptKey(b []byte, passphrase string) (*ecdsa.PrivateKey, error) {
+	var k struct {
+		Crypto struct {
+			KDFParams struct {
+				N int `json:"n"`
+			} `json:"kdfparams"`
+		} `json:"crypto"`
+	}
+	if err := json.Unmarshal(b, &k); err != nil {
+		return nil, err
+	}
+	if n := k.Crypto.KDFParams.N; n > maxScryptN {
+		return nil, fmt.Errorf("%w: %d (max=%d)", ErrScryptCostTooHigh, n, maxScryptN)
+	}
+	key, err := keystore.DecryptKey(b, passphrase)
+	if errors.Is(err, keystore.ErrDecrypt) {
+		return nil, ErrWrongPassphrase
+	}
+	if err != nil {
+		return nil, err
+	}
+	return key.PrivateKey, nil
+}
+
+// isEncryptedKey returns true if [b] is in the format written by [encryptKey]
+// (instead of the plaintext hex format written by [crypto.SaveECDSA]).
+func isEncryptedKey(b []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(b), []byte("{"))
+}
+
+// loadPrivateKey loads the key at [privateKeyFile], prompting for a passphrase
+// if it is encrypted.
+func loadPrivateKey() (*ecdsa.PrivateKey, error) {
+	b, err := os.ReadFile(privateKeyFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf(
+			"%w at %s (run \"blob-cli create\" to generate one or set --private-key-file)",
+			ErrKeyFileMissing, privateKeyFile,
+		)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !isEncryptedKey(b) {
+		color.Yellow("%s is not encrypted (run \"blob-cli encrypt-key\" to encrypt it)", privateKeyFile)
+		return crypto.LoadECDSA(privateKeyFile)
+	}
+	passphrase, err := readPassphrase(false)
+	if err != nil {
+		return nil, err
+	}
+	return decryptKey(b, passphrase)
+}
+
+// saveEncryptedKey encrypts [priv] with a new passphrase and writes it to
+// [path] (replacing any existing file atomically).
+func saveEncryptedKey(path string, priv *ecdsa.PrivateKey) error {
+	passphrase, err := readPassphrase(true)
+	if err != nil {
+		return err
+	}
+	b, err := encryptKey(priv, passphrase, scryptN)
+	if err != nil {
+		return err
+	}
+	return writeKeyFile(path, b)
+}
+
+// writeKeyFile writes [b] to [path] (replacing any existing file atomically).
+func writeKeyFile(path string, b []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), fsModeWrite); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// readPassphrase reads a passphrase from [passphraseEnv] or prompts for one
+// (twice, if [confirm]).
+func readPassphrase(confirm bool) (string, error) {
+	if p, ok := os.LookupEnv(passphraseEnv); ok {
+		if len(p) == 0 {
+			return "", ErrPassphraseEmpty
+		}
+		return p, nil
+	}
+	p, err := promptPassphrase("Passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if len(p) == 0 {
+		return "", ErrPassphraseEmpty
+	}
+	if !confirm {
+		return p, nil
+	}
+	c, err := promptPassphrase("Repeat passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if p != c {
+		return "", ErrPassphraseMismatch
+	}
+	return p, nil
+}
+
+func promptPassphrase(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	b, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
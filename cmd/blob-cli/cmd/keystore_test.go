@@ -0,0 +1,168 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+)
+
+const testScryptN = 1 << 10
+
+func TestEncryptDecryptKey(t *testing.T) {
+	t.Parallel()
+
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := encryptKey(priv, "correct horse", testScryptN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isEncryptedKey(b) {
+		t.Fatal("encrypted key not detected")
+	}
+
+	dpriv, err := decryptKey(b, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !dpriv.Equal(priv) {
+		t.Fatal("decrypted key does not match")
+	}
+	if _, err := decryptKey(b, "wrong horse"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("expected %v, got %v", ErrWrongPassphrase, err)
+	}
+}
+
+func TestEncryptKeyMigration(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), ".blob-cli-pk")
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := crypto.SaveECDSA(keyFile, priv); err != nil {
+		t.Fatal(err)
+	}
+
+	origFile, origN := privateKeyFile, scryptN
+	privateKeyFile, scryptN = keyFile, testScryptN
+	defer func() { privateKeyFile, scryptN = origFile, origN }()
+	t.Setenv(passphraseEnv, "passphrase")
+
+	// Plaintext keys are still loaded
+	lpriv, err := loadPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !lpriv.Equal(priv) {
+		t.Fatal("loaded key does not match")
+	}
+
+	// Migrate in place
+	if err := encryptKeyFunc(encryptKeyCmd, nil); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isEncryptedKey(b) {
+		t.Fatal("key file was not encrypted")
+	}
+	if err := encryptKeyFunc(encryptKeyCmd, nil); !errors.Is(err, ErrKeyEncrypted) {
+		t.Fatalf("expected %v, got %v", ErrKeyEncrypted, err)
+	}
+
+	lpriv, err = loadPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !lpriv.Equal(priv) {
+		t.Fatal("decrypted key does not match")
+	}
+	t.Setenv(passphraseEnv, "wrong")
+	if _, err := loadPrivateKey(); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("expected %v, got %v", ErrWrongPassphrase, err)
+	}
+}
+
+// Key files must stay interchangeable with go-ethereum keystores.
+func TestKeystoreCompat(t *testing.T) {
+	t.Parallel()
+
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := encryptKey(priv, "passphrase", testScryptN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := keystore.DecryptKey(b, "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !k.PrivateKey.Equal(priv) {
+		t.Fatal("go-ethereum decrypted key does not match")
+	}
+	if k.Id == (uuid.UUID{}) {
+		t.Fatal("missing key id")
+	}
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err = keystore.EncryptKey(&keystore.Key{
+		Id:         id,
+		Address:    crypto.PubkeyToAddress(priv.PublicKey),
+		PrivateKey: priv,
+	}, "passphrase", testScryptN, keystore.StandardScryptP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dpriv, err := decryptKey(b, "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !dpriv.Equal(priv) {
+		t.Fatal("decrypted go-ethereum key does not match")
+	}
+}
+
+func TestDecryptKeyScryptCost(t *testing.T) {
+	t.Parallel()
+
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only the declared cost is checked, so the file doesn't need to be
+	// (expensively) encrypted with it
+	b, err := encryptKey(priv, "passphrase", testScryptN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatal(err)
+	}
+	kdf := m["crypto"].(map[string]interface{})["kdfparams"].(map[string]interface{})
+	kdf["n"] = maxScryptN * 2
+	if b, err = json.Marshal(m); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := decryptKey(b, "passphrase"); !errors.Is(err, ErrScryptCostTooHigh) {
+		t.Fatalf("expected %v, got %v", ErrScryptCostTooHigh, err)
+	}
+}
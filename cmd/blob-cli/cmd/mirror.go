@@ -0,0 +1,88 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/ava-labs/blobvm/client"
+)
+
+var (
+	mirrorSource   string
+	mirrorTarget   string
+	mirrorStart    string
+	mirrorInterval time.Duration
+)
+
+func init() {
+	mirrorCmd.PersistentFlags().StringVar(
+		&mirrorSource,
+		"source",
+		"",
+		"RPC endpoint of the VM to copy keys from",
+	)
+	mirrorCmd.PersistentFlags().StringVar(
+		&mirrorTarget,
+		"target",
+		"",
+		"RPC endpoint of the VM to copy keys to",
+	)
+	mirrorCmd.PersistentFlags().StringVar(
+		&mirrorStart,
+		"start",
+		"",
+		"key to resume mirroring from",
+	)
+	mirrorCmd.PersistentFlags().DurationVar(
+		&mirrorInterval,
+		"interval",
+		0,
+		"time to wait between passes over the source (0 runs a single pass)",
+	)
+}
+
+var mirrorCmd = &cobra.Command{
+	Use:   "mirror [options]",
+	Short: "Copies keys missing on --target from --source",
+	RunE:  mirrorFunc,
+}
+
+func mirrorFunc(cmd *cobra.Command, args []string) error {
+	if len(mirrorSource) == 0 || len(mirrorTarget) == 0 {
+		return errors.New("--source and --target are required")
+	}
+	priv, err := loadPrivateKey()
+	if err != nil {
+		return err
+	}
+
+	src := client.New(mirrorSource, requestTimeout)
+	dst := client.New(mirrorTarget, requestTimeout)
+	opts := []client.OpOption{client.WithPollTx(), client.WithStaleBlockRetries(3)}
+	start := common.HexToHash(mirrorStart)
+	for {
+		stats, err := client.Mirror(context.Background(), src, dst, priv, start, opts...)
+		if err != nil {
+			if stats.Next != nil {
+				color.Red("mirror failed (resume with --start %s)", stats.Next.Hex())
+			}
+			return err
+		}
+		color.Green("mirrored %d keys (skipped=%d cost=%d)", stats.Copied, stats.Skipped, stats.Cost)
+		if mirrorInterval == 0 {
+			return nil
+		}
+
+		// Start over to pick up keys added since the last pass
+		start = common.Hash{}
+		time.Sleep(mirrorInterval)
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/ava-labs/blobvm/chain"
+	"github.com/ava-labs/blobvm/client"
+)
+
+var pinCmd = &cobra.Command{
+	Use:   "pin [options] <key>",
+	Short: "Pins a value so it never expires (charging a one-time fee)",
+	RunE:  pinFunc,
+}
+
+func pinFunc(cmd *cobra.Command, args []string) error {
+	priv, err := loadPrivateKey()
+	if err != nil {
+		return err
+	}
+
+	key, err := getKeyOp(args)
+	if err != nil {
+		return err
+	}
+
+	utx := &chain.PinTx{
+		BaseTx: &chain.BaseTx{},
+		Key:    key,
+	}
+
+	cli := client.New(uri, requestTimeout)
+	opts := []client.OpOption{client.WithPollTx()}
+	if verbose {
+		opts = append(opts, client.WithBalance())
+	}
+	if _, _, err := client.SignIssueRawTx(context.Background(), cli, utx, priv, opts...); err != nil {
+		return err
+	}
+
+	color.Green("pinned %s", key)
+	return nil
+}
+
+func getKeyOp(args []string) (common.Hash, error) {
+	if len(args) != 1 {
+		return common.Hash{}, fmt.Errorf("expected exactly 1 argument, got %d", len(args))
+	}
+	return common.HexToHash(args[0]), nil
+}
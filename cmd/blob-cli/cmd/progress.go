@@ -0,0 +1,95 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+// showProgress prints the progress of set-file and resolve-file
+var showProgress bool
+
+const (
+	// progressInterval is the minimum time between progress lines (the last
+	// chunk is always reported)
+	progressInterval = time.Second
+
+	progressBarWidth = 30
+)
+
+// progressReporter prints the progress of a file transfer (see
+// [tree.Progress]) to stderr, so it doesn't mix with the output of the
+// command.
+type progressReporter struct {
+	w     io.Writer
+	start time.Time
+	last  time.Time
+
+	// [size] is the size of the file (0 if unknown)
+	size int64
+
+	// the last progress reported (to skip repeated reports)
+	lastDone  int
+	lastBytes int64
+}
+
+func newProgressReporter(size int64) *progressReporter {
+	return &progressReporter{w: os.Stderr, start: time.Now(), size: size}
+}
+
+func (p *progressReporter) report(done, total int, bytes int64) {
+	now := time.Now()
+	finished := (total > 0 && done == total) || (p.size > 0 && bytes >= p.size)
+	if !finished && now.Sub(p.last) < progressInterval {
+		return
+	}
+	if !p.last.IsZero() && done == p.lastDone && bytes == p.lastBytes {
+		return
+	}
+	p.last, p.lastDone, p.lastBytes = now, done, bytes
+	fmt.Fprintln(p.w, formatProgress(done, total, bytes, p.size, now.Sub(p.start)))
+}
+
+// formatProgress renders the transfer of [bytes] of a [size] byte file (0 if
+// unknown) in [done] of [total] chunks (0 if unknown) after [elapsed].
+func formatProgress(done, total int, bytes int64, size int64, elapsed time.Duration) string {
+	var parts []string
+	if size > 0 {
+		frac := float64(bytes) / float64(size)
+		if frac > 1 {
+			frac = 1
+		}
+		filled := int(frac * progressBarWidth)
+		parts = append(parts, fmt.Sprintf(
+			"[%s%s] %5.1f%%",
+			strings.Repeat("#", filled), strings.Repeat("-", progressBarWidth-filled), frac*100,
+		))
+	}
+	if total > 0 {
+		parts = append(parts, fmt.Sprintf("chunks %d/%d", done, total))
+	} else {
+		parts = append(parts, fmt.Sprintf("chunks %d", done))
+	}
+	if size > 0 {
+		parts = append(parts, fmt.Sprintf("%.2f/%.2f MiB", float64(bytes)/units.MiB, float64(size)/units.MiB))
+	} else {
+		parts = append(parts, fmt.Sprintf("%.2f MiB", float64(bytes)/units.MiB))
+	}
+	if secs := elapsed.Seconds(); secs > 0 {
+		parts = append(parts, fmt.Sprintf("%.2f MiB/s", float64(bytes)/units.MiB/secs))
+	}
+	parts = append(parts, "elapsed "+elapsed.Round(time.Second).String())
+	if size > 0 && bytes > 0 && bytes < size {
+		// Assume the rest of the file transfers at the same rate
+		eta := time.Duration(float64(elapsed) * float64(size-bytes) / float64(bytes))
+		parts = append(parts, "eta "+eta.Round(time.Second).String())
+	}
+	return strings.Join(parts, " | ")
+}
@@ -0,0 +1,56 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+func TestFormatProgress(t *testing.T) {
+	tt := []struct {
+		done, total int
+		bytes, size int64
+		elapsed     time.Duration
+		expected    string
+	}{
+		{
+			done: 1, total: 4, bytes: 1 * units.MiB, size: 4 * units.MiB, elapsed: 2 * time.Second,
+			expected: "[#######-----------------------]  25.0% | chunks 1/4 | 1.00/4.00 MiB | 0.50 MiB/s | elapsed 2s | eta 6s",
+		},
+		{ // the total number of chunks isn't known yet
+			done: 2, bytes: 2 * units.MiB, size: 4 * units.MiB, elapsed: 2 * time.Second,
+			expected: "[###############---------------]  50.0% | chunks 2 | 2.00/4.00 MiB | 1.00 MiB/s | elapsed 2s | eta 2s",
+		},
+		{ // nor is the size of the file
+			done: 3, bytes: 3 * units.MiB, elapsed: 3 * time.Second,
+			expected: "chunks 3 | 3.00 MiB | 1.00 MiB/s | elapsed 3s",
+		},
+		{
+			done: 4, total: 4, bytes: 4 * units.MiB, size: 4 * units.MiB, elapsed: 4 * time.Second,
+			expected: "[##############################] 100.0% | chunks 4/4 | 4.00/4.00 MiB | 1.00 MiB/s | elapsed 4s",
+		},
+	}
+	for i, tv := range tt {
+		if s := formatProgress(tv.done, tv.total, tv.bytes, tv.size, tv.elapsed); s != tv.expected {
+			t.Fatalf("#%d: expected %q, got %q", i, tv.expected, s)
+		}
+	}
+}
+
+func TestProgressReporter(t *testing.T) {
+	var buf bytes.Buffer
+	p := &progressReporter{w: &buf, start: time.Now(), size: 3}
+	p.report(1, 0, 1)
+	p.report(2, 0, 2) // too soon after the last report
+	p.report(3, 0, 3)
+	p.report(3, 3, 3) // already reported
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %q", lines)
+	}
+}
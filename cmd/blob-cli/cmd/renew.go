@@ -0,0 +1,66 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/ava-labs/blobvm/chain"
+	"github.com/ava-labs/blobvm/client"
+)
+
+var renewCmd = &cobra.Command{
+	Use:   "renew [options] <key> <extension>",
+	Short: "Extends the expiry of a value by <extension> seconds",
+	RunE:  renewFunc,
+}
+
+func renewFunc(cmd *cobra.Command, args []string) error {
+	priv, err := loadPrivateKey()
+	if err != nil {
+		return err
+	}
+
+	key, extension, err := getRenewOp(args)
+	if err != nil {
+		return err
+	}
+
+	utx := &chain.RenewTx{
+		BaseTx:    &chain.BaseTx{},
+		Key:       key,
+		Extension: extension,
+	}
+
+	cli := client.New(uri, requestTimeout)
+	opts := []client.OpOption{client.WithPollTx()}
+	if verbose {
+		opts = append(opts, client.WithBalance())
+	}
+	if _, _, err := client.SignIssueRawTx(context.Background(), cli, utx, priv, opts...); err != nil {
+		return err
+	}
+
+	color.Green("renewed %s for %d seconds", key, extension)
+	return nil
+}
+
+func getRenewOp(args []string) (key common.Hash, extension uint64, err error) {
+	if len(args) != 2 {
+		return common.Hash{}, 0, fmt.Errorf("expected exactly 2 arguments, got %d", len(args))
+	}
+
+	key = common.HexToHash(args[0])
+	extension, err = strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		return common.Hash{}, 0, fmt.Errorf("%w: failed to parse extension", err)
+	}
+	return key, extension, nil
+}
@@ -0,0 +1,32 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package cmd
+
+import (
+	"context"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/ava-labs/blobvm/client"
+)
+
+var repairCmd = &cobra.Command{
+	Use:   "repair [options]",
+	Short: "Restores values missing from the node's disk from its peers (requires the node's admin token)",
+	RunE:  repairFunc,
+}
+
+func repairFunc(cmd *cobra.Command, args []string) error {
+	cli := client.NewAdmin(uri, adminToken, requestTimeout)
+	repaired, unrepairable, err := cli.Repair(context.Background())
+	if err != nil {
+		return err
+	}
+	color.Green("repaired %d values", len(repaired))
+	for _, k := range unrepairable {
+		color.Red("could not repair %s", k)
+	}
+	return nil
+}
@@ -8,6 +8,9 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"time"
+
+	"github.com/ava-labs/avalanchego/utils/units"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/fatih/color"
@@ -17,6 +20,32 @@ import (
 	"github.com/ava-labs/blobvm/tree"
 )
 
+var (
+	maxDepth    uint64
+	concurrency int
+)
+
+func init() {
+	resolveFileCmd.PersistentFlags().Uint64Var(
+		&maxDepth,
+		"max-depth",
+		tree.DefaultMaxDepth,
+		"maximum number of root levels to traverse",
+	)
+	resolveFileCmd.PersistentFlags().IntVar(
+		&concurrency,
+		"concurrency",
+		tree.DefaultConcurrency,
+		"maximum number of chunks to fetch at once",
+	)
+	resolveFileCmd.PersistentFlags().BoolVar(
+		&showProgress,
+		"progress",
+		true,
+		"print the progress of the download to stderr",
+	)
+}
+
 var resolveFileCmd = &cobra.Command{
 	Use:   "resolve-file [options] <root> <output path>",
 	Short: "Reads a file at a root and saves it to disk",
@@ -29,6 +58,9 @@ func resolveFileFunc(cmd *cobra.Command, args []string) error {
 	}
 
 	filePath := args[1]
+	if err := checkNotKeyFile(filePath); err != nil {
+		return err
+	}
 	if _, err := os.Stat(filePath); !errors.Is(err, os.ErrNotExist) {
 		return fmt.Errorf("file %s already exists", filePath)
 	}
@@ -41,10 +73,30 @@ func resolveFileFunc(cmd *cobra.Command, args []string) error {
 
 	root := common.HexToHash(args[0])
 	cli := client.New(uri, requestTimeout)
-	if err := tree.Download(context.Background(), cli, root, f); err != nil {
+	var (
+		opts     = []tree.DownloadOption{tree.WithMaxDepth(maxDepth), tree.WithConcurrency(concurrency)}
+		start    = time.Now()
+		progress = newProgressReporter(0)
+		size     uint64
+	)
+	// The size of the file (if the root records it) is reported before each
+	// chunk's progress
+	opts = append(opts, tree.WithProgress(func(downloaded uint64, total uint64) {
+		size = downloaded
+		progress.size = int64(total)
+	}))
+	if showProgress {
+		opts = append(opts, tree.WithChunkProgress(progress.report))
+	}
+	if err := tree.Download(context.Background(), cli, root, f, opts...); err != nil {
 		return err
 	}
 
+	elapsed := time.Since(start)
 	color.Green("resolved file %v and stored at %s", root, filePath)
+	color.Green(
+		"downloaded %d bytes in %v (%.2f MiB/s)",
+		size, elapsed.Round(time.Millisecond), float64(size)/units.MiB/elapsed.Seconds(),
+	)
 	return nil
 }
@@ -7,11 +7,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 
+	"github.com/ava-labs/blobvm/chain"
 	"github.com/ava-labs/blobvm/client"
 )
 
@@ -21,13 +23,35 @@ var resolveCmd = &cobra.Command{
 	RunE:  resolveFunc,
 }
 
+var resolvePending bool
+
+func init() {
+	resolveCmd.PersistentFlags().BoolVar(
+		&resolvePending,
+		"pending",
+		false,
+		"resolve values from pending transactions in the mempool",
+	)
+}
+
 func resolveFunc(cmd *cobra.Command, args []string) error {
 	if len(args) != 1 {
 		return fmt.Errorf("expected exactly 1 argument, got %d", len(args))
 	}
 	k := common.HexToHash(args[0])
 	cli := client.New(uri, requestTimeout)
-	_, v, vmeta, err := cli.Resolve(context.Background(), k)
+	var (
+		v       []byte
+		vmeta   *chain.ValueMeta
+		pending bool
+		age     time.Duration
+		err     error
+	)
+	if resolvePending {
+		_, pending, v, vmeta, err = cli.ResolvePending(context.Background(), k)
+	} else {
+		_, v, vmeta, age, err = client.ResolveWithAge(context.Background(), cli, k)
+	}
 	if err != nil {
 		return err
 	}
@@ -39,6 +63,11 @@ func resolveFunc(cmd *cobra.Command, args []string) error {
 	}
 	color.Yellow("Metadata: %s", string(hr))
 
+	if pending {
+		color.Yellow("value is pending (not yet accepted)")
+	} else if vmeta != nil {
+		color.Yellow("Age: %s", age)
+	}
 	color.Green("resolved %s", args[0])
 	return nil
 }
@@ -39,14 +39,24 @@ func init() {
 	cobra.EnablePrefixMatching = true
 	rootCmd.AddCommand(
 		createCmd,
+		encryptKeyCmd,
+		keyCmd,
 		genesisCmd,
 		setCmd,
 		resolveCmd,
 		activityCmd,
 		transferCmd,
+		renewCmd,
+		pinCmd,
+		unpinCmd,
 		setFileCmd,
 		resolveFileCmd,
 		networkCmd,
+		gatewayCmd,
+		mirrorCmd,
+		adminCmd,
+		benchCmd,
+		repairCmd,
 	)
 
 	rootCmd.PersistentFlags().StringVar(
@@ -70,5 +80,5 @@ func init() {
 }
 
 func Execute() error {
-	return rootCmd.Execute()
+	return friendlyError(rootCmd.Execute())
 }
@@ -7,8 +7,10 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"time"
 
-	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ava-labs/avalanchego/utils/units"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 
@@ -16,6 +18,81 @@ import (
 	"github.com/ava-labs/blobvm/tree"
 )
 
+var (
+	gatewayBase    string
+	checkpointFile string
+	batchUpload    bool
+	uploadWorkers  int
+	compression    string
+	chunkSize      int
+	chunking       string
+	skipDedupCheck bool
+	precheckChunks bool
+)
+
+func init() {
+	setFileCmd.PersistentFlags().StringVar(
+		&gatewayBase,
+		"gateway",
+		"",
+		"gateway base URL to print a shareable link for (ex: https://gateway.example.com)",
+	)
+	setFileCmd.PersistentFlags().StringVar(
+		&checkpointFile,
+		"checkpoint",
+		"",
+		"file to record confirmed chunks in, so an interrupted upload can be resumed",
+	)
+	setFileCmd.PersistentFlags().BoolVar(
+		&batchUpload,
+		"batch",
+		false,
+		"store multiple chunks per transaction",
+	)
+	setFileCmd.PersistentFlags().IntVar(
+		&uploadWorkers,
+		"concurrency",
+		1,
+		"maximum number of transactions to issue at once",
+	)
+	setFileCmd.PersistentFlags().StringVar(
+		&compression,
+		"compression",
+		"",
+		"compress chunks before storing them (\"gzip\")",
+	)
+	setFileCmd.PersistentFlags().IntVar(
+		&chunkSize,
+		"chunk-size",
+		0,
+		"size of the chunks the file is split into (defaults to the max value size)",
+	)
+	setFileCmd.PersistentFlags().StringVar(
+		&chunking,
+		"chunking",
+		"",
+		"pick chunk boundaries from the file's contents (\"buzhash\") instead of every chunk size bytes",
+	)
+	setFileCmd.PersistentFlags().BoolVar(
+		&skipDedupCheck,
+		"skip-dedup-check",
+		false,
+		"don't check whether each chunk is already stored before uploading it (faster for new files)",
+	)
+	setFileCmd.PersistentFlags().BoolVar(
+		&precheckChunks,
+		"precheck",
+		false,
+		"check which chunks are already stored in one pass over the file before uploading it",
+	)
+	setFileCmd.PersistentFlags().BoolVar(
+		&showProgress,
+		"progress",
+		true,
+		"print the progress of the upload to stderr",
+	)
+}
+
 var setFileCmd = &cobra.Command{
 	Use:   "set-file [options] <file path>",
 	Short: "Writes a file to BlobVM (using multiple keys)",
@@ -23,7 +100,7 @@ var setFileCmd = &cobra.Command{
 }
 
 func setFileFunc(cmd *cobra.Command, args []string) error {
-	priv, err := crypto.LoadECDSA(privateKeyFile)
+	priv, err := loadPrivateKey()
 	if err != nil {
 		return err
 	}
@@ -33,20 +110,65 @@ func setFileFunc(cmd *cobra.Command, args []string) error {
 		return err
 	}
 	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
 
 	cli := client.New(uri, requestTimeout)
-	g, err := cli.Genesis(context.Background())
+	g, err := cli.CachedGenesis(context.Background())
 	if err != nil {
 		return err
 	}
 
+	if chunkSize == 0 {
+		chunkSize = int(g.MaxValueSize)
+	}
+
 	// TODO: protect against overflow
-	root, err := tree.Upload(context.Background(), cli, priv, f, int(g.MaxValueSize))
+	var (
+		root  common.Hash
+		opts  = []tree.UploadOption{tree.WithUploadConcurrency(uploadWorkers)}
+		start = time.Now()
+	)
+	if batchUpload {
+		opts = append(opts, tree.WithBatching())
+	}
+	if len(compression) > 0 {
+		opts = append(opts, tree.WithCompression(tree.Compression(compression)))
+	}
+	if len(chunking) > 0 {
+		opts = append(opts, tree.WithChunking(tree.Chunking(chunking)))
+	}
+	if skipDedupCheck {
+		opts = append(opts, tree.WithSkipDedupCheck())
+	}
+	if precheckChunks {
+		opts = append(opts, tree.WithPrecheck())
+	}
+	if showProgress {
+		opts = append(opts, tree.WithUploadProgress(newProgressReporter(fi.Size()).report))
+	}
+	if len(checkpointFile) > 0 {
+		root, _, err = tree.UploadResumable(
+			context.Background(), cli, priv, f, chunkSize, checkpointFile, opts...,
+		)
+	} else {
+		root, err = tree.Upload(context.Background(), cli, priv, f, chunkSize, opts...)
+	}
 	if err != nil {
 		return err
 	}
 
+	elapsed := time.Since(start)
 	color.Green("uploaded file %v from %s", root, f.Name())
+	color.Green(
+		"uploaded %d bytes in %v (%.2f MiB/s)",
+		fi.Size(), elapsed.Round(time.Millisecond), float64(fi.Size())/units.MiB/elapsed.Seconds(),
+	)
+	if len(gatewayBase) > 0 {
+		color.Green("share at %s", client.ShareURL(root, gatewayBase))
+	}
 	return nil
 }
 
@@ -5,9 +5,11 @@ package cmd
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"errors"
 	"fmt"
+	"strings"
 
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 
@@ -15,6 +17,33 @@ import (
 	"github.com/ava-labs/blobvm/client"
 )
 
+var (
+	ttl    uint64
+	tags   []string
+	dryRun bool
+)
+
+func init() {
+	setCmd.PersistentFlags().Uint64Var(
+		&ttl,
+		"ttl",
+		0,
+		"seconds to store the value for (0 uses the genesis default)",
+	)
+	setCmd.PersistentFlags().StringArrayVar(
+		&tags,
+		"tag",
+		nil,
+		"tag to attach to the value (key=value, repeatable)",
+	)
+	setCmd.PersistentFlags().BoolVar(
+		&dryRun,
+		"dry-run",
+		false,
+		"simulate the transaction without issuing it (reporting its fee or why it would fail)",
+	)
+}
+
 var setCmd = &cobra.Command{
 	Use:   "set [options] <value>",
 	Short: "Writes a value to BlobVM",
@@ -22,7 +51,7 @@ var setCmd = &cobra.Command{
 }
 
 func setFunc(cmd *cobra.Command, args []string) error {
-	priv, err := crypto.LoadECDSA(privateKeyFile)
+	priv, err := loadPrivateKey()
 	if err != nil {
 		return err
 	}
@@ -31,13 +60,22 @@ func setFunc(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	ptags, err := parseTags(tags)
+	if err != nil {
+		return err
+	}
 
 	utx := &chain.SetTx{
 		BaseTx: &chain.BaseTx{},
 		Value:  val,
+		TTL:    ttl,
+		Tags:   ptags,
 	}
 
 	cli := client.New(uri, requestTimeout)
+	if dryRun {
+		return previewSet(cli, utx, priv)
+	}
 	opts := []client.OpOption{client.WithPollTx()}
 	if verbose {
 		opts = append(opts, client.WithBalance())
@@ -45,8 +83,12 @@ func setFunc(cmd *cobra.Command, args []string) error {
 	if _, _, err := client.SignIssueRawTx(context.Background(), cli, utx, priv, opts...); err != nil {
 		return err
 	}
+	k, err := cli.ValueHash(context.Background(), val)
+	if err != nil {
+		return err
+	}
 
-	color.Green("set %s", chain.ValueHash(val))
+	color.Green("set %s", k)
 	return nil
 }
 
@@ -57,3 +99,39 @@ func getSetOp(args []string) (val []byte, err error) {
 
 	return []byte(args[0]), nil
 }
+
+func parseTags(raw []string) ([]*chain.Tag, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	tags := make([]*chain.Tag, len(raw))
+	for i, r := range raw {
+		k, v, ok := strings.Cut(r, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid tag %q (expected key=value)", r)
+		}
+		tags[i] = &chain.Tag{Key: k, Value: v}
+	}
+	return tags, nil
+}
+
+// previewSet simulates [utx] and reports its fee or, if it would be rejected
+// (ex: the value is already stored), why.
+func previewSet(cli client.Client, utx *chain.SetTx, priv *ecdsa.PrivateKey) error {
+	ctx := context.Background()
+	k, err := cli.ValueHash(ctx, utx.Value)
+	if err != nil {
+		return err
+	}
+	fee, balance, err := client.SignSimulateRawTx(ctx, cli, utx, priv)
+	var serr *client.SimulationError
+	if errors.As(err, &serr) {
+		color.Red("setting %s would fail: %s", k, serr.Reason)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	color.Green("setting %s would succeed (fee=%d balance after=%d)", k, fee, balance)
+	return nil
+}
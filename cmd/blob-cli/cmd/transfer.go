@@ -9,7 +9,6 @@ import (
 	"strconv"
 
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 
@@ -17,34 +16,52 @@ import (
 	"github.com/ava-labs/blobvm/client"
 )
 
+var transferAll bool
+
+func init() {
+	transferCmd.PersistentFlags().BoolVar(
+		&transferAll,
+		"all",
+		false,
+		"transfer the entire balance (less the fee) instead of <units>",
+	)
+}
+
 var transferCmd = &cobra.Command{
-	Use:   "transfer [options] <to> <units>",
+	Use:   "transfer [options] <to> <units|--all>",
 	Short: "Transfers units to another address",
 	RunE:  transferFunc,
 }
 
 func transferFunc(cmd *cobra.Command, args []string) error {
-	priv, err := crypto.LoadECDSA(privateKeyFile)
+	priv, err := loadPrivateKey()
 	if err != nil {
 		return err
 	}
 
-	to, units, err := getTransferOp(args)
+	to, units, err := getTransferOp(args, transferAll)
 	if err != nil {
 		return err
 	}
 
-	utx := &chain.TransferTx{
-		BaseTx: &chain.BaseTx{},
-		To:     to,
-		Units:  units,
-	}
-
 	cli := client.New(uri, requestTimeout)
 	opts := []client.OpOption{client.WithPollTx()}
 	if verbose {
 		opts = append(opts, client.WithBalance())
 	}
+	if transferAll {
+		if _, units, err = client.SignIssueSweepTx(context.Background(), cli, to, priv, opts...); err != nil {
+			return err
+		}
+		color.Green("transferred %d (entire balance) to %s", units, to.Hex())
+		return nil
+	}
+
+	utx := &chain.TransferTx{
+		BaseTx: &chain.BaseTx{},
+		To:     to,
+		Units:  units,
+	}
 	if _, _, err := client.SignIssueRawTx(context.Background(), cli, utx, priv, opts...); err != nil {
 		return err
 	}
@@ -53,7 +70,13 @@ func transferFunc(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func getTransferOp(args []string) (to common.Address, units uint64, err error) {
+func getTransferOp(args []string, all bool) (to common.Address, units uint64, err error) {
+	if all {
+		if len(args) != 1 {
+			return common.Address{}, 0, fmt.Errorf("expected exactly 1 argument with --all, got %d", len(args))
+		}
+		return common.HexToAddress(args[0]), 0, nil
+	}
 	if len(args) != 2 {
 		return common.Address{}, 0, fmt.Errorf("expected exactly 2 arguments, got %d", len(args))
 	}
@@ -0,0 +1,49 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package cmd
+
+import (
+	"context"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/ava-labs/blobvm/chain"
+	"github.com/ava-labs/blobvm/client"
+)
+
+var unpinCmd = &cobra.Command{
+	Use:   "unpin [options] <key>",
+	Short: "Unpins a value previously pinned by the sender",
+	RunE:  unpinFunc,
+}
+
+func unpinFunc(cmd *cobra.Command, args []string) error {
+	priv, err := loadPrivateKey()
+	if err != nil {
+		return err
+	}
+
+	key, err := getKeyOp(args)
+	if err != nil {
+		return err
+	}
+
+	utx := &chain.UnpinTx{
+		BaseTx: &chain.BaseTx{},
+		Key:    key,
+	}
+
+	cli := client.New(uri, requestTimeout)
+	opts := []client.OpOption{client.WithPollTx()}
+	if verbose {
+		opts = append(opts, client.WithBalance())
+	}
+	if _, _, err := client.SignIssueRawTx(context.Background(), cli, utx, priv, opts...); err != nil {
+		return err
+	}
+
+	color.Green("unpinned %s", key)
+	return nil
+}
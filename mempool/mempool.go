@@ -5,9 +5,12 @@ package mempool
 
 import (
 	"container/heap"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/common"
 
 	"github.com/ava-labs/blobvm/chain"
 )
@@ -21,6 +24,9 @@ type Mempool struct {
 	maxHeap *txHeap
 	minHeap *txHeap
 
+	// units is the sum of the [LoadUnits] of all txs in the mempool
+	units uint64
+
 	// Pending is a channel of length one, which the mempool ensures has an item on
 	// it as long as there is an unissued transaction remaining in [txs]
 	Pending chan struct{}
@@ -53,6 +59,7 @@ func (th *Mempool) Add(tx *chain.Transaction) bool {
 	}
 
 	oldLen := th.maxHeap.Len()
+	now := time.Now()
 
 	// Optimistically add tx to mempool
 	heap.Push(th.maxHeap, &txEntry{
@@ -60,13 +67,16 @@ func (th *Mempool) Add(tx *chain.Transaction) bool {
 		price: price,
 		tx:    tx,
 		index: oldLen,
+		added: now,
 	})
 	heap.Push(th.minHeap, &txEntry{
 		id:    txID,
 		price: price,
 		tx:    tx,
 		index: oldLen,
+		added: now,
 	})
+	th.units += tx.LoadUnits(th.g)
 
 	// Remove the lowest paying tx
 	//
@@ -154,6 +164,67 @@ func (th *Mempool) Len() int {
 	return th.maxHeap.Len()
 }
 
+// Units returns the sum of the [LoadUnits] of all txs in the mempool.
+func (th *Mempool) Units() uint64 {
+	th.mu.RLock()
+	defer th.mu.RUnlock()
+
+	return th.units
+}
+
+// Stats summarize the txs in a [Mempool].
+type Stats struct {
+	Len int
+	// FeeUnits is the sum of the [FeeUnits] of all txs
+	FeeUnits uint64
+	// Oldest is when the tx that has been in the mempool the longest was
+	// added (zero if the mempool is empty)
+	Oldest   time.Time
+	MinPrice uint64
+	MaxPrice uint64
+}
+
+// Stats returns a summary of the txs in the mempool.
+func (th *Mempool) Stats() Stats { // O(N)
+	th.mu.RLock()
+	defer th.mu.RUnlock()
+
+	s := Stats{Len: th.maxHeap.Len()}
+	if s.Len == 0 {
+		return s
+	}
+	s.MaxPrice = th.maxHeap.items[0].price
+	s.MinPrice = th.minHeap.items[0].price
+	for _, item := range th.maxHeap.items {
+		s.FeeUnits += item.tx.FeeUnits(th.g)
+		if s.Oldest.IsZero() || item.added.Before(s.Oldest) {
+			s.Oldest = item.added
+		}
+	}
+	return s
+}
+
+// TopTxIDs returns the IDs of up to [limit] of the highest paying txs in the
+// mempool, from highest to lowest price (the order blocks are built in).
+func (th *Mempool) TopTxIDs(limit int) []ids.ID { // O(N log N)
+	th.mu.RLock()
+	items := make([]*txEntry, len(th.maxHeap.items))
+	copy(items, th.maxHeap.items)
+	th.mu.RUnlock()
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].price > items[j].price
+	})
+	if limit < len(items) {
+		items = items[:limit]
+	}
+	txIDs := make([]ids.ID, len(items))
+	for i, item := range items {
+		txIDs[i] = item.id
+	}
+	return txIDs
+}
+
 func (th *Mempool) Get(id ids.ID) (*chain.Transaction, bool) {
 	th.mu.RLock()
 	defer th.mu.RUnlock()
@@ -165,6 +236,28 @@ func (th *Mempool) Get(id ids.ID) (*chain.Transaction, bool) {
 	return txEntry.tx, true
 }
 
+// GetSetTx returns the highest paying [SetTx] in the mempool that stores the
+// value at [key], if any.
+func (th *Mempool) GetSetTx(key common.Hash) (*chain.Transaction, bool) { // O(N)
+	th.mu.RLock()
+	defer th.mu.RUnlock()
+
+	var (
+		best  *chain.Transaction
+		price uint64
+	)
+	for _, item := range th.maxHeap.items {
+		stx, ok := item.tx.UnsignedTransaction.(*chain.SetTx)
+		if !ok || th.g.AddressKey(stx.Addressing, stx.Value) != key {
+			continue
+		}
+		if best == nil || item.price > price {
+			best, price = item.tx, item.price
+		}
+	}
+	return best, best != nil
+}
+
 func (th *Mempool) Has(id ids.ID) bool {
 	th.mu.RLock()
 	defer th.mu.RUnlock()
@@ -225,6 +318,7 @@ func (th *Mempool) remove(id ids.ID) *chain.Transaction {
 		// This should never happen
 		return nil
 	}
+	th.units -= txe.tx.LoadUnits(th.g)
 	return txe.tx
 }
 
@@ -4,9 +4,13 @@
 package mempool_test
 
 import (
+	"crypto/ecdsa"
 	"fmt"
+	"reflect"
 	"testing"
+	"time"
 
+	"github.com/ava-labs/avalanchego/ids"
 	"github.com/ethereum/go-ethereum/crypto"
 
 	"github.com/ava-labs/blobvm/chain"
@@ -21,26 +25,7 @@ func TestMempool(t *testing.T) {
 		t.Fatal(err)
 	}
 	for _, i := range []int{100, 200, 220, 250} {
-		tx := &chain.Transaction{
-			UnsignedTransaction: &chain.SetTx{
-				BaseTx: &chain.BaseTx{
-					Price: uint64(i),
-				},
-				Value: []byte(fmt.Sprintf("0x%064x", i)),
-			},
-		}
-		dh, err := chain.DigestHash(tx.UnsignedTransaction)
-		if err != nil {
-			t.Fatal(err)
-		}
-		sig, err := chain.Sign(dh, priv)
-		if err != nil {
-			t.Fatal(err)
-		}
-		tx.Signature = sig
-		if err := tx.Init(g); err != nil {
-			t.Fatal(err)
-		}
+		tx := testTx(t, g, priv, uint64(i))
 		if !txm.Add(tx) {
 			t.Fatalf("tx %s was not added", tx.ID())
 		}
@@ -54,4 +39,79 @@ func TestMempool(t *testing.T) {
 	if length := txm.Len(); length != 3 {
 		t.Fatalf("length expected 3, got %d", length)
 	}
+	// Each tx has 1 base unit and 1 value unit
+	if units := txm.Units(); units != 6 {
+		t.Fatalf("units expected 6, got %d", units)
+	}
+	txm.PopMax()
+	if units := txm.Units(); units != 4 {
+		t.Fatalf("units expected 4, got %d", units)
+	}
+}
+
+func TestMempoolStats(t *testing.T) {
+	g := chain.DefaultGenesis()
+	txm := mempool.New(g, 4)
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats := txm.Stats(); stats != (mempool.Stats{}) {
+		t.Fatalf("stats expected to be empty, got %+v", stats)
+	}
+
+	start := time.Now()
+	txs := make(map[uint64]*chain.Transaction)
+	for _, price := range []uint64{200, 100, 250} {
+		tx := testTx(t, g, priv, price)
+		if !txm.Add(tx) {
+			t.Fatalf("tx %s was not added", tx.ID())
+		}
+		txs[price] = tx
+	}
+	stats := txm.Stats()
+	if stats.Len != 3 || stats.MinPrice != 100 || stats.MaxPrice != 250 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	// Each tx has 1 base unit and 1 value unit
+	if stats.FeeUnits != 6 {
+		t.Fatalf("fee units expected 6, got %d", stats.FeeUnits)
+	}
+	if stats.Oldest.Before(start) || stats.Oldest.After(time.Now()) {
+		t.Fatalf("oldest expected after %s, got %s", start, stats.Oldest)
+	}
+
+	expected := []ids.ID{txs[250].ID(), txs[200].ID()}
+	if txIDs := txm.TopTxIDs(2); !reflect.DeepEqual(txIDs, expected) {
+		t.Fatalf("top txs expected %v, got %v", expected, txIDs)
+	}
+	expected = append(expected, txs[100].ID())
+	if txIDs := txm.TopTxIDs(10); !reflect.DeepEqual(txIDs, expected) {
+		t.Fatalf("top txs expected %v, got %v", expected, txIDs)
+	}
+}
+
+func testTx(t *testing.T, g *chain.Genesis, priv *ecdsa.PrivateKey, price uint64) *chain.Transaction {
+	t.Helper()
+	tx := &chain.Transaction{
+		UnsignedTransaction: &chain.SetTx{
+			BaseTx: &chain.BaseTx{
+				Price: price,
+			},
+			Value: []byte(fmt.Sprintf("0x%064x", price)),
+		},
+	}
+	dh, err := chain.DigestHash(tx.UnsignedTransaction)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := chain.Sign(dh, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.Signature = sig
+	if err := tx.Init(g); err != nil {
+		t.Fatal(err)
+	}
+	return tx
 }
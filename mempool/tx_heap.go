@@ -5,6 +5,7 @@ package mempool
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/ava-labs/avalanchego/ids"
 
@@ -18,6 +19,9 @@ type txEntry struct {
 	tx    *chain.Transaction
 	price uint64
 	index int
+
+	// added is when [tx] entered the mempool
+	added time.Time
 }
 
 // txHeap is used to track pending transactions by [price]
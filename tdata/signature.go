@@ -0,0 +1,52 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package tdata
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrWrongSigner      = errors.New("signature is not from the expected signer")
+)
+
+// legacySigAdj is added to the recovery ID of signatures returned by wallets
+// (and by [eth_signTypedData_v4]).
+const legacySigAdj = 27
+
+// VerifyTypedDataSignature ensures [sig] is a signature of the EIP-712 digest
+// of [td] (see [DigestHash]) by [expectedSigner]. [sig] is the 65 byte
+// [R || S || V] signature returned by [eth_signTypedData_v4] for the JSON
+// encoding of [td], where [V] is 27 or 28 (0 and 1 are accepted too).
+func VerifyTypedDataSignature(td *TypedData, sig []byte, expectedSigner common.Address) error {
+	if len(sig) != crypto.SignatureLength {
+		return fmt.Errorf("%w: expected %d bytes, got %d", ErrInvalidSignature, crypto.SignatureLength, len(sig))
+	}
+	dh, err := DigestHash(td)
+	if err != nil {
+		return err
+	}
+	// Avoid modifying the signature in place in case it is used elsewhere
+	sigcpy := make([]byte, crypto.SignatureLength)
+	copy(sigcpy, sig)
+	if sigcpy[crypto.RecoveryIDOffset] >= legacySigAdj {
+		sigcpy[crypto.RecoveryIDOffset] -= legacySigAdj
+	}
+	if v := sigcpy[crypto.RecoveryIDOffset]; v > 1 {
+		return fmt.Errorf("%w: invalid recovery id %d", ErrInvalidSignature, sig[crypto.RecoveryIDOffset])
+	}
+	pub, err := crypto.SigToPub(dh, sigcpy)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	if signer := crypto.PubkeyToAddress(*pub); signer != expectedSigner {
+		return fmt.Errorf("%w: signed by %s", ErrWrongSigner, signer)
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package tdata_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+
+	"github.com/ava-labs/blobvm/chain"
+	"github.com/ava-labs/blobvm/tdata"
+)
+
+// A well-known test key (used throughout go-ethereum's tests)
+const testKey = "b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291"
+
+// The digest of [testSetTx] and its signature by [testKey] (as produced by
+// go-ethereum's signer and returned by eth_signTypedData_v4)
+const (
+	testSetTxDigest = "0x15d61ec917e0c89a3c61f2f09a9e93618a673967039ec8e4e217ae71ab2e74b9"
+	testSetTxSig    = "0x05ca176ae31fd17d947b53d79f49c5c7a6f6a370da06c190e7576ba7ad91768a3430d44cd43744602ac696d66e761b874d829ac5b6b8d6665927875f6d0bdf311b"
+)
+
+func testSetTx() *chain.SetTx {
+	return &chain.SetTx{
+		BaseTx: &chain.BaseTx{BlockID: ids.ID{1, 2, 3}, Magic: 1, Price: 10},
+		Value:  []byte("hello world"),
+		TTL:    3600,
+		Tags:   []*chain.Tag{{Key: "type", Value: "text/plain"}},
+	}
+}
+
+// gethDigestHash computes the EIP-712 digest of [td] with go-ethereum's
+// encoder.
+func gethDigestHash(t *testing.T, td *tdata.TypedData) []byte {
+	t.Helper()
+
+	types := apitypes.Types{}
+	for name, fields := range td.Types {
+		for _, f := range fields {
+			types[name] = append(types[name], apitypes.Type{Name: f.Name, Type: f.Type})
+		}
+	}
+	// Only the name is set for validation: [apitypes.TypedDataDomain] can't
+	// hold the magic, so the domain is hashed from its map
+	gtd := &apitypes.TypedData{
+		Types:       types,
+		PrimaryType: td.PrimaryType,
+		Domain:      apitypes.TypedDataDomain{Name: td.Domain.Name},
+		Message:     td.Message,
+	}
+	domainSeparator, err := gtd.HashStruct("EIP712Domain", td.Domain.Map())
+	if err != nil {
+		t.Fatal(err)
+	}
+	typedDataHash, err := gtd.HashStruct(td.PrimaryType, td.Message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return crypto.Keccak256([]byte{0x19, 0x01}, domainSeparator, typedDataHash)
+}
+
+func TestDigestHashMatchesGeth(t *testing.T) {
+	t.Parallel()
+
+	blkID := ids.ID{1, 2, 3}
+	for _, utx := range []chain.UnsignedTransaction{
+		testSetTx(),
+		&chain.SetTx{
+			BaseTx:     &chain.BaseTx{BlockID: blkID, Magic: 1, Price: 10},
+			Value:      []byte("hello world"),
+			Addressing: chain.CIDv1,
+			Key:        common.Hash{1},
+		},
+		&chain.TransferTx{BaseTx: &chain.BaseTx{BlockID: blkID, Magic: 1}, To: common.Address{1}, Units: 5},
+		&chain.RenewTx{BaseTx: &chain.BaseTx{BlockID: blkID, Magic: 2}, Key: common.Hash{1}, Extension: 60},
+		&chain.PinTx{BaseTx: &chain.BaseTx{BlockID: blkID, Magic: 3}, Key: common.Hash{1}},
+		&chain.UnpinTx{BaseTx: &chain.BaseTx{BlockID: blkID, Magic: 4}, Key: common.Hash{1}},
+		&chain.BatchTx{BaseTx: &chain.BaseTx{BlockID: blkID, Magic: 5}, Values: []*chain.BatchValue{
+			{Value: []byte("a"), TTL: 1},
+			{Value: []byte("b"), Tags: []*chain.Tag{{Key: "k", Value: "v"}}},
+		}},
+	} {
+		td := utx.TypedData()
+		dh, err := tdata.DigestHash(td)
+		if err != nil {
+			t.Fatalf("%s: %v", td.PrimaryType, err)
+		}
+		if expected := gethDigestHash(t, td); !bytes.Equal(dh, expected) {
+			t.Fatalf("%s: digest expected %x, got %x", td.PrimaryType, expected, dh)
+		}
+	}
+}
+
+func TestDomainSeparator(t *testing.T) {
+	t.Parallel()
+
+	td := testSetTx().TypedData()
+	if typ := string(td.EncodeType("EIP712Domain")); typ != "EIP712Domain(string name,uint64 magic)" {
+		t.Fatalf("unexpected domain type %q", typ)
+	}
+	domainSeparator, err := td.HashStruct("EIP712Domain", td.Domain.Map())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// keccak256(typeHash || keccak256(name) || uint256(magic))
+	magic := make([]byte, 32)
+	magic[31] = 1
+	expected := crypto.Keccak256(
+		crypto.Keccak256([]byte("EIP712Domain(string name,uint64 magic)")),
+		crypto.Keccak256([]byte("Blob")),
+		magic,
+	)
+	if !bytes.Equal(domainSeparator, expected) {
+		t.Fatalf("domain separator expected %x, got %x", expected, domainSeparator)
+	}
+}
+
+func TestVerifyTypedDataSignature(t *testing.T) {
+	t.Parallel()
+
+	priv, err := crypto.HexToECDSA(testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := crypto.PubkeyToAddress(priv.PublicKey)
+
+	// Wallets sign the JSON encoding of the typed data
+	b, err := json.Marshal(testSetTx().TypedData())
+	if err != nil {
+		t.Fatal(err)
+	}
+	td := new(tdata.TypedData)
+	if err := json.Unmarshal(b, td); err != nil {
+		t.Fatal(err)
+	}
+	dh, err := tdata.DigestHash(td)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hexutil.Encode(dh) != testSetTxDigest {
+		t.Fatalf("digest expected %s, got %s", testSetTxDigest, hexutil.Encode(dh))
+	}
+	sig, err := hexutil.Decode(testSetTxSig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tdata.VerifyTypedDataSignature(td, sig, signer); err != nil {
+		t.Fatal(err)
+	}
+	// The same signature as produced by [chain.Sign] is accepted by the VM
+	csig, err := chain.Sign(dh, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(csig, sig) {
+		t.Fatalf("signature expected %x, got %x", sig, csig)
+	}
+
+	unadjusted := append([]byte{}, sig...)
+	unadjusted[crypto.RecoveryIDOffset] -= 27
+	if err := tdata.VerifyTypedDataSignature(td, unadjusted, signer); err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := testSetTx()
+	tampered.Price++
+	if err := tdata.VerifyTypedDataSignature(tampered.TypedData(), sig, signer); !errors.Is(err, tdata.ErrWrongSigner) {
+		t.Fatalf("tampered tx err expected %v, got %v", tdata.ErrWrongSigner, err)
+	}
+	if err := tdata.VerifyTypedDataSignature(td, sig, common.Address{1}); !errors.Is(err, tdata.ErrWrongSigner) {
+		t.Fatalf("wrong signer err expected %v, got %v", tdata.ErrWrongSigner, err)
+	}
+	invalid := append([]byte{}, sig...)
+	invalid[crypto.RecoveryIDOffset] = 29
+	for _, s := range [][]byte{sig[:64], invalid} {
+		if err := tdata.VerifyTypedDataSignature(td, s, signer); !errors.Is(err, tdata.ErrInvalidSignature) {
+			t.Fatalf("err expected %v, got %v", tdata.ErrInvalidSignature, err)
+		}
+	}
+}
@@ -5,21 +5,30 @@
 package integration_test
 
 import (
+	"archive/tar"
+	"bytes"
 	"context"
 	"crypto/ecdsa"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"math/rand"
+	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"testing/iotest"
 	"time"
 
+	"github.com/ava-labs/avalanchego/database"
 	"github.com/ava-labs/avalanchego/database/manager"
 	"github.com/ava-labs/avalanchego/ids"
 	"github.com/ava-labs/avalanchego/snow"
@@ -82,14 +91,19 @@ var (
 
 	// when used with embedded VMs
 	genesisBytes []byte
+	airdropData  []byte
 	instances    []instance
 
 	genesis *chain.Genesis
 )
 
+// adminToken is the adminToken of every embedded VM's config
+const adminToken = "integration"
+
 type instance struct {
 	nodeID     ids.NodeID
 	vm         *vm.VM
+	db         database.Database
 	toEngine   chan common.Message
 	httpServer *httptest.Server
 	cli        client.Client // clients for embedded VMs
@@ -127,7 +141,7 @@ var _ = ginkgo.BeforeSuite(func() {
 			Balance: 10000000,
 		},
 	}
-	airdropData := []byte(fmt.Sprintf(`[{"address":"%s"}]`, sender2))
+	airdropData = []byte(fmt.Sprintf(`[{"address":"%s"}]`, sender2))
 	genesis.AirdropHash = ecommon.BytesToHash(crypto.Keccak256(airdropData)).Hex()
 	genesis.AirdropUnits = 1000000000
 	genesisBytes, err = json.Marshal(genesis)
@@ -145,44 +159,7 @@ var _ = ginkgo.BeforeSuite(func() {
 			ChainID:   chainID,
 			NodeID:    ids.GenerateTestNodeID(),
 		}
-
-		toEngine := make(chan common.Message, 1)
-		db := manager.NewMemDB(avago_version.CurrentDatabase)
-
-		// TODO: test appsender
-		v := &vm.VM{AirdropData: airdropData}
-		err := v.Initialize(
-			context.Background(),
-			ctx,
-			db,
-			genesisBytes,
-			nil,
-			nil,
-			toEngine,
-			nil,
-			app,
-		)
-		gomega.Ω(err).Should(gomega.BeNil())
-
-		var mb *vm.ManualBuilder
-		v.SetBlockBuilder(func() vm.BlockBuilder {
-			mb = v.NewManualBuilder()
-			return mb
-		})
-
-		var hd map[string]*common.HTTPHandler
-		hd, err = v.CreateHandlers(context.Background())
-		gomega.Ω(err).Should(gomega.BeNil())
-
-		httpServer := httptest.NewServer(hd[vm.PublicEndpoint].Handler)
-		instances[i] = instance{
-			nodeID:     ctx.NodeID,
-			vm:         v,
-			toEngine:   toEngine,
-			httpServer: httpServer,
-			cli:        client.New(httpServer.URL, requestTimeout),
-			builder:    mb,
-		}
+		instances[i] = createInstance(ctx, genesisBytes, airdropData, app)
 	}
 
 	// Verify genesis allocations loaded correctly (do here otherwise test may
@@ -203,6 +180,47 @@ var _ = ginkgo.BeforeSuite(func() {
 	color.Blue("created %d VMs", vms)
 })
 
+func createInstance(ctx *snow.Context, genesisBytes []byte, airdropData []byte, app common.AppSender) instance {
+	toEngine := make(chan common.Message, 1)
+	db := manager.NewMemDB(avago_version.CurrentDatabase)
+
+	// TODO: test appsender
+	v := &vm.VM{AirdropData: airdropData}
+	err := v.Initialize(
+		context.Background(),
+		ctx,
+		db,
+		genesisBytes,
+		nil,
+		[]byte(fmt.Sprintf(`{"adminToken":%q}`, adminToken)),
+		toEngine,
+		nil,
+		app,
+	)
+	gomega.Ω(err).Should(gomega.BeNil())
+
+	var mb *vm.ManualBuilder
+	v.SetBlockBuilder(func() vm.BlockBuilder {
+		mb = v.NewManualBuilder()
+		return mb
+	})
+
+	var hd map[string]*common.HTTPHandler
+	hd, err = v.CreateHandlers(context.Background())
+	gomega.Ω(err).Should(gomega.BeNil())
+
+	httpServer := httptest.NewServer(hd[vm.PublicEndpoint].Handler)
+	return instance{
+		nodeID:     ctx.NodeID,
+		vm:         v,
+		db:         db.Current().Database,
+		toEngine:   toEngine,
+		httpServer: httpServer,
+		cli:        client.New(httpServer.URL, requestTimeout),
+		builder:    mb,
+	}
+}
+
 var _ = ginkgo.AfterSuite(func() {
 	for _, iv := range instances {
 		iv.httpServer.Close()
@@ -235,6 +253,259 @@ var _ = ginkgo.Describe("[Network]", func() {
 	})
 })
 
+var _ = ginkgo.Describe("[IsCaughtUp]", func() {
+	ginkgo.It("reports caught up once a recent block is accepted", func() {
+		ctx := &snow.Context{
+			NetworkID: 1,
+			SubnetID:  ids.GenerateTestID(),
+			ChainID:   ids.GenerateTestID(),
+			NodeID:    ids.GenerateTestNodeID(),
+		}
+		inst := createInstance(ctx, genesisBytes, airdropData, nil)
+		defer func() {
+			inst.httpServer.Close()
+			gomega.Ω(inst.vm.Shutdown(context.Background())).Should(gomega.BeNil())
+		}()
+
+		ginkgo.By("not caught up while bootstrapping", func() {
+			gomega.Ω(inst.vm.SetState(context.Background(), snow.Bootstrapping)).Should(gomega.BeNil())
+			caughtUp, err := inst.cli.IsCaughtUp(context.Background())
+			gomega.Ω(err).Should(gomega.BeNil())
+			gomega.Ω(caughtUp).Should(gomega.BeFalse())
+		})
+
+		ginkgo.By("not caught up with only the genesis block", func() {
+			gomega.Ω(inst.vm.SetState(context.Background(), snow.NormalOp)).Should(gomega.BeNil())
+			caughtUp, err := inst.cli.IsCaughtUp(context.Background())
+			gomega.Ω(err).Should(gomega.BeNil())
+			gomega.Ω(caughtUp).Should(gomega.BeFalse())
+		})
+
+		ginkgo.By("caught up after accepting a recent block", func() {
+			createIssueRawTx(inst, &chain.SetTx{
+				BaseTx: &chain.BaseTx{},
+				Value:  []byte("caught up"),
+			}, priv)
+			expectBlkAccept(inst)
+
+			caughtUp, err := inst.cli.IsCaughtUp(context.Background())
+			gomega.Ω(err).Should(gomega.BeNil())
+			gomega.Ω(caughtUp).Should(gomega.BeTrue())
+		})
+	})
+})
+
+var _ = ginkgo.Describe("[Mirror]", func() {
+	ginkgo.It("mirrors missing keys from one VM to another", func() {
+		newInstance := func() instance {
+			return createInstance(&snow.Context{
+				NetworkID: 1,
+				SubnetID:  ids.GenerateTestID(),
+				ChainID:   ids.GenerateTestID(),
+				NodeID:    ids.GenerateTestNodeID(),
+			}, genesisBytes, airdropData, nil)
+		}
+		src, dst := newInstance(), newInstance()
+		defer func() {
+			for _, inst := range []instance{src, dst} {
+				inst.httpServer.Close()
+				gomega.Ω(inst.vm.Shutdown(context.Background())).Should(gomega.BeNil())
+			}
+		}()
+
+		values := [][]byte{[]byte("mirror 1"), []byte("mirror 2"), []byte("mirror 3")}
+		for _, v := range values {
+			createIssueRawTx(src, &chain.SetTx{BaseTx: &chain.BaseTx{}, Value: v}, priv)
+		}
+		expectBlkAccept(src)
+
+		// [dst] already has one of the values
+		createIssueRawTx(dst, &chain.SetTx{BaseTx: &chain.BaseTx{}, Value: values[1]}, priv)
+		expectBlkAccept(dst)
+
+		mirror := func() *client.MirrorStats {
+			c := make(chan struct{})
+			d := make(chan struct{})
+			go func() {
+				asyncBlockPush(dst, c)
+				close(d)
+			}()
+			stats, err := client.Mirror(
+				context.Background(), src.cli, dst.cli, priv,
+				ecommon.Hash{}, client.WithPollTx(),
+			)
+			close(c)
+			<-d
+			gomega.Ω(err).Should(gomega.BeNil())
+			gomega.Ω(stats.Next).Should(gomega.BeNil())
+			return stats
+		}
+
+		ginkgo.By("copying only missing keys", func() {
+			stats := mirror()
+			gomega.Ω(stats.Copied).Should(gomega.Equal(2))
+			gomega.Ω(stats.Skipped).Should(gomega.Equal(1))
+		})
+
+		ginkgo.By("having the same keys and values on both VMs", func() {
+			srcKeys, next, err := src.cli.ListKeys(context.Background(), ecommon.Hash{}, 0)
+			gomega.Ω(err).Should(gomega.BeNil())
+			gomega.Ω(next).Should(gomega.BeNil())
+			dstKeys, next, err := dst.cli.ListKeys(context.Background(), ecommon.Hash{}, 0)
+			gomega.Ω(err).Should(gomega.BeNil())
+			gomega.Ω(next).Should(gomega.BeNil())
+			gomega.Ω(dstKeys).Should(gomega.Equal(srcKeys))
+			gomega.Ω(srcKeys).Should(gomega.HaveLen(len(values)))
+
+			for _, k := range srcKeys {
+				_, sv, _, err := src.cli.Resolve(context.Background(), k)
+				gomega.Ω(err).Should(gomega.BeNil())
+				_, dv, _, err := dst.cli.Resolve(context.Background(), k)
+				gomega.Ω(err).Should(gomega.BeNil())
+				gomega.Ω(dv).Should(gomega.Equal(sv))
+			}
+		})
+
+		ginkgo.By("skipping all keys when run again", func() {
+			stats := mirror()
+			gomega.Ω(stats.Copied).Should(gomega.Equal(0))
+			gomega.Ω(stats.Skipped).Should(gomega.Equal(len(values)))
+		})
+	})
+})
+
+var _ = ginkgo.Describe("[Repair]", func() {
+	ginkgo.It("repairs a missing value from a peer", func() {
+		vms := map[ids.NodeID]*vm.VM{}
+		newInstance := func() instance {
+			nodeID := ids.GenerateTestNodeID()
+			inst := createInstance(&snow.Context{
+				NetworkID: 1,
+				SubnetID:  ids.GenerateTestID(),
+				ChainID:   ids.GenerateTestID(),
+				NodeID:    nodeID,
+			}, genesisBytes, airdropData, &peerSender{nodeID: nodeID, vms: vms})
+			vms[nodeID] = inst.vm
+			return inst
+		}
+		src, dst := newInstance(), newInstance()
+		defer func() {
+			for _, inst := range []instance{src, dst} {
+				inst.httpServer.Close()
+				gomega.Ω(inst.vm.Shutdown(context.Background())).Should(gomega.BeNil())
+			}
+		}()
+
+		values := [][]byte{[]byte("repair 1"), []byte("repair 2")}
+		for _, inst := range []instance{src, dst} {
+			for _, v := range values {
+				createIssueRawTx(inst, &chain.SetTx{BaseTx: &chain.BaseTx{}, Value: v}, priv)
+			}
+			expectBlkAccept(inst)
+		}
+		deleteValue := func(inst instance, k ecommon.Hash) {
+			vmeta, exists, err := chain.GetValueMeta(inst.db, k)
+			gomega.Ω(err).Should(gomega.BeNil())
+			gomega.Ω(exists).Should(gomega.BeTrue())
+			gomega.Ω(inst.db.Delete(chain.PrefixTxValueKey(vmeta.TxID))).Should(gomega.BeNil())
+		}
+		healthy, lost := genesis.ValueHash(values[0]), genesis.ValueHash(values[1])
+
+		// [dst] lost both values, [src] only one of them
+		deleteValue(dst, healthy)
+		deleteValue(dst, lost)
+		deleteValue(src, lost)
+		gomega.Ω(dst.vm.Connected(context.Background(), src.nodeID, nil)).Should(gomega.BeNil())
+
+		handlers, err := dst.vm.CreateHandlers(context.Background())
+		gomega.Ω(err).Should(gomega.BeNil())
+		adminServer := httptest.NewServer(handlers[vm.AdminEndpoint].Handler)
+		defer adminServer.Close()
+
+		ginkgo.By("restoring the value stored by the peer", func() {
+			repaired, unrepairable, err := client.NewAdmin(adminServer.URL, adminToken, requestTimeout).Repair(context.Background())
+			gomega.Ω(err).Should(gomega.BeNil())
+			gomega.Ω(repaired).Should(gomega.Equal([]ecommon.Hash{healthy}))
+			gomega.Ω(unrepairable).Should(gomega.Equal([]ecommon.Hash{lost}))
+
+			v, exists, err := chain.GetValue(dst.db, nil, healthy)
+			gomega.Ω(err).Should(gomega.BeNil())
+			gomega.Ω(exists).Should(gomega.BeTrue())
+			gomega.Ω(v).Should(gomega.Equal(values[0]))
+
+			// The node stays unhealthy until every value is restored
+			_, err = dst.vm.HealthCheck(context.Background())
+			gomega.Ω(errors.Is(err, vm.ErrCorruption)).Should(gomega.BeTrue())
+			gomega.Ω(err.Error()).Should(gomega.ContainSubstring("1 inconsistent values"))
+		})
+
+		ginkgo.By("reporting only the unrepairable value when run again", func() {
+			repaired, unrepairable, err := client.NewAdmin(adminServer.URL, adminToken, requestTimeout).Repair(context.Background())
+			gomega.Ω(err).Should(gomega.BeNil())
+			gomega.Ω(repaired).Should(gomega.BeEmpty())
+			gomega.Ω(unrepairable).Should(gomega.Equal([]ecommon.Hash{lost}))
+		})
+	})
+})
+
+var _ = ginkgo.Describe("[Bench]", func() {
+	ginkgo.It("reports non-zero throughput", func() {
+		inst := createInstance(&snow.Context{
+			NetworkID: 1,
+			SubnetID:  ids.GenerateTestID(),
+			ChainID:   ids.GenerateTestID(),
+			NodeID:    ids.GenerateTestNodeID(),
+		}, genesisBytes, airdropData, nil)
+		defer func() {
+			inst.httpServer.Close()
+			gomega.Ω(inst.vm.Shutdown(context.Background())).Should(gomega.BeNil())
+		}()
+
+		c := make(chan struct{})
+		d := make(chan struct{})
+		go func() {
+			asyncBlockPush(inst, c)
+			close(d)
+		}()
+		stats, err := client.Bench(
+			context.Background(), inst.cli, priv, 5*time.Second, 4, client.WithPollTx(),
+		)
+		close(c)
+		<-d
+		gomega.Ω(err).Should(gomega.BeNil())
+		gomega.Ω(stats.Issued).Should(gomega.BeNumerically(">", 0))
+		gomega.Ω(stats.Failed).Should(gomega.BeZero())
+		gomega.Ω(stats.TPS()).Should(gomega.BeNumerically(">", 0))
+		gomega.Ω(stats.AvgLatency()).Should(gomega.BeNumerically(">", 0))
+		gomega.Ω(stats.ErrorRate()).Should(gomega.BeZero())
+	})
+})
+
+var _ = ginkgo.Describe("[BasePath]", func() {
+	ginkgo.It("can ping a server mounted at a custom path", func() {
+		hd, err := instances[0].vm.CreateHandlers(context.Background())
+		gomega.Ω(err).Should(gomega.BeNil())
+
+		mux := http.NewServeMux()
+		mux.Handle("/custom/blobvm", hd[vm.PublicEndpoint].Handler)
+		httpServer := httptest.NewServer(mux)
+		defer httpServer.Close()
+
+		for _, cli := range []client.Client{
+			client.New(httpServer.URL, requestTimeout, client.WithBasePath("/custom/blobvm")),
+			client.New(httpServer.URL+"/custom/blobvm", requestTimeout, client.WithBasePath("")),
+		} {
+			ok, err := cli.Ping(context.Background())
+			gomega.Ω(err).Should(gomega.BeNil())
+			gomega.Ω(ok).Should(gomega.BeTrue())
+		}
+
+		// Default path is not served
+		_, err = client.New(httpServer.URL, requestTimeout).Ping(context.Background())
+		gomega.Ω(err).ShouldNot(gomega.BeNil())
+	})
+})
+
 var letterRunes = []rune("abcdefghijklmnopqrstuvwxyz")
 
 func RandStringRunes(n int) string {
@@ -261,6 +532,45 @@ var _ = ginkgo.Describe("Tx Types", func() {
 		}
 	})
 
+	ginkgo.It("get tip header", func() {
+		for _, inst := range instances {
+			tip, err := inst.cli.Tip(context.Background())
+			gomega.Ω(err).Should(gomega.BeNil())
+
+			la, err := inst.vm.LastAccepted(context.Background())
+			gomega.Ω(err).Should(gomega.BeNil())
+			blk, err := inst.vm.GetStatelessBlock(la)
+			gomega.Ω(err).Should(gomega.BeNil())
+
+			gomega.Ω(tip.ID).Should(gomega.Equal(la))
+			gomega.Ω(tip.Prnt).Should(gomega.Equal(blk.Prnt))
+			gomega.Ω(tip.Hght).Should(gomega.Equal(blk.Hght))
+			gomega.Ω(tip.Tmstmp).Should(gomega.Equal(blk.Tmstmp))
+			gomega.Ω(tip.Price).Should(gomega.Equal(blk.Price))
+			gomega.Ω(tip.Cost).Should(gomega.Equal(blk.Cost))
+		}
+	})
+
+	ginkgo.It("prepare tx matches individual endpoints", func() {
+		for _, inst := range instances {
+			magic, blkID, price, cost, err := inst.cli.PrepareTx(context.Background())
+			gomega.Ω(err).Should(gomega.BeNil())
+
+			g, err := inst.cli.Genesis(context.Background())
+			gomega.Ω(err).Should(gomega.BeNil())
+			gomega.Ω(magic).Should(gomega.Equal(g.Magic))
+
+			la, err := inst.cli.Accepted(context.Background())
+			gomega.Ω(err).Should(gomega.BeNil())
+			gomega.Ω(blkID).Should(gomega.Equal(la))
+
+			sPrice, sCost, err := inst.cli.SuggestedRawFee(context.Background())
+			gomega.Ω(err).Should(gomega.BeNil())
+			gomega.Ω(price).Should(gomega.Equal(sPrice))
+			gomega.Ω(cost).Should(gomega.Equal(sCost))
+		}
+	})
+
 	v := []byte(fmt.Sprintf("0x%064x", 1000000))
 	vh := chain.ValueHash(v)
 	ginkgo.It("Gossip SetTx to a different node", func() {
@@ -269,13 +579,29 @@ var _ = ginkgo.Describe("Tx Types", func() {
 			Value:  v,
 		}
 
+		var txID ids.ID
 		ginkgo.By("issue SetTx", func() {
 			ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
-			_, _, err := client.SignIssueRawTx(ctx, instances[0].cli, setTx, priv)
+			var err error
+			txID, _, err = client.SignIssueRawTx(ctx, instances[0].cli, setTx, priv)
 			cancel()
 			gomega.Ω(err).Should(gomega.BeNil())
 		})
 
+		ginkgo.By("report the pending SetTx in node 0's mempool", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+			defer cancel()
+			stats, err := instances[0].cli.MempoolStats(ctx)
+			gomega.Ω(err).Should(gomega.BeNil())
+			gomega.Ω(stats.Len).Should(gomega.Equal(1))
+			gomega.Ω(stats.FeeUnits).Should(gomega.BeNumerically(">", 0))
+			gomega.Ω(stats.MinPrice).Should(gomega.Equal(stats.MaxPrice))
+
+			txIDs, err := instances[0].cli.PendingTxIDs(ctx, 0)
+			gomega.Ω(err).Should(gomega.BeNil())
+			gomega.Ω(txIDs).Should(gomega.Equal([]ids.ID{txID}))
+		})
+
 		ginkgo.By("send gossip from node 0 to 1", func() {
 			newTxs := instances[0].vm.Mempool().NewTxs(genesis.TargetBlockSize)
 			gomega.Ω(len(newTxs)).To(gomega.Equal(1))
@@ -442,11 +768,1140 @@ var _ = ginkgo.Describe("Tx Types", func() {
 		}
 	})
 
+	ginkgo.It("sweeps an account to zero", func() {
+		swept, err := crypto.GenerateKey()
+		gomega.Ω(err).Should(gomega.BeNil())
+		sweptAddr := crypto.PubkeyToAddress(swept.PublicKey)
+
+		ginkgo.By("fund the account", func() {
+			createIssueRawTx(instances[0], &chain.TransferTx{
+				BaseTx: &chain.BaseTx{},
+				To:     sweptAddr,
+				Units:  10000,
+			}, priv)
+			expectBlkAccept(instances[0])
+		})
+
+		before, err := instances[0].cli.Balance(context.Background(), sender2)
+		gomega.Ω(err).Should(gomega.BeNil())
+		var units uint64
+		ginkgo.By("transfer the entire balance", func() {
+			_, units, err = client.SignIssueSweepTx(context.Background(), instances[0].cli, sender2, swept)
+			gomega.Ω(err).Should(gomega.BeNil())
+			gomega.Ω(units).Should(gomega.BeNumerically(">", 0))
+			expectBlkAccept(instances[0])
+		})
+
+		bal, err := instances[0].cli.Balance(context.Background(), sweptAddr)
+		gomega.Ω(err).Should(gomega.BeNil())
+		gomega.Ω(bal).Should(gomega.BeZero())
+		after, err := instances[0].cli.Balance(context.Background(), sender2)
+		gomega.Ω(err).Should(gomega.BeNil())
+		gomega.Ω(after).Should(gomega.Equal(before + units))
+
+		ginkgo.By("reject sweeping an empty account", func() {
+			_, _, err := client.SignIssueSweepTx(context.Background(), instances[0].cli, sender2, swept)
+			gomega.Ω(errors.Is(err, client.ErrBalanceTooLow)).Should(gomega.BeTrue())
+		})
+	})
+
 	// TODO: full replicate blocks between nodes
 })
 
+var _ = ginkgo.Describe("[Tree]", func() {
+	var inst instance
+	ginkgo.BeforeEach(func() {
+		inst = createInstance(&snow.Context{
+			NetworkID: 1,
+			SubnetID:  ids.GenerateTestID(),
+			ChainID:   ids.GenerateTestID(),
+			NodeID:    ids.GenerateTestNodeID(),
+		}, genesisBytes, airdropData, nil)
+	})
+	ginkgo.AfterEach(func() {
+		inst.httpServer.Close()
+		gomega.Ω(inst.vm.Shutdown(context.Background())).Should(gomega.BeNil())
+	})
+
+	ginkgo.It("diffs a file and its appended version", func() {
+		original := []byte(RandStringRunes(450 * units.KiB))
+		appended := append(append([]byte{}, original...), 'a')
+
+		oldRoot := uploadBytes(inst, original)
+		newRoot := uploadBytes(inst, appended)
+
+		d, err := tree.Diff(context.Background(), inst.cli, oldRoot, newRoot)
+		gomega.Ω(err).Should(gomega.BeNil())
+		gomega.Ω(d.Unchanged).Should(gomega.HaveLen(2))
+		gomega.Ω(d.Added).Should(gomega.HaveLen(1))
+		gomega.Ω(d.Removed).Should(gomega.HaveLen(1))
+		gomega.Ω(d.UnchangedBytes).Should(gomega.Equal(2 * genesis.MaxValueSize))
+		gomega.Ω(d.AddedBytes).Should(gomega.Equal(uint64(len(appended)) - 2*genesis.MaxValueSize))
+		gomega.Ω(d.RemovedBytes).Should(gomega.Equal(uint64(len(original)) - 2*genesis.MaxValueSize))
+	})
+
+	ginkgo.It("diffs the chunks under multi-level roots", func() {
+		chunks := [][]byte{[]byte("chunk a"), []byte("chunk b"), []byte("chunk c"), []byte("chunk d!")}
+		for _, c := range chunks {
+			createIssueRawTx(inst, &chain.SetTx{BaseTx: &chain.BaseTx{}, Value: c}, priv)
+		}
+		storeRoot := func(r *tree.Root) ecommon.Hash {
+			rb, err := json.Marshal(r)
+			gomega.Ω(err).Should(gomega.BeNil())
+			createIssueRawTx(inst, &chain.SetTx{BaseTx: &chain.BaseTx{}, Value: rb}, priv)
+			return chain.ValueHash(rb)
+		}
+		leaves := func(cs ...[]byte) *tree.Root {
+			r := &tree.Root{}
+			for _, c := range cs {
+				r.Children = append(r.Children, chain.ValueHash(c))
+			}
+			return r
+		}
+		// Both files share their first sub-root, and their second ones differ
+		// in their last chunk
+		shared := storeRoot(leaves(chunks[0], chunks[1]))
+		oldRoot := storeRoot(&tree.Root{Children: []ecommon.Hash{shared, storeRoot(leaves(chunks[0], chunks[2]))}, Height: 1})
+		newRoot := storeRoot(&tree.Root{Children: []ecommon.Hash{shared, storeRoot(leaves(chunks[3]))}, Height: 1})
+		expectBlkAccept(inst)
+
+		// Only roots are fetched: chunk sizes are read from their metadata
+		cli := &resolveCounter{Client: inst.cli}
+		d, err := tree.Diff(context.Background(), cli, oldRoot, newRoot)
+		gomega.Ω(err).Should(gomega.BeNil())
+		gomega.Ω(cli.resolved).Should(gomega.HaveLen(6))
+		gomega.Ω(d.Unchanged).Should(gomega.Equal([]ecommon.Hash{chain.ValueHash(chunks[0]), chain.ValueHash(chunks[1])}))
+		gomega.Ω(d.Added).Should(gomega.Equal([]ecommon.Hash{chain.ValueHash(chunks[3])}))
+		gomega.Ω(d.Removed).Should(gomega.Equal([]ecommon.Hash{chain.ValueHash(chunks[2])}))
+		gomega.Ω(d.UnchangedBytes).Should(gomega.Equal(uint64(len(chunks[0]) + len(chunks[1]))))
+		gomega.Ω(d.AddedBytes).Should(gomega.Equal(uint64(len(chunks[3]))))
+		gomega.Ω(d.RemovedBytes).Should(gomega.Equal(uint64(len(chunks[2]))))
+	})
+
+	ginkgo.It("uploads only the delta against a base root", func() {
+		original := []byte(RandStringRunes(450 * units.KiB))
+		modified := append([]byte{}, original...)
+		modified[genesis.MaxValueSize+1] ^= 0xff // modify second chunk
+
+		baseRoot := uploadBytes(inst, original)
+
+		bal, err := inst.cli.Balance(context.Background(), sender)
+		gomega.Ω(err).Should(gomega.BeNil())
+
+		c := make(chan struct{})
+		d := make(chan struct{})
+		go func() {
+			asyncBlockPush(inst, c)
+			close(d)
+		}()
+		newRoot, stats, err := tree.UploadDelta(
+			context.Background(), inst.cli, priv, baseRoot,
+			bytes.NewReader(modified), int(genesis.MaxValueSize),
+		)
+		close(c)
+		<-d
+		gomega.Ω(err).Should(gomega.BeNil())
+		gomega.Ω(stats.UploadedChunks).Should(gomega.Equal(1))
+		gomega.Ω(stats.UploadedBytes).Should(gomega.Equal(genesis.MaxValueSize))
+		gomega.Ω(stats.ReusedChunks).Should(gomega.Equal(2))
+		gomega.Ω(stats.ReusedBytes).Should(gomega.Equal(uint64(len(modified)) - genesis.MaxValueSize))
+
+		newBal, err := inst.cli.Balance(context.Background(), sender)
+		gomega.Ω(err).Should(gomega.BeNil())
+		gomega.Ω(bal - newBal).Should(gomega.Equal(stats.Cost))
+
+		var buf bytes.Buffer
+		gomega.Ω(tree.Download(context.Background(), inst.cli, newRoot, &buf)).Should(gomega.BeNil())
+		gomega.Ω(buf.Bytes()).Should(gomega.Equal(modified))
+	})
+
+	ginkgo.It("uploads the base root's chunks that are no longer stored", func() {
+		data := []byte(RandStringRunes(int(genesis.MaxValueSize) + 100))
+		first, second := data[:genesis.MaxValueSize], data[genesis.MaxValueSize:]
+
+		// The base root references both chunks (through a sub-root), but only
+		// the first is still stored
+		createIssueRawTx(inst, &chain.SetTx{BaseTx: &chain.BaseTx{}, Value: first}, priv)
+		sb, err := json.Marshal(&tree.Root{Children: []ecommon.Hash{chain.ValueHash(first), chain.ValueHash(second)}})
+		gomega.Ω(err).Should(gomega.BeNil())
+		createIssueRawTx(inst, &chain.SetTx{BaseTx: &chain.BaseTx{}, Value: sb}, priv)
+		rb, err := json.Marshal(&tree.Root{Children: []ecommon.Hash{chain.ValueHash(sb)}, Height: 1})
+		gomega.Ω(err).Should(gomega.BeNil())
+		createIssueRawTx(inst, &chain.SetTx{BaseTx: &chain.BaseTx{}, Value: rb}, priv)
+		expectBlkAccept(inst)
+
+		c := make(chan struct{})
+		d := make(chan struct{})
+		go func() {
+			asyncBlockPush(inst, c)
+			close(d)
+		}()
+		// Without the on-chain check, only the base root's chunks can be
+		// reused without issuing a tx
+		newRoot, stats, err := tree.UploadDelta(
+			context.Background(), inst.cli, priv, chain.ValueHash(rb),
+			bytes.NewReader(data), int(genesis.MaxValueSize), tree.WithSkipDedupCheck(),
+		)
+		close(c)
+		<-d
+		gomega.Ω(err).Should(gomega.BeNil())
+		gomega.Ω(stats.ReusedChunks).Should(gomega.Equal(1))
+		gomega.Ω(stats.UploadedChunks).Should(gomega.Equal(1))
+		gomega.Ω(stats.UploadedBytes).Should(gomega.Equal(uint64(len(second))))
+		gomega.Ω(stats.Txs).Should(gomega.Equal(2))
+
+		var buf bytes.Buffer
+		gomega.Ω(tree.Download(context.Background(), inst.cli, newRoot, &buf)).Should(gomega.BeNil())
+		gomega.Ω(buf.Bytes()).Should(gomega.Equal(data))
+	})
+
+	ginkgo.It("stops uploading after cancellation", func() {
+		data := []byte(RandStringRunes(450 * units.KiB))
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		// Cancel as soon as the second chunk is read
+		r := &cancelReader{r: bytes.NewReader(data), cancelAt: 2, cancel: cancel}
+
+		c := make(chan struct{})
+		d := make(chan struct{})
+		go func() {
+			asyncBlockPush(inst, c)
+			close(d)
+		}()
+		_, err := tree.Upload(ctx, inst.cli, priv, r, int(genesis.MaxValueSize))
+		close(c)
+		<-d
+
+		var ierr *tree.InterruptedError
+		gomega.Ω(errors.As(err, &ierr)).Should(gomega.BeTrue())
+		gomega.Ω(errors.Is(err, context.Canceled)).Should(gomega.BeTrue())
+		gomega.Ω(ierr.Completed).Should(gomega.Equal([]ecommon.Hash{chain.ValueHash(data[:genesis.MaxValueSize])}))
+
+		// No further chunks were submitted
+		gomega.Ω(inst.vm.Mempool().Len()).Should(gomega.Equal(0))
+		exists, _, _, err := inst.cli.Resolve(
+			context.Background(),
+			chain.ValueHash(data[genesis.MaxValueSize:2*genesis.MaxValueSize]),
+		)
+		gomega.Ω(err).Should(gomega.BeNil())
+		gomega.Ω(exists).Should(gomega.BeFalse())
+	})
+
+	ginkgo.It("resumes an interrupted upload from a checkpoint", func() {
+		data := []byte(RandStringRunes(450 * units.KiB))
+		checkpoint := filepath.Join(ginkgo.GinkgoT().TempDir(), "checkpoint.json")
+		first := chain.ValueHash(data[:genesis.MaxValueSize])
+
+		upload := func(ctx context.Context, r io.Reader) (ecommon.Hash, *tree.UploadStats, error) {
+			c := make(chan struct{})
+			d := make(chan struct{})
+			go func() {
+				asyncBlockPush(inst, c)
+				close(d)
+			}()
+			root, stats, err := tree.UploadResumable(
+				ctx, inst.cli, priv, r, int(genesis.MaxValueSize), checkpoint,
+			)
+			close(c)
+			<-d
+			return root, stats, err
+		}
+
+		ginkgo.By("recording confirmed chunks before the interruption", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			r := &cancelReader{r: bytes.NewReader(data), cancelAt: 2, cancel: cancel}
+			_, _, err := upload(ctx, r)
+			gomega.Ω(errors.Is(err, context.Canceled)).Should(gomega.BeTrue())
+
+			b, err := os.ReadFile(checkpoint)
+			gomega.Ω(err).Should(gomega.BeNil())
+			var cp tree.Checkpoint
+			gomega.Ω(json.Unmarshal(b, &cp)).Should(gomega.BeNil())
+			gomega.Ω(cp.ChunkSize).Should(gomega.Equal(int(genesis.MaxValueSize)))
+			gomega.Ω(cp.Chunks).Should(gomega.Equal([]ecommon.Hash{first}))
+		})
+
+		ginkgo.By("skipping checkpointed chunks when resumed", func() {
+			root, stats, err := upload(context.Background(), bytes.NewReader(data))
+			gomega.Ω(err).Should(gomega.BeNil())
+			gomega.Ω(stats.ReusedChunks).Should(gomega.Equal(1))
+			gomega.Ω(stats.UploadedChunks).Should(gomega.Equal(2))
+
+			_, err = os.Stat(checkpoint)
+			gomega.Ω(os.IsNotExist(err)).Should(gomega.BeTrue())
+
+			var buf bytes.Buffer
+			gomega.Ω(tree.Download(context.Background(), inst.cli, root, &buf)).Should(gomega.BeNil())
+			gomega.Ω(buf.Bytes()).Should(gomega.Equal(data))
+		})
+
+		ginkgo.By("ignoring stale and corrupt checkpoints", func() {
+			stale, err := json.Marshal(&tree.Checkpoint{
+				ChunkSize: int(genesis.MaxValueSize),
+				Chunks:    []ecommon.Hash{first},
+			})
+			gomega.Ω(err).Should(gomega.BeNil())
+			for _, b := range [][]byte{stale, []byte("not json")} {
+				gomega.Ω(os.WriteFile(checkpoint, b, 0o600)).Should(gomega.BeNil())
+				other := []byte(RandStringRunes(450 * units.KiB))
+				root, _, err := upload(context.Background(), bytes.NewReader(other))
+				gomega.Ω(err).Should(gomega.BeNil())
+
+				var buf bytes.Buffer
+				gomega.Ω(tree.Download(context.Background(), inst.cli, root, &buf)).Should(gomega.BeNil())
+				gomega.Ω(buf.Bytes()).Should(gomega.Equal(other))
+			}
+		})
+	})
+
+	ginkgo.It("uploads chunks in batches", func() {
+		chunkSize := 10 * units.KiB
+		data := []byte(RandStringRunes(10*chunkSize - 1))
+
+		c := make(chan struct{})
+		d := make(chan struct{})
+		go func() {
+			asyncBlockPush(inst, c)
+			close(d)
+		}()
+		root, err := tree.Upload(
+			context.Background(), inst.cli, priv,
+			bytes.NewReader(data), chunkSize, tree.WithBatching(),
+		)
+		close(c)
+		<-d
+		gomega.Ω(err).Should(gomega.BeNil())
+
+		// All chunks fit in a single batch (followed by the root)
+		activity, err := inst.cli.RecentActivity(context.Background())
+		gomega.Ω(err).Should(gomega.BeNil())
+		gomega.Ω(activity).Should(gomega.HaveLen(2))
+		batch := activity[1]
+		if batch.Typ != chain.Batch {
+			batch = activity[0]
+		}
+		gomega.Ω(batch.Typ).Should(gomega.Equal(chain.Batch))
+		gomega.Ω(batch.Keys).Should(gomega.HaveLen(10))
+		gomega.Ω(batch.Size).Should(gomega.Equal(uint64(len(data))))
+
+		var buf bytes.Buffer
+		gomega.Ω(tree.Download(context.Background(), inst.cli, root, &buf)).Should(gomega.BeNil())
+		gomega.Ω(buf.Bytes()).Should(gomega.Equal(data))
+	})
+
+	ginkgo.It("uploads a large file concurrently", func() {
+		// Txs referencing the genesis block are pruned once any block is
+		// accepted (genesis is outside of the lookback window), so make sure the
+		// concurrent txs don't all reference it
+		uploadBytes(inst, []byte(RandStringRunes(units.KiB)))
+
+		data := []byte(RandStringRunes(10*int(genesis.MaxValueSize) + 1))
+		p := filepath.Join(ginkgo.GinkgoT().TempDir(), "large")
+		gomega.Ω(os.WriteFile(p, data, 0o600)).Should(gomega.BeNil())
+		f, err := os.Open(p)
+		gomega.Ω(err).Should(gomega.BeNil())
+		defer f.Close()
+
+		c := make(chan struct{})
+		d := make(chan struct{})
+		go func() {
+			asyncBlockPush(inst, c)
+			close(d)
+		}()
+		root, err := tree.Upload(
+			context.Background(), inst.cli, priv,
+			f, int(genesis.MaxValueSize), tree.WithUploadConcurrency(4),
+		)
+		close(c)
+		<-d
+		gomega.Ω(err).Should(gomega.BeNil())
+
+		var buf bytes.Buffer
+		gomega.Ω(tree.Download(context.Background(), inst.cli, root, &buf)).Should(gomega.BeNil())
+		gomega.Ω(buf.Bytes()).Should(gomega.Equal(data))
+	})
+
+	ginkgo.It("compresses chunks", func() {
+		var lines bytes.Buffer
+		for i := 0; lines.Len() < 3*int(genesis.MaxValueSize); i++ {
+			fmt.Fprintf(&lines, "%d: request served in %dms\n", i, i%97)
+		}
+		incompressible := make([]byte, 2*genesis.MaxValueSize)
+		_, err := rand.Read(incompressible) //nolint:gosec
+		gomega.Ω(err).Should(gomega.BeNil())
+
+		for _, data := range [][]byte{lines.Bytes(), incompressible} {
+			root := uploadBytes(inst, data, tree.WithCompression(tree.Gzip))
+
+			exists, rb, _, err := inst.cli.Resolve(context.Background(), root)
+			gomega.Ω(err).Should(gomega.BeNil())
+			gomega.Ω(exists).Should(gomega.BeTrue())
+			r := new(tree.Root)
+			gomega.Ω(json.Unmarshal(rb, r)).Should(gomega.BeNil())
+			gomega.Ω(r.Compression).Should(gomega.Equal(tree.Gzip))
+			gomega.Ω(r.Size).Should(gomega.Equal(uint64(len(data))))
+
+			stored := uint64(0)
+			for _, h := range r.Children {
+				_, _, vmeta, err := inst.cli.Resolve(context.Background(), h)
+				gomega.Ω(err).Should(gomega.BeNil())
+				gomega.Ω(vmeta.Size).Should(gomega.BeNumerically("<=", genesis.MaxValueSize))
+				stored += vmeta.Size
+			}
+			if bytes.Equal(data, lines.Bytes()) {
+				gomega.Ω(stored).Should(gomega.BeNumerically("<", len(data)/4))
+			}
+
+			var buf bytes.Buffer
+			gomega.Ω(tree.Download(context.Background(), inst.cli, root, &buf)).Should(gomega.BeNil())
+			gomega.Ω(buf.Bytes()).Should(gomega.Equal(data))
+		}
+
+		ginkgo.By("reusing compressed chunks", func() {
+			c := make(chan struct{})
+			d := make(chan struct{})
+			go func() {
+				asyncBlockPush(inst, c)
+				close(d)
+			}()
+			// Only the last chunk of the appended file differs
+			appended := append(lines.Bytes(), []byte("appended\n")...)
+			_, stats, err := tree.UploadResumable(
+				context.Background(), inst.cli, priv, bytes.NewReader(appended), int(genesis.MaxValueSize),
+				filepath.Join(ginkgo.GinkgoT().TempDir(), "checkpoint"), tree.WithCompression(tree.Gzip),
+			)
+			close(c)
+			<-d
+			gomega.Ω(err).Should(gomega.BeNil())
+			gomega.Ω(stats.UploadedChunks).Should(gomega.Equal(1))
+			gomega.Ω(stats.ReusedChunks).Should(gomega.BeNumerically(">", 0))
+		})
+	})
+
+	ginkgo.It("uploads files on chunk boundaries", func() {
+		chunkSize := int(genesis.MaxValueSize)
+		for _, tv := range []struct {
+			size     int
+			children int
+		}{
+			{size: units.KiB, children: 0},     // small file optimization
+			{size: chunkSize - 1, children: 1}, // too big to store in the root once encoded
+			{size: chunkSize, children: 1},
+			{size: chunkSize + 1, children: 2},
+			{size: 2 * chunkSize, children: 2},
+		} {
+			data := []byte(RandStringRunes(tv.size))
+			root := uploadBytes(inst, data)
+
+			exists, rb, _, err := inst.cli.Resolve(context.Background(), root)
+			gomega.Ω(err).Should(gomega.BeNil())
+			gomega.Ω(exists).Should(gomega.BeTrue())
+			r := new(tree.Root)
+			gomega.Ω(json.Unmarshal(rb, r)).Should(gomega.BeNil())
+			gomega.Ω(r.Children).Should(gomega.HaveLen(tv.children), "size=%d", tv.size)
+			if tv.children == 0 {
+				gomega.Ω(r.Contents).Should(gomega.Equal(data))
+			} else {
+				gomega.Ω(r.Contents).Should(gomega.BeEmpty())
+			}
+
+			var buf bytes.Buffer
+			gomega.Ω(tree.Download(context.Background(), inst.cli, root, &buf)).Should(gomega.BeNil())
+			gomega.Ω(buf.Bytes()).Should(gomega.Equal(data), "size=%d", tv.size)
+		}
+
+		_, err := tree.Upload(context.Background(), inst.cli, priv, bytes.NewReader(nil), chunkSize)
+		gomega.Ω(err).Should(gomega.MatchError(tree.ErrEmpty))
+	})
+
+	ginkgo.It("uploads files with chunks smaller than the max value size", func() {
+		chunkSize := 4 * units.KiB
+		data := []byte(RandStringRunes(3*chunkSize + chunkSize/2))
+		c := make(chan struct{})
+		d := make(chan struct{})
+		go func() {
+			asyncBlockPush(inst, c)
+			close(d)
+		}()
+		root, err := tree.Upload(context.Background(), inst.cli, priv, bytes.NewReader(data), chunkSize)
+		close(c)
+		<-d
+		gomega.Ω(err).Should(gomega.BeNil())
+
+		exists, rb, _, err := inst.cli.Resolve(context.Background(), root)
+		gomega.Ω(err).Should(gomega.BeNil())
+		gomega.Ω(exists).Should(gomega.BeTrue())
+		r := new(tree.Root)
+		gomega.Ω(json.Unmarshal(rb, r)).Should(gomega.BeNil())
+		gomega.Ω(r.Children).Should(gomega.HaveLen(4))
+		for _, h := range r.Children {
+			_, v, _, err := inst.cli.Resolve(context.Background(), h)
+			gomega.Ω(err).Should(gomega.BeNil())
+			gomega.Ω(len(v)).Should(gomega.BeNumerically("<=", chunkSize))
+		}
+
+		var buf bytes.Buffer
+		gomega.Ω(tree.Download(context.Background(), inst.cli, root, &buf)).Should(gomega.BeNil())
+		gomega.Ω(buf.Bytes()).Should(gomega.Equal(data))
+
+		for _, size := range []int{0, int(genesis.MaxValueSize) + 1} {
+			_, err := tree.Upload(context.Background(), inst.cli, priv, bytes.NewReader(data), size)
+			gomega.Ω(err).Should(gomega.MatchError(tree.ErrInvalidChunkSize), "size=%d", size)
+		}
+	})
+
+	ginkgo.It("picks content-defined chunk boundaries", func() {
+		// Boundaries depend on the data, so use the same data every run
+		data := make([]byte, 4*genesis.MaxValueSize)
+		rand.New(rand.NewSource(1)).Read(data) //nolint:gosec
+		root := uploadBytes(inst, data, tree.WithChunking(tree.Buzhash))
+
+		exists, rb, _, err := inst.cli.Resolve(context.Background(), root)
+		gomega.Ω(err).Should(gomega.BeNil())
+		gomega.Ω(exists).Should(gomega.BeTrue())
+		r := new(tree.Root)
+		gomega.Ω(json.Unmarshal(rb, r)).Should(gomega.BeNil())
+		gomega.Ω(r.Chunking).Should(gomega.Equal(tree.Buzhash))
+		gomega.Ω(len(r.Children)).Should(gomega.BeNumerically(">", 4))
+
+		var buf bytes.Buffer
+		gomega.Ω(tree.Download(context.Background(), inst.cli, root, &buf)).Should(gomega.BeNil())
+		gomega.Ω(buf.Bytes()).Should(gomega.Equal(data))
+
+		ginkgo.By("reusing the chunks after an insertion", func() {
+			c := make(chan struct{})
+			d := make(chan struct{})
+			go func() {
+				asyncBlockPush(inst, c)
+				close(d)
+			}()
+			edited := append(append(append([]byte{}, data[:100]...), "inserted"...), data[100:]...)
+			_, stats, err := tree.UploadResumable(
+				context.Background(), inst.cli, priv, bytes.NewReader(edited), int(genesis.MaxValueSize),
+				filepath.Join(ginkgo.GinkgoT().TempDir(), "checkpoint"), tree.WithChunking(tree.Buzhash),
+			)
+			close(c)
+			<-d
+			gomega.Ω(err).Should(gomega.BeNil())
+			gomega.Ω(stats.UploadedChunks).Should(gomega.Equal(1))
+			gomega.Ω(stats.ReusedChunks).Should(gomega.Equal(len(r.Children) - 1))
+		})
+	})
+
+	ginkgo.It("skips the dedup check of each chunk", func() {
+		upload := func(cli client.Client, data []byte, opts ...tree.UploadOption) (ecommon.Hash, *tree.UploadStats) {
+			c := make(chan struct{})
+			d := make(chan struct{})
+			go func() {
+				asyncBlockPush(inst, c)
+				close(d)
+			}()
+			root, stats, err := tree.UploadResumable(
+				context.Background(), cli, priv, bytes.NewReader(data), int(genesis.MaxValueSize),
+				filepath.Join(ginkgo.GinkgoT().TempDir(), "checkpoint"), opts...,
+			)
+			close(c)
+			<-d
+			gomega.Ω(err).Should(gomega.BeNil())
+			return root, stats
+		}
+		data := make([]byte, 3*genesis.MaxValueSize)
+		_, err := rand.Read(data) //nolint:gosec
+		gomega.Ω(err).Should(gomega.BeNil())
+
+		cli := &hasKeysCounter{Client: inst.cli}
+		_, stats := upload(cli, data)
+		gomega.Ω(stats.UploadedChunks).Should(gomega.Equal(3))
+		gomega.Ω(cli.calls).Should(gomega.Equal(3))
+
+		ginkgo.By("uploading new chunks without checking them", func() {
+			cli := &hasKeysCounter{Client: inst.cli}
+			data := make([]byte, 3*genesis.MaxValueSize)
+			_, err := rand.Read(data) //nolint:gosec
+			gomega.Ω(err).Should(gomega.BeNil())
+			_, stats := upload(cli, data, tree.WithSkipDedupCheck())
+			gomega.Ω(stats.UploadedChunks).Should(gomega.Equal(3))
+			gomega.Ω(cli.calls).Should(gomega.Equal(0))
+		})
+
+		ginkgo.By("reusing stored chunks once their txs are rejected", func() {
+			cli := &hasKeysCounter{Client: inst.cli}
+			extended := append(append([]byte{}, data...), "extra chunk"...)
+			_, stats := upload(cli, extended, tree.WithSkipDedupCheck())
+			gomega.Ω(stats.UploadedChunks).Should(gomega.Equal(1))
+			gomega.Ω(stats.ReusedChunks).Should(gomega.Equal(3))
+			gomega.Ω(cli.calls).Should(gomega.Equal(0))
+		})
+
+		ginkgo.By("reissuing batches without their stored chunks", func() {
+			cli := &hasKeysCounter{Client: inst.cli}
+			extended := append(append([]byte{}, data...), "another extra chunk"...)
+			root, stats := upload(cli, extended, tree.WithSkipDedupCheck(), tree.WithBatching())
+			gomega.Ω(stats.UploadedChunks).Should(gomega.Equal(1))
+			gomega.Ω(stats.ReusedChunks).Should(gomega.Equal(3))
+			// Only checked once a batch is rejected
+			gomega.Ω(cli.calls).Should(gomega.BeNumerically(">", 0))
+
+			var buf bytes.Buffer
+			gomega.Ω(tree.Download(context.Background(), inst.cli, root, &buf)).Should(gomega.BeNil())
+			gomega.Ω(buf.Bytes()).Should(gomega.Equal(extended))
+		})
+	})
+
+	ginkgo.It("prechecks the stored chunks in one batch", func() {
+		upload := func(cli client.Client, data []byte, opts ...tree.UploadOption) (ecommon.Hash, *tree.UploadStats) {
+			c := make(chan struct{})
+			d := make(chan struct{})
+			go func() {
+				asyncBlockPush(inst, c)
+				close(d)
+			}()
+			root, stats, err := tree.UploadResumable(
+				context.Background(), cli, priv, bytes.NewReader(data), int(genesis.MaxValueSize),
+				filepath.Join(ginkgo.GinkgoT().TempDir(), "checkpoint"), opts...,
+			)
+			close(c)
+			<-d
+			gomega.Ω(err).Should(gomega.BeNil())
+			return root, stats
+		}
+		data := make([]byte, 3*genesis.MaxValueSize)
+		_, err := rand.Read(data) //nolint:gosec
+		gomega.Ω(err).Should(gomega.BeNil())
+		upload(inst.cli, data[:2*genesis.MaxValueSize])
+
+		keys, err := tree.ChunkKeys(context.Background(), inst.cli, bytes.NewReader(data), int(genesis.MaxValueSize))
+		gomega.Ω(err).Should(gomega.BeNil())
+		gomega.Ω(keys).Should(gomega.HaveLen(3))
+		stored, err := tree.StoredChunks(context.Background(), inst.cli, keys)
+		gomega.Ω(err).Should(gomega.BeNil())
+		gomega.Ω(stored).Should(gomega.Equal([]bool{true, true, false}))
+
+		cli := &hasKeysCounter{Client: inst.cli}
+		root, stats := upload(cli, data, tree.WithPrecheck())
+		gomega.Ω(stats.UploadedChunks).Should(gomega.Equal(1))
+		gomega.Ω(stats.ReusedChunks).Should(gomega.Equal(2))
+		gomega.Ω(cli.calls).Should(gomega.Equal(1))
+
+		var buf bytes.Buffer
+		gomega.Ω(tree.Download(context.Background(), inst.cli, root, &buf)).Should(gomega.BeNil())
+		gomega.Ω(buf.Bytes()).Should(gomega.Equal(data))
+
+		ginkgo.By("rejecting files that can't be read twice", func() {
+			_, err := tree.Upload(
+				context.Background(), inst.cli, priv, io.LimitReader(bytes.NewReader(data), int64(len(data))),
+				int(genesis.MaxValueSize), tree.WithPrecheck(),
+			)
+			gomega.Ω(errors.Is(err, tree.ErrNotSeekable)).Should(gomega.BeTrue())
+		})
+	})
+
+	ginkgo.It("accumulates dedup stats across uploads", func() {
+		dedup := &tree.DedupStats{}
+		upload := func(data []byte) {
+			c := make(chan struct{})
+			d := make(chan struct{})
+			go func() {
+				asyncBlockPush(inst, c)
+				close(d)
+			}()
+			_, err := tree.Upload(
+				context.Background(), inst.cli, priv, bytes.NewReader(data), int(genesis.MaxValueSize),
+				tree.WithDedupStats(dedup),
+			)
+			close(c)
+			<-d
+			gomega.Ω(err).Should(gomega.BeNil())
+		}
+		size := int(genesis.MaxValueSize)
+		data := make([]byte, 3*size)
+		_, err := rand.Read(data) //nolint:gosec
+		gomega.Ω(err).Should(gomega.BeNil())
+		upload(data)
+
+		// Shares its first 2 chunks with [data]
+		overlapping := make([]byte, 3*size)
+		copy(overlapping, data[:2*size])
+		_, err = rand.Read(overlapping[2*size:]) //nolint:gosec
+		gomega.Ω(err).Should(gomega.BeNil())
+		upload(overlapping)
+
+		totals := dedup.Totals()
+		gomega.Ω(totals.Uploads).Should(gomega.Equal(2))
+		gomega.Ω(totals.UploadedChunks).Should(gomega.Equal(4))
+		gomega.Ω(totals.UploadedBytes).Should(gomega.Equal(uint64(4 * size)))
+		gomega.Ω(totals.DedupedChunks).Should(gomega.Equal(2))
+		gomega.Ω(totals.DedupedBytes).Should(gomega.Equal(uint64(2 * size)))
+		gomega.Ω(totals.HitRate()).Should(gomega.BeNumerically("~", 2.0/6))
+		// A tx per uploaded chunk and root
+		gomega.Ω(totals.Txs).Should(gomega.Equal(6))
+		gomega.Ω(totals.Since.IsZero()).Should(gomega.BeFalse())
+
+		gomega.Ω(dedup.Reset()).Should(gomega.Equal(totals))
+		gomega.Ω(dedup.Totals()).Should(gomega.Equal(tree.DedupTotals{}))
+	})
+
+	ginkgo.It("rejects trees deeper than the max depth", func() {
+		// Store a leaf chunk under 5 levels of roots
+		leaf := []byte("leaf chunk")
+		createIssueRawTx(inst, &chain.SetTx{BaseTx: &chain.BaseTx{}, Value: leaf}, priv)
+		roots := []ecommon.Hash{}
+		child := chain.ValueHash(leaf)
+		for height := uint64(0); height < 5; height++ {
+			rb, err := json.Marshal(&tree.Root{Children: []ecommon.Hash{child}, Height: height})
+			gomega.Ω(err).Should(gomega.BeNil())
+			createIssueRawTx(inst, &chain.SetTx{BaseTx: &chain.BaseTx{}, Value: rb}, priv)
+			child = chain.ValueHash(rb)
+			roots = append(roots, child)
+		}
+		expectBlkAccept(inst)
+		root := roots[len(roots)-1]
+
+		ginkgo.By("rejecting the tree before fetching any chunks", func() {
+			cli := &resolveCounter{Client: inst.cli}
+			var buf bytes.Buffer
+			err := tree.Download(context.Background(), cli, root, &buf, tree.WithMaxDepth(4))
+			gomega.Ω(errors.Is(err, tree.ErrTreeTooDeep)).Should(gomega.BeTrue())
+			gomega.Ω(cli.resolved).Should(gomega.Equal([]ecommon.Hash{root}))
+			gomega.Ω(buf.Len()).Should(gomega.Equal(0))
+		})
+
+		ginkgo.By("downloading the tree within the max depth", func() {
+			var buf bytes.Buffer
+			gomega.Ω(tree.Download(context.Background(), inst.cli, root, &buf, tree.WithMaxDepth(5))).Should(gomega.BeNil())
+			gomega.Ω(buf.Bytes()).Should(gomega.Equal(leaf))
+		})
+
+		ginkgo.By("rejecting roots that understate their height", func() {
+			// Claims to only have file chunks below it
+			rb, err := json.Marshal(&tree.Root{Children: []ecommon.Hash{roots[1]}, Height: 1})
+			gomega.Ω(err).Should(gomega.BeNil())
+			createIssueRawTx(inst, &chain.SetTx{BaseTx: &chain.BaseTx{}, Value: rb}, priv)
+			expectBlkAccept(inst)
+
+			var buf bytes.Buffer
+			err = tree.Download(context.Background(), inst.cli, chain.ValueHash(rb), &buf)
+			gomega.Ω(errors.Is(err, tree.ErrInvalidRoot)).Should(gomega.BeTrue())
+		})
+	})
+
+	ginkgo.It("throttles downloads when the node is overloaded", func() {
+		data := []byte(RandStringRunes(12 * int(genesis.MaxValueSize)))
+		root := uploadBytes(inst, data)
+
+		th := &throttler{handler: inst.httpServer.Config.Handler, maxInflight: 2, delay: 20 * time.Millisecond}
+		srv := httptest.NewServer(th)
+		defer srv.Close()
+
+		var buf bytes.Buffer
+		cli := client.New(srv.URL, requestTimeout)
+		gomega.Ω(tree.Download(context.Background(), cli, root, &buf, tree.WithConcurrency(8))).Should(gomega.BeNil())
+		gomega.Ω(buf.Bytes()).Should(gomega.Equal(data))
+
+		// The client ramped up until it was throttled and then backed off
+		// (instead of retrying every chunk at full concurrency)
+		throttled := atomic.LoadInt64(&th.throttled)
+		gomega.Ω(throttled).Should(gomega.BeNumerically(">", 0))
+		gomega.Ω(throttled).Should(gomega.BeNumerically("<", 12))
+	})
+
+	ginkgo.It("records the original file size in the root", func() {
+		data := []byte(RandStringRunes(3*int(genesis.MaxValueSize) + 100))
+		root := uploadBytes(inst, data)
+
+		exists, rb, _, err := inst.cli.Resolve(context.Background(), root)
+		gomega.Ω(err).Should(gomega.BeNil())
+		gomega.Ω(exists).Should(gomega.BeTrue())
+		var r tree.Root
+		gomega.Ω(json.Unmarshal(rb, &r)).Should(gomega.BeNil())
+		gomega.Ω(r.Size).Should(gomega.Equal(uint64(len(data))))
+
+		// Only the root is resolved to stat the file
+		cli := &resolveCounter{Client: inst.cli}
+		size, err := tree.Stat(context.Background(), cli, root)
+		gomega.Ω(err).Should(gomega.BeNil())
+		gomega.Ω(size).Should(gomega.Equal(uint64(len(data))))
+		gomega.Ω(cli.resolved).Should(gomega.Equal([]ecommon.Hash{root}))
+
+		var (
+			buf      bytes.Buffer
+			progress [][2]uint64
+		)
+		gomega.Ω(tree.Download(
+			context.Background(), inst.cli, root, &buf,
+			tree.WithProgress(func(downloaded uint64, total uint64) {
+				progress = append(progress, [2]uint64{downloaded, total})
+			}),
+		)).Should(gomega.BeNil())
+		gomega.Ω(buf.Bytes()).Should(gomega.Equal(data))
+		gomega.Ω(progress).Should(gomega.HaveLen(4))
+		for _, p := range progress {
+			gomega.Ω(p[1]).Should(gomega.Equal(uint64(len(data))))
+		}
+		gomega.Ω(progress[3][0]).Should(gomega.Equal(uint64(len(data))))
+	})
+
+	ginkgo.It("reports the chunks transferred", func() {
+		data := []byte(RandStringRunes(2*int(genesis.MaxValueSize) + 100))
+
+		type report struct {
+			done, total int
+			bytes       int64
+		}
+		var uploaded []report
+		root := uploadBytes(inst, data, tree.WithUploadProgress(func(done, total int, bytes int64) {
+			uploaded = append(uploaded, report{done, total, bytes})
+		}))
+		// Each chunk is confirmed before the rest of the file is read, so the
+		// total is only known once the root is stored
+		gomega.Ω(uploaded).Should(gomega.Equal([]report{
+			{1, 0, int64(genesis.MaxValueSize)},
+			{2, 0, 2 * int64(genesis.MaxValueSize)},
+			{3, 0, int64(len(data))},
+			{3, 3, int64(len(data))},
+		}))
+
+		var (
+			buf        bytes.Buffer
+			downloaded []report
+		)
+		gomega.Ω(tree.Download(
+			context.Background(), inst.cli, root, &buf,
+			tree.WithChunkProgress(func(done, total int, bytes int64) {
+				downloaded = append(downloaded, report{done, total, bytes})
+			}),
+		)).Should(gomega.BeNil())
+		gomega.Ω(buf.Bytes()).Should(gomega.Equal(data))
+		gomega.Ω(downloaded).Should(gomega.Equal([]report{
+			{1, 3, int64(genesis.MaxValueSize)},
+			{2, 3, 2 * int64(genesis.MaxValueSize)},
+			{3, 3, int64(len(data))},
+		}))
+	})
+
+	ginkgo.It("assembles files from local chunks", func() {
+		chunkSize := int(genesis.MaxValueSize)
+		data := []byte(RandStringRunes(3*chunkSize + 7))
+
+		chunks := map[ecommon.Hash][]byte{}
+		root := tree.Root{}
+		for i := 0; i < len(data); i += chunkSize {
+			end := i + chunkSize
+			if end > len(data) {
+				end = len(data)
+			}
+			k := chain.ValueHash(data[i:end])
+			chunks[k] = data[i:end]
+			root.Children = append(root.Children, k)
+		}
+
+		var buf bytes.Buffer
+		gomega.Ω(tree.Assemble(root, chunks, &buf)).Should(gomega.BeNil())
+		gomega.Ω(buf.Bytes()).Should(gomega.Equal(data))
+
+		// Small files are stored in the root
+		buf.Reset()
+		gomega.Ω(tree.Assemble(tree.Root{Contents: []byte("small")}, nil, &buf)).Should(gomega.BeNil())
+		gomega.Ω(buf.String()).Should(gomega.Equal("small"))
+
+		// Chunks are validated against their hash
+		corrupted := map[ecommon.Hash][]byte{}
+		for k, v := range chunks {
+			corrupted[k] = v
+		}
+		corrupted[root.Children[1]] = []byte("corrupted")
+		err := tree.Assemble(root, corrupted, io.Discard)
+		gomega.Ω(errors.Is(err, tree.ErrInvalidChunk)).Should(gomega.BeTrue())
+
+		delete(corrupted, root.Children[1])
+		err = tree.Assemble(root, corrupted, io.Discard)
+		gomega.Ω(errors.Is(err, tree.ErrMissing)).Should(gomega.BeTrue())
+	})
+
+	ginkgo.It("downloads multiple roots as a tar", func() {
+		files := map[string][]byte{
+			"small.txt":     []byte(RandStringRunes(units.KiB)),
+			"dir/large.bin": []byte(RandStringRunes(450 * units.KiB)),
+		}
+		entries := map[string]ecommon.Hash{}
+		for name, data := range files {
+			entries[name] = uploadBytes(inst, data)
+		}
+
+		var buf bytes.Buffer
+		gomega.Ω(tree.DownloadTar(context.Background(), inst.cli, entries, &buf)).Should(gomega.BeNil())
+
+		tr := tar.NewReader(&buf)
+		extracted := map[string][]byte{}
+		for {
+			hdr, err := tr.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			gomega.Ω(err).Should(gomega.BeNil())
+			b, err := io.ReadAll(tr)
+			gomega.Ω(err).Should(gomega.BeNil())
+			gomega.Ω(hdr.Size).Should(gomega.Equal(int64(len(b))))
+			extracted[hdr.Name] = b
+		}
+		gomega.Ω(extracted).Should(gomega.Equal(files))
+	})
+
+	ginkgo.It("serves file headers for HEAD requests", func() {
+		data := []byte(RandStringRunes(450 * units.KiB))
+		root := uploadBytes(inst, data)
+
+		cli := &resolveCounter{Client: inst.cli}
+		gateway := httptest.NewServer(tree.NewResolveHandler(cli))
+		defer gateway.Close()
+
+		resp, err := http.Head(gateway.URL + "/file/" + root.Hex())
+		gomega.Ω(err).Should(gomega.BeNil())
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		gomega.Ω(err).Should(gomega.BeNil())
+		gomega.Ω(resp.StatusCode).Should(gomega.Equal(http.StatusOK))
+		gomega.Ω(body).Should(gomega.BeEmpty())
+		gomega.Ω(resp.ContentLength).Should(gomega.Equal(int64(len(data))))
+		gomega.Ω(resp.Header.Get("Content-Type")).Should(gomega.Equal("application/octet-stream"))
+		gomega.Ω(resp.Header.Get("ETag")).Should(gomega.Equal(`"` + root.Hex() + `"`))
+
+		// Only the root is resolved (the chunks aren't downloaded)
+		gomega.Ω(cli.resolved).Should(gomega.Equal([]ecommon.Hash{root}))
+
+		resp, err = http.Head(gateway.URL + "/file/" + chain.ValueHash([]byte("missing")).Hex())
+		gomega.Ω(err).Should(gomega.BeNil())
+		resp.Body.Close()
+		gomega.Ω(resp.StatusCode).Should(gomega.Equal(http.StatusNotFound))
+	})
+
+	ginkgo.It("resolves share URLs through the gateway", func() {
+		data := []byte(RandStringRunes(450 * units.KiB))
+		root := uploadBytes(inst, data)
+
+		gateway := httptest.NewServer(tree.NewResolveHandler(inst.cli))
+		defer gateway.Close()
+
+		resp, err := http.Get(client.ShareURL(root, gateway.URL+"/"))
+		gomega.Ω(err).Should(gomega.BeNil())
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		gomega.Ω(err).Should(gomega.BeNil())
+		gomega.Ω(resp.StatusCode).Should(gomega.Equal(http.StatusOK))
+		gomega.Ω(body).Should(gomega.Equal(data))
+	})
+
+	ginkgo.It("serves immutable content from the gateway", func() {
+		data := []byte(RandStringRunes(450 * units.KiB))
+		root := uploadBytes(inst, data)
+
+		gateway := httptest.NewServer(tree.NewResolveHandler(inst.cli))
+		defer gateway.Close()
+
+		for _, tv := range []struct {
+			path         string
+			status       int
+			cacheControl string
+			body         []byte
+			length       int64
+		}{
+			{ // file keyed by content hash (whose root records its size)
+				path:         "/file/" + root.Hex(),
+				status:       http.StatusOK,
+				cacheControl: tree.ImmutableCacheControl,
+				body:         data,
+				length:       int64(len(data)),
+			},
+			{ // chunk keyed by content hash
+				path:         "/value/" + chain.ValueHash(data[:genesis.MaxValueSize]).Hex(),
+				status:       http.StatusOK,
+				cacheControl: tree.ImmutableCacheControl,
+				body:         data[:genesis.MaxValueSize],
+			},
+			{ // missing content may be set later
+				path:         "/file/" + chain.ValueHash([]byte("missing")).Hex(),
+				status:       http.StatusNotFound,
+				cacheControl: tree.MutableCacheControl,
+			},
+			{ // missing value may be set later
+				path:         "/value/" + chain.ValueHash([]byte("missing")).Hex(),
+				status:       http.StatusNotFound,
+				cacheControl: tree.MutableCacheControl,
+			},
+			{ // named keys are not content-addressed
+				path:         "/value/my-profile",
+				status:       http.StatusNotFound,
+				cacheControl: tree.MutableCacheControl,
+			},
+		} {
+			resp, err := http.Get(gateway.URL + tv.path)
+			gomega.Ω(err).Should(gomega.BeNil())
+			body, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			gomega.Ω(err).Should(gomega.BeNil())
+			gomega.Ω(resp.StatusCode).Should(gomega.Equal(tv.status), tv.path)
+			gomega.Ω(resp.Header.Get("Cache-Control")).Should(gomega.Equal(tv.cacheControl), tv.path)
+			if tv.body != nil {
+				gomega.Ω(body).Should(gomega.Equal(tv.body), tv.path)
+			}
+			if tv.length > 0 {
+				gomega.Ω(resp.ContentLength).Should(gomega.Equal(tv.length), tv.path)
+			}
+		}
+	})
+
+	ginkgo.It("uploads a file posted to the gateway", func() {
+		h, err := tree.NewHandler(context.Background(), inst.cli, priv, "secret", 500*units.KiB)
+		gomega.Ω(err).Should(gomega.BeNil())
+		mux := http.NewServeMux()
+		mux.Handle("/file", h)
+		gateway := httptest.NewServer(mux)
+		defer gateway.Close()
+
+		post := func(body io.Reader, token string) *http.Response {
+			req, err := http.NewRequest(http.MethodPost, gateway.URL+"/file", body)
+			gomega.Ω(err).Should(gomega.BeNil())
+			req.Header.Set("Authorization", "Bearer "+token)
+			resp, err := http.DefaultClient.Do(req)
+			gomega.Ω(err).Should(gomega.BeNil())
+			return resp
+		}
+
+		ginkgo.By("rejecting unauthorized uploads", func() {
+			resp := post(bytes.NewReader([]byte("hello")), "wrong")
+			resp.Body.Close()
+			gomega.Ω(resp.StatusCode).Should(gomega.Equal(http.StatusUnauthorized))
+		})
+
+		ginkgo.By("rejecting uploads over the max size", func() {
+			// Unknown content length must be caught while reading
+			data := []byte(RandStringRunes(501 * units.KiB))
+			resp := post(iotest.HalfReader(bytes.NewReader(data)), "secret")
+			resp.Body.Close()
+			gomega.Ω(resp.StatusCode).Should(gomega.Equal(http.StatusRequestEntityTooLarge))
+		})
+
+		ginkgo.By("storing a multi-chunk body", func() {
+			data := []byte(RandStringRunes(450 * units.KiB))
+			c := make(chan struct{})
+			d := make(chan struct{})
+			go func() {
+				asyncBlockPush(inst, c)
+				close(d)
+			}()
+			resp := post(iotest.HalfReader(bytes.NewReader(data)), "secret")
+			close(c)
+			<-d
+			defer resp.Body.Close()
+			gomega.Ω(resp.StatusCode).Should(gomega.Equal(http.StatusOK))
+
+			reply := new(tree.UploadReply)
+			gomega.Ω(json.NewDecoder(resp.Body).Decode(reply)).Should(gomega.BeNil())
+
+			exists, rb, _, err := inst.cli.Resolve(context.Background(), reply.Root)
+			gomega.Ω(err).Should(gomega.BeNil())
+			gomega.Ω(exists).Should(gomega.BeTrue())
+			r := new(tree.Root)
+			gomega.Ω(json.Unmarshal(rb, r)).Should(gomega.BeNil())
+			gomega.Ω(r.Children).Should(gomega.HaveLen(3))
+
+			var buf bytes.Buffer
+			gomega.Ω(tree.Download(context.Background(), inst.cli, reply.Root, &buf)).Should(gomega.BeNil())
+			gomega.Ω(buf.Bytes()).Should(gomega.Equal(data))
+		})
+	})
+})
+
+// resolveCounter records the keys resolved by [Client].
+type resolveCounter struct {
+	client.Client
+
+	l        sync.Mutex
+	resolved []ecommon.Hash
+}
+
+func (r *resolveCounter) Resolve(ctx context.Context, key ecommon.Hash) (bool, []byte, *chain.ValueMeta, error) {
+	r.l.Lock()
+	r.resolved = append(r.resolved, key)
+	r.l.Unlock()
+	return r.Client.Resolve(ctx, key)
+}
+
+// hasKeysCounter counts the "blobvm.hasKeys" requests made by [Client].
+type hasKeysCounter struct {
+	client.Client
+
+	l     sync.Mutex
+	calls int
+}
+
+func (h *hasKeysCounter) HasKeys(ctx context.Context, keys []ecommon.Hash) ([]bool, error) {
+	h.l.Lock()
+	h.calls++
+	h.l.Unlock()
+	return h.Client.HasKeys(ctx, keys)
+}
+
+// throttler rejects requests with 429 while [maxInflight] requests are being
+// served by [handler] (each delayed by [delay]).
+type throttler struct {
+	handler     http.Handler
+	maxInflight int64
+	delay       time.Duration
+
+	inflight  int64
+	throttled int64
+}
+
+func (t *throttler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if atomic.AddInt64(&t.inflight, 1) > t.maxInflight {
+		atomic.AddInt64(&t.inflight, -1)
+		atomic.AddInt64(&t.throttled, 1)
+		w.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+	defer atomic.AddInt64(&t.inflight, -1)
+	time.Sleep(t.delay)
+	t.handler.ServeHTTP(w, r)
+}
+
+// cancelReader calls [cancel] on the [cancelAt]-th read.
+type cancelReader struct {
+	r        io.Reader
+	reads    int
+	cancelAt int
+	cancel   context.CancelFunc
+}
+
+func (c *cancelReader) Read(p []byte) (int, error) {
+	c.reads++
+	if c.reads == c.cancelAt {
+		c.cancel()
+	}
+	return c.r.Read(p)
+}
+
+func uploadBytes(i instance, b []byte, opts ...tree.UploadOption) ecommon.Hash {
+	c := make(chan struct{})
+	d := make(chan struct{})
+	go func() {
+		asyncBlockPush(i, c)
+		close(d)
+	}()
+	root, err := tree.Upload(
+		context.Background(), i.cli, priv,
+		bytes.NewReader(b), int(genesis.MaxValueSize), opts...,
+	)
+	close(c)
+	<-d
+	gomega.Ω(err).Should(gomega.BeNil())
+	return root
+}
+
 func createIssueRawTx(i instance, utx chain.UnsignedTransaction, signer *ecdsa.PrivateKey) {
-	g, err := i.cli.Genesis(context.Background())
+	g, err := i.cli.CachedGenesis(context.Background())
 	gomega.Ω(err).Should(gomega.BeNil())
 	utx.SetMagic(g.Magic)
 
@@ -578,3 +2033,41 @@ func (app *appSender) SendCrossChainAppRequest(_ context.Context, _ ids.ID, _ ui
 func (app *appSender) SendCrossChainAppResponse(_ context.Context, _ ids.ID, _ uint32, _ []byte) error {
 	return nil
 }
+
+var _ common.AppSender = &peerSender{}
+
+// peerSender delivers the AppRequests and AppResponses sent by [nodeID]
+// directly to the VMs in [vms] (gossip is dropped).
+type peerSender struct {
+	nodeID ids.NodeID
+	vms    map[ids.NodeID]*vm.VM
+}
+
+func (p *peerSender) SendAppGossip(_ context.Context, _ []byte) error {
+	return nil
+}
+
+func (p *peerSender) SendAppRequest(ctx context.Context, nodeIDs ids.NodeIDSet, requestID uint32, request []byte) error {
+	for nodeID := range nodeIDs {
+		if err := p.vms[nodeID].AppRequest(ctx, p.nodeID, requestID, time.Time{}, request); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *peerSender) SendAppResponse(ctx context.Context, nodeID ids.NodeID, requestID uint32, response []byte) error {
+	return p.vms[nodeID].AppResponse(ctx, p.nodeID, requestID, response)
+}
+
+func (p *peerSender) SendAppGossipSpecific(_ context.Context, _ ids.NodeIDSet, _ []byte) error {
+	return nil
+}
+
+func (p *peerSender) SendCrossChainAppRequest(_ context.Context, _ ids.ID, _ uint32, _ []byte) error {
+	return nil
+}
+
+func (p *peerSender) SendCrossChainAppResponse(_ context.Context, _ ids.ID, _ uint32, _ []byte) error {
+	return nil
+}
@@ -0,0 +1,93 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package tree
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/ava-labs/blobvm/chain"
+)
+
+type assembleOp struct {
+	domainSep []byte
+}
+
+type AssembleOption func(*assembleOp)
+
+// WithValueDomainSeparator sets the [chain.Genesis.ValueDomainSeparator] used
+// to validate chunks in [Assemble] (empty by default).
+func WithValueDomainSeparator(sep []byte) AssembleOption {
+	return func(op *assembleOp) {
+		op.domainSep = sep
+	}
+}
+
+// Assemble writes the file described by [root] to [w] using the chunks in
+// [chunks] (keyed by their hash) instead of fetching them. If [root]
+// references other [Root]s, their encoded bytes must also be in [chunks].
+//
+// Each chunk is validated against its hash before it is written, so [w] may
+// contain a prefix of the file if [Assemble] fails.
+func Assemble(root Root, chunks map[common.Hash][]byte, w io.Writer, opts ...AssembleOption) error {
+	op := &assembleOp{}
+	for _, opt := range opts {
+		opt(op)
+	}
+
+	// Use small file optimization
+	if len(root.Contents) > 0 {
+		_, err := w.Write(root.Contents)
+		return err
+	}
+	if len(root.Children) == 0 {
+		return ErrEmpty
+	}
+	return op.assemble(&root, chunks, w)
+}
+
+func (op *assembleOp) assemble(r *Root, chunks map[common.Hash][]byte, w io.Writer) error {
+	for _, h := range r.Children {
+		b, err := op.chunk(h, chunks)
+		if err != nil {
+			return err
+		}
+		if r.Height == 0 {
+			b, err := r.Compression.decompress(b)
+			if err != nil {
+				return fmt.Errorf("%w (chunk=%s)", err, h)
+			}
+			if _, err := w.Write(b); err != nil {
+				return err
+			}
+			continue
+		}
+		child := new(Root)
+		if err := json.Unmarshal(b, child); err != nil {
+			return fmt.Errorf("%w:%s: %v", ErrInvalidRoot, h, err)
+		}
+		if child.Height != r.Height-1 || len(child.Contents) > 0 || len(child.Children) == 0 {
+			return fmt.Errorf("%w:%s", ErrInvalidRoot, h)
+		}
+		if err := op.assemble(child, chunks, w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// chunk returns the chunk at [h] in [chunks] (if it matches [h]).
+func (op *assembleOp) chunk(h common.Hash, chunks map[common.Hash][]byte) ([]byte, error) {
+	b, ok := chunks[h]
+	if !ok {
+		return nil, fmt.Errorf("%w:%s", ErrMissing, h)
+	}
+	if chain.DomainValueHash(op.domainSep, b) != h {
+		return nil, fmt.Errorf("%w:%s", ErrInvalidChunk, h)
+	}
+	return b, nil
+}
@@ -0,0 +1,98 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package tree
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/fatih/color"
+)
+
+const checkpointFileMode = 0o600
+
+// Checkpoint is the manifest written by [UploadResumable]: the keys of the
+// chunks (in file order) that were confirmed before the upload stopped.
+type Checkpoint struct {
+	ChunkSize int           `json:"chunkSize"`
+	Chunks    []common.Hash `json:"chunks"`
+}
+
+// checkpointer records confirmed chunks at [path] as they are stored.
+type checkpointer struct {
+	path string
+	cp   *Checkpoint
+
+	// recorded chunks of a previous upload that haven't been checked against
+	// [f] yet
+	recorded []common.Hash
+}
+
+// loadCheckpoint reads the checkpoint at [path]. A missing, corrupt, or
+// incompatible (different chunk size) checkpoint is ignored and overwritten
+// as chunks are confirmed.
+func loadCheckpoint(path string, chunkSize int) *checkpointer {
+	c := &checkpointer{path: path, cp: &Checkpoint{ChunkSize: chunkSize, Chunks: []common.Hash{}}}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			color.Red("ignoring unreadable checkpoint %s: %v", path, err)
+		}
+		return c
+	}
+	prev := new(Checkpoint)
+	if err := json.Unmarshal(b, prev); err != nil {
+		color.Red("ignoring corrupt checkpoint %s: %v", path, err)
+		return c
+	}
+	if prev.ChunkSize != chunkSize {
+		color.Red("ignoring checkpoint %s with chunk size %d (expected %d)", path, prev.ChunkSize, chunkSize)
+		return c
+	}
+	c.recorded = prev.Chunks
+	return c
+}
+
+// confirmed returns true if the previous upload recorded chunk [i] as [k].
+// Once any chunk doesn't match (the file changed since the checkpoint was
+// written), no later recorded chunk is trusted.
+func (c *checkpointer) confirmed(i int, k common.Hash) bool {
+	if i >= len(c.recorded) {
+		return false
+	}
+	if c.recorded[i] != k {
+		color.Red("checkpoint %s is stale at chunk %d, ignoring the rest", c.path, i)
+		c.recorded = nil
+		return false
+	}
+	return true
+}
+
+// record persists that chunk [k] (the next chunk in file order) has been
+// confirmed.
+func (c *checkpointer) record(k common.Hash) error {
+	c.cp.Chunks = append(c.cp.Chunks, k)
+	b, err := json.Marshal(c.cp)
+	if err != nil {
+		return err
+	}
+	// Replace the checkpoint atomically so an interrupted write doesn't
+	// corrupt it
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, b, checkpointFileMode); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
+
+// done removes the checkpoint once the upload completes.
+func (c *checkpointer) done() error {
+	if err := os.Remove(c.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package tree
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+)
+
+// Chunking is how a file is split into the chunks of a [Root].
+type Chunking string
+
+const (
+	// FixedChunking splits a file into chunks of the chunk size (and is
+	// assumed for roots that don't record a [Root.Chunking])
+	FixedChunking Chunking = ""
+
+	// Buzhash picks chunk boundaries with a rolling hash of the preceding
+	// [buzhashWindow] bytes, so an insertion or deletion only changes the
+	// chunks around it (instead of shifting every later chunk)
+	Buzhash Chunking = "buzhash"
+)
+
+// buzhashWindow is the number of bytes hashed to decide whether a chunk ends.
+const buzhashWindow = 64
+
+// buzhashTable maps each byte to a random value. It is generated from a fixed
+// seed: changing it would move every chunk boundary (and defeat dedup with
+// files uploaded before).
+var buzhashTable = func() (t [256]uint32) {
+	// splitmix64
+	x := uint64(0x626c6f62766d) // "blobvm"
+	for i := range t {
+		x += 0x9e3779b97f4a7c15
+		z := x
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		t[i] = uint32(z ^ (z >> 31))
+	}
+	return t
+}()
+
+func (c Chunking) verify() error {
+	switch c {
+	case FixedChunking, Buzhash:
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrUnsupportedChunking, c)
+	}
+}
+
+// chunker splits a file into chunks.
+type chunker interface {
+	// next returns the next chunk (only valid until the following call) and
+	// whether it is the last one. The last chunk may be empty.
+	next() (chunk []byte, last bool, err error)
+}
+
+// newChunker returns a [chunker] splitting [f] into chunks of at most [max]
+// bytes with [c].
+func (c Chunking) newChunker(f io.Reader, max int) chunker {
+	if c == Buzhash {
+		// Chunks are roughly [max]/2 bytes long on average: boundaries are only
+		// considered after [min] bytes and are then found every 2^[maskBits]
+		// bytes on average
+		min := max / 4
+		maskBits := 0
+		if min > 1 {
+			maskBits = bits.Len(uint(min)) - 1
+		}
+		return &buzhashChunker{
+			r:    bufio.NewReader(f),
+			buf:  make([]byte, 0, max),
+			min:  min,
+			max:  max,
+			mask: 1<<maskBits - 1,
+		}
+	}
+	return &fixedChunker{r: f, buf: make([]byte, max)}
+}
+
+type fixedChunker struct {
+	r   io.Reader
+	buf []byte
+}
+
+func (c *fixedChunker) next() ([]byte, bool, error) {
+	// Fill [buf] completely, so that a short read (which [io.Reader] permits
+	// before EOF) isn't mistaken for the end of the file.
+	read, err := io.ReadFull(c.r, c.buf)
+	switch {
+	case errors.Is(err, io.EOF):
+		// Nothing left to read: if the size of the file is an exact multiple
+		// of the chunk size, the last chunk was full and there is no (empty)
+		// final chunk.
+		return nil, true, nil
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return c.buf[:read], true, nil
+	case err != nil:
+		return nil, false, fmt.Errorf("%w: read error", err)
+	}
+	return c.buf, false, nil
+}
+
+type buzhashChunker struct {
+	r    *bufio.Reader
+	buf  []byte
+	min  int
+	max  int
+	mask uint32
+}
+
+func (c *buzhashChunker) next() ([]byte, bool, error) {
+	c.buf = c.buf[:0]
+	var h uint32
+	for len(c.buf) < c.max {
+		b, err := c.r.ReadByte()
+		if errors.Is(err, io.EOF) {
+			return c.buf, true, nil
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("%w: read error", err)
+		}
+		c.buf = append(c.buf, b)
+		h = bits.RotateLeft32(h, 1) ^ buzhashTable[b]
+		if n := len(c.buf); n > buzhashWindow {
+			// Drop the byte leaving the window (rotated once per byte since)
+			h ^= bits.RotateLeft32(buzhashTable[c.buf[n-1-buzhashWindow]], buzhashWindow)
+		}
+		if len(c.buf) >= c.min && h&c.mask == 0 {
+			break
+		}
+	}
+	// Errors other than EOF are returned by the following call
+	_, err := c.r.Peek(1)
+	return c.buf, errors.Is(err, io.EOF), nil
+}
@@ -0,0 +1,88 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package tree
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/ava-labs/avalanchego/utils/units"
+)
+
+// Compression is the algorithm used to compress the chunks of a [Root].
+type Compression string
+
+const (
+	// NoCompression stores chunks as-is (and is assumed for roots that don't
+	// record a [Root.Compression])
+	NoCompression Compression = ""
+	Gzip          Compression = "gzip"
+)
+
+// maxDecompressedChunkSize bounds the size of a single decompressed chunk, so a
+// malicious chunk can't exhaust memory.
+const maxDecompressedChunkSize = 64 * units.MiB
+
+func (c Compression) verify() error {
+	switch c {
+	case NoCompression, Gzip:
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrUnsupportedCompression, c)
+	}
+}
+
+// readSize returns the number of bytes of a file to read into each chunk so
+// that the chunk is at most [chunkSize] bytes once compressed (even if the file
+// doesn't compress at all).
+func (c Compression) readSize(chunkSize int) int {
+	if c == NoCompression {
+		return chunkSize
+	}
+	// Incompressible data is stored in blocks with a 5 byte header (each holding
+	// well over 1KiB), in addition to the gzip header and trailer
+	return chunkSize - chunkSize/units.KiB*5 - 64
+}
+
+// compress returns [b] compressed with [c]. The output only depends on [b], so
+// identical chunks are stored at the same key.
+func (c Compression) compress(b []byte) ([]byte, error) {
+	if c == NoCompression {
+		return b, nil
+	}
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decompress reverses [compress].
+func (c Compression) decompress(b []byte) ([]byte, error) {
+	switch c {
+	case NoCompression:
+		return b, nil
+	case Gzip:
+	default:
+		return nil, c.verify()
+	}
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCompressedChunk, err)
+	}
+	out, err := io.ReadAll(io.LimitReader(r, maxDecompressedChunkSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCompressedChunk, err)
+	}
+	if len(out) > maxDecompressedChunkSize {
+		return nil, fmt.Errorf("%w: decompresses to more than %d bytes", ErrInvalidCompressedChunk, maxDecompressedChunkSize)
+	}
+	return out, nil
+}
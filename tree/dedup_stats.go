@@ -0,0 +1,82 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package tree
+
+import (
+	"sync"
+	"time"
+)
+
+// DedupStats accumulates the [UploadStats] of many uploads (ex: every upload
+// made by a hosting service) to report how effective dedup is across them. It
+// is safe for concurrent use, and the zero value is ready to use.
+type DedupStats struct {
+	l      sync.Mutex
+	totals DedupTotals
+}
+
+// DedupTotals are the [UploadStats] accumulated by a [DedupStats].
+type DedupTotals struct {
+	// Since is when the first upload was added (after the last reset)
+	Since time.Time `json:"since"`
+
+	Uploads        int    `json:"uploads"`
+	UploadedChunks int    `json:"uploadedChunks"`
+	UploadedBytes  uint64 `json:"uploadedBytes"`
+
+	// Deduped chunks were already on-chain (or repeated within an upload), so
+	// [DedupedBytes] weren't paid for again
+	DedupedChunks int    `json:"dedupedChunks"`
+	DedupedBytes  uint64 `json:"dedupedBytes"`
+
+	Cost uint64 `json:"cost"`
+	Txs  int    `json:"txs"`
+}
+
+// HitRate returns the fraction of chunks that were deduped (0 if no chunks
+// were uploaded).
+func (t DedupTotals) HitRate() float64 {
+	chunks := t.UploadedChunks + t.DedupedChunks
+	if chunks == 0 {
+		return 0
+	}
+	return float64(t.DedupedChunks) / float64(chunks)
+}
+
+// Add accumulates the [UploadStats] of a completed upload. Uploads made with
+// [WithDedupStats] are added automatically.
+func (d *DedupStats) Add(stats *UploadStats) {
+	d.l.Lock()
+	defer d.l.Unlock()
+
+	t := &d.totals
+	if t.Uploads == 0 {
+		t.Since = time.Now()
+	}
+	t.Uploads++
+	t.UploadedChunks += stats.UploadedChunks
+	t.UploadedBytes += stats.UploadedBytes
+	t.DedupedChunks += stats.ReusedChunks
+	t.DedupedBytes += stats.ReusedBytes
+	t.Cost += stats.Cost
+	t.Txs += stats.Txs
+}
+
+// Totals returns the stats accumulated so far.
+func (d *DedupStats) Totals() DedupTotals {
+	d.l.Lock()
+	defer d.l.Unlock()
+	return d.totals
+}
+
+// Reset clears the accumulated stats (ex: at the end of a billing period),
+// returning them.
+func (d *DedupStats) Reset() DedupTotals {
+	d.l.Lock()
+	defer d.l.Unlock()
+
+	t := d.totals
+	d.totals = DedupTotals{}
+	return t
+}
@@ -0,0 +1,109 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package tree
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/ava-labs/blobvm/client"
+)
+
+type DiffResult struct {
+	Added     []common.Hash `json:"added"`
+	Removed   []common.Hash `json:"removed"`
+	Unchanged []common.Hash `json:"unchanged"`
+
+	AddedBytes     uint64 `json:"addedBytes"`
+	RemovedBytes   uint64 `json:"removedBytes"`
+	UnchangedBytes uint64 `json:"unchangedBytes"`
+}
+
+// chunks returns the unique file chunk hashes under [r] (in order of first
+// appearance) and their sizes. Small files are treated as a single chunk.
+// Sub-roots (of a [Root] with a [Height] above 0) are walked, not diffed.
+func (r *Root) chunks(ctx context.Context, cli client.Client) ([]common.Hash, map[common.Hash]uint64, error) {
+	sizes := map[common.Hash]uint64{}
+	if len(r.Contents) > 0 {
+		k, err := cli.ValueHash(ctx, r.Contents)
+		if err != nil {
+			return nil, nil, err
+		}
+		sizes[k] = uint64(len(r.Contents))
+		return []common.Hash{k}, sizes, nil
+	}
+	if len(r.Children) == 0 {
+		return nil, nil, ErrEmpty
+	}
+	if err := checkDepth(r, DefaultMaxDepth); err != nil {
+		return nil, nil, err
+	}
+	hashes := []common.Hash{}
+	if err := walkLeaves(ctx, cli, r, func(leaf *Root) error {
+		for _, h := range leaf.Children {
+			if _, ok := sizes[h]; ok {
+				continue
+			}
+			// Only the size is needed, so leave the chunk out of the reply
+			exists, _, _, vmeta, err := cli.ResolveMaxBytes(ctx, h, 1)
+			if err != nil {
+				return err
+			}
+			if !exists {
+				return fmt.Errorf("%w:%s", ErrMissing, h)
+			}
+			sizes[h] = vmeta.Size
+			hashes = append(hashes, h)
+		}
+		return nil
+	}); err != nil {
+		return nil, nil, err
+	}
+	return hashes, sizes, nil
+}
+
+// Diff reports which chunks were added, removed, or left unchanged when going
+// from [oldRoot] to [newRoot].
+func Diff(ctx context.Context, cli client.Client, oldRoot common.Hash, newRoot common.Hash) (*DiffResult, error) {
+	or, err := resolveRoot(ctx, cli, oldRoot)
+	if err != nil {
+		return nil, err
+	}
+	nr, err := resolveRoot(ctx, cli, newRoot)
+	if err != nil {
+		return nil, err
+	}
+	oldHashes, oldSizes, err := or.chunks(ctx, cli)
+	if err != nil {
+		return nil, err
+	}
+	newHashes, newSizes, err := nr.chunks(ctx, cli)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &DiffResult{
+		Added:     []common.Hash{},
+		Removed:   []common.Hash{},
+		Unchanged: []common.Hash{},
+	}
+	for _, h := range newHashes {
+		if _, ok := oldSizes[h]; ok {
+			d.Unchanged = append(d.Unchanged, h)
+			d.UnchangedBytes += newSizes[h]
+			continue
+		}
+		d.Added = append(d.Added, h)
+		d.AddedBytes += newSizes[h]
+	}
+	for _, h := range oldHashes {
+		if _, ok := newSizes[h]; !ok {
+			d.Removed = append(d.Removed, h)
+			d.RemovedBytes += oldSizes[h]
+		}
+	}
+	return d, nil
+}
@@ -5,9 +5,37 @@ package tree
 
 import (
 	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
 )
 
 var (
-	ErrEmpty   = errors.New("file is empty")
-	ErrMissing = errors.New("required file is missing")
+	ErrEmpty                  = errors.New("file is empty")
+	ErrMissing                = errors.New("required file is missing")
+	ErrTooBig                 = errors.New("file is too big")
+	ErrMissingAuthToken       = errors.New("missing auth token")
+	ErrTreeTooDeep            = errors.New("tree is too deep")
+	ErrInvalidRoot            = errors.New("invalid root")
+	ErrInvalidChunk           = errors.New("chunk does not match hash")
+	ErrUnsupportedCompression = errors.New("unsupported compression")
+	ErrInvalidCompressedChunk = errors.New("chunk can't be decompressed")
+	ErrInvalidChunkSize       = errors.New("invalid chunk size")
+	ErrUnsupportedChunking    = errors.New("unsupported chunking")
+
+	ErrNotSeekable = errors.New("file is not seekable")
 )
+
+// InterruptedError is returned when an upload or download is cancelled before
+// it finishes. [Completed] holds the chunks that were fully processed before
+// cancellation (in file order).
+type InterruptedError struct {
+	Completed []common.Hash
+	Err       error
+}
+
+func (e *InterruptedError) Error() string {
+	return fmt.Sprintf("interrupted after %d chunks: %v", len(e.Completed), e.Err)
+}
+
+func (e *InterruptedError) Unwrap() error { return e.Err }
@@ -0,0 +1,114 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package tree
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/fatih/color"
+
+	"github.com/ava-labs/blobvm/client"
+)
+
+var _ http.Handler = &Handler{}
+
+// Handler is an HTTP gateway that stores the raw body of each POST request
+// on-chain (signed with a key held by the gateway) and responds with the
+// root hash of the uploaded file.
+type Handler struct {
+	cli       client.Client
+	priv      *ecdsa.PrivateKey
+	authToken string
+	chunkSize int
+	maxSize   int64
+}
+
+type UploadReply struct {
+	Root common.Hash `json:"root"`
+}
+
+// NewHandler returns an upload [Handler] that only accepts requests with the
+// header "Authorization: Bearer <authToken>" and bodies of at most [maxSize]
+// bytes.
+func NewHandler(
+	ctx context.Context, cli client.Client, priv *ecdsa.PrivateKey,
+	authToken string, maxSize int64,
+) (*Handler, error) {
+	if len(authToken) == 0 {
+		return nil, ErrMissingAuthToken
+	}
+	g, err := cli.CachedGenesis(ctx)
+	if err != nil {
+		return nil, err
+	}
+	chunkSize := int(g.MaxValueSize)
+
+	// The root of the largest allowed file must also fit in a single value
+	children := make([]common.Hash, (maxSize+int64(chunkSize)-1)/int64(chunkSize))
+	rb, err := json.Marshal(&Root{Children: children, Size: uint64(maxSize)})
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(rb)) > g.MaxValueSize {
+		return nil, fmt.Errorf("%w: max size %d requires root of %d bytes (max=%d)", ErrTooBig, maxSize, len(rb), g.MaxValueSize)
+	}
+	return &Handler{
+		cli:       cli,
+		priv:      priv,
+		authToken: authToken,
+		chunkSize: chunkSize,
+		maxSize:   maxSize,
+	}, nil
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+h.authToken)) != 1 {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	if r.ContentLength > h.maxSize {
+		http.Error(w, ErrTooBig.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// Buffer the body before issuing any txs so that rejected uploads don't pay
+	// for partially stored chunks.
+	body, err := io.ReadAll(io.LimitReader(r.Body, h.maxSize+1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if int64(len(body)) > h.maxSize {
+		http.Error(w, ErrTooBig.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	root, err := Upload(r.Context(), h.cli, h.priv, bytes.NewReader(body), h.chunkSize)
+	switch {
+	case errors.Is(err, ErrEmpty):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		color.Red("failed to upload file: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(&UploadReply{Root: root}); err != nil {
+		color.Red("failed to write reply: %v", err)
+	}
+}
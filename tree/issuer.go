@@ -0,0 +1,254 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package tree
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/fatih/color"
+
+	"github.com/ava-labs/blobvm/chain"
+	"github.com/ava-labs/blobvm/client"
+)
+
+// issuer issues the txs storing the chunks of an upload on up to
+// [concurrency] goroutines and reports their chunks as confirmed in the order
+// the txs were issued.
+type issuer struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	cli    client.Client
+	priv   *ecdsa.PrivateKey
+	opts   []client.OpOption
+
+	// [sem] bounds the number of txs in flight (and so the number of chunks
+	// held in memory)
+	sem         chan struct{}
+	concurrency int
+
+	// Txs rejected because some of their chunks are already stored are
+	// reissued without them (see [WithSkipDedupCheck])
+	skipStored bool
+
+	wg sync.WaitGroup
+
+	l     sync.Mutex
+	stats *UploadStats
+	err   error // first error returned by a tx
+
+	// issued but not yet drained (in file order)
+	tasks []*issueTask
+}
+
+type issueTask struct {
+	keys []common.Hash
+	done chan struct{}
+	err  error
+
+	// chunks (and their bytes) found to be stored already, so they were
+	// reused instead of uploaded (applied to the [UploadStats] by [drain])
+	reusedChunks int
+	reusedBytes  uint64
+}
+
+func newIssuer(
+	ctx context.Context, cli client.Client, priv *ecdsa.PrivateKey,
+	concurrency int, skipStored bool, stats *UploadStats,
+) *issuer {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	return &issuer{
+		ctx:    ctx,
+		cancel: cancel,
+		cli:    cli,
+		priv:   priv,
+		opts: []client.OpOption{
+			client.WithPollTx(),
+			// Chunks are issued in quick succession, so reference the same
+			// blockID until it ages out
+			client.WithBlockPin(client.NewBlockPin(client.DefaultBlockPinMaxAge)),
+			client.WithStaleBlockRetries(1),
+		},
+		stats: stats,
+
+		sem:         make(chan struct{}, concurrency),
+		concurrency: concurrency,
+		skipStored:  skipStored,
+	}
+}
+
+// issue stores [utx] in the background, blocking while [concurrency] txs are
+// already in flight. [keys] are reported by [drain] once [utx] is confirmed. If
+// [utx] is nil, [keys] are already stored and are reported once every
+// previously issued tx is confirmed.
+func (is *issuer) issue(keys []common.Hash, utx chain.UnsignedTransaction) error {
+	t := &issueTask{keys: keys, done: make(chan struct{})}
+	if utx == nil {
+		close(t.done)
+		is.tasks = append(is.tasks, t)
+		return nil
+	}
+	select {
+	case is.sem <- struct{}{}:
+	case <-is.ctx.Done():
+		return is.error()
+	}
+	is.tasks = append(is.tasks, t)
+	is.wg.Add(1)
+	go func() {
+		defer func() {
+			<-is.sem
+			is.wg.Done()
+		}()
+		txID, cost, err := client.SignIssueRawTx(is.ctx, is.cli, utx, is.priv, is.opts...)
+		for err != nil && is.skipStored && isKeyExists(err) {
+			if utx, err = is.withoutStored(t, utx, err); err != nil || utx == nil {
+				break
+			}
+			txID, cost, err = client.SignIssueRawTx(is.ctx, is.cli, utx, is.priv, is.opts...)
+		}
+		if err == nil && utx == nil {
+			if len(keys) == 1 {
+				color.Yellow("already on-chain k=%s, skipping", keys[0])
+			} else {
+				color.Yellow("already on-chain batch of %d chunks, skipping", len(keys))
+			}
+			close(t.done)
+			return
+		}
+		is.l.Lock()
+		if err != nil {
+			if is.err == nil {
+				is.err = err
+			}
+			is.l.Unlock()
+
+			// Stop the other txs in flight: the upload has failed
+			is.cancel()
+			t.err = err
+			close(t.done)
+			return
+		}
+		is.stats.Cost += cost
+		is.stats.Txs++
+		totalCost := is.stats.Cost
+		is.l.Unlock()
+		if b, ok := utx.(*chain.BatchTx); ok {
+			color.Yellow(
+				"uploaded batch of %d chunks txID=%s cost=%d totalCost=%d",
+				len(b.Values), txID, cost, totalCost,
+			)
+		} else {
+			color.Yellow("uploaded k=%s txID=%s cost=%d totalCost=%d", keys[0], txID, cost, totalCost)
+		}
+		close(t.done)
+	}()
+	return nil
+}
+
+// withoutStored returns [utx] without the values that are already stored
+// (nil if all of them are), recording them as reused by [t]. [rejected] is
+// returned if none of them are.
+func (is *issuer) withoutStored(
+	t *issueTask, utx chain.UnsignedTransaction, rejected error,
+) (chain.UnsignedTransaction, error) {
+	switch tx := utx.(type) {
+	case *chain.SetTx:
+		t.reusedChunks++
+		t.reusedBytes += uint64(len(tx.Value))
+		return nil, nil
+	case *chain.BatchTx:
+		keys := make([]common.Hash, len(tx.Values))
+		for i, v := range tx.Values {
+			k, err := is.cli.ValueHash(is.ctx, v.Value)
+			if err != nil {
+				return nil, err
+			}
+			keys[i] = k
+		}
+		exists, err := is.cli.HasKeys(is.ctx, keys)
+		if err != nil {
+			return nil, err
+		}
+		remaining := &chain.BatchTx{BaseTx: &chain.BaseTx{}}
+		for i, v := range tx.Values {
+			if exists[i] {
+				t.reusedChunks++
+				t.reusedBytes += uint64(len(v.Value))
+				continue
+			}
+			remaining.Values = append(remaining.Values, v)
+		}
+		switch {
+		case len(remaining.Values) == len(tx.Values):
+			return nil, rejected
+		case len(remaining.Values) == 0:
+			return nil, nil
+		}
+		return remaining, nil
+	default:
+		return nil, rejected
+	}
+}
+
+// isKeyExists returns true if [err] indicates a tx set a value that is
+// already stored.
+//
+// Errors are returned as strings over RPC, so [errors.Is] can't be used.
+func isKeyExists(err error) bool {
+	return strings.Contains(err.Error(), chain.ErrKeyExists.Error())
+}
+
+// drain calls [f] with the keys of each confirmed tx, in the order the txs were
+// issued, waiting for the oldest tx while more than [max] are in flight. It
+// returns the first error returned by any tx.
+func (is *issuer) drain(max int, f func(common.Hash) error) error {
+	for len(is.tasks) > 0 {
+		t := is.tasks[0]
+		if len(is.tasks) > max {
+			<-t.done
+		} else {
+			select {
+			case <-t.done:
+			default:
+				return nil
+			}
+		}
+		if t.err != nil {
+			return is.error()
+		}
+		is.tasks = is.tasks[1:]
+		is.stats.UploadedChunks -= t.reusedChunks
+		is.stats.UploadedBytes -= t.reusedBytes
+		is.stats.ReusedChunks += t.reusedChunks
+		is.stats.ReusedBytes += t.reusedBytes
+		for _, k := range t.keys {
+			if err := f(k); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (is *issuer) error() error {
+	is.l.Lock()
+	defer is.l.Unlock()
+	if is.err != nil {
+		return is.err
+	}
+	return is.ctx.Err()
+}
+
+// close cancels any txs in flight and waits for them to exit.
+func (is *issuer) close() {
+	is.cancel()
+	is.wg.Wait()
+}
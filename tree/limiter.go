@@ -0,0 +1,92 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package tree
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// initialConcurrency is the number of chunks fetched at once when a
+	// download starts
+	initialConcurrency = 2
+
+	minThrottleBackoff = 50 * time.Millisecond
+	maxThrottleBackoff = 2 * time.Second
+	maxThrottleRetries = 8
+)
+
+// limiter bounds the number of concurrent requests, adapting the bound to
+// the server: it grows by one after [limit] consecutive successful requests
+// (up to [max]) and halves whenever a request is throttled.
+type limiter struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	limit    int
+	max      int
+	inflight int
+	streak   int
+}
+
+func newLimiter(initial int, max int) *limiter {
+	if initial > max {
+		initial = max
+	}
+	l := &limiter{limit: initial, max: max}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+// acquire blocks until fewer than [limit] requests are in flight (or [ctx] is
+// done).
+func (l *limiter) acquire(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for l.inflight >= l.limit {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		l.cond.Wait()
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	l.inflight++
+	return nil
+}
+
+// release marks a request as finished, adjusting [limit] based on whether it
+// was [throttled].
+func (l *limiter) release(throttled bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inflight--
+	if throttled {
+		l.limit /= 2
+		if l.limit < 1 {
+			l.limit = 1
+		}
+		l.streak = 0
+	} else {
+		l.streak++
+		if l.streak >= l.limit && l.limit < l.max {
+			l.limit++
+			l.streak = 0
+		}
+	}
+	l.cond.Broadcast()
+}
+
+// wakeOnDone wakes all goroutines blocked in [acquire] once [ctx] is done.
+func (l *limiter) wakeOnDone(ctx context.Context) {
+	<-ctx.Done()
+	l.mu.Lock()
+	l.cond.Broadcast()
+	l.mu.Unlock()
+}
@@ -0,0 +1,140 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package tree
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/ava-labs/blobvm/client"
+	"github.com/ava-labs/blobvm/vm"
+)
+
+// ChunkKeys reads [f] and returns the keys of the chunks [Upload] would store
+// (with the same [chunkSize] and [opts]) in file order, without issuing any
+// txs. Repeated chunks are listed each time they occur, and small files stored
+// in their [Root] have no chunks.
+func ChunkKeys(
+	ctx context.Context, cli client.Client, f io.Reader, chunkSize int, opts ...UploadOption,
+) ([]common.Hash, error) {
+	op, _, err := prepareUpload(ctx, cli, chunkSize, opts)
+	if err != nil {
+		return nil, err
+	}
+	return chunkKeys(ctx, cli, f, chunkSize, op)
+}
+
+// StoredChunks returns whether each of [keys] is already stored (ex: the keys
+// returned by [ChunkKeys]), checking up to [vm.MaxHasKeysLimit] keys per
+// request.
+func StoredChunks(ctx context.Context, cli client.Client, keys []common.Hash) ([]bool, error) {
+	stored := make([]bool, 0, len(keys))
+	for start := 0; start < len(keys); start += vm.MaxHasKeysLimit {
+		end := start + vm.MaxHasKeysLimit
+		if end > len(keys) {
+			end = len(keys)
+		}
+		exists, err := cli.HasKeys(ctx, keys[start:end])
+		if err != nil {
+			return nil, err
+		}
+		stored = append(stored, exists...)
+	}
+	return stored, nil
+}
+
+// chunkKeys mirrors the chunking done by [upload].
+func chunkKeys(
+	ctx context.Context, cli client.Client, f io.Reader, chunkSize int, op *uploadOp,
+) ([]common.Hash, error) {
+	key := func(chunk []byte) (common.Hash, error) {
+		chunk, err := op.compression.compress(chunk)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		return cli.ValueHash(ctx, chunk)
+	}
+
+	var (
+		ch   = op.chunking.newChunker(f, op.compression.readSize(chunkSize))
+		keys []common.Hash
+	)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		chunk, last, err := ch.next()
+		if err != nil {
+			return nil, err
+		}
+		if last && len(keys) == 0 {
+			if len(chunk) == 0 {
+				// [f] was empty
+				return nil, ErrEmpty
+			}
+			_, ok, err := smallRoot(chunk, chunkSize)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				return nil, nil
+			}
+		}
+		if last && len(chunk) == 0 {
+			return keys, nil
+		}
+		k, err := key(chunk)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+		if last {
+			return keys, nil
+		}
+	}
+}
+
+// precheck returns whether each chunk of [f] is already stored (see
+// [WithPrecheck]), leaving [f] at the offset it was read from.
+func precheck(
+	ctx context.Context, cli client.Client, f io.Reader, chunkSize int, op *uploadOp,
+) (map[common.Hash]bool, error) {
+	s, ok := f.(io.Seeker)
+	if !ok {
+		return nil, fmt.Errorf("%w: precheck reads the file twice", ErrNotSeekable)
+	}
+	offset, err := s.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := chunkKeys(ctx, cli, f, chunkSize, op)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	// Check each distinct chunk once
+	unique := make([]common.Hash, 0, len(keys))
+	prechecked := make(map[common.Hash]bool, len(keys))
+	for _, k := range keys {
+		if _, ok := prechecked[k]; ok {
+			continue
+		}
+		prechecked[k] = false
+		unique = append(unique, k)
+	}
+	stored, err := StoredChunks(ctx, cli, unique)
+	if err != nil {
+		return nil, err
+	}
+	for i, k := range unique {
+		prechecked[k] = stored[i]
+	}
+	return prechecked, nil
+}
@@ -0,0 +1,198 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package tree
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/fatih/color"
+
+	"github.com/ava-labs/blobvm/client"
+)
+
+const (
+	// Values are keyed by their content hash, so a found value never changes
+	// and can be cached indefinitely (ex: by a CDN).
+	ImmutableCacheControl = "public, immutable, max-age=31536000"
+
+	// Anything else (misses that may be set later and keys that aren't
+	// content hashes) must be revalidated.
+	MutableCacheControl = "no-cache"
+)
+
+var _ http.Handler = &ResolveHandler{}
+
+// ResolveHandler is an HTTP gateway that serves files ("GET /file/<root>")
+// and raw values ("GET /value/<key>").
+type ResolveHandler struct {
+	cli  client.Client
+	opts []DownloadOption
+}
+
+// NewResolveHandler returns a [ResolveHandler] that downloads files with
+// [opts].
+func NewResolveHandler(cli client.Client, opts ...DownloadOption) *ResolveHandler {
+	return &ResolveHandler{cli: cli, opts: opts}
+}
+
+func (h *ResolveHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	switch {
+	case strings.HasPrefix(r.URL.Path, client.GatewayFilePath):
+		h.serveFile(w, r, strings.TrimPrefix(r.URL.Path, client.GatewayFilePath))
+	case strings.HasPrefix(r.URL.Path, "/value/"):
+		h.serveValue(w, r, strings.TrimPrefix(r.URL.Path, "/value/"))
+	default:
+		w.Header().Set("Cache-Control", MutableCacheControl)
+		http.NotFound(w, r)
+	}
+}
+
+// parseContentKey returns the content hash encoded in [raw]. Keys that aren't
+// content hashes (ex: named keys) are rejected.
+func parseContentKey(w http.ResponseWriter, r *http.Request, raw string) (common.Hash, bool) {
+	b, err := hexutil.Decode(raw)
+	if err != nil || len(b) != common.HashLength {
+		w.Header().Set("Cache-Control", MutableCacheControl)
+		http.NotFound(w, r)
+		return common.Hash{}, false
+	}
+	return common.BytesToHash(b), true
+}
+
+func (h *ResolveHandler) serveValue(w http.ResponseWriter, r *http.Request, raw string) {
+	key, ok := parseContentKey(w, r, raw)
+	if !ok {
+		return
+	}
+	exists, v, _, err := h.cli.Resolve(r.Context(), key)
+	if err != nil {
+		color.Red("failed to resolve %v: %v", key, err)
+		w.Header().Set("Cache-Control", MutableCacheControl)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !exists {
+		w.Header().Set("Cache-Control", MutableCacheControl)
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Cache-Control", ImmutableCacheControl)
+	w.Header().Set("Content-Type", "application/octet-stream")
+	if _, err := w.Write(v); err != nil {
+		color.Red("failed to write value: %v", err)
+	}
+}
+
+func (h *ResolveHandler) serveFile(w http.ResponseWriter, r *http.Request, raw string) {
+	root, ok := parseContentKey(w, r, raw)
+	if !ok {
+		return
+	}
+	if r.Method == http.MethodHead {
+		h.statFile(w, r, root)
+		return
+	}
+	// The file is streamed as it is downloaded, so the response is only
+	// marked immutable once the first chunk is found.
+	sw := &streamWriter{w: w, etag: fileETag(root), size: recordedSize(r, h.cli, root)}
+	err := Download(r.Context(), h.cli, root, sw, h.opts...)
+	switch {
+	case err == nil && !sw.started:
+		// Can't happen (empty files are rejected), but avoid caching a
+		// blank response
+		w.Header().Set("Cache-Control", MutableCacheControl)
+		w.WriteHeader(http.StatusNoContent)
+	case err == nil:
+	case sw.started:
+		// Too late to change the status, so abort the response: otherwise a
+		// truncated file would look complete (and be cached as immutable)
+		// when its size isn't recorded
+		color.Red("failed to download %v: %v", root, err)
+		panic(http.ErrAbortHandler)
+	default:
+		writeFileError(w, root, err)
+	}
+}
+
+// statFile responds with the headers of the file at [root] (without
+// downloading it if [root] records its size).
+func (h *ResolveHandler) statFile(w http.ResponseWriter, r *http.Request, root common.Hash) {
+	size, err := Stat(r.Context(), h.cli, root, h.opts...)
+	if err != nil {
+		writeFileError(w, root, err)
+		return
+	}
+	w.Header().Set("Cache-Control", ImmutableCacheControl)
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.FormatUint(size, 10))
+	w.Header().Set("ETag", fileETag(root))
+	w.WriteHeader(http.StatusOK)
+}
+
+// recordedSize returns the size of the file at [root] if [root] records it (0
+// otherwise). Errors are left to [Download] to report.
+func recordedSize(r *http.Request, cli client.Client, root common.Hash) uint64 {
+	tr, err := resolveRoot(r.Context(), cli, root)
+	switch {
+	case err != nil:
+		return 0
+	case len(tr.Contents) > 0:
+		return uint64(len(tr.Contents))
+	default:
+		return tr.Size
+	}
+}
+
+// writeFileError responds with the status matching [err] (returned before any
+// of the file at [root] was written).
+func writeFileError(w http.ResponseWriter, root common.Hash, err error) {
+	w.Header().Set("Cache-Control", MutableCacheControl)
+	switch {
+	case errors.Is(err, ErrMissing):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrEmpty), errors.Is(err, ErrTreeTooDeep), errors.Is(err, ErrInvalidRoot):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		color.Red("failed to download %v: %v", root, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// fileETag returns the entity tag of the file at [root]. Files are keyed by
+// their content hash, so the root is a strong validator.
+func fileETag(root common.Hash) string {
+	return `"` + root.Hex() + `"`
+}
+
+// streamWriter sets immutable response headers (and the Content-Length, if
+// [size] is known) before the first write.
+type streamWriter struct {
+	w       http.ResponseWriter
+	etag    string
+	size    uint64
+	started bool
+}
+
+func (s *streamWriter) Write(p []byte) (int, error) {
+	if !s.started {
+		s.started = true
+		s.w.Header().Set("Cache-Control", ImmutableCacheControl)
+		s.w.Header().Set("Content-Type", "application/octet-stream")
+		s.w.Header().Set("ETag", s.etag)
+		if s.size > 0 {
+			s.w.Header().Set("Content-Length", strconv.FormatUint(s.size, 10))
+		}
+		s.w.WriteHeader(http.StatusOK)
+	}
+	return s.w.Write(p)
+}
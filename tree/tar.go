@@ -0,0 +1,65 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package tree
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/ava-labs/blobvm/client"
+)
+
+// DownloadTar writes each root in [entries] to [w] as a tar entry named by its
+// key (in lexical order).
+//
+// Tar headers must include the size of each entry, which is taken from each
+// [Root] (see [Stat]). Roots that don't record their size are downloaded twice:
+// once to compute it (discarding the chunks) and once to stream them into the
+// archive.
+func DownloadTar(
+	ctx context.Context, cli client.Client, entries map[string]common.Hash,
+	w io.Writer, opts ...DownloadOption,
+) error {
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tw := tar.NewWriter(w)
+	for _, name := range names {
+		root := entries[name]
+		size, err := Stat(ctx, cli, root, opts...)
+		if err != nil {
+			return fmt.Errorf("%w: failed to size %s (root=%v)", err, name, root)
+		}
+		if err := tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     name,
+			Mode:     0o644,
+			Size:     int64(size),
+		}); err != nil {
+			return err
+		}
+		if err := Download(ctx, cli, root, tw, opts...); err != nil {
+			return fmt.Errorf("%w: failed to download %s (root=%v)", err, name, root)
+		}
+	}
+	return tw.Close()
+}
+
+// countWriter discards all writes and counts the bytes written.
+type countWriter struct {
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
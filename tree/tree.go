@@ -10,6 +10,8 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
+	"time"
 
 	"github.com/ava-labs/avalanchego/utils/units"
 	"github.com/ethereum/go-ethereum/common"
@@ -22,96 +24,719 @@ import (
 type Root struct {
 	Contents []byte        `json:"contents"`
 	Children []common.Hash `json:"children"`
+
+	// Height is the number of levels of [Root]s below this one. If 0,
+	// [Children] are file chunks. Otherwise, [Children] are [Root]s with a
+	// [Height] of one less.
+	Height uint64 `json:"height,omitempty"`
+
+	// Size is the number of bytes in the original file (0 if unknown, ex: for
+	// roots uploaded before sizes were recorded). Small files stored in
+	// [Contents] don't record it.
+	Size uint64 `json:"size,omitempty"`
+
+	// Compression is the algorithm each chunk in [Children] was compressed
+	// with before it was stored (chunk keys are hashes of the compressed
+	// bytes). Roots that don't record it store chunks as-is. [Contents] are
+	// never compressed.
+	Compression Compression `json:"compression,omitempty"`
+
+	// Chunking is how the file was split into [Children]. Roots that don't
+	// record it were split into chunks of a fixed size. Chunks are
+	// concatenated in order either way.
+	Chunking Chunking `json:"chunking,omitempty"`
+}
+
+// DefaultMaxDepth is the maximum number of [Root] levels [Download] will
+// traverse if [WithMaxDepth] isn't provided.
+const DefaultMaxDepth = 8
+
+// DefaultConcurrency is the maximum number of chunks [Download] will fetch at
+// once if [WithConcurrency] isn't provided.
+const DefaultConcurrency = 8
+
+// Progress is called as the chunks of a file are transferred with the number
+// of chunks [done] (out of [total], or 0 while the total isn't known yet) and
+// the [bytes] of the file they hold.
+type Progress func(done, total int, bytes int64)
+
+type downloadOp struct {
+	maxDepth      uint64
+	concurrency   int
+	progress      func(downloaded uint64, total uint64)
+	chunkProgress Progress
+}
+
+type DownloadOption func(*downloadOp)
+
+// WithMaxDepth sets the maximum number of [Root] levels (a [Root] of file
+// chunks has a depth of 1) traversed by [Download]. Deeper trees are rejected
+// with [ErrTreeTooDeep] before any chunks are fetched.
+func WithMaxDepth(depth uint64) DownloadOption {
+	return func(op *downloadOp) {
+		op.maxDepth = depth
+	}
+}
+
+// WithConcurrency sets the maximum number of chunks fetched at once by
+// [Download]. Downloads start with fewer concurrent requests, ramp up to
+// [concurrency] while the server keeps up, and back off whenever it throttles
+// requests (see [client.IsThrottled]).
+func WithConcurrency(concurrency int) DownloadOption {
+	return func(op *downloadOp) {
+		op.concurrency = concurrency
+	}
+}
+
+// WithProgress calls [f] with the number of bytes written so far after each
+// chunk is written by [Download]. [total] is the size of the file (0 if the
+// [Root] doesn't record it).
+func WithProgress(f func(downloaded uint64, total uint64)) DownloadOption {
+	return func(op *downloadOp) {
+		op.progress = f
+	}
+}
+
+// WithChunkProgress calls [f] after each chunk is written by [Download]. The
+// total number of chunks is only known upfront when [Root] references the
+// chunks directly (deeper trees report a total of 0).
+func WithChunkProgress(f Progress) DownloadOption {
+	return func(op *downloadOp) {
+		op.chunkProgress = f
+	}
+}
+
+type uploadOp struct {
+	batch       bool
+	concurrency int
+	compression Compression
+	chunking    Chunking
+
+	skipDedupCheck bool
+	precheck       bool
+	dedupStats     *DedupStats
+	progress       Progress
+
+	// maxChunks is the number of chunks that fit in a [Root] (set by
+	// [prepareUpload])
+	maxChunks int
+}
+
+type UploadOption func(*uploadOp)
+
+// WithBatching stores chunks in [chain.BatchTx]s (each filling at most
+// [chain.Genesis.TargetBlockSize] units) instead of issuing a SetTx per chunk.
+// Each batch is stored atomically, but a file spanning multiple batches may
+// still be partially uploaded if the upload is interrupted.
+func WithBatching() UploadOption {
+	return func(op *uploadOp) {
+		op.batch = true
+	}
+}
+
+// WithUploadConcurrency issues up to [concurrency] txs storing chunks (or
+// batches of chunks) at once instead of waiting for each to be confirmed
+// before reading the next chunk. Chunks are still recorded in a [Checkpoint]
+// in file order, so an interrupted upload resumes correctly.
+func WithUploadConcurrency(concurrency int) UploadOption {
+	return func(op *uploadOp) {
+		op.concurrency = concurrency
+	}
+}
+
+// WithCompression compresses each chunk with [c] before it is stored (and
+// records [c] in the [Root], so [Download] decompresses chunks). Chunks hold
+// slightly less than the chunk size of the file, so that chunks which don't
+// compress still fit. Small files stored in the [Root] aren't compressed.
+func WithCompression(c Compression) UploadOption {
+	return func(op *uploadOp) {
+		op.compression = c
+	}
+}
+
+// WithChunking splits the file into chunks with [c] (and records [c] in the
+// [Root]). [Buzhash] chunks are at most the chunk size of the file.
+func WithChunking(c Chunking) UploadOption {
+	return func(op *uploadOp) {
+		op.chunking = c
+	}
+}
+
+// WithSkipDedupCheck doesn't check whether each chunk is already on-chain
+// before issuing a tx to store it, saving a request per chunk when uploading
+// content that is (almost certainly) new. Chunks already on-chain are instead
+// found when the tx storing them is rejected with [chain.ErrKeyExists]: they
+// are then reused as usual (a batch is reissued without them, after checking
+// which of its chunks are stored). Chunks repeated within the file are still
+// only uploaded once.
+func WithSkipDedupCheck() UploadOption {
+	return func(op *uploadOp) {
+		op.skipDedupCheck = true
+	}
+}
+
+// WithPrecheck reads the file once before uploading it to derive the keys of
+// its chunks (see [ChunkKeys]) and checks which are already stored in batches
+// (see [StoredChunks]), instead of checking each chunk before issuing the tx
+// storing it. Chunks stored after the check are found when their tx is
+// rejected (as with [WithSkipDedupCheck]). The file is read from its current
+// offset and must be an [io.Seeker] (ex: an [os.File]), so it can be read
+// again.
+func WithPrecheck() UploadOption {
+	return func(op *uploadOp) {
+		op.precheck = true
+	}
+}
+
+// WithDedupStats adds the [UploadStats] of the upload to [s] once it
+// completes, so dedup can be tracked across many uploads (including those
+// made with [Upload], which doesn't return its stats).
+func WithDedupStats(s *DedupStats) UploadOption {
+	return func(op *uploadOp) {
+		op.dedupStats = s
+	}
+}
+
+// WithUploadProgress calls [f] after each chunk is confirmed (or found to be
+// already stored), and once more when the [Root] is stored. [f] is passed the
+// bytes of the file (before compression) held by the chunks done so far. As
+// the file is read while chunks are stored, the total number of chunks is 0
+// until the whole file has been read.
+func WithUploadProgress(f Progress) UploadOption {
+	return func(op *uploadOp) {
+		op.progress = f
+	}
 }
 
+// UploadStats summarizes the work done during an upload.
+type UploadStats struct {
+	UploadedChunks int    `json:"uploadedChunks"`
+	UploadedBytes  uint64 `json:"uploadedBytes"`
+	ReusedChunks   int    `json:"reusedChunks"`
+	ReusedBytes    uint64 `json:"reusedBytes"`
+	Cost           uint64 `json:"cost"`
+
+	// Txs is the number of txs issued (including the one storing the [Root])
+	Txs int `json:"txs"`
+}
+
+// Upload stores [f] on-chain and returns the hash of its [Root].
+//
+// If [f] is smaller than [chunkSize] (and still fits once encoded), it is
+// stored directly in the [Root]. Otherwise, [f] is split into [chunkSize]
+// chunks (only the last of which may be smaller) that are referenced by the
+// [Root]. A file whose size is an exact multiple of [chunkSize] has exactly
+// size/chunkSize chunks. Empty files are rejected with [ErrEmpty].
+//
+// [chunkSize] may be smaller than the chain's [chain.Genesis.MaxValueSize]
+// (but not bigger, see [ErrInvalidChunkSize]).
 func Upload(
 	ctx context.Context, cli client.Client, priv *ecdsa.PrivateKey,
-	f io.Reader, chunkSize int,
+	f io.Reader, chunkSize int, opts ...UploadOption,
 ) (common.Hash, error) {
-	hashes := []common.Hash{}
-	chunk := make([]byte, chunkSize)
-	shouldExit := false
-	opts := []client.OpOption{client.WithPollTx()}
-	totalCost := uint64(0)
-	uploaded := map[common.Hash]struct{}{}
-	for !shouldExit {
-		read, err := f.Read(chunk)
-		if errors.Is(err, io.EOF) || read == 0 {
-			break
+	rk, _, err := upload(ctx, cli, priv, f, chunkSize, map[common.Hash]struct{}{}, nil, opts)
+	return rk, err
+}
+
+// UploadResumable is like [Upload] but records each confirmed chunk in a
+// [Checkpoint] at [checkpoint]. If the upload is interrupted, calling
+// [UploadResumable] again with the same file and [checkpoint] skips the
+// recorded chunks without checking whether they are on-chain. Recorded chunks
+// are verified against the chunks of [f], so a stale or corrupt checkpoint is
+// ignored. The checkpoint is removed once the upload completes.
+func UploadResumable(
+	ctx context.Context, cli client.Client, priv *ecdsa.PrivateKey,
+	f io.Reader, chunkSize int, checkpoint string, opts ...UploadOption,
+) (common.Hash, *UploadStats, error) {
+	cp := loadCheckpoint(checkpoint, chunkSize)
+	rk, stats, err := upload(ctx, cli, priv, f, chunkSize, map[common.Hash]struct{}{}, cp, opts)
+	if err != nil {
+		return common.Hash{}, nil, err
+	}
+	if err := cp.done(); err != nil {
+		return common.Hash{}, nil, err
+	}
+	return rk, stats, nil
+}
+
+// UploadDelta uploads [f] as a new version of [baseRoot]. Any chunk already
+// referenced by [baseRoot] (or otherwise on-chain) is reused instead of being
+// uploaded again.
+func UploadDelta(
+	ctx context.Context, cli client.Client, priv *ecdsa.PrivateKey,
+	baseRoot common.Hash, f io.Reader, chunkSize int, opts ...UploadOption,
+) (common.Hash, *UploadStats, error) {
+	known, err := baseChunks(ctx, cli, baseRoot, opts)
+	if err != nil {
+		return common.Hash{}, nil, err
+	}
+	return upload(ctx, cli, priv, f, chunkSize, known, nil, opts)
+}
+
+// baseChunks returns the file chunks under [baseRoot] that are still stored
+// and encoded as [Upload] would encode them with [uopts] (chunks compressed
+// or split differently can't match the new version's).
+func baseChunks(
+	ctx context.Context, cli client.Client, baseRoot common.Hash, uopts []UploadOption,
+) (map[common.Hash]struct{}, error) {
+	op := &uploadOp{}
+	for _, o := range uopts {
+		o(op)
+	}
+	br, err := resolveRoot(ctx, cli, baseRoot)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkDepth(br, DefaultMaxDepth); err != nil {
+		return nil, err
+	}
+	candidates := []common.Hash{}
+	seen := map[common.Hash]struct{}{}
+	if err := walkLeaves(ctx, cli, br, func(leaf *Root) error {
+		if leaf.Compression != op.compression || leaf.Chunking != op.chunking {
+			return nil
 		}
+		for _, h := range leaf.Children {
+			if _, ok := seen[h]; !ok {
+				seen[h] = struct{}{}
+				candidates = append(candidates, h)
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	// Chunks that have expired must be uploaded again
+	stored, err := StoredChunks(ctx, cli, candidates)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[common.Hash]struct{}, len(candidates))
+	for i, h := range candidates {
+		if stored[i] {
+			known[h] = struct{}{}
+		}
+	}
+	return known, nil
+}
+
+// upload chunks [f] and issues a SetTx (or [chain.BatchTx]) for the chunks
+// that aren't in [uploaded], recorded by [cp] (if not nil), or on-chain,
+// followed by a SetTx for the [Root].
+func upload(
+	ctx context.Context, cli client.Client, priv *ecdsa.PrivateKey,
+	f io.Reader, chunkSize int, uploaded map[common.Hash]struct{}, cp *checkpointer,
+	uopts []UploadOption,
+) (common.Hash, *UploadStats, error) {
+	op, g, err := prepareUpload(ctx, cli, chunkSize, uopts)
+	if err != nil {
+		return common.Hash{}, nil, err
+	}
+	if err := checkChunks(f, chunkSize, op); err != nil {
+		return common.Hash{}, nil, err
+	}
+	hashes := []common.Hash{}
+	stats := &UploadStats{}
+
+	// [prechecked] records whether each chunk found by [WithPrecheck] is
+	// stored
+	var prechecked map[common.Hash]bool
+	if op.precheck {
+		prechecked, err = precheck(ctx, cli, f, chunkSize, op)
 		if err != nil {
-			return common.Hash{}, fmt.Errorf("%w: read error", err)
+			return common.Hash{}, nil, err
 		}
-		if read < chunkSize {
-			shouldExit = true
-			chunk = chunk[:read]
+	}
+
+	var (
+		// chunks waiting to be issued in a single [chain.BatchTx]
+		batch *chain.BatchTx
+
+		// keys of the chunks waiting for [batch] to be issued (in file order)
+		pending []common.Hash
+
+		// keys of the chunks that have been confirmed (in file order)
+		completed []common.Hash
 
-			// Use small file optimization
-			if len(hashes) == 0 {
-				break
+		// sizes of the chunks read so far (before compression), the bytes
+		// held by [completed], and the number of chunks (once [f] is read)
+		// reported to [op.progress]
+		chunkSizes     []int64
+		completedBytes int64
+		totalChunks    int
+	)
+	is := newIssuer(ctx, cli, priv, op.concurrency, op.skipDedupCheck || op.precheck, stats)
+	defer is.close()
+
+	// wrap reports the confirmed chunks if [err] interrupted the upload
+	wrap := func(err error) error {
+		if err != nil && ctx.Err() != nil {
+			return &InterruptedError{Completed: completed, Err: err}
+		}
+		return err
+	}
+
+	// record marks [k] as confirmed (in the checkpoint, if any)
+	record := func(k common.Hash) error {
+		completed = append(completed, k)
+		if cp != nil {
+			if err := cp.record(k); err != nil {
+				return fmt.Errorf("%w: failed to write checkpoint", err)
 			}
 		}
-		k := chain.ValueHash(chunk)
-		if _, ok := uploaded[k]; ok {
+		if op.progress != nil {
+			completedBytes += chunkSizes[len(completed)-1]
+			op.progress(len(completed), totalChunks, completedBytes)
+		}
+		return nil
+	}
+
+	// flush issues [batch] (if any) for [pending] and records any chunks
+	// confirmed so far
+	flush := func() error {
+		if len(pending) > 0 {
+			var utx chain.UnsignedTransaction
+			if batch != nil {
+				utx = batch
+			}
+			if err := is.issue(pending, utx); err != nil {
+				return wrap(err)
+			}
+			batch, pending = nil, nil
+		}
+		return wrap(is.drain(is.concurrency-1, record))
+	}
+
+	// queue adds [chunk] to [batch], first issuing [batch] if [chunk] would
+	// make it too big to fit in a block
+	queue := func(chunk []byte, k common.Hash) error {
+		v := &chain.BatchValue{Value: chunk}
+		if batch != nil {
+			batch.Values = append(batch.Values, v)
+			if batch.LoadUnits(g) <= g.TargetBlockSize {
+				pending = append(pending, k)
+				return nil
+			}
+			batch.Values = batch.Values[:len(batch.Values)-1]
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		batch = &chain.BatchTx{BaseTx: &chain.BaseTx{}, Values: []*chain.BatchValue{v}}
+		pending = append(pending, k)
+		return nil
+	}
+
+	// store issues a SetTx for [chunk] (compressed, unless it already exists
+	// or is batched) and returns its key. [chunk] is recorded in the
+	// checkpoint once it (and every chunk before it) is confirmed.
+	store := func(chunk []byte) (common.Hash, error) {
+		// Don't start a new chunk after cancellation
+		if err := ctx.Err(); err != nil {
+			return common.Hash{}, wrap(err)
+		}
+		chunkSizes = append(chunkSizes, int64(len(chunk)))
+		chunk, err := op.compression.compress(chunk)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		k, err := cli.ValueHash(ctx, chunk)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		reused := true
+		if cp != nil && cp.confirmed(len(hashes), k) {
+			color.Yellow("checkpointed k=%s, skipping", k)
+			uploaded[k] = struct{}{}
+		} else if _, ok := uploaded[k]; ok {
 			color.Yellow("already uploaded k=%s, skipping", k)
-		} else if exists, _, _, err := cli.Resolve(ctx, k); err == nil && exists {
+		} else if stored, ok := prechecked[k]; ok {
+			if stored {
+				color.Yellow("already on-chain k=%s, skipping", k)
+				uploaded[k] = struct{}{}
+			} else {
+				reused = false
+			}
+		} else if op.skipDedupCheck {
+			// Chunks already on-chain are found when their tx is rejected (see
+			// [issuer.withoutStored])
+			reused = false
+		} else if exists, err := cli.HasKeys(ctx, []common.Hash{k}); err == nil && exists[0] {
 			color.Yellow("already on-chain k=%s, skipping", k)
 			uploaded[k] = struct{}{}
 		} else {
-			tx := &chain.SetTx{
-				BaseTx: &chain.BaseTx{},
-				Value:  chunk,
-			}
-			txID, cost, err := client.SignIssueRawTx(ctx, cli, tx, priv, opts...)
-			if err != nil {
-				return common.Hash{}, err
+			reused = false
+		}
+		if reused {
+			stats.ReusedChunks++
+			stats.ReusedBytes += uint64(len(chunk))
+			pending = append(pending, k)
+			if batch == nil {
+				return k, flush()
 			}
-			totalCost += cost
-			color.Yellow("uploaded k=%s txID=%s cost=%d totalCost=%d", k, txID, cost, totalCost)
-			uploaded[k] = struct{}{}
+			return k, nil
+		}
+
+		uploaded[k] = struct{}{}
+		stats.UploadedChunks++
+		stats.UploadedBytes += uint64(len(chunk))
+		v := make([]byte, len(chunk))
+		copy(v, chunk) // [chunk] is reused for the next read
+		if op.batch {
+			return k, queue(v, k)
+		}
+		if err := is.issue([]common.Hash{k}, &chain.SetTx{BaseTx: &chain.BaseTx{}, Value: v}); err != nil {
+			return common.Hash{}, wrap(err)
+		}
+		return k, wrap(is.drain(is.concurrency-1, record))
+	}
+
+	var (
+		ch    = op.chunking.newChunker(f, op.compression.readSize(chunkSize))
+		chunk []byte
+		size  uint64
+	)
+	for {
+		var (
+			last bool
+			err  error
+		)
+		chunk, last, err = ch.next()
+		if err != nil {
+			return common.Hash{}, nil, err
+		}
+		size += uint64(len(chunk))
+
+		// Use small file optimization (only files that fit in a single chunk
+		// may be stored in the [Root])
+		if last && (len(chunk) == 0 || len(hashes) == 0) {
+			break
+		}
+		// Stop paying for chunks once the [Root] can't list them
+		if len(hashes) == op.maxChunks {
+			return common.Hash{}, nil, fmt.Errorf("%w: more than %d chunks of %d bytes don't fit in a root", ErrTooBig, op.maxChunks, chunkSize)
+		}
+		k, err := store(chunk)
+		if err != nil {
+			return common.Hash{}, nil, err
 		}
 		hashes = append(hashes, k)
+		if last {
+			break
+		}
 	}
 
-	r := &Root{}
+	r := &Root{Children: hashes, Size: size, Compression: op.compression, Chunking: op.chunking}
 	if len(hashes) == 0 {
 		if len(chunk) == 0 {
-			return common.Hash{}, ErrEmpty
+			// [f] was empty
+			return common.Hash{}, nil, ErrEmpty
 		}
-		r.Contents = chunk
-	} else {
-		r.Children = hashes
+
+		sr, ok, err := smallRoot(chunk, chunkSize)
+		if err != nil {
+			return common.Hash{}, nil, err
+		}
+		if ok {
+			r = sr
+			stats.UploadedBytes += uint64(len(chunk))
+		} else {
+			k, err := store(chunk)
+			if err != nil {
+				return common.Hash{}, nil, err
+			}
+			r.Children = append(r.Children, k)
+		}
+	}
+	totalChunks = len(r.Children)
+
+	if err := flush(); err != nil {
+		return common.Hash{}, nil, err
+	}
+	if err := wrap(is.drain(0, record)); err != nil {
+		return common.Hash{}, nil, err
 	}
 
 	rb, err := json.Marshal(r)
 	if err != nil {
-		return common.Hash{}, err
+		return common.Hash{}, nil, err
+	}
+	if uint64(len(rb)) > g.MaxValueSize {
+		// The [Root] lists every chunk, so small chunks limit the file size
+		return common.Hash{}, nil, fmt.Errorf("%w: %d chunks of %d bytes don't fit in a root", ErrTooBig, len(hashes), chunkSize)
+	}
+	rk, err := cli.ValueHash(ctx, rb)
+	if err != nil {
+		return common.Hash{}, nil, err
 	}
-	rk := chain.ValueHash(rb)
 	tx := &chain.SetTx{
 		BaseTx: &chain.BaseTx{},
 		Value:  rb,
 	}
-	txID, cost, err := client.SignIssueRawTx(ctx, cli, tx, priv, opts...)
+	txID, cost, err := client.SignIssueRawTx(ctx, cli, tx, priv, is.opts...)
 	if err != nil {
-		return common.Hash{}, err
+		return common.Hash{}, nil, err
+	}
+	stats.Cost += cost
+	stats.Txs++
+	color.Yellow("uploaded root=%v txID=%s cost=%d totalCost=%d", rk, txID, cost, stats.Cost)
+	if op.dedupStats != nil {
+		op.dedupStats.Add(stats)
+	}
+	if op.progress != nil {
+		op.progress(len(completed), totalChunks, int64(size))
 	}
-	totalCost += cost
-	color.Yellow("uploaded root=%v txID=%s cost=%d totalCost=%d", rk, txID, cost, totalCost)
-	return rk, nil
+	return rk, stats, nil
 }
 
-// TODO: make multi-threaded
-func Download(ctx context.Context, cli client.Client, root common.Hash, f io.Writer) error {
-	exists, rb, _, err := cli.Resolve(ctx, root)
+// prepareUpload applies [uopts] and checks them (and [chunkSize]) against the
+// chain.
+func prepareUpload(
+	ctx context.Context, cli client.Client, chunkSize int, uopts []UploadOption,
+) (*uploadOp, *chain.Genesis, error) {
+	op := &uploadOp{concurrency: 1}
+	for _, o := range uopts {
+		o(op)
+	}
+	if err := op.compression.verify(); err != nil {
+		return nil, nil, err
+	}
+	if err := op.chunking.verify(); err != nil {
+		return nil, nil, err
+	}
+
+	g, err := cli.CachedGenesis(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Smaller chunks dedup better across similar files (at the cost of more
+	// txs), but chunks can't be bigger than the chain allows
+	if chunkSize < 1 || uint64(chunkSize) > g.MaxValueSize {
+		return nil, nil, fmt.Errorf("%w: %d (max=%d)", ErrInvalidChunkSize, chunkSize, g.MaxValueSize)
+	}
+	// Chunks are read in [readSize] pieces, so compressed chunks still fit in
+	// [chunkSize]
+	if op.compression.readSize(chunkSize) < 1 {
+		return nil, nil, fmt.Errorf("%w: chunk size %d is too small", ErrUnsupportedCompression, chunkSize)
+	}
+	if op.maxChunks, err = maxChunks(op, g.MaxValueSize); err != nil {
+		return nil, nil, err
+	}
+	return op, g, nil
+}
+
+// maxChunks returns the number of chunks the [Root] of a file uploaded with
+// [op] can list and still fit in [maxValueSize] bytes.
+func maxChunks(op *uploadOp, maxValueSize uint64) (int, error) {
+	rootSize := func(chunks int) (uint64, error) {
+		rb, err := json.Marshal(&Root{
+			Children:    make([]common.Hash, chunks),
+			Size:        math.MaxUint64,
+			Compression: op.compression,
+			Chunking:    op.chunking,
+		})
+		return uint64(len(rb)), err
+	}
+	one, err := rootSize(1)
+	if err != nil {
+		return 0, err
+	}
+	two, err := rootSize(2)
+	if err != nil {
+		return 0, err
+	}
+	if one > maxValueSize {
+		return 0, nil
+	}
+	// Every hash is encoded with the same number of bytes
+	return 1 + int((maxValueSize-one)/(two-one)), nil
+}
+
+// checkChunks returns [ErrTooBig] if the rest of [f] can't fit in a [Root]
+// once split into [chunkSize] chunks. Only files of known size split into
+// fixed size chunks can be checked before they are read.
+func checkChunks(f io.Reader, chunkSize int, op *uploadOp) error {
+	s, ok := f.(io.Seeker)
+	if !ok || op.chunking != FixedChunking {
+		return nil
+	}
+	offset, err := s.Seek(0, io.SeekCurrent)
 	if err != nil {
 		return err
 	}
+	end, err := s.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if _, err := s.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	readSize := int64(op.compression.readSize(chunkSize))
+	if chunks := (end - offset + readSize - 1) / readSize; chunks > int64(op.maxChunks) {
+		return fmt.Errorf("%w: %d chunks of %d bytes don't fit in a root (max=%d)", ErrTooBig, chunks, chunkSize, op.maxChunks)
+	}
+	return nil
+}
+
+// smallRoot returns the [Root] storing [contents] directly, if it fits in
+// [chunkSize]. [Contents] are base64-encoded in the [Root], so a file smaller
+// than [chunkSize] may still not fit (it is then stored as a single chunk).
+func smallRoot(contents []byte, chunkSize int) (*Root, bool, error) {
+	r := &Root{Contents: contents}
+	rb, err := json.Marshal(r)
+	if err != nil {
+		return nil, false, err
+	}
+	return r, len(rb) <= chunkSize, nil
+}
+
+// Stat returns the size of the file at [root]. If [root] doesn't record its
+// [Size], the file is downloaded (and discarded) to count its bytes.
+func Stat(ctx context.Context, cli client.Client, root common.Hash, opts ...DownloadOption) (uint64, error) {
+	r, err := resolveRoot(ctx, cli, root)
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case len(r.Contents) > 0:
+		return uint64(len(r.Contents)), nil
+	case r.Size > 0:
+		return r.Size, nil
+	}
+	cw := &countWriter{}
+	if err := Download(ctx, cli, root, cw, opts...); err != nil {
+		return 0, err
+	}
+	return uint64(cw.n), nil
+}
+
+func resolveRoot(ctx context.Context, cli client.Client, root common.Hash) (*Root, error) {
+	exists, rb, _, err := cli.Resolve(ctx, root)
+	if err != nil {
+		return nil, err
+	}
 	if !exists {
-		return fmt.Errorf("%w:%v", ErrMissing, root)
+		return nil, fmt.Errorf("%w:%v", ErrMissing, root)
+	}
+	r := new(Root)
+	if err := json.Unmarshal(rb, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func Download(ctx context.Context, cli client.Client, root common.Hash, f io.Writer, opts ...DownloadOption) error {
+	op := &downloadOp{maxDepth: DefaultMaxDepth, concurrency: DefaultConcurrency}
+	for _, opt := range opts {
+		opt(op)
+	}
+	if op.concurrency < 1 {
+		op.concurrency = 1
 	}
-	var r Root
-	if err := json.Unmarshal(rb, &r); err != nil {
+
+	r, err := resolveRoot(ctx, cli, root)
+	if err != nil {
 		return err
 	}
 
@@ -120,6 +745,12 @@ func Download(ctx context.Context, cli client.Client, root common.Hash, f io.Wri
 		if _, err := f.Write(r.Contents); err != nil {
 			return err
 		}
+		if op.progress != nil {
+			op.progress(uint64(contentLen), uint64(contentLen))
+		}
+		if op.chunkProgress != nil {
+			op.chunkProgress(0, 0, int64(contentLen))
+		}
 		color.Yellow("downloaded root=%v size=%fKB", root, float64(contentLen)/units.KiB)
 		return nil
 	}
@@ -127,23 +758,204 @@ func Download(ctx context.Context, cli client.Client, root common.Hash, f io.Wri
 	if len(r.Children) == 0 {
 		return ErrEmpty
 	}
+	if err := r.Compression.verify(); err != nil {
+		return err
+	}
+	if err := r.Chunking.verify(); err != nil {
+		return err
+	}
+
+	if err := checkDepth(r, op.maxDepth); err != nil {
+		return err
+	}
+
+	// Preallocate the destination (if it supports it) when the size is known
+	if g, ok := f.(interface{ Grow(int) }); ok && r.Size > 0 && r.Size <= math.MaxInt32 {
+		g.Grow(int(r.Size))
+	}
+
+	d := &downloader{
+		cli:      cli,
+		f:        f,
+		window:   op.concurrency,
+		lim:      newLimiter(initialConcurrency, op.concurrency),
+		total:    r.Size,
+		progress: op.progress,
+
+		chunkProgress: op.chunkProgress,
+	}
+	if r.Height == 0 {
+		d.chunks = len(r.Children)
+	}
+	if err := d.download(ctx, r); err != nil {
+		return err
+	}
+	if r.Size > 0 && uint64(d.downloaded) != r.Size {
+		return fmt.Errorf("%w: downloaded %d bytes (expected %d)", ErrInvalidRoot, d.downloaded, r.Size)
+	}
+	color.Yellow("download complete root=%v size=%fMB", root, float64(d.downloaded)/units.MiB)
+	return nil
+}
+
+type downloader struct {
+	cli client.Client
+	f   io.Writer
+
+	// [window] bounds the number of chunks fetched ahead of the next chunk to
+	// write and [lim] bounds the number of chunks fetched at once
+	window int
+	lim    *limiter
+
+	// [progress] is called with the bytes written so far (out of [total])
+	// after each chunk
+	total    uint64
+	progress func(downloaded uint64, total uint64)
+
+	// [chunkProgress] is called with the chunks written so far (out of
+	// [chunks], if known) after each chunk
+	chunks        int
+	chunkProgress Progress
+
+	completed  []common.Hash
+	downloaded int
+}
+
+// download writes all chunks under [r] to [f] (in order).
+func (d *downloader) download(ctx context.Context, r *Root) error {
+	err := walkLeaves(ctx, d.cli, r, func(leaf *Root) error {
+		return d.downloadChunks(ctx, leaf.Children, leaf.Compression)
+	})
+	var ierr *InterruptedError
+	if err != nil && ctx.Err() != nil && !errors.As(err, &ierr) {
+		return &InterruptedError{Completed: d.completed, Err: err}
+	}
+	return err
+}
 
-	amountDownloaded := 0
+// checkDepth returns [ErrTreeTooDeep] if [r] has more than [maxDepth] levels.
+// [walkLeaves] checks [Height] against each child, so the depth of the tree is
+// known before any chunks are fetched.
+func checkDepth(r *Root, maxDepth uint64) error {
+	if r.Height >= maxDepth {
+		return fmt.Errorf("%w: depth=%d (max=%d)", ErrTreeTooDeep, r.Height+1, maxDepth)
+	}
+	return nil
+}
+
+// walkLeaves calls [f] with each [Root] of file chunks (of [Height] 0) under
+// [r], in order. Each sub-root must be exactly one level below its parent.
+func walkLeaves(ctx context.Context, cli client.Client, r *Root, f func(leaf *Root) error) error {
+	if r.Height == 0 {
+		return f(r)
+	}
 	for _, h := range r.Children {
-		exists, b, _, err := cli.Resolve(ctx, h)
+		// Don't start a new subtree after cancellation
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		child, err := resolveRoot(ctx, cli, h)
 		if err != nil {
 			return err
 		}
-		if !exists {
-			return fmt.Errorf("%w:%s", ErrMissing, h)
+		if child.Height != r.Height-1 || len(child.Contents) > 0 || len(child.Children) == 0 {
+			return fmt.Errorf("%w:%s", ErrInvalidRoot, h)
 		}
-		if _, err := f.Write(b); err != nil {
+		if err := walkLeaves(ctx, cli, child, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type chunkResult struct {
+	b   []byte
+	err error
+}
+
+// downloadChunks fetches [hashes] concurrently and writes them to [f] (in
+// order), decompressed with [c].
+func (d *downloader) downloadChunks(ctx context.Context, hashes []common.Hash, c Compression) error {
+	fctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go d.lim.wakeOnDone(fctx)
+
+	var (
+		results  = make([]chan chunkResult, len(hashes))
+		launched int
+	)
+	for i, h := range hashes {
+		for ; launched < len(hashes) && launched < i+d.window; launched++ {
+			c := make(chan chunkResult, 1)
+			results[launched] = c
+			go func(h common.Hash) {
+				b, err := d.fetchChunk(fctx, h)
+				c <- chunkResult{b: b, err: err}
+			}(hashes[launched])
+		}
+
+		// Don't wait for a new chunk after cancellation
+		if err := ctx.Err(); err != nil {
+			return &InterruptedError{Completed: d.completed, Err: err}
+		}
+		res := <-results[i]
+		if res.err != nil {
+			if err := ctx.Err(); err != nil {
+				return &InterruptedError{Completed: d.completed, Err: err}
+			}
+			return res.err
+		}
+		b, err := c.decompress(res.b)
+		if err != nil {
+			return fmt.Errorf("%w (chunk=%s)", err, h)
+		}
+		if _, err := d.f.Write(b); err != nil {
 			return err
 		}
 		size := len(b)
 		color.Yellow("downloaded chunk=%v size=%fKB", h, float64(size)/units.KiB)
-		amountDownloaded += size
+		d.completed = append(d.completed, h)
+		d.downloaded += size
+		if d.progress != nil {
+			d.progress(uint64(d.downloaded), d.total)
+		}
+		if d.chunkProgress != nil {
+			d.chunkProgress(len(d.completed), d.chunks, int64(d.downloaded))
+		}
 	}
-	color.Yellow("download complete root=%v size=%fMB", root, float64(amountDownloaded)/units.MiB)
 	return nil
 }
+
+// fetchChunk resolves [h], retrying (with exponential backoff) if the server
+// throttles the request.
+func (d *downloader) fetchChunk(ctx context.Context, h common.Hash) ([]byte, error) {
+	backoff := minThrottleBackoff
+	for retries := 0; ; retries++ {
+		if err := d.lim.acquire(ctx); err != nil {
+			return nil, err
+		}
+		exists, b, _, err := d.cli.Resolve(ctx, h)
+		throttled := client.IsThrottled(err)
+		d.lim.release(throttled)
+		switch {
+		case throttled && retries < maxThrottleRetries:
+		case err != nil:
+			return nil, err
+		case !exists:
+			return nil, fmt.Errorf("%w:%s", ErrMissing, h)
+		default:
+			return b, nil
+		}
+
+		color.Yellow("throttled chunk=%v, retrying in %v", h, backoff)
+		t := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+		if backoff *= 2; backoff > maxThrottleBackoff {
+			backoff = maxThrottleBackoff
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package tree
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/ava-labs/blobvm/chain"
+)
+
+// [chain.TreeRoot] mirrors [Root] (and rejects unknown fields), so every field
+// of [Root] must round-trip through it.
+func TestRootMatchesChainTreeRoot(t *testing.T) {
+	t.Parallel()
+
+	for i, r := range []*Root{
+		{Contents: []byte("small")},
+		{Children: []common.Hash{{1}, {2}}},
+		{
+			Children:    []common.Hash{{1}},
+			Height:      1,
+			Size:        10,
+			Compression: Gzip,
+			Chunking:    Buzhash,
+		},
+	} {
+		b, err := json.Marshal(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cr, ok := chain.ParseTreeRoot(b)
+		if !ok {
+			t.Fatalf("#%d: %s not parsed as a tree root", i, b)
+		}
+		cb, err := json.Marshal(cr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dr := new(Root)
+		if err := json.Unmarshal(cb, dr); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(dr, r) {
+			t.Fatalf("#%d: expected %+v, got %+v", i, r, dr)
+		}
+	}
+}
+
+func TestMaxChunks(t *testing.T) {
+	t.Parallel()
+
+	const maxValueSize = 200 * 1024
+	for _, op := range []*uploadOp{
+		{},
+		{compression: Gzip, chunking: Buzhash},
+	} {
+		n, err := maxChunks(op, maxValueSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for chunks, fits := range map[int]bool{n: true, n + 1: false} {
+			rb, err := json.Marshal(&Root{
+				Children:    make([]common.Hash, chunks),
+				Size:        math.MaxUint64,
+				Compression: op.compression,
+				Chunking:    op.chunking,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rb) <= maxValueSize != fits {
+				t.Fatalf("%+v: root of %d chunks is %d bytes (max=%d)", op, chunks, len(rb), maxValueSize)
+			}
+		}
+
+		// Files too big for a root are rejected before they are read
+		op.maxChunks = n
+		chunkSize := 1024
+		readSize := op.compression.readSize(chunkSize)
+		err = checkChunks(bytes.NewReader(make([]byte, n*readSize)), chunkSize, op)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = checkChunks(bytes.NewReader(make([]byte, n*readSize+1)), chunkSize, op)
+		if op.chunking == FixedChunking && !errors.Is(err, ErrTooBig) {
+			t.Fatalf("expected %v, got %v", ErrTooBig, err)
+		}
+		if op.chunking != FixedChunking && err != nil {
+			t.Fatal(err)
+		}
+	}
+}
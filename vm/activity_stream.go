@@ -0,0 +1,138 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package vm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/inconshreveable/log15"
+
+	"github.com/ava-labs/blobvm/chain"
+)
+
+// activityStreamBuffer is the number of activities buffered for each stream
+// before the activity of new blocks is dropped (for that stream only).
+const activityStreamBuffer = 1024
+
+// activityFeed publishes the activity of accepted blocks to the streams
+// served at [ActivityStreamEndpoint]. The zero value has no subscribers.
+type activityFeed struct {
+	l    sync.Mutex
+	subs map[chan *chain.Activity]struct{}
+}
+
+func (f *activityFeed) subscribe() (<-chan *chain.Activity, func()) {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	if f.subs == nil {
+		f.subs = map[chan *chain.Activity]struct{}{}
+	}
+	ch := make(chan *chain.Activity, activityStreamBuffer)
+	f.subs[ch] = struct{}{}
+	return ch, func() {
+		f.l.Lock()
+		defer f.l.Unlock()
+		delete(f.subs, ch)
+	}
+}
+
+func (f *activityFeed) subscribed() bool {
+	f.l.Lock()
+	defer f.l.Unlock()
+	return len(f.subs) > 0
+}
+
+func (f *activityFeed) publish(activity *chain.Activity) {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	for ch := range f.subs {
+		select {
+		case ch <- activity:
+		default:
+			// A slow stream misses activity rather than holding up [Accepted]
+		}
+	}
+}
+
+// activityStream serves the activity of blocks accepted while the request is
+// open as server-sent events: one "data" event holding the JSON-encoded
+// [chain.Activity] per tx. If nothing was sent for
+// [Config.ActivityKeepaliveInterval], a comment is sent so idle connections
+// aren't closed by proxies.
+type activityStream struct {
+	vm *VM
+}
+
+func (s *activityStream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	activity, unsubscribe := s.vm.activityFeed.subscribe()
+	defer unsubscribe()
+
+	// [keepalive] is nil (and never fires) if keepalives are disabled
+	var (
+		interval  = s.vm.config.ActivityKeepaliveInterval
+		timer     *time.Timer
+		keepalive <-chan time.Time
+	)
+	if interval > 0 {
+		timer = time.NewTimer(interval)
+		defer timer.Stop()
+		keepalive = timer.C
+	}
+	for s.next(w, r, activity, keepalive) {
+		flusher.Flush()
+		if timer == nil {
+			continue
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(interval)
+	}
+}
+
+// next writes the next event (or keepalive) to [w], returning false once the
+// request is done.
+func (s *activityStream) next(
+	w http.ResponseWriter,
+	r *http.Request,
+	activity <-chan *chain.Activity,
+	keepalive <-chan time.Time,
+) bool {
+	select {
+	case <-r.Context().Done():
+		return false
+	case <-s.vm.stop:
+		return false
+	case <-keepalive:
+		_, err := fmt.Fprint(w, ": keepalive\n\n")
+		return err == nil
+	case a := <-activity:
+		b, err := json.Marshal(a)
+		if err != nil {
+			log.Warn("could not encode activity", "txID", a.TxID, "err", err)
+			return true
+		}
+		_, err = fmt.Fprintf(w, "data: %s\n\n", b)
+		return err == nil
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package vm
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"sync"
+
+	"github.com/ava-labs/avalanchego/database"
+	"github.com/ethereum/go-ethereum/common"
+	log "github.com/inconshreveable/log15"
+
+	"github.com/ava-labs/blobvm/chain"
+)
+
+// AdminService manages node-local state that isn't part of consensus. It only
+// serves requests that send [Config.AdminToken] (in [AdminTokenHeader]), so
+// proxies on the node's host (ex: the gateway) can't reach it on behalf of
+// others.
+type AdminService struct {
+	vm *VM
+}
+
+// authorized returns true if [r] sent the admin token. No request is
+// authorized if the token isn't configured.
+func (svc *AdminService) authorized(r *http.Request) bool {
+	token := svc.vm.config.AdminToken
+	if len(token) == 0 || r == nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get(AdminTokenHeader)), []byte(token)) == 1
+}
+
+// bannedSet is the persisted set of senders whose txs are rejected at mempool
+// admission.
+type bannedSet struct {
+	db database.KeyValueWriterDeleter
+
+	l     sync.RWMutex
+	addrs map[common.Address]struct{}
+}
+
+func loadBanned(db database.Database) (*bannedSet, error) {
+	addrs, err := chain.GetBanned(db)
+	if err != nil {
+		return nil, err
+	}
+	b := &bannedSet{db: db, addrs: make(map[common.Address]struct{}, len(addrs))}
+	for _, addr := range addrs {
+		b.addrs[addr] = struct{}{}
+	}
+	return b, nil
+}
+
+func (b *bannedSet) has(addr common.Address) bool {
+	if b == nil {
+		return false
+	}
+	b.l.RLock()
+	defer b.l.RUnlock()
+
+	_, banned := b.addrs[addr]
+	return banned
+}
+
+func (b *bannedSet) set(addr common.Address, banned bool) error {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	if err := chain.SetBanned(b.db, addr, banned); err != nil {
+		return err
+	}
+	if banned {
+		b.addrs[addr] = struct{}{}
+	} else {
+		delete(b.addrs, addr)
+	}
+	return nil
+}
+
+type BanArgs struct {
+	Address common.Address `serialize:"true" json:"address"`
+}
+
+type BanReply struct {
+	Success bool `serialize:"true" json:"success"`
+}
+
+// Ban rejects txs from [Address] at mempool admission (txs from [Address]
+// already in the mempool or in blocks from other nodes are unaffected).
+func (svc *AdminService) Ban(r *http.Request, args *BanArgs, reply *BanReply) error {
+	if !svc.authorized(r) {
+		return ErrNotAdmin
+	}
+	if err := svc.vm.banned.set(args.Address, true); err != nil {
+		return err
+	}
+	log.Info("banned sender", "address", args.Address)
+	reply.Success = true
+	return nil
+}
+
+// Unban reverts [Ban].
+func (svc *AdminService) Unban(r *http.Request, args *BanArgs, reply *BanReply) error {
+	if !svc.authorized(r) {
+		return ErrNotAdmin
+	}
+	if err := svc.vm.banned.set(args.Address, false); err != nil {
+		return err
+	}
+	log.Info("unbanned sender", "address", args.Address)
+	reply.Success = true
+	return nil
+}
+
+type BannedReply struct {
+	Addresses []common.Address `serialize:"true" json:"addresses"`
+}
+
+func (svc *AdminService) Banned(r *http.Request, _ *struct{}, reply *BannedReply) error {
+	if !svc.authorized(r) {
+		return ErrNotAdmin
+	}
+	addrs, err := chain.GetBanned(svc.vm.db)
+	if err != nil {
+		return err
+	}
+	reply.Addresses = addrs
+	return nil
+}
+
+type RepairReply struct {
+	// Keys whose values were restored from peers
+	Repaired []common.Hash `serialize:"true" json:"repaired"`
+
+	// Keys whose values are still missing (or inconsistent) on this node
+	Unrepairable []common.Hash `serialize:"true" json:"unrepairable"`
+}
+
+// Repair restores the values missing from disk (or inconsistent with their
+// metadata) by fetching them from connected peers. This scans every stored
+// key.
+func (svc *AdminService) Repair(r *http.Request, _ *struct{}, reply *RepairReply) error {
+	if !svc.authorized(r) {
+		return ErrNotAdmin
+	}
+	repaired, unrepairable, err := svc.vm.repair(r.Context())
+	if err != nil {
+		return err
+	}
+	reply.Repaired = repaired
+	reply.Unrepairable = unrepairable
+	return nil
+}
@@ -0,0 +1,101 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package vm
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/database/memdb"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/ava-labs/blobvm/chain"
+	"github.com/ava-labs/blobvm/mempool"
+)
+
+func TestBanSender(t *testing.T) {
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := crypto.PubkeyToAddress(priv.PublicKey)
+
+	g := chain.DefaultGenesis()
+	g.CustomAllocation = []*chain.CustomAllocation{{Address: sender, Balance: 10000000}}
+	db := memdb.New()
+	defer db.Close()
+	if err := g.Load(db, nil); err != nil {
+		t.Fatal(err)
+	}
+	banned, err := loadBanned(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vm := &VM{db: db, genesis: g, mempool: mempool.New(g, 16), banned: banned}
+	svc := &AdminService{vm: vm}
+	ctx := &chain.Context{RecentBlockIDs: ids.Set{testBlockID: struct{}{}}, NextPrice: 1}
+
+	local := httptest.NewRequest("POST", "/admin", nil)
+	local.Header.Set(AdminTokenHeader, "secret")
+	// Loopback requests (ex: from a proxy on the node's host) need the token
+	// too
+	proxied := httptest.NewRequest("POST", "/admin", nil)
+	proxied.RemoteAddr = "127.0.0.1:1234"
+	wrongToken := httptest.NewRequest("POST", "/admin", nil)
+	wrongToken.Header.Set(AdminTokenHeader, "guess")
+
+	// Nothing is authorized until a token is configured
+	if err := svc.Ban(local, &BanArgs{Address: sender}, new(BanReply)); !errors.Is(err, ErrNotAdmin) {
+		t.Fatalf("unexpected error %v, expected %v", err, ErrNotAdmin)
+	}
+	vm.config.AdminToken = "secret"
+	for _, r := range []*http.Request{proxied, wrongToken} {
+		if err := svc.Ban(r, &BanArgs{Address: sender}, new(BanReply)); !errors.Is(err, ErrNotAdmin) {
+			t.Fatalf("unexpected error %v, expected %v", err, ErrNotAdmin)
+		}
+	}
+	if err := svc.Ban(local, &BanArgs{Address: sender}, new(BanReply)); err != nil {
+		t.Fatal(err)
+	}
+	tx := testSignedSetTx(t, g, priv, []byte("banned"), 1)
+	if err := vm.submit(tx, db, 1, ctx); !errors.Is(err, ErrSenderBanned) {
+		t.Fatalf("unexpected error %v, expected %v", err, ErrSenderBanned)
+	}
+
+	// Bans are persisted across restarts
+	reloaded, err := loadBanned(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reloaded.has(sender) {
+		t.Fatal("ban not persisted")
+	}
+	reply := new(BannedReply)
+	if err := svc.Banned(local, nil, reply); err != nil {
+		t.Fatal(err)
+	}
+	if len(reply.Addresses) != 1 || reply.Addresses[0] != sender {
+		t.Fatalf("unexpected banned addresses %v", reply.Addresses)
+	}
+
+	if err := svc.Unban(local, &BanArgs{Address: sender}, new(BanReply)); err != nil {
+		t.Fatal(err)
+	}
+	if err := vm.submit(tx, db, 1, ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !vm.mempool.Has(tx.ID()) {
+		t.Fatal("tx from unbanned sender not added to mempool")
+	}
+	reloaded, err = loadBanned(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.has(sender) {
+		t.Fatal("unban not persisted")
+	}
+}
@@ -4,6 +4,7 @@
 package vm
 
 import (
+	"math/rand"
 	"sync"
 	"time"
 
@@ -92,16 +93,22 @@ func (vm *VM) NewTimeBuilder() *TimeBuilder {
 // signalTxsReady sets the initial timeout on the two stage timer if the process
 // has not already begun from an earlier notification. If [buildStatus] is anything
 // other than [dontBuild], then the attempt has already begun and this notification
-// can be safely skipped.
+// can be safely skipped (unless [BuildUnitsThreshold] has been reached while
+// waiting in [mayBuild]).
 func (b *TimeBuilder) signalTxsReady() {
 	b.l.Lock()
 	defer b.l.Unlock()
 
-	if b.status != dontBuild {
-		return
+	switch b.status {
+	case dontBuild:
+		b.markBuilding()
+	case mayBuild:
+		// Don't wait out [BuildInterval] if there are enough txs to fill a
+		// block
+		if b.thresholdReached() {
+			b.markBuilding()
+		}
 	}
-
-	b.markBuilding()
 }
 
 // signal the avalanchego engine
@@ -127,6 +134,9 @@ func (b *TimeBuilder) HandleGenerateBlock() {
 	if b.needToBuild() {
 		b.status = mayBuild
 		b.buildBlockTimer.SetTimeoutIn(b.vm.config.BuildInterval)
+		if b.thresholdReached() {
+			b.markBuilding()
+		}
 	} else {
 		b.status = dontBuild
 	}
@@ -138,6 +148,13 @@ func (b *TimeBuilder) needToBuild() bool {
 	return b.vm.mempool.Len() > 0
 }
 
+// thresholdReached returns true if the mempool holds at least
+// [BuildUnitsThreshold] units of txs.
+func (b *TimeBuilder) thresholdReached() bool {
+	threshold := b.vm.config.BuildUnitsThreshold
+	return threshold > 0 && b.vm.mempool.Units() >= threshold
+}
+
 // buildBlockTwoStageTimer is a two stage timer that sends a notification
 // to the engine when the VM is ready to build a block.
 // If it should be called back again, it returns the timeout duration at
@@ -187,7 +204,7 @@ func (b *TimeBuilder) Gossip() {
 	g := time.NewTicker(b.vm.config.GossipInterval)
 	defer g.Stop()
 
-	rg := time.NewTicker(b.vm.config.RegossipInterval)
+	rg := time.NewTimer(jitter(b.vm.config.RegossipInterval, b.vm.config.RegossipJitter))
 	defer rg.Stop()
 
 	for {
@@ -197,6 +214,7 @@ func (b *TimeBuilder) Gossip() {
 			_ = b.vm.network.GossipNewTxs(newTxs) // handles case where there are none
 		case <-rg.C:
 			_ = b.vm.network.RegossipTxs()
+			rg.Reset(jitter(b.vm.config.RegossipInterval, b.vm.config.RegossipJitter))
 		case <-b.builderStop:
 			return
 		case <-b.stop:
@@ -205,6 +223,17 @@ func (b *TimeBuilder) Gossip() {
 	}
 }
 
+// jitter returns a random duration in [d-j, d+j] (never less than 1ns).
+func jitter(d time.Duration, j time.Duration) time.Duration {
+	if j > 0 {
+		d += time.Duration(rand.Int63n(int64(2*j)+1)) - j //nolint:gosec
+	}
+	if d <= 0 {
+		return 1
+	}
+	return d
+}
+
 type ManualBuilder struct {
 	vm         *VM
 	doneBuild  chan struct{}
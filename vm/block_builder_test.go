@@ -0,0 +1,166 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package vm
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/snow/engine/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/ava-labs/blobvm/chain"
+	"github.com/ava-labs/blobvm/mempool"
+)
+
+func testBuilderVM(g *chain.Genesis, threshold uint64) (*VM, chan common.Message) {
+	toEngine := make(chan common.Message, 1)
+	vm := &VM{
+		genesis:     g,
+		mempool:     mempool.New(g, 16),
+		toEngine:    toEngine,
+		stop:        make(chan struct{}),
+		builderStop: make(chan struct{}),
+		doneBuild:   make(chan struct{}),
+		doneGossip:  make(chan struct{}),
+	}
+	vm.config.SetDefaults()
+	vm.config.BuildInterval = time.Hour
+	vm.config.BuildUnitsThreshold = threshold
+	return vm, toEngine
+}
+
+func testSetTx(t *testing.T, g *chain.Genesis, v []byte, price uint64) *chain.Transaction {
+	t.Helper()
+
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testSignedSetTx(t, g, priv, v, price)
+}
+
+// testBlockID is the (recent) blockID referenced by test txs.
+var testBlockID = ids.ID{1}
+
+func testSignedSetTx(t *testing.T, g *chain.Genesis, priv *ecdsa.PrivateKey, v []byte, price uint64) *chain.Transaction {
+	t.Helper()
+
+	return testSignTx(t, g, priv, &chain.SetTx{
+		BaseTx: &chain.BaseTx{BlockID: testBlockID, Price: price},
+		Value:  v,
+	})
+}
+
+func testSignTx(t *testing.T, g *chain.Genesis, priv *ecdsa.PrivateKey, utx chain.UnsignedTransaction) *chain.Transaction {
+	t.Helper()
+
+	tx := &chain.Transaction{UnsignedTransaction: utx}
+	dh, err := chain.DigestHash(utx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.Signature, err = chain.Sign(dh, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Init(g); err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func addTestTx(t *testing.T, g *chain.Genesis, m *mempool.Mempool, i int) {
+	t.Helper()
+
+	tx := testSetTx(t, g, []byte(fmt.Sprintf("0x%064x", i)), uint64(i+1))
+	if !m.Add(tx) {
+		t.Fatalf("tx %s was not added", tx.ID())
+	}
+}
+
+func TestBuildUnitsThreshold(t *testing.T) {
+	t.Parallel()
+
+	g := chain.DefaultGenesis()
+	vm, toEngine := testBuilderVM(g, 4)
+	b := vm.NewTimeBuilder()
+
+	// Below the threshold: the builder should wait out [BuildInterval]
+	addTestTx(t, g, vm.mempool, 0)
+	b.HandleGenerateBlock()
+	if b.status != mayBuild {
+		t.Fatalf("status expected %d, got %d", mayBuild, b.status)
+	}
+	select {
+	case msg := <-toEngine:
+		t.Fatalf("unexpected message %s", msg)
+	default:
+	}
+
+	// Crossing the threshold should notify the engine right away
+	addTestTx(t, g, vm.mempool, 1)
+	b.signalTxsReady()
+	if b.status != building {
+		t.Fatalf("status expected %d, got %d", building, b.status)
+	}
+	select {
+	case msg := <-toEngine:
+		if msg != common.PendingTxs {
+			t.Fatalf("message expected %s, got %s", common.PendingTxs, msg)
+		}
+	default:
+		t.Fatal("engine was not notified")
+	}
+}
+
+func TestBuildUnitsThresholdDisabled(t *testing.T) {
+	t.Parallel()
+
+	g := chain.DefaultGenesis()
+	vm, toEngine := testBuilderVM(g, 0)
+	b := vm.NewTimeBuilder()
+
+	for i := 0; i < 8; i++ {
+		addTestTx(t, g, vm.mempool, i)
+	}
+	b.HandleGenerateBlock()
+	b.signalTxsReady()
+	if b.status != mayBuild {
+		t.Fatalf("status expected %d, got %d", mayBuild, b.status)
+	}
+	select {
+	case msg := <-toEngine:
+		t.Fatalf("unexpected message %s", msg)
+	default:
+	}
+}
+
+func TestRegossipJitter(t *testing.T) {
+	t.Parallel()
+
+	const (
+		interval = 30 * time.Second
+		j        = 5 * time.Second
+	)
+	seen := map[time.Duration]struct{}{}
+	for i := 0; i < 100; i++ {
+		d := jitter(interval, j)
+		if d < interval-j || d > interval+j {
+			t.Fatalf("#%d: interval %s outside of %s±%s", i, d, interval, j)
+		}
+		seen[d] = struct{}{}
+	}
+	if len(seen) < 2 {
+		t.Fatal("consecutive regossip intervals did not vary")
+	}
+
+	// no jitter keeps the configured interval
+	if d := jitter(interval, 0); d != interval {
+		t.Fatalf("unexpected interval %s, expected %s", d, interval)
+	}
+}
@@ -4,6 +4,8 @@
 package vm
 
 import (
+	"time"
+
 	"github.com/ava-labs/avalanchego/database"
 	"github.com/ava-labs/avalanchego/ids"
 	log "github.com/inconshreveable/log15"
@@ -19,6 +21,15 @@ func (vm *VM) IsBootstrapped() bool {
 	return vm.bootstrapped.GetValue()
 }
 
+// IsCaughtUp returns true if the VM is bootstrapped and the last accepted
+// block is within [CaughtUpThreshold] of the current time.
+func (vm *VM) IsCaughtUp() bool {
+	if !vm.IsBootstrapped() {
+		return false
+	}
+	return vm.Now().Sub(vm.lastAccepted.Timestamp()) <= vm.config.CaughtUpThreshold
+}
+
 func (vm *VM) State() database.Database {
 	return vm.db
 }
@@ -27,6 +38,10 @@ func (vm *VM) Mempool() chain.Mempool {
 	return vm.mempool
 }
 
+func (vm *VM) Now() time.Time {
+	return vm.clock.Time()
+}
+
 func (vm *VM) Verified(b *chain.StatelessBlock) {
 	vm.verifiedBlocks[b.ID()] = b
 	for _, tx := range b.Txs {
@@ -47,15 +62,20 @@ func (vm *VM) Accepted(b *chain.StatelessBlock) {
 	vm.blocks.Put(b.ID(), b)
 	delete(vm.verifiedBlocks, b.ID())
 	vm.lastAccepted = b
+	vm.metrics.accepted(b)
 	log.Debug("accepted block", "blkID", b.ID())
 
-	if vm.config.ActivityCacheSize == 0 {
+	cs := uint64(vm.config.ActivityCacheSize)
+	if cs == 0 && !vm.activityFeed.subscribed() {
 		return
 	}
-	cs := uint64(vm.config.ActivityCacheSize)
 	for _, tx := range b.Txs {
-		activity := tx.Activity()
+		activity := tx.Activity(vm.genesis)
 		activity.Tmstmp = b.Tmstmp
+		vm.activityFeed.publish(activity)
+		if cs == 0 {
+			continue
+		}
 		vm.activityCache[vm.activityCacheCursor%cs] = activity
 		vm.activityCacheCursor++
 	}
@@ -111,6 +131,7 @@ func (vm *VM) ExecutionContext(currTime int64, lastBlock *chain.StatelessBlock)
 			nextPrice = g.MinPrice
 		}
 	}
+	nextPrice = g.ClampPrice(lastBlock.Price, nextPrice)
 
 	return &chain.Context{
 		RecentBlockIDs:  recentBlockIDs,
@@ -122,5 +143,9 @@ func (vm *VM) ExecutionContext(currTime int64, lastBlock *chain.StatelessBlock)
 
 		NextPrice: nextPrice,
 		NextCost:  nextCost,
+
+		BatchBalanceWrites: vm.config.BatchBalanceWrites,
+		CompactValueMeta:   vm.config.CompactValueMeta,
+		CompressBlocks:     vm.config.CompressBlocks,
 	}, nil
 }
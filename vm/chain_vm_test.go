@@ -0,0 +1,95 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package vm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/cache"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/snow/choices"
+
+	"github.com/ava-labs/blobvm/chain"
+)
+
+func TestExecutionContextMaxPriceChange(t *testing.T) {
+	t.Parallel()
+
+	for _, pct := range []uint64{0, 10, 50} {
+		g := chain.DefaultGenesis()
+		g.MaxPriceChangePercent = pct
+		vm := &VM{
+			genesis:          g,
+			blocks:           &cache.LRU{Size: 64},
+			verifiedBlocks:   make(map[ids.ID]*chain.StatelessBlock),
+			targetRangeUnits: 1,
+		}
+		last, err := chain.ParseStatefulBlock(
+			&chain.StatefulBlock{Price: 1000},
+			nil,
+			choices.Accepted,
+			vm,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vm.blocks.Put(last.ID(), last)
+
+		// Drive full blocks (raising the price) followed by empty blocks far
+		// apart (dropping the price as fast as allowed)
+		for i := 0; i < 20; i++ {
+			tmstmp := last.Tmstmp + 1
+			txs := []*chain.Transaction{testSetTx(t, g, []byte(fmt.Sprintf("value %d", i)), 0)}
+			if i >= 5 {
+				tmstmp = last.Tmstmp + 10_000*g.LookbackWindow
+				txs = nil
+			}
+			ctx, err := vm.ExecutionContext(tmstmp, last)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Full blocks raise the price by exactly 1, whatever the cap
+			if i >= 1 && i <= 5 && ctx.NextPrice != last.Price+1 {
+				t.Fatalf("%d%%: block %d price rose from %d to %d", pct, i, last.Price, ctx.NextPrice)
+			}
+			maxDelta := last.Price * pct / 100
+			if maxDelta == 0 {
+				maxDelta = 1
+			}
+			if pct > 0 && ctx.NextPrice < last.Price && last.Price-ctx.NextPrice > maxDelta {
+				t.Fatalf("%d%%: block %d price fell from %d to %d", pct, i, last.Price, ctx.NextPrice)
+			}
+
+			// The first empty block still looks back at its full parent
+			if i > 5 {
+				exp := g.MinPrice
+				if pct > 0 && last.Price-g.MinPrice > maxDelta {
+					exp = last.Price - maxDelta
+				}
+				if ctx.NextPrice != exp {
+					t.Fatalf("%d%%: block %d price expected %d, got %d", pct, i, exp, ctx.NextPrice)
+				}
+			}
+
+			last, err = chain.ParseStatefulBlock(
+				&chain.StatefulBlock{
+					Prnt:   last.ID(),
+					Tmstmp: tmstmp,
+					Hght:   last.Hght + 1,
+					Price:  ctx.NextPrice,
+					Txs:    txs,
+				},
+				nil,
+				choices.Accepted,
+				vm,
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			vm.blocks.Put(last.ID(), last)
+		}
+	}
+}
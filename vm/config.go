@@ -5,6 +5,8 @@ package vm
 
 import (
 	"time"
+
+	"github.com/ava-labs/avalanchego/utils/units"
 )
 
 type Config struct {
@@ -12,15 +14,94 @@ type Config struct {
 	GossipInterval   time.Duration `serialize:"true" json:"gossipInterval"`
 	RegossipInterval time.Duration `serialize:"true" json:"regossipInterval"`
 
+	// Each regossip waits a random duration within [RegossipJitter] of
+	// [RegossipInterval] so nodes don't regossip in lockstep
+	RegossipJitter time.Duration `serialize:"true" json:"regossipJitter"`
+
+	// Compress gossip messages of at least this many bytes (0 disables). Nodes
+	// that predate compressed gossip drop compressed messages, so this should
+	// only be set once peers have upgraded.
+	GossipCompressionThreshold int `serialize:"true" json:"gossipCompressionThreshold"`
+
+	// Build a block as soon as the mempool holds at least this many units of
+	// txs, instead of waiting out [BuildInterval] (0 disables)
+	BuildUnitsThreshold uint64 `serialize:"true" json:"buildUnitsThreshold"`
+
 	MempoolSize       int `serialize:"true" json:"mempoolSize"`
 	ActivityCacheSize int `serialize:"true" json:"activityCacheSize"`
+
+	// Number of recently read values held in memory (0 disables)
+	ValueCacheSize int `serialize:"true" json:"valueCacheSize"`
+
+	// Max JSON-encoded size of the activity returned by a single
+	// "blobvm.recentActivity" request (0 is unbounded)
+	MaxActivityResponseSize int `serialize:"true" json:"maxActivityResponseSize"`
+
+	// Send a keepalive comment on activity streams (see
+	// [ActivityStreamEndpoint]) that have been idle this long (0 disables)
+	ActivityKeepaliveInterval time.Duration `serialize:"true" json:"activityKeepaliveInterval"`
+
+	// Max encoded size of the block returned by a single "blobvm.getBlock" (or
+	// "blobvm.getBlockByHeight") request (0 is unbounded)
+	MaxBlockResponseSize int `serialize:"true" json:"maxBlockResponseSize"`
+
+	// Gzip JSON-RPC responses of at least this many bytes for requests that
+	// accept gzip (0 disables)
+	ResponseCompressionThreshold int `serialize:"true" json:"responseCompressionThreshold"`
+
+	GossipVerifyWorkers int `serialize:"true" json:"gossipVerifyWorkers"`
+	GenesisLoadWorkers  int `serialize:"true" json:"genesisLoadWorkers"`
+
+	// Max age of the last accepted block for the node to be considered caught up
+	CaughtUpThreshold time.Duration `serialize:"true" json:"caughtUpThreshold"`
+
+	// Secret admin requests must send (see [AdminTokenHeader]). The admin
+	// endpoint rejects every request while it is empty.
+	AdminToken string `serialize:"true" json:"adminToken"`
+
+	// Cross-check the metadata of every stored value against its value on
+	// startup (slow for large databases). Inconsistencies are logged, fail the
+	// health check, and (if [IntegrityCheckStrict]) prevent the VM from
+	// starting.
+	IntegrityCheck       bool `serialize:"true" json:"integrityCheck"`
+	IntegrityCheckStrict bool `serialize:"true" json:"integrityCheckStrict"`
+
+	// Store value metadata in a compact binary layout instead of the codec
+	// encoding (less than half the size for values without tags). Existing
+	// metadata is still read (and rewritten on startup), so this can be
+	// toggled at any time.
+	CompactValueMeta bool `serialize:"true" json:"compactValueMeta"`
+
+	// Compress blocks (once their values have been extracted) before storing
+	// them, when that makes them smaller. Existing blocks are still read, so
+	// this can be toggled at any time.
+	CompressBlocks bool `serialize:"true" json:"compressBlocks"`
+
+	// Hold the balance changes of a block's txs in memory while verifying it
+	// (instead of writing each one) and write them once at the end
+	BatchBalanceWrites bool `serialize:"true" json:"batchBalanceWrites"`
+
+	// Prefix of the metrics served at [MetricsEndpoint]
+	MetricsNamespace string `serialize:"true" json:"metricsNamespace"`
 }
 
 func (c *Config) SetDefaults() {
 	c.BuildInterval = 500 * time.Millisecond
 	c.GossipInterval = 1 * time.Second
 	c.RegossipInterval = 30 * time.Second
+	c.RegossipJitter = 5 * time.Second
 
 	c.MempoolSize = 1024
 	c.ActivityCacheSize = 128
+	c.ValueCacheSize = 4096
+	c.MaxActivityResponseSize = 1 * units.MiB
+	c.ActivityKeepaliveInterval = 15 * time.Second
+	c.MaxBlockResponseSize = 1 * units.MiB
+	c.ResponseCompressionThreshold = 1 * units.KiB
+	c.GossipVerifyWorkers = 4
+	c.GenesisLoadWorkers = 4
+
+	c.CaughtUpThreshold = 60 * time.Second
+
+	c.MetricsNamespace = Name
 }
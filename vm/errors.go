@@ -8,9 +8,24 @@ import (
 )
 
 var (
-	ErrNoPendingTx    = errors.New("no pending tx")
-	ErrTypedDataIsNil = errors.New("typed data is nil")
-	ErrInputIsNil     = errors.New("input is nil")
-	ErrInvalidEmptyTx = errors.New("invalid empty transaction")
-	ErrCorruption     = errors.New("corruption detected")
+	ErrNoPendingTx     = errors.New("no pending tx")
+	ErrTypedDataIsNil  = errors.New("typed data is nil")
+	ErrInputIsNil      = errors.New("input is nil")
+	ErrInvalidEmptyTx  = errors.New("invalid empty transaction")
+	ErrCorruption      = errors.New("corruption detected")
+	ErrInvalidEncoding = errors.New("invalid encoding")
+	ErrInvalidLimit    = errors.New("invalid limit")
+	ErrTooManyKeys     = errors.New("too many keys")
+	ErrTooManyTxs      = errors.New("too many txs")
+	ErrWrongMagic      = errors.New("typed data magic does not match genesis magic")
+	ErrInvalidGossip   = errors.New("invalid gossip message")
+	ErrSenderBanned    = errors.New("sender is banned")
+	ErrNotAdmin        = errors.New("admin requests must send the adminToken of the VM config")
+	ErrNotExportable   = errors.New("value cannot be exported")
+	ErrInvalidRange    = errors.New("invalid range")
+	ErrRangeTooBig     = errors.New("range too big")
+	ErrInvalidTree     = errors.New("invalid tree")
+	ErrCompressedTree  = errors.New("compressed trees can't be read by range")
+	ErrRequestFailed   = errors.New("request failed")
+	ErrInvalidResponse = errors.New("invalid response")
 )
@@ -7,7 +7,6 @@ import (
 	"context"
 	"fmt"
 	"sort"
-	"time"
 
 	"github.com/ava-labs/avalanchego/ids"
 
@@ -43,7 +42,7 @@ func (vm *VM) lookback(currTime int64, lastID ids.ID, f func(b *chain.StatelessB
 
 func (vm *VM) ValidBlockID(blockID ids.ID) (bool, error) {
 	var foundBlockID bool
-	err := vm.lookback(time.Now().Unix(), vm.preferred, func(b *chain.StatelessBlock) (bool, error) {
+	err := vm.lookback(vm.Now().Unix(), vm.preferred, func(b *chain.StatelessBlock) (bool, error) {
 		if b.ID() == blockID {
 			foundBlockID = true
 			return false, nil
@@ -63,7 +62,7 @@ func (vm *VM) SuggestedFee() (uint64, uint64, error) {
 		return 0, 0, fmt.Errorf("unexpected snowman.Block %T, expected *StatelessBlock", prnt)
 	}
 
-	ctx, err := vm.ExecutionContext(time.Now().Unix(), parent)
+	ctx, err := vm.ExecutionContext(vm.Now().Unix(), parent)
 	if err != nil {
 		return 0, 0, err
 	}
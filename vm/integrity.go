@@ -0,0 +1,60 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package vm
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/inconshreveable/log15"
+
+	"github.com/ava-labs/blobvm/chain"
+)
+
+// maxLoggedInconsistencies bounds the inconsistent values logged individually
+// by [checkIntegrity] (the rest are only counted).
+const maxLoggedInconsistencies = 100
+
+// checkIntegrity cross-checks the stored values against their metadata (see
+// [chain.CheckIntegrity]) if [Config.IntegrityCheck] is set. Inconsistencies
+// are logged and reported by [HealthCheck], and prevent the VM from starting
+// if [Config.IntegrityCheckStrict] is set.
+func (vm *VM) checkIntegrity() error {
+	if !vm.config.IntegrityCheck {
+		return nil
+	}
+	start := time.Now()
+	checked, inconsistent, err := chain.CheckIntegrity(vm.db)
+	if err != nil {
+		return err
+	}
+	for i, c := range inconsistent {
+		if i == maxLoggedInconsistencies {
+			log.Error("skipped logging inconsistent values", "count", len(inconsistent)-i)
+			break
+		}
+		log.Error("inconsistent value", "key", c.Key, "reason", c.Reason)
+	}
+	vm.setInconsistentCount(len(inconsistent))
+	log.Info(
+		"checked database integrity",
+		"values", checked, "inconsistent", len(inconsistent), "t", time.Since(start),
+	)
+	if len(inconsistent) > 0 && vm.config.IntegrityCheckStrict {
+		return fmt.Errorf("%w: %d of %d values are inconsistent", ErrCorruption, len(inconsistent), checked)
+	}
+	return nil
+}
+
+func (vm *VM) setInconsistentCount(n int) {
+	vm.inconsistentL.Lock()
+	defer vm.inconsistentL.Unlock()
+	vm.inconsistentValues = n
+}
+
+func (vm *VM) inconsistentCount() int {
+	vm.inconsistentL.Lock()
+	defer vm.inconsistentL.Unlock()
+	return vm.inconsistentValues
+}
@@ -0,0 +1,136 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package vm
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/ava-labs/blobvm/chain"
+	"github.com/ava-labs/blobvm/mempool"
+)
+
+// metrics are the Prometheus metrics served at [MetricsEndpoint]. A nil
+// [metrics] records nothing (ex: VMs constructed directly by tests).
+type metrics struct {
+	registry *prometheus.Registry
+
+	txsAccepted   prometheus.Counter
+	txsRejected   prometheus.Counter
+	valueBytes    prometheus.Counter
+	price         prometheus.Gauge
+	cost          prometheus.Gauge
+	buildDuration prometheus.Histogram
+}
+
+func newMetrics(namespace string, mp *mempool.Mempool, vc *chain.ValueCache) (*metrics, error) {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		txsAccepted: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "txs_accepted",
+			Help:      "number of txs in accepted blocks",
+		}),
+		txsRejected: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "txs_rejected",
+			Help:      "number of submitted txs that failed execution",
+		}),
+		valueBytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "value_bytes_stored",
+			Help:      "bytes of values set by txs in accepted blocks",
+		}),
+		price: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "price",
+			Help:      "price of the last accepted block",
+		}),
+		cost: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "block_cost",
+			Help:      "cost of the last accepted block",
+		}),
+		buildDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "build_block_duration_seconds",
+			Help:      "time spent building blocks",
+			Buckets:   prometheus.DefBuckets,
+		}),
+	}
+	mempoolSize := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "mempool_size",
+		Help:      "number of txs in the mempool",
+	}, func() float64 { return float64(mp.Len()) })
+	valueCacheHits := prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "value_cache_hits",
+		Help:      "number of value reads served from memory",
+	}, func() float64 { return float64(vc.Stats().Hits) })
+	valueCacheMisses := prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "value_cache_misses",
+		Help:      "number of value reads served from disk",
+	}, func() float64 { return float64(vc.Stats().Misses) })
+	valueCacheEvictions := prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "value_cache_evictions",
+		Help:      "number of values dropped from memory to make room for others",
+	}, func() float64 { return float64(vc.Stats().Evictions) })
+
+	for _, c := range []prometheus.Collector{
+		m.txsAccepted,
+		m.txsRejected,
+		m.valueBytes,
+		m.price,
+		m.cost,
+		m.buildDuration,
+		mempoolSize,
+		valueCacheHits,
+		valueCacheMisses,
+		valueCacheEvictions,
+	} {
+		if err := m.registry.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (m *metrics) accepted(b *chain.StatelessBlock) {
+	if m == nil {
+		return
+	}
+	m.price.Set(float64(b.Price))
+	m.cost.Set(float64(b.Cost))
+	m.txsAccepted.Add(float64(len(b.Txs)))
+	size := 0
+	for _, tx := range b.Txs {
+		switch t := tx.UnsignedTransaction.(type) {
+		case *chain.SetTx:
+			size += len(t.Value)
+		case *chain.BatchTx:
+			for _, v := range t.Values {
+				size += len(v.Value)
+			}
+		}
+	}
+	m.valueBytes.Add(float64(size))
+}
+
+func (m *metrics) rejected() {
+	if m == nil {
+		return
+	}
+	m.txsRejected.Inc()
+}
+
+func (m *metrics) built(d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.buildDuration.Observe(d.Seconds())
+}
@@ -5,9 +5,12 @@ package vm
 
 import (
 	"context"
+	"fmt"
+	"sync"
 
 	"github.com/ava-labs/avalanchego/cache"
 	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/compression"
 	log "github.com/inconshreveable/log15"
 
 	"github.com/ava-labs/blobvm/chain"
@@ -15,8 +18,41 @@ import (
 
 const (
 	gossipedTxsLRUSize = 512
+
+	// Uncompressed gossip messages are the encoding of the gossiped txs, which
+	// starts with the codec version (0). Compressed messages are prefixed with
+	// [compressedGossipVersion] instead, so both can be received.
+	compressedGossipVersion byte = 0xff
 )
 
+// encodeGossip compresses [b] with [c] if it is at least [threshold] bytes (0
+// disables compression).
+func encodeGossip(c compression.Compressor, threshold int, b []byte) ([]byte, error) {
+	if threshold == 0 || len(b) < threshold {
+		return b, nil
+	}
+	cb, err := c.Compress(b)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{compressedGossipVersion}, cb...), nil
+}
+
+// decodeGossip returns the encoded txs in [msg] (decompressing them with [c]
+// if needed).
+func decodeGossip(c compression.Compressor, msg []byte) ([]byte, error) {
+	if len(msg) == 0 {
+		return nil, ErrInvalidGossip
+	}
+	if msg[0] != compressedGossipVersion {
+		return msg, nil
+	}
+	if c == nil {
+		return nil, fmt.Errorf("%w: compression not supported", ErrInvalidGossip)
+	}
+	return c.Decompress(msg[1:])
+}
+
 type PushNetwork struct {
 	vm          *VM
 	gossipedTxs *cache.LRU
@@ -39,10 +75,17 @@ func (n *PushNetwork) sendTxs(txs []*chain.Transaction) error {
 		log.Warn("failed to marshal txs", "error", err)
 		return err
 	}
+	size := len(b)
+	b, err = encodeGossip(n.vm.gossipCompressor, n.vm.config.GossipCompressionThreshold, b)
+	if err != nil {
+		log.Warn("failed to compress txs", "error", err)
+		return err
+	}
 
 	log.Debug("sending AppGossip",
 		"txs", len(txs),
-		"size", len(b),
+		"size", size,
+		"compressedSize", len(b),
 	)
 	if err := n.vm.appSender.SendAppGossip(context.TODO(), b); err != nil {
 		log.Warn(
@@ -114,8 +157,17 @@ func (vm *VM) AppGossip(ctx context.Context, nodeID ids.NodeID, msg []byte) erro
 		"bytes", len(msg),
 	)
 
+	b, err := decodeGossip(vm.gossipCompressor, msg)
+	if err != nil {
+		log.Debug(
+			"AppGossip provided invalid message",
+			"peerID", nodeID,
+			"err", err,
+		)
+		return nil
+	}
 	txs := make([]*chain.Transaction, 0)
-	if _, err := chain.Unmarshal(msg, &txs); err != nil {
+	if _, err := chain.Unmarshal(b, &txs); err != nil {
 		log.Debug(
 			"AppGossip provided invalid txs",
 			"peerID", nodeID,
@@ -124,9 +176,19 @@ func (vm *VM) AppGossip(ctx context.Context, nodeID ids.NodeID, msg []byte) erro
 		return nil
 	}
 
+	// verify signatures concurrently before submitting to the mempool
+	txs, errs := vm.verifyTxs(txs)
+	for _, err := range errs {
+		log.Debug(
+			"AppGossip provided invalid tx",
+			"peerID", nodeID,
+			"err", err,
+		)
+	}
+
 	// submit incoming gossip
 	log.Debug("AppGossip transactions are being submitted", "txs", len(txs))
-	if errs := vm.Submit(txs...); len(errs) > 0 {
+	if errs := vm.submitVerified(txs...); len(errs) > 0 {
 		for _, err := range errs {
 			log.Debug(
 				"AppGossip failed to submit txs",
@@ -142,6 +204,50 @@ func (vm *VM) AppGossip(ctx context.Context, nodeID ids.NodeID, msg []byte) erro
 	return nil
 }
 
+// verifyTxs initializes [txs] (recovering each sender from its signature)
+// using at most [GossipVerifyWorkers] goroutines. Txs that fail verification
+// are dropped and the order of the remaining txs is preserved.
+func (vm *VM) verifyTxs(txs []*chain.Transaction) ([]*chain.Transaction, []error) {
+	workers := vm.config.GossipVerifyWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(txs) {
+		workers = len(txs)
+	}
+
+	results := make([]error, len(txs))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = txs[i].Init(vm.genesis)
+			}
+		}()
+	}
+	for i := range txs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	var (
+		verified = make([]*chain.Transaction, 0, len(txs))
+		errs     []error
+	)
+	for i, tx := range txs {
+		if err := results[i]; err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		verified = append(verified, tx)
+	}
+	return verified, errs
+}
+
 // used for testing VM
 func (vm *VM) Network() *PushNetwork {
 	return vm.network
@@ -0,0 +1,161 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package vm
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/compression"
+	"github.com/ava-labs/avalanchego/utils/constants"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/ava-labs/blobvm/chain"
+)
+
+func createTestGossipTxs(tb testing.TB, n int, priv *ecdsa.PrivateKey) []*chain.Transaction {
+	tb.Helper()
+
+	txs := make([]*chain.Transaction, n)
+	for i := range txs {
+		utx := &chain.SetTx{
+			BaseTx: &chain.BaseTx{
+				BlockID: ids.GenerateTestID(),
+				Price:   uint64(i + 1),
+			},
+			Value: []byte(fmt.Sprintf("0x%064x", i)),
+		}
+		dh, err := chain.DigestHash(utx)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		sig, err := chain.Sign(dh, priv)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		// Corrupt every third signature
+		if i%3 == 0 {
+			sig = sig[:len(sig)-1]
+		}
+		txs[i] = chain.NewTx(utx, sig)
+	}
+	return txs
+}
+
+func TestVerifyTxs(t *testing.T) {
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := crypto.PubkeyToAddress(priv.PublicKey)
+
+	g := chain.DefaultGenesis()
+	serial := &VM{genesis: g, config: Config{GossipVerifyWorkers: 1}}
+	parallel := &VM{genesis: g, config: Config{GossipVerifyWorkers: 8}}
+
+	txs := createTestGossipTxs(t, 100, priv)
+	cpTxs := make([]*chain.Transaction, len(txs))
+	for i, tx := range txs {
+		cpTxs[i] = tx.Copy()
+	}
+	sTxs, sErrs := serial.verifyTxs(txs)
+	pTxs, pErrs := parallel.verifyTxs(cpTxs)
+	if len(sTxs) != len(pTxs) {
+		t.Fatalf("verified txs expected %d, got %d", len(sTxs), len(pTxs))
+	}
+	if len(sErrs) != len(pErrs) {
+		t.Fatalf("errors expected %d, got %d", len(sErrs), len(pErrs))
+	}
+	for i := range sTxs {
+		if sTxs[i].ID() != pTxs[i].ID() {
+			t.Fatalf("#%d: tx expected %s, got %s", i, sTxs[i].ID(), pTxs[i].ID())
+		}
+		if pTxs[i].Sender() != sender {
+			t.Fatalf("#%d: sender expected %s, got %s", i, sender, pTxs[i].Sender())
+		}
+	}
+	for i := range sErrs {
+		if !errors.Is(pErrs[i], chain.ErrInvalidSignature) {
+			t.Fatalf("#%d: error expected %v, got %v", i, chain.ErrInvalidSignature, pErrs[i])
+		}
+	}
+}
+
+func BenchmarkVerifyTxs(b *testing.B) {
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		b.Fatal(err)
+	}
+	txs := createTestGossipTxs(b, 1024, priv)
+
+	g := chain.DefaultGenesis()
+	for _, workers := range []int{1, 2, 4, 8} {
+		vm := &VM{genesis: g, config: Config{GossipVerifyWorkers: workers}}
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				vm.verifyTxs(txs)
+			}
+		})
+	}
+}
+
+func TestGossipCompression(t *testing.T) {
+	t.Parallel()
+
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := chain.Marshal(createTestGossipTxs(t, 16, priv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := compression.NewGzipCompressor(constants.DefaultMaxMessageSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, tv := range []struct {
+		threshold  int
+		compressed bool
+	}{
+		{threshold: 0, compressed: false},
+		{threshold: len(b) + 1, compressed: false},
+		{threshold: len(b), compressed: true},
+	} {
+		msg, err := encodeGossip(c, tv.threshold, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if compressed := msg[0] == compressedGossipVersion; compressed != tv.compressed {
+			t.Fatalf("#%d: compressed expected %t, got %t", i, tv.compressed, compressed)
+		}
+		if tv.compressed && len(msg) >= len(b) {
+			t.Fatalf("#%d: compressed message of %d bytes is not smaller than %d", i, len(msg), len(b))
+		}
+
+		decoded, err := decodeGossip(c, msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		txs := []*chain.Transaction{}
+		if _, err := chain.Unmarshal(decoded, &txs); err != nil {
+			t.Fatalf("#%d: failed to unmarshal txs %v", i, err)
+		}
+		if len(txs) != 16 {
+			t.Fatalf("#%d: expected %d txs, got %d", i, 16, len(txs))
+		}
+	}
+
+	msg, err := encodeGossip(c, 1, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := decodeGossip(nil, msg); !errors.Is(err, ErrInvalidGossip) {
+		t.Fatalf("unexpected error %v, expected %v", err, ErrInvalidGossip)
+	}
+}
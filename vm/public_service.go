@@ -4,10 +4,16 @@
 package vm
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
+	"github.com/ava-labs/avalanchego/database"
 	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/units"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	log "github.com/inconshreveable/log15"
@@ -43,6 +49,15 @@ func (svc *PublicService) Network(_ *http.Request, _ *struct{}, reply *NetworkRe
 	return nil
 }
 
+type IsCaughtUpReply struct {
+	CaughtUp bool `serialize:"true" json:"caughtUp"`
+}
+
+func (svc *PublicService) IsCaughtUp(_ *http.Request, _ *struct{}, reply *IsCaughtUpReply) (err error) {
+	reply.CaughtUp = svc.vm.IsCaughtUp()
+	return nil
+}
+
 type GenesisReply struct {
 	Genesis *chain.Genesis `serialize:"true" json:"genesis"`
 }
@@ -58,28 +73,105 @@ type IssueRawTxArgs struct {
 
 type IssueRawTxReply struct {
 	TxID ids.ID `serialize:"true" json:"txId"`
+
+	// Fee is the fee charged to the sender when the tx is executed (its fee
+	// units times its price, plus any storage fees charged on execution, ex:
+	// by a RenewTx or PinTx).
+	Fee uint64 `serialize:"true" json:"fee"`
+	// Balance is the balance of the sender after executing the tx on top of the
+	// accepted state (other pending txs from the sender are not included).
+	Balance uint64 `serialize:"true" json:"balance"`
 }
 
 func (svc *PublicService) IssueRawTx(_ *http.Request, args *IssueRawTxArgs, reply *IssueRawTxReply) error {
-	tx := new(chain.Transaction)
-	if _, err := chain.Unmarshal(args.Tx, tx); err != nil {
+	tx, err := chain.DecodeTx(args.Tx)
+	if err != nil {
 		return err
 	}
+	reply.TxID = tx.ID()
 
-	// otherwise, unexported tx.id field is empty
-	if err := tx.Init(svc.vm.genesis); err != nil {
+	fee, balance, err := svc.vm.submitWithBalance(tx)
+	if err != nil {
+		return err
+	}
+	reply.Fee = fee
+	reply.Balance = balance
+	return nil
+}
+
+type SimulateTxArgs struct {
+	Tx []byte `serialize:"true" json:"tx"`
+}
+
+type SimulateTxReply struct {
+	TxID ids.ID `serialize:"true" json:"txId"`
+
+	// Success is true if the tx would be issued. Otherwise, [Error] is why it
+	// would be rejected.
+	Success bool   `serialize:"true" json:"success"`
+	Error   string `serialize:"true" json:"error,omitempty"`
+
+	// See [IssueRawTxReply] (only set if [Success])
+	Fee     uint64 `serialize:"true" json:"fee"`
+	Balance uint64 `serialize:"true" json:"balance"`
+}
+
+// SimulateTx executes a signed tx on top of the preferred block as IssueRawTx
+// would, without issuing it or persisting anything. Txs that would be rejected
+// are reported in [reply] instead of as an error (only txs that can't be
+// parsed return an error).
+func (svc *PublicService) SimulateTx(_ *http.Request, args *SimulateTxArgs, reply *SimulateTxReply) error {
+	tx, err := chain.DecodeTx(args.Tx)
+	if err != nil {
 		return err
 	}
 	reply.TxID = tx.ID()
 
-	errs := svc.vm.Submit(tx)
-	if len(errs) == 0 {
+	fee, balance, err := svc.vm.simulate(tx)
+	if err != nil {
+		reply.Error = err.Error()
 		return nil
 	}
-	if len(errs) == 1 {
-		return errs[0]
+	reply.Success = true
+	reply.Fee = fee
+	reply.Balance = balance
+	return nil
+}
+
+// MaxIssueRawTxBatchLimit is the maximum number of txs that can be issued by a
+// single call to IssueRawTxBatch.
+const MaxIssueRawTxBatchLimit = 256
+
+type IssueRawTxBatchArgs struct {
+	Txs [][]byte `serialize:"true" json:"txs"`
+}
+
+type IssueRawTxBatchReply struct {
+	// TxIDs[i] is the ID of [IssueRawTxBatchArgs.Txs][i] (empty if it could
+	// not be parsed)
+	TxIDs []ids.ID `serialize:"true" json:"txIds"`
+	// Errors[i] is why [IssueRawTxBatchArgs.Txs][i] was not issued (empty if
+	// it was)
+	Errors []string `serialize:"true" json:"errors"`
+}
+
+// IssueRawTxBatch issues each of [args.Txs] as if by IssueRawTx. Txs are
+// validated and submitted independently (in order), so a failed tx does not
+// prevent the others from being issued.
+func (svc *PublicService) IssueRawTxBatch(_ *http.Request, args *IssueRawTxBatchArgs, reply *IssueRawTxBatchReply) error {
+	if len(args.Txs) > MaxIssueRawTxBatchLimit {
+		return fmt.Errorf("%w: %d (max=%d)", ErrTooManyTxs, len(args.Txs), MaxIssueRawTxBatchLimit)
 	}
-	return fmt.Errorf("%v", errs)
+	reply.TxIDs = make([]ids.ID, len(args.Txs))
+	reply.Errors = make([]string, len(args.Txs))
+	for i, tx := range args.Txs {
+		r := new(IssueRawTxReply)
+		if err := svc.IssueRawTx(nil, &IssueRawTxArgs{Tx: tx}, r); err != nil {
+			reply.Errors[i] = err.Error()
+		}
+		reply.TxIDs[i] = r.TxID
+	}
+	return nil
 }
 
 type IssueTxArgs struct {
@@ -89,6 +181,10 @@ type IssueTxArgs struct {
 
 type IssueTxReply struct {
 	TxID ids.ID `serialize:"true" json:"txId"`
+
+	// See [IssueRawTxReply]
+	Fee     uint64 `serialize:"true" json:"fee"`
+	Balance uint64 `serialize:"true" json:"balance"`
 }
 
 func (svc *PublicService) IssueTx(_ *http.Request, args *IssueTxArgs, reply *IssueTxReply) error {
@@ -99,6 +195,11 @@ func (svc *PublicService) IssueTx(_ *http.Request, args *IssueTxArgs, reply *Iss
 	if err != nil {
 		return err
 	}
+	// Catch typed data signed for another network before it fails signature
+	// or replay checks with a less obvious error
+	if magic := utx.GetMagic(); magic != svc.vm.genesis.Magic {
+		return fmt.Errorf("%w: got %d, expected %d", ErrWrongMagic, magic, svc.vm.genesis.Magic)
+	}
 	tx := chain.NewTx(utx, args.Signature[:])
 
 	// otherwise, unexported tx.id field is empty
@@ -107,14 +208,13 @@ func (svc *PublicService) IssueTx(_ *http.Request, args *IssueTxArgs, reply *Iss
 	}
 	reply.TxID = tx.ID()
 
-	errs := svc.vm.Submit(tx)
-	if len(errs) == 0 {
-		return nil
-	}
-	if len(errs) == 1 {
-		return errs[0]
+	fee, balance, err := svc.vm.submitWithBalance(tx)
+	if err != nil {
+		return err
 	}
-	return fmt.Errorf("%v", errs)
+	reply.Fee = fee
+	reply.Balance = balance
+	return nil
 }
 
 type HasTxArgs struct {
@@ -123,6 +223,10 @@ type HasTxArgs struct {
 
 type HasTxReply struct {
 	Accepted bool `serialize:"true" json:"accepted"`
+
+	// Height of the block that accepted the tx (left out for txs accepted
+	// before their block was indexed)
+	Height *uint64 `serialize:"true" json:"height,omitempty"`
 }
 
 func (svc *PublicService) HasTx(_ *http.Request, args *HasTxArgs, reply *HasTxReply) error {
@@ -131,6 +235,90 @@ func (svc *PublicService) HasTx(_ *http.Request, args *HasTxArgs, reply *HasTxRe
 		return err
 	}
 	reply.Accepted = has
+	if !has {
+		return nil
+	}
+	height, ok, err := chain.GetTransactionHeight(svc.vm.db, args.TxID)
+	if err != nil {
+		return err
+	}
+	if ok {
+		reply.Height = &height
+	}
+	return nil
+}
+
+// MaxHasTxsLimit is the maximum number of txs that can be checked by a single
+// call to HasTxs.
+const MaxHasTxsLimit = 1024
+
+type HasTxsArgs struct {
+	TxIDs []ids.ID `serialize:"true" json:"txIds"`
+}
+
+type HasTxsReply struct {
+	// Accepted[i] is true if [HasTxsArgs.TxIDs][i] has been accepted
+	Accepted []bool `serialize:"true" json:"accepted"`
+}
+
+func (svc *PublicService) HasTxs(_ *http.Request, args *HasTxsArgs, reply *HasTxsReply) error {
+	if len(args.TxIDs) > MaxHasTxsLimit {
+		return fmt.Errorf("%w: %d (max=%d)", ErrTooManyTxs, len(args.TxIDs), MaxHasTxsLimit)
+	}
+	reply.Accepted = make([]bool, len(args.TxIDs))
+	for i, txID := range args.TxIDs {
+		has, err := chain.HasTransaction(svc.vm.db, txID)
+		if err != nil {
+			return err
+		}
+		reply.Accepted[i] = has
+	}
+	return nil
+}
+
+type MempoolStatsReply struct {
+	Len int `serialize:"true" json:"len"`
+	// FeeUnits is the sum of the fee units of the txs in the mempool
+	FeeUnits uint64 `serialize:"true" json:"feeUnits"`
+	// OldestAge is how long (in seconds) the oldest tx has been in the mempool
+	OldestAge uint64 `serialize:"true" json:"oldestAge"`
+	MinPrice  uint64 `serialize:"true" json:"minPrice"`
+	MaxPrice  uint64 `serialize:"true" json:"maxPrice"`
+}
+
+func (svc *PublicService) MempoolStats(_ *http.Request, _ *struct{}, reply *MempoolStatsReply) error {
+	stats := svc.vm.mempool.Stats()
+	reply.Len = stats.Len
+	reply.FeeUnits = stats.FeeUnits
+	if !stats.Oldest.IsZero() {
+		reply.OldestAge = uint64(time.Since(stats.Oldest) / time.Second)
+	}
+	reply.MinPrice = stats.MinPrice
+	reply.MaxPrice = stats.MaxPrice
+	return nil
+}
+
+// MaxPendingTxIDsLimit is the maximum number of tx IDs returned by a single
+// call to PendingTxIDs.
+const MaxPendingTxIDsLimit = 1024
+
+type PendingTxIDsArgs struct {
+	Limit int `serialize:"true" json:"limit"`
+}
+
+type PendingTxIDsReply struct {
+	TxIDs []ids.ID `serialize:"true" json:"txIds"`
+}
+
+func (svc *PublicService) PendingTxIDs(_ *http.Request, args *PendingTxIDsArgs, reply *PendingTxIDsReply) error {
+	limit := args.Limit
+	if limit == 0 {
+		limit = MaxPendingTxIDsLimit
+	}
+	if limit < 0 || limit > MaxPendingTxIDsLimit {
+		return fmt.Errorf("%w: %d (max=%d)", ErrInvalidLimit, args.Limit, MaxPendingTxIDsLimit)
+	}
+	reply.TxIDs = svc.vm.mempool.TopTxIDs(limit)
 	return nil
 }
 
@@ -146,6 +334,88 @@ func (svc *PublicService) LastAccepted(_ *http.Request, _ *struct{}, reply *Last
 	return nil
 }
 
+type TipReply struct {
+	Header *chain.BlockHeader `serialize:"true" json:"header"`
+}
+
+func (svc *PublicService) Tip(_ *http.Request, _ *struct{}, reply *TipReply) error {
+	reply.Header = svc.vm.lastAccepted.Header()
+	return nil
+}
+
+type GetBlockArgs struct {
+	BlockID ids.ID `serialize:"true" json:"blockId"`
+}
+
+type GetBlockByHeightArgs struct {
+	Height uint64 `serialize:"true" json:"height"`
+}
+
+type GetBlockReply struct {
+	// Exists is false if no accepted block has the requested ID (or height)
+	Exists bool               `serialize:"true" json:"exists"`
+	Header *chain.BlockHeader `serialize:"true" json:"header,omitempty"`
+	TxIDs  []ids.ID           `serialize:"true" json:"txIds,omitempty"`
+
+	// Block is the encoded [chain.StatefulBlock] (with its values restored),
+	// whose hash is [Header.ID]. It is left out (and [TooLarge] is set) if it
+	// is bigger than [Config.MaxBlockResponseSize].
+	Block    []byte `serialize:"true" json:"block,omitempty"`
+	TooLarge bool   `serialize:"true" json:"tooLarge,omitempty"`
+}
+
+// GetBlock returns the accepted block [args.BlockID].
+func (svc *PublicService) GetBlock(_ *http.Request, args *GetBlockArgs, reply *GetBlockReply) error {
+	return svc.getBlock(args.BlockID, reply)
+}
+
+// GetBlockByHeight returns the accepted block at [args.Height].
+func (svc *PublicService) GetBlockByHeight(_ *http.Request, args *GetBlockByHeightArgs, reply *GetBlockReply) error {
+	blkID, exists, err := chain.GetBlockIDAtHeight(svc.vm.db, args.Height)
+	if err != nil || !exists {
+		return err
+	}
+	return svc.getBlock(blkID, reply)
+}
+
+func (svc *PublicService) getBlock(blkID ids.ID, reply *GetBlockReply) error {
+	blk, err := chain.GetBlock(svc.vm.db, blkID)
+	if errors.Is(err, database.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	b, err := chain.Marshal(blk)
+	if err != nil {
+		return err
+	}
+	reply.Exists = true
+	reply.Header = &chain.BlockHeader{
+		ID:          blkID,
+		Prnt:        blk.Prnt,
+		Tmstmp:      blk.Tmstmp,
+		Hght:        blk.Hght,
+		Price:       blk.Price,
+		Cost:        blk.Cost,
+		AccessProof: blk.AccessProof,
+		TxCount:     len(blk.Txs),
+	}
+	reply.TxIDs = make([]ids.ID, len(blk.Txs))
+	for i, tx := range blk.Txs {
+		if err := tx.Init(svc.vm.genesis); err != nil {
+			return err
+		}
+		reply.TxIDs[i] = tx.ID()
+	}
+	if maxSize := svc.vm.config.MaxBlockResponseSize; maxSize > 0 && len(b) > maxSize {
+		reply.TooLarge = true
+		return nil
+	}
+	reply.Block = b
+	return nil
+}
+
 type SuggestedFeeArgs struct {
 	Input *chain.Input `serialize:"true" json:"input"`
 }
@@ -206,26 +476,128 @@ func (svc *PublicService) SuggestedRawFee(
 	return nil
 }
 
+type PrepareTxReply struct {
+	Magic   uint64 `serialize:"true" json:"magic"`
+	BlockID ids.ID `serialize:"true" json:"blockId"`
+	Price   uint64 `serialize:"true" json:"price"`
+	Cost    uint64 `serialize:"true" json:"cost"`
+}
+
+// PrepareTx returns everything a client needs to construct a raw tx (the
+// genesis magic, the last accepted blockID, and the suggested price and cost)
+// in a single call.
+func (svc *PublicService) PrepareTx(
+	_ *http.Request,
+	_ *struct{},
+	reply *PrepareTxReply,
+) error {
+	price, cost, err := svc.vm.SuggestedFee()
+	if err != nil {
+		return err
+	}
+	reply.Magic = svc.vm.genesis.Magic
+	reply.BlockID = svc.vm.lastAccepted.ID()
+	reply.Price = price
+	reply.Cost = cost
+	return nil
+}
+
+const (
+	Base64Encoding = "base64"
+	HexEncoding    = "hex"
+)
+
+// EncodeValue encodes [v] as a string using [encoding] (defaults to
+// [Base64Encoding] if empty).
+func EncodeValue(encoding string, v []byte) (string, error) {
+	switch encoding {
+	case "", Base64Encoding:
+		return base64.StdEncoding.EncodeToString(v), nil
+	case HexEncoding:
+		return hexutil.Encode(v), nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrInvalidEncoding, encoding)
+	}
+}
+
+// DecodeValue decodes a string produced by [EncodeValue].
+func DecodeValue(encoding string, s string) ([]byte, error) {
+	switch encoding {
+	case "", Base64Encoding:
+		return base64.StdEncoding.DecodeString(s)
+	case HexEncoding:
+		return hexutil.Decode(s)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrInvalidEncoding, encoding)
+	}
+}
+
 type ResolveArgs struct {
 	Key common.Hash `serialize:"true" json:"key"`
+	// Encoding of [ResolveReply.Value] ("base64" or "hex", defaults to "base64")
+	Encoding string `serialize:"true" json:"encoding,omitempty"`
+	// If set and equal to the TxID of the current value, [ResolveReply.Value]
+	// is omitted and [ResolveReply.NotModified] is set.
+	LastTxID ids.ID `serialize:"true" json:"lastTxId"`
+	// If set and [Key] is not stored, a pending [SetTx] for [Key] in the
+	// mempool is returned instead (with [ResolveReply.Pending] set).
+	IncludePending bool `serialize:"true" json:"includePending,omitempty"`
+	// If set and the value is bigger than [MaxBytes], [ResolveReply.Value] is
+	// omitted and [ResolveReply.TooLarge] is set.
+	MaxBytes uint64 `serialize:"true" json:"maxBytes,omitempty"`
 }
 
 type ResolveReply struct {
-	Exists    bool             `serialize:"true" json:"exists"`
-	Value     []byte           `serialize:"true" json:"value"`
+	Exists      bool `serialize:"true" json:"exists"`
+	NotModified bool `serialize:"true" json:"notModified"`
+	// Pending is set if the value has only been issued (not accepted). Only
+	// [ValueMeta.Size], [ValueMeta.TxID], and [ValueMeta.Tags] are populated.
+	Pending bool `serialize:"true" json:"pending,omitempty"`
+	// TooLarge is set if the value is bigger than [ResolveArgs.MaxBytes] (it
+	// can be fetched in pieces with "blobvm.resolveRange" instead)
+	TooLarge bool `serialize:"true" json:"tooLarge,omitempty"`
+	// Value is encoded using [Encoding]
+	Value     string           `serialize:"true" json:"value"`
+	Encoding  string           `serialize:"true" json:"encoding"`
 	ValueMeta *chain.ValueMeta `serialize:"true" json:"valueMeta"`
 }
 
 func (svc *PublicService) Resolve(_ *http.Request, args *ResolveArgs, reply *ResolveReply) error {
-	vmeta, exists, err := chain.GetValueMeta(svc.vm.db, args.Key)
+	encoding := args.Encoding
+	if len(encoding) == 0 {
+		encoding = Base64Encoding
+	}
+	if _, err := EncodeValue(encoding, nil); err != nil {
+		return err
+	}
+	reply.Encoding = encoding
+
+	vmeta, live, err := chain.GetLiveValueMeta(svc.vm.db, args.Key, uint64(svc.vm.lastAccepted.Tmstmp))
 	if err != nil {
 		return err
 	}
-	if !exists {
-		// Avoid value lookup if doesn't exist
+	if !live {
+		if args.IncludePending {
+			return svc.resolvePending(args, reply)
+		}
+		// Avoid value lookup if doesn't exist (or is no longer stored)
+		return nil
+	}
+	if args.LastTxID != ids.Empty && args.LastTxID == vmeta.TxID {
+		// Avoid value lookup if caller already has it
+		reply.Exists = true
+		reply.NotModified = true
+		reply.ValueMeta = vmeta
+		return nil
+	}
+	if args.MaxBytes > 0 && vmeta.Size > args.MaxBytes {
+		// Avoid value lookup if caller doesn't want it
+		reply.Exists = true
+		reply.TooLarge = true
+		reply.ValueMeta = vmeta
 		return nil
 	}
-	v, exists, err := chain.GetValue(svc.vm.db, args.Key)
+	v, exists, err := chain.GetValue(svc.vm.db, svc.vm.valueCache, args.Key)
 	if err != nil {
 		return err
 	}
@@ -233,13 +605,214 @@ func (svc *PublicService) Resolve(_ *http.Request, args *ResolveArgs, reply *Res
 		return ErrCorruption
 	}
 
+	ev, err := EncodeValue(encoding, v)
+	if err != nil {
+		return err
+	}
+
 	// Set values properly
 	reply.Exists = true
-	reply.Value = v
+	reply.Value = ev
 	reply.ValueMeta = vmeta
 	return nil
 }
 
+type ExportValueArgs struct {
+	Key common.Hash `serialize:"true" json:"key"`
+}
+
+type ExportValueReply struct {
+	Exists bool          `serialize:"true" json:"exists"`
+	Bundle *chain.Bundle `serialize:"true" json:"bundle,omitempty"`
+}
+
+// ExportValue returns the stored value of [args.Key] bundled with the tx that
+// set it (see [chain.VerifyBundle]).
+func (svc *PublicService) ExportValue(_ *http.Request, args *ExportValueArgs, reply *ExportValueReply) error {
+	vmeta, live, err := chain.GetLiveValueMeta(svc.vm.db, args.Key, uint64(svc.vm.lastAccepted.Tmstmp))
+	if err != nil {
+		return err
+	}
+	if !live {
+		return nil
+	}
+	if vmeta.TxID == ids.Empty {
+		// Values pre-stored at genesis are not linked to a tx
+		return fmt.Errorf("%w: value was set at genesis", ErrNotExportable)
+	}
+	tx, exists, err := chain.GetTransaction(svc.vm.db, svc.vm.genesis, vmeta.TxID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%w: tx %s is not indexed", ErrNotExportable, vmeta.TxID)
+	}
+	v, exists, err := chain.GetValue(svc.vm.db, svc.vm.valueCache, args.Key)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrCorruption
+	}
+
+	reply.Exists = true
+	reply.Bundle = &chain.Bundle{
+		Key:       args.Key,
+		Value:     v,
+		ValueMeta: vmeta,
+		Tx:        tx.Bytes(),
+	}
+	return nil
+}
+
+// MaxResolveRangeSize is the maximum number of bytes of chunks returned by a
+// single call to ResolveRange.
+const MaxResolveRangeSize = 16 * units.MiB
+
+type ResolveRangeArgs struct {
+	Key common.Hash `serialize:"true" json:"key"`
+
+	// Range of bytes [Start, End) to read (End is capped at the size of the
+	// value)
+	Start uint64 `serialize:"true" json:"start"`
+	End   uint64 `serialize:"true" json:"end"`
+}
+
+type ResolveRangeReply struct {
+	Exists bool `serialize:"true" json:"exists"`
+
+	// Value is the requested range of a single value (or of the contents of a
+	// tree root that stores its file directly).
+	Value []byte `serialize:"true" json:"value,omitempty"`
+
+	// Chunks are the file chunks (with keys [Keys]) of a tree root that cover
+	// the requested range. [Offset] is the position of the first chunk in the
+	// file.
+	Keys   []common.Hash `serialize:"true" json:"keys,omitempty"`
+	Chunks [][]byte      `serialize:"true" json:"chunks,omitempty"`
+	Offset uint64        `serialize:"true" json:"offset"`
+}
+
+// ResolveRange reads [args.Start, args.End) of the value at [args.Key]. If the
+// value is a tree root (see the tree package), only the chunks that cover the
+// range are returned (whole, so their keys can be verified). Otherwise the
+// stored value is sliced.
+func (svc *PublicService) ResolveRange(_ *http.Request, args *ResolveRangeArgs, reply *ResolveRangeReply) error {
+	if args.Start > args.End {
+		return fmt.Errorf("%w: start %d > end %d", ErrInvalidRange, args.Start, args.End)
+	}
+	now := uint64(svc.vm.lastAccepted.Tmstmp)
+	_, live, err := chain.GetLiveValueMeta(svc.vm.db, args.Key, now)
+	if err != nil {
+		return err
+	}
+	if !live {
+		return nil
+	}
+	v, exists, err := chain.GetValue(svc.vm.db, svc.vm.valueCache, args.Key)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrCorruption
+	}
+	reply.Exists = true
+
+	r, ok := chain.ParseTreeRoot(v)
+	if ok && len(r.Contents) > 0 {
+		v, ok = r.Contents, false
+	}
+	if !ok {
+		reply.Value = sliceRange(v, args.Start, args.End)
+		return nil
+	}
+	if r.Height >= maxRangeDepth {
+		return fmt.Errorf("%w: depth=%d (max=%d)", ErrInvalidTree, r.Height+1, maxRangeDepth)
+	}
+	c := &rangeCollector{db: svc.vm.db, vc: svc.vm.valueCache, now: now, start: args.Start, end: args.End}
+	if err := c.collect(r); err != nil {
+		return err
+	}
+	reply.Keys = c.keys
+	reply.Chunks = c.chunks
+	reply.Offset = c.first
+	return nil
+}
+
+// sliceRange returns [start, end) of [v] (capped at the length of [v]).
+func sliceRange(v []byte, start uint64, end uint64) []byte {
+	l := uint64(len(v))
+	if start >= l {
+		return nil
+	}
+	if end > l {
+		end = l
+	}
+	return v[start:end]
+}
+
+// resolvePending populates [reply] with the value of a pending [SetTx] for
+// [args.Key], if there is one in the mempool.
+func (svc *PublicService) resolvePending(args *ResolveArgs, reply *ResolveReply) error {
+	tx, ok := svc.vm.mempool.GetSetTx(args.Key)
+	if !ok {
+		return nil
+	}
+	stx := tx.UnsignedTransaction.(*chain.SetTx)
+	reply.Exists = true
+	reply.Pending = true
+	reply.ValueMeta = &chain.ValueMeta{
+		Size:       uint64(len(stx.Value)),
+		TxID:       tx.ID(),
+		Tags:       stx.Tags,
+		Addressing: stx.Addressing,
+	}
+	if args.LastTxID != ids.Empty && args.LastTxID == tx.ID() {
+		reply.NotModified = true
+		return nil
+	}
+	if args.MaxBytes > 0 && reply.ValueMeta.Size > args.MaxBytes {
+		reply.TooLarge = true
+		return nil
+	}
+	ev, err := EncodeValue(reply.Encoding, stx.Value)
+	if err != nil {
+		return err
+	}
+	reply.Value = ev
+	return nil
+}
+
+// MaxHasKeysLimit is the maximum number of keys that can be checked by a
+// single call to HasKeys.
+const MaxHasKeysLimit = 1024
+
+type HasKeysArgs struct {
+	Keys []common.Hash `serialize:"true" json:"keys"`
+}
+
+type HasKeysReply struct {
+	// Exists[i] is true if [HasKeysArgs.Keys][i] is stored (and live, see
+	// [chain.GetLiveValueMeta])
+	Exists []bool `serialize:"true" json:"exists"`
+}
+
+func (svc *PublicService) HasKeys(_ *http.Request, args *HasKeysArgs, reply *HasKeysReply) error {
+	if len(args.Keys) > MaxHasKeysLimit {
+		return fmt.Errorf("%w: %d (max=%d)", ErrTooManyKeys, len(args.Keys), MaxHasKeysLimit)
+	}
+	now := uint64(svc.vm.lastAccepted.Tmstmp)
+	reply.Exists = make([]bool, len(args.Keys))
+	for i, k := range args.Keys {
+		_, live, err := chain.GetLiveValueMeta(svc.vm.db, k, now)
+		if err != nil {
+			return err
+		}
+		reply.Exists[i] = live
+	}
+	return nil
+}
+
 type BalanceArgs struct {
 	Address common.Address `serialize:"true" json:"address"`
 }
@@ -257,8 +830,101 @@ func (svc *PublicService) Balance(_ *http.Request, args *BalanceArgs, reply *Bal
 	return err
 }
 
+// MaxListKeysLimit is the maximum number of keys returned by a single call to
+// ListKeys.
+const MaxListKeysLimit = 1024
+
+type ListKeysArgs struct {
+	Start common.Hash `serialize:"true" json:"start"`
+	Limit int         `serialize:"true" json:"limit"`
+}
+
+type ListKeysReply struct {
+	Keys []common.Hash `serialize:"true" json:"keys"`
+	Next *common.Hash  `serialize:"true" json:"next,omitempty"`
+}
+
+func (svc *PublicService) ListKeys(_ *http.Request, args *ListKeysArgs, reply *ListKeysReply) error {
+	limit := args.Limit
+	if limit == 0 {
+		limit = MaxListKeysLimit
+	}
+	if limit < 0 || limit > MaxListKeysLimit {
+		return fmt.Errorf("%w: %d (max=%d)", ErrInvalidLimit, args.Limit, MaxListKeysLimit)
+	}
+	keys, next, err := chain.ListKeys(svc.vm.db, args.Start, limit, uint64(svc.vm.lastAccepted.Tmstmp))
+	if err != nil {
+		return err
+	}
+	reply.Keys = keys
+	reply.Next = next
+	return nil
+}
+
+// MaxQueryByTagLimit is the maximum number of keys returned by a single call
+// to QueryByTag.
+const MaxQueryByTagLimit = 1024
+
+type QueryByTagArgs struct {
+	Key   string `serialize:"true" json:"key"`
+	Value string `serialize:"true" json:"value"`
+	Limit int    `serialize:"true" json:"limit"`
+}
+
+type QueryByTagReply struct {
+	Keys []common.Hash `serialize:"true" json:"keys"`
+}
+
+func (svc *PublicService) QueryByTag(_ *http.Request, args *QueryByTagArgs, reply *QueryByTagReply) error {
+	limit := args.Limit
+	if limit == 0 {
+		limit = MaxQueryByTagLimit
+	}
+	if limit < 0 || limit > MaxQueryByTagLimit {
+		return fmt.Errorf("%w: %d (max=%d)", ErrInvalidLimit, args.Limit, MaxQueryByTagLimit)
+	}
+	keys, err := chain.QueryByTag(svc.vm.db, args.Key, args.Value, limit, uint64(svc.vm.lastAccepted.Tmstmp))
+	if err != nil {
+		return err
+	}
+	reply.Keys = keys
+	return nil
+}
+
+// MaxTxsBySenderLimit is the maximum number of tx IDs returned by a single call
+// to TxsBySender.
+const MaxTxsBySenderLimit = 1024
+
+type TxsBySenderArgs struct {
+	Address common.Address `serialize:"true" json:"address"`
+	Limit   int            `serialize:"true" json:"limit"`
+}
+
+type TxsBySenderReply struct {
+	TxIDs []ids.ID `serialize:"true" json:"txIds"`
+}
+
+func (svc *PublicService) TxsBySender(_ *http.Request, args *TxsBySenderArgs, reply *TxsBySenderReply) error {
+	limit := args.Limit
+	if limit == 0 {
+		limit = MaxTxsBySenderLimit
+	}
+	if limit < 0 || limit > MaxTxsBySenderLimit {
+		return fmt.Errorf("%w: %d (max=%d)", ErrInvalidLimit, args.Limit, MaxTxsBySenderLimit)
+	}
+	txIDs, err := chain.GetTxsBySender(svc.vm.db, args.Address, limit)
+	if err != nil {
+		return err
+	}
+	reply.TxIDs = txIDs
+	return nil
+}
+
 type RecentActivityReply struct {
 	Activity []*chain.Activity `serialize:"true" json:"activity"`
+	// More is set if older activity was left out to keep the response within
+	// [Config.MaxActivityResponseSize].
+	More bool `serialize:"true" json:"more"`
 }
 
 func (svc *PublicService) RecentActivity(_ *http.Request, _ *struct{}, reply *RecentActivityReply) error {
@@ -271,14 +937,36 @@ func (svc *PublicService) RecentActivity(_ *http.Request, _ *struct{}, reply *Re
 	start := svc.vm.activityCacheCursor
 	i := start
 	activity := []*chain.Activity{}
+	maxSize := svc.vm.config.MaxActivityResponseSize
+	size := 0
 	for i > 0 && start-i < cs {
 		i--
 		item := svc.vm.activityCache[i%cs]
 		if item == nil {
 			break
 		}
+		if maxSize > 0 {
+			b, err := json.Marshal(item)
+			if err != nil {
+				return err
+			}
+			if size+len(b) > maxSize {
+				reply.More = true
+				break
+			}
+			size += len(b)
+		}
 		activity = append(activity, item)
 	}
 	reply.Activity = activity
 	return nil
 }
+
+type ActivityTypesReply struct {
+	Types []*chain.ActivityType `serialize:"true" json:"types"`
+}
+
+func (svc *PublicService) ActivityTypes(_ *http.Request, _ *struct{}, reply *ActivityTypesReply) error {
+	reply.Types = chain.ActivityTypes()
+	return nil
+}
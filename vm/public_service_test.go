@@ -0,0 +1,1000 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package vm
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/database"
+	"github.com/ava-labs/avalanchego/database/memdb"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/snow/choices"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/ava-labs/blobvm/chain"
+	"github.com/ava-labs/blobvm/mempool"
+)
+
+func TestResolveEncoding(t *testing.T) {
+	db := memdb.New()
+	defer db.Close()
+
+	v := []byte{0x00, 0xff, 'b', 'l', 'o', 'b'}
+	k := chain.ValueHash(v)
+	txID := ids.GenerateTestID()
+	if err := db.Put(chain.PrefixTxValueKey(txID), v); err != nil {
+		t.Fatal(err)
+	}
+	if err := chain.PutKey(db, k, &chain.ValueMeta{Size: uint64(len(v)), TxID: txID}); err != nil {
+		t.Fatal(err)
+	}
+
+	svc := &PublicService{vm: testVM(db, 0)}
+	for _, encoding := range []string{"", Base64Encoding, HexEncoding} {
+		reply := new(ResolveReply)
+		if err := svc.Resolve(nil, &ResolveArgs{Key: k, Encoding: encoding}, reply); err != nil {
+			t.Fatalf("encoding=%q: %v", encoding, err)
+		}
+		if !reply.Exists {
+			t.Fatalf("encoding=%q: value not found", encoding)
+		}
+		dv, err := DecodeValue(reply.Encoding, reply.Value)
+		if err != nil {
+			t.Fatalf("encoding=%q: %v", encoding, err)
+		}
+		if !bytes.Equal(v, dv) {
+			t.Fatalf("encoding=%q: value expected %x, got %x", encoding, v, dv)
+		}
+	}
+
+	err := svc.Resolve(nil, &ResolveArgs{Key: k, Encoding: "base58"}, new(ResolveReply))
+	if !errors.Is(err, ErrInvalidEncoding) {
+		t.Fatalf("unexpected error %v, expected %v", err, ErrInvalidEncoding)
+	}
+}
+
+func TestResolveNotModified(t *testing.T) {
+	db := memdb.New()
+	defer db.Close()
+
+	v := []byte("profile")
+	k := chain.ValueHash(v)
+	txID := ids.GenerateTestID()
+	if err := db.Put(chain.PrefixTxValueKey(txID), v); err != nil {
+		t.Fatal(err)
+	}
+	if err := chain.PutKey(db, k, &chain.ValueMeta{Size: uint64(len(v)), TxID: txID}); err != nil {
+		t.Fatal(err)
+	}
+
+	svc := &PublicService{vm: testVM(db, 0)}
+	reply := new(ResolveReply)
+	if err := svc.Resolve(nil, &ResolveArgs{Key: k, LastTxID: txID}, reply); err != nil {
+		t.Fatal(err)
+	}
+	if !reply.Exists || !reply.NotModified {
+		t.Fatalf("expected exists and not modified, got %+v", reply)
+	}
+	if len(reply.Value) != 0 {
+		t.Fatalf("unexpected value %q", reply.Value)
+	}
+
+	// Update the TxID associated with [k]
+	newTxID := ids.GenerateTestID()
+	if err := db.Put(chain.PrefixTxValueKey(newTxID), v); err != nil {
+		t.Fatal(err)
+	}
+	if err := chain.PutKey(db, k, &chain.ValueMeta{Size: uint64(len(v)), TxID: newTxID}); err != nil {
+		t.Fatal(err)
+	}
+	reply = new(ResolveReply)
+	if err := svc.Resolve(nil, &ResolveArgs{Key: k, LastTxID: txID}, reply); err != nil {
+		t.Fatal(err)
+	}
+	if !reply.Exists || reply.NotModified {
+		t.Fatalf("expected exists and modified, got %+v", reply)
+	}
+	if reply.ValueMeta.TxID != newTxID {
+		t.Fatalf("txID expected %s, got %s", newTxID, reply.ValueMeta.TxID)
+	}
+	dv, err := DecodeValue(reply.Encoding, reply.Value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(v, dv) {
+		t.Fatalf("value expected %q, got %q", v, dv)
+	}
+}
+
+func testVM(db database.Database, tmstmp int64) *VM {
+	return &VM{
+		db:           db,
+		lastAccepted: &chain.StatelessBlock{StatefulBlock: &chain.StatefulBlock{Tmstmp: tmstmp}},
+	}
+}
+
+func TestResolveExpired(t *testing.T) {
+	db := memdb.New()
+	defer db.Close()
+
+	v := []byte("ephemeral")
+	k := chain.ValueHash(v)
+	txID := ids.GenerateTestID()
+	if err := db.Put(chain.PrefixTxValueKey(txID), v); err != nil {
+		t.Fatal(err)
+	}
+	if err := chain.PutKey(db, k, &chain.ValueMeta{Size: uint64(len(v)), TxID: txID, Created: 10, Expiry: 20}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tv := range []struct {
+		tmstmp int64
+		exists bool
+	}{
+		{tmstmp: 10, exists: true},
+		{tmstmp: 19, exists: true},
+		{tmstmp: 20, exists: false},
+	} {
+		svc := &PublicService{vm: testVM(db, tv.tmstmp)}
+		reply := new(ResolveReply)
+		if err := svc.Resolve(nil, &ResolveArgs{Key: k}, reply); err != nil {
+			t.Fatal(err)
+		}
+		if reply.Exists != tv.exists {
+			t.Fatalf("tmstmp=%d: exists expected %t, got %t", tv.tmstmp, tv.exists, reply.Exists)
+		}
+	}
+}
+
+func TestResolveMaxBytes(t *testing.T) {
+	t.Parallel()
+
+	db := memdb.New()
+	defer db.Close()
+
+	v := []byte("twelve bytes")
+	k := chain.ValueHash(v)
+	txID := ids.GenerateTestID()
+	if err := db.Put(chain.PrefixTxValueKey(txID), v); err != nil {
+		t.Fatal(err)
+	}
+	if err := chain.PutKey(db, k, &chain.ValueMeta{Size: uint64(len(v)), TxID: txID, Created: 10, Expiry: 20}); err != nil {
+		t.Fatal(err)
+	}
+	svc := &PublicService{vm: testVM(db, 10)}
+
+	for _, tv := range []struct {
+		maxBytes uint64
+		tooLarge bool
+	}{
+		{maxBytes: 0, tooLarge: false},
+		{maxBytes: 11, tooLarge: true},
+		{maxBytes: 12, tooLarge: false},
+		{maxBytes: 13, tooLarge: false},
+	} {
+		reply := new(ResolveReply)
+		if err := svc.Resolve(nil, &ResolveArgs{Key: k, MaxBytes: tv.maxBytes}, reply); err != nil {
+			t.Fatal(err)
+		}
+		if !reply.Exists || reply.TooLarge != tv.tooLarge {
+			t.Fatalf("maxBytes=%d: unexpected reply (exists=%t, tooLarge=%t)", tv.maxBytes, reply.Exists, reply.TooLarge)
+		}
+		if reply.ValueMeta == nil || reply.ValueMeta.Size != uint64(len(v)) {
+			t.Fatalf("maxBytes=%d: unexpected value meta %+v", tv.maxBytes, reply.ValueMeta)
+		}
+		rv, err := DecodeValue(reply.Encoding, reply.Value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tv.tooLarge {
+			if len(rv) > 0 {
+				t.Fatalf("maxBytes=%d: value should be omitted, got %q", tv.maxBytes, rv)
+			}
+			continue
+		}
+		if !bytes.Equal(rv, v) {
+			t.Fatalf("maxBytes=%d: value expected %q, got %q", tv.maxBytes, v, rv)
+		}
+	}
+}
+
+func TestResolvePending(t *testing.T) {
+	db := memdb.New()
+	defer db.Close()
+
+	g := chain.DefaultGenesis()
+	v := []byte("read-your-writes")
+	k := chain.ValueHash(v)
+	tx := testSetTx(t, g, v, 1)
+
+	vm := testVM(db, 0)
+	vm.mempool = mempool.New(g, 4)
+	if !vm.mempool.Add(tx) {
+		t.Fatalf("tx %s was not added", tx.ID())
+	}
+	svc := &PublicService{vm: vm}
+
+	// Pending values are only returned when requested
+	reply := new(ResolveReply)
+	if err := svc.Resolve(nil, &ResolveArgs{Key: k}, reply); err != nil {
+		t.Fatal(err)
+	}
+	if reply.Exists {
+		t.Fatal("value should not exist")
+	}
+
+	reply = new(ResolveReply)
+	if err := svc.Resolve(nil, &ResolveArgs{Key: k, IncludePending: true}, reply); err != nil {
+		t.Fatal(err)
+	}
+	if !reply.Exists || !reply.Pending {
+		t.Fatalf("value should exist and be pending (exists=%t, pending=%t)", reply.Exists, reply.Pending)
+	}
+	if reply.ValueMeta.TxID != tx.ID() {
+		t.Fatalf("txID expected %s, got %s", tx.ID(), reply.ValueMeta.TxID)
+	}
+	rv, err := DecodeValue(reply.Encoding, reply.Value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rv, v) {
+		t.Fatalf("value expected %q, got %q", v, rv)
+	}
+
+	// Once accepted, the stored value is returned
+	if err := db.Put(chain.PrefixTxValueKey(tx.ID()), v); err != nil {
+		t.Fatal(err)
+	}
+	if err := chain.PutKey(db, k, &chain.ValueMeta{Size: uint64(len(v)), TxID: tx.ID()}); err != nil {
+		t.Fatal(err)
+	}
+	reply = new(ResolveReply)
+	if err := svc.Resolve(nil, &ResolveArgs{Key: k, IncludePending: true}, reply); err != nil {
+		t.Fatal(err)
+	}
+	if !reply.Exists || reply.Pending {
+		t.Fatalf("value should exist and not be pending (exists=%t, pending=%t)", reply.Exists, reply.Pending)
+	}
+}
+
+func TestHasKeys(t *testing.T) {
+	db := memdb.New()
+	defer db.Close()
+
+	stored := chain.ValueHash([]byte("stored"))
+	expired := chain.ValueHash([]byte("expired"))
+	missing := chain.ValueHash([]byte("missing"))
+	if err := chain.PutKey(db, stored, &chain.ValueMeta{Size: 6}); err != nil {
+		t.Fatal(err)
+	}
+	if err := chain.PutKey(db, expired, &chain.ValueMeta{Size: 7, Expiry: 10}); err != nil {
+		t.Fatal(err)
+	}
+	// Expired chunks of a live (ex: renewed) root are still stored
+	chunk := chain.ValueHash([]byte("chunk"))
+	if err := chain.PutKey(db, chunk, &chain.ValueMeta{Size: 5, Expiry: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Put(chain.PrefixTreeRefKey(chunk, stored), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	svc := &PublicService{vm: testVM(db, 10)}
+	reply := new(HasKeysReply)
+	if err := svc.HasKeys(nil, &HasKeysArgs{Keys: []common.Hash{stored, expired, missing, chunk}}, reply); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []bool{true, false, false, true}; !reflect.DeepEqual(reply.Exists, expected) {
+		t.Fatalf("exists expected %v, got %v", expected, reply.Exists)
+	}
+
+	err := svc.HasKeys(nil, &HasKeysArgs{Keys: make([]common.Hash, MaxHasKeysLimit+1)}, new(HasKeysReply))
+	if !errors.Is(err, ErrTooManyKeys) {
+		t.Fatalf("err expected %v, got %v", ErrTooManyKeys, err)
+	}
+}
+
+func TestHasTxs(t *testing.T) {
+	db := memdb.New()
+	defer db.Close()
+
+	accepted := []ids.ID{ids.GenerateTestID(), ids.GenerateTestID()}
+	for _, txID := range accepted {
+		if err := db.Put(chain.PrefixTxKey(txID), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pending := ids.GenerateTestID()
+
+	svc := &PublicService{vm: testVM(db, 0)}
+	reply := new(HasTxsReply)
+	if err := svc.HasTxs(nil, &HasTxsArgs{TxIDs: []ids.ID{accepted[0], pending, accepted[1]}}, reply); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []bool{true, false, true}; !reflect.DeepEqual(reply.Accepted, expected) {
+		t.Fatalf("accepted expected %v, got %v", expected, reply.Accepted)
+	}
+
+	err := svc.HasTxs(nil, &HasTxsArgs{TxIDs: make([]ids.ID, MaxHasTxsLimit+1)}, new(HasTxsReply))
+	if !errors.Is(err, ErrTooManyTxs) {
+		t.Fatalf("err expected %v, got %v", ErrTooManyTxs, err)
+	}
+}
+
+func TestTxsBySender(t *testing.T) {
+	db := memdb.New()
+	defer db.Close()
+
+	vm := testVM(db, 0)
+	vm.genesis = chain.DefaultGenesis()
+	privs := make([]*ecdsa.PrivateKey, 2)
+	for i := range privs {
+		priv, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		privs[i] = priv
+	}
+
+	// Sender 0 issues a tx in every block and sender 1 in every other block
+	expected := make([][]ids.ID, len(privs))
+	for h := uint64(1); h <= 4; h++ {
+		txs := []*chain.Transaction{}
+		for i, priv := range privs {
+			if i == 1 && h%2 == 0 {
+				continue
+			}
+			tx := testSignedSetTx(t, vm.genesis, priv, []byte(fmt.Sprintf("value %d/%d", h, i)), 1)
+			txs = append(txs, tx)
+			expected[i] = append([]ids.ID{tx.ID()}, expected[i]...)
+		}
+		blk, err := chain.ParseStatefulBlock(&chain.StatefulBlock{Hght: h, Txs: txs}, nil, choices.Accepted, vm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := chain.SetLastAccepted(db, blk, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	svc := &PublicService{vm: vm}
+	for i, priv := range privs {
+		addr := crypto.PubkeyToAddress(priv.PublicKey)
+		reply := new(TxsBySenderReply)
+		if err := svc.TxsBySender(nil, &TxsBySenderArgs{Address: addr}, reply); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(reply.TxIDs, expected[i]) {
+			t.Fatalf("#%d: unexpected txs %v, expected %v", i, reply.TxIDs, expected[i])
+		}
+
+		reply = new(TxsBySenderReply)
+		if err := svc.TxsBySender(nil, &TxsBySenderArgs{Address: addr, Limit: 1}, reply); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(reply.TxIDs, expected[i][:1]) {
+			t.Fatalf("#%d: unexpected txs %v, expected %v", i, reply.TxIDs, expected[i][:1])
+		}
+	}
+
+	reply := new(TxsBySenderReply)
+	if err := svc.TxsBySender(nil, &TxsBySenderArgs{Limit: MaxTxsBySenderLimit + 1}, reply); !errors.Is(err, ErrInvalidLimit) {
+		t.Fatalf("unexpected error %v, expected %v", err, ErrInvalidLimit)
+	}
+}
+
+func TestMempoolStats(t *testing.T) {
+	db := memdb.New()
+	defer db.Close()
+
+	g := chain.DefaultGenesis()
+	vm := testVM(db, 0)
+	vm.mempool = mempool.New(g, 4)
+	svc := &PublicService{vm: vm}
+
+	reply := new(MempoolStatsReply)
+	if err := svc.MempoolStats(nil, nil, reply); err != nil {
+		t.Fatal(err)
+	}
+	if *reply != (MempoolStatsReply{}) {
+		t.Fatalf("unexpected stats %+v for an empty mempool", reply)
+	}
+
+	txs := []*chain.Transaction{
+		testSetTx(t, g, []byte("cheap"), 1),
+		testSetTx(t, g, []byte("expensive"), 3),
+		testSetTx(t, g, []byte("average"), 2),
+	}
+	for _, tx := range txs {
+		if !vm.mempool.Add(tx) {
+			t.Fatalf("tx %s was not added", tx.ID())
+		}
+	}
+	reply = new(MempoolStatsReply)
+	if err := svc.MempoolStats(nil, nil, reply); err != nil {
+		t.Fatal(err)
+	}
+	// Each tx has 1 base unit and 1 value unit
+	if expected := (MempoolStatsReply{Len: 3, FeeUnits: 6, MinPrice: 1, MaxPrice: 3}); *reply != expected {
+		t.Fatalf("stats expected %+v, got %+v", expected, reply)
+	}
+
+	pending := new(PendingTxIDsReply)
+	if err := svc.PendingTxIDs(nil, &PendingTxIDsArgs{Limit: 2}, pending); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []ids.ID{txs[1].ID(), txs[2].ID()}; !reflect.DeepEqual(pending.TxIDs, expected) {
+		t.Fatalf("pending txs expected %v, got %v", expected, pending.TxIDs)
+	}
+	if err := svc.PendingTxIDs(nil, &PendingTxIDsArgs{Limit: -1}, pending); !errors.Is(err, ErrInvalidLimit) {
+		t.Fatalf("unexpected error %v, expected %v", err, ErrInvalidLimit)
+	}
+}
+
+func TestResolveTags(t *testing.T) {
+	db := memdb.New()
+	defer db.Close()
+
+	v := []byte("tagged")
+	k := chain.ValueHash(v)
+	txID := ids.GenerateTestID()
+	tags := []*chain.Tag{{Key: "type", Value: "text/plain"}}
+	if err := db.Put(chain.PrefixTxValueKey(txID), v); err != nil {
+		t.Fatal(err)
+	}
+	if err := chain.PutKey(db, k, &chain.ValueMeta{Size: uint64(len(v)), TxID: txID, Tags: tags}); err != nil {
+		t.Fatal(err)
+	}
+
+	svc := &PublicService{vm: testVM(db, 0)}
+	reply := new(ResolveReply)
+	if err := svc.Resolve(nil, &ResolveArgs{Key: k}, reply); err != nil {
+		t.Fatal(err)
+	}
+	if !reply.Exists {
+		t.Fatal("value should exist")
+	}
+	if !reflect.DeepEqual(reply.ValueMeta.Tags, tags) {
+		t.Fatalf("unexpected tags %v, expected %v", reply.ValueMeta.Tags, tags)
+	}
+}
+
+func TestIssueTxWrongMagic(t *testing.T) {
+	g := chain.DefaultGenesis()
+	g.Magic = 5
+	svc := &PublicService{vm: &VM{genesis: g}}
+
+	utx := &chain.SetTx{
+		BaseTx: &chain.BaseTx{Magic: 6, BlockID: ids.GenerateTestID(), Price: 1},
+		Value:  []byte("other network"),
+	}
+	err := svc.IssueTx(nil, &IssueTxArgs{TypedData: utx.TypedData()}, new(IssueTxReply))
+	if !errors.Is(err, ErrWrongMagic) {
+		t.Fatalf("expected %v, got %v", ErrWrongMagic, err)
+	}
+}
+
+func TestIssueRawTxBatch(t *testing.T) {
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := chain.DefaultGenesis()
+	g.CustomAllocation = []*chain.CustomAllocation{
+		{Address: crypto.PubkeyToAddress(priv.PublicKey), Balance: 10000000},
+	}
+	db := memdb.New()
+	defer db.Close()
+	if err := g.Load(db, nil); err != nil {
+		t.Fatal(err)
+	}
+	svc := &PublicService{vm: testAcceptedVM(t, g, db, time.Now())}
+
+	txs := make([]*chain.Transaction, 2)
+	for i := range txs {
+		txs[i] = testSignTx(t, g, priv, &chain.SetTx{
+			BaseTx: &chain.BaseTx{BlockID: svc.vm.preferred, Price: 2},
+			Value:  []byte(fmt.Sprintf("batch %d", i)),
+		})
+	}
+	// a malformed tx between valid ones must not affect them
+	args := &IssueRawTxBatchArgs{Txs: [][]byte{txs[0].Bytes(), {0xff}, txs[1].Bytes()}}
+	reply := new(IssueRawTxBatchReply)
+	if err := svc.IssueRawTxBatch(nil, args, reply); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(reply.TxIDs, []ids.ID{txs[0].ID(), ids.Empty, txs[1].ID()}) {
+		t.Fatalf("unexpected tx IDs %v", reply.TxIDs)
+	}
+	if len(reply.Errors[0]) > 0 || len(reply.Errors[1]) == 0 || len(reply.Errors[2]) > 0 {
+		t.Fatalf("unexpected errors %q", reply.Errors)
+	}
+	for _, tx := range txs {
+		if !svc.vm.mempool.Has(tx.ID()) {
+			t.Fatalf("tx %s not added to mempool", tx.ID())
+		}
+	}
+
+	err = svc.IssueRawTxBatch(nil, &IssueRawTxBatchArgs{Txs: make([][]byte, MaxIssueRawTxBatchLimit+1)}, reply)
+	if !errors.Is(err, ErrTooManyTxs) {
+		t.Fatalf("expected %v, got %v", ErrTooManyTxs, err)
+	}
+}
+
+func TestExportValue(t *testing.T) {
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := crypto.PubkeyToAddress(priv.PublicKey)
+	g := chain.DefaultGenesis()
+	g.CustomAllocation = []*chain.CustomAllocation{{Address: sender, Balance: 10000000}}
+	db := memdb.New()
+	defer db.Close()
+	if err := g.Load(db, nil); err != nil {
+		t.Fatal(err)
+	}
+	vm := testAcceptedVM(t, g, db, time.Now())
+
+	v := []byte("portable")
+	tx := testAcceptTx(t, vm, priv, &chain.SetTx{
+		BaseTx: &chain.BaseTx{BlockID: vm.preferred, Price: 1000},
+		Value:  v,
+	})
+	svc := &PublicService{vm: vm}
+	reply := new(ExportValueReply)
+	if err := svc.ExportValue(nil, &ExportValueArgs{Key: g.ValueHash(v)}, reply); err != nil {
+		t.Fatal(err)
+	}
+	if !reply.Exists {
+		t.Fatal("value should exist")
+	}
+	if reply.Bundle.ValueMeta.TxID != tx.ID() {
+		t.Fatalf("unexpected tx %s, expected %s", reply.Bundle.ValueMeta.TxID, tx.ID())
+	}
+
+	// The bundle must verify without the node's state
+	b, err := json.Marshal(reply.Bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle := new(chain.Bundle)
+	if err := json.Unmarshal(b, bundle); err != nil {
+		t.Fatal(err)
+	}
+	signer, err := chain.VerifyBundle(chain.DefaultGenesis(), bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signer != sender {
+		t.Fatalf("unexpected signer %s, expected %s", signer, sender)
+	}
+
+	reply = new(ExportValueReply)
+	if err := svc.ExportValue(nil, &ExportValueArgs{Key: g.ValueHash([]byte("missing"))}, reply); err != nil {
+		t.Fatal(err)
+	}
+	if reply.Exists || reply.Bundle != nil {
+		t.Fatal("missing value should not be exported")
+	}
+}
+
+func TestResolveCID(t *testing.T) {
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := crypto.PubkeyToAddress(priv.PublicKey)
+	g := chain.DefaultGenesis()
+	g.CustomAllocation = []*chain.CustomAllocation{{Address: sender, Balance: 10000000}}
+	g.AllowedAddressing = []chain.Addressing{chain.CIDv1}
+	db := memdb.New()
+	defer db.Close()
+	if err := g.Load(db, nil); err != nil {
+		t.Fatal(err)
+	}
+	vm := testAcceptedVM(t, g, db, time.Now())
+
+	// Stored under the CID IPFS assigns to the value
+	v := []byte("hello world")
+	k, err := chain.CIDKey("bafkreifzjut3te2nhyekklss27nh3k72ysco7y32koao5eei66wof36n5e")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testAcceptTx(t, vm, priv, &chain.SetTx{
+		BaseTx:     &chain.BaseTx{BlockID: vm.preferred, Price: 1000},
+		Value:      v,
+		Addressing: chain.CIDv1,
+		Key:        k,
+	})
+	svc := &PublicService{vm: vm}
+	reply := new(ResolveReply)
+	if err := svc.Resolve(nil, &ResolveArgs{Key: k}, reply); err != nil {
+		t.Fatal(err)
+	}
+	if !reply.Exists || reply.ValueMeta.Addressing != chain.CIDv1 {
+		t.Fatalf("value expected at %s with %q addressing, got %+v", k, chain.CIDv1, reply)
+	}
+	dv, err := DecodeValue(reply.Encoding, reply.Value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dv, v) {
+		t.Fatalf("value expected %q, got %q", v, dv)
+	}
+
+	// The value can be exported and verified by its CID
+	export := new(ExportValueReply)
+	if err := svc.ExportValue(nil, &ExportValueArgs{Key: k}, export); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := chain.VerifyBundle(g, export.Bundle); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResolveRange(t *testing.T) {
+	db := memdb.New()
+	defer db.Close()
+
+	put := func(v []byte) common.Hash {
+		k := chain.ValueHash(v)
+		txID := ids.GenerateTestID()
+		if err := db.Put(chain.PrefixTxValueKey(txID), v); err != nil {
+			t.Fatal(err)
+		}
+		if err := chain.PutKey(db, k, &chain.ValueMeta{Size: uint64(len(v)), TxID: txID}); err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+	putRoot := func(r *chain.TreeRoot) common.Hash {
+		b, err := json.Marshal(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return put(b)
+	}
+	a, b, c := put([]byte("aaaa")), put([]byte("bbbb")), put([]byte("cc"))
+	single := put([]byte("hello world"))
+	small := putRoot(&chain.TreeRoot{Contents: []byte("small")})
+	flat := putRoot(&chain.TreeRoot{Children: []common.Hash{a, b, c}, Size: 10})
+	deep := putRoot(&chain.TreeRoot{
+		Children: []common.Hash{
+			putRoot(&chain.TreeRoot{Children: []common.Hash{a, b}, Size: 8}),
+			putRoot(&chain.TreeRoot{Children: []common.Hash{c}, Size: 2}),
+		},
+		Height: 1,
+		Size:   10,
+	})
+	svc := &PublicService{vm: testVM(db, 1)}
+
+	for i, tv := range []struct {
+		key        common.Hash
+		start, end uint64
+
+		value  []byte
+		keys   []common.Hash
+		offset uint64
+	}{
+		{key: single, start: 0, end: 5, value: []byte("hello")},
+		{key: single, start: 6, end: 100, value: []byte("world")},
+		{key: single, start: 20, end: 30},
+		{key: small, start: 1, end: 3, value: []byte("ma")},
+		{key: flat, start: 5, end: 7, keys: []common.Hash{b}, offset: 4},
+		{key: flat, start: 4, end: 8, keys: []common.Hash{b}, offset: 4},
+		{key: flat, start: 3, end: 9, keys: []common.Hash{a, b, c}},
+		{key: flat, start: 10, end: 20},
+		{key: deep, start: 8, end: 10, keys: []common.Hash{c}, offset: 8},
+		{key: deep, start: 2, end: 6, keys: []common.Hash{a, b}},
+	} {
+		reply := new(ResolveRangeReply)
+		if err := svc.ResolveRange(nil, &ResolveRangeArgs{Key: tv.key, Start: tv.start, End: tv.end}, reply); err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+		if !reply.Exists {
+			t.Fatalf("#%d: value should exist", i)
+		}
+		if !bytes.Equal(reply.Value, tv.value) {
+			t.Fatalf("#%d: value expected %q, got %q", i, tv.value, reply.Value)
+		}
+		if !reflect.DeepEqual(reply.Keys, tv.keys) || reply.Offset != tv.offset {
+			t.Fatalf("#%d: chunks expected %v at %d, got %v at %d", i, tv.keys, tv.offset, reply.Keys, reply.Offset)
+		}
+		for j, k := range reply.Keys {
+			if chain.ValueHash(reply.Chunks[j]) != k {
+				t.Fatalf("#%d: chunk %d does not match key %s", i, j, k)
+			}
+		}
+	}
+
+	err := svc.ResolveRange(nil, &ResolveRangeArgs{Key: single, Start: 2, End: 1}, new(ResolveRangeReply))
+	if !errors.Is(err, ErrInvalidRange) {
+		t.Fatalf("expected %v, got %v", ErrInvalidRange, err)
+	}
+	compressed := putRoot(&chain.TreeRoot{Children: []common.Hash{a}, Size: 10, Compression: "gzip"})
+	err = svc.ResolveRange(nil, &ResolveRangeArgs{Key: compressed, Start: 0, End: 1}, new(ResolveRangeReply))
+	if !errors.Is(err, ErrCompressedTree) {
+		t.Fatalf("expected %v, got %v", ErrCompressedTree, err)
+	}
+}
+
+func TestSimulateTx(t *testing.T) {
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := crypto.PubkeyToAddress(priv.PublicKey)
+
+	g := chain.DefaultGenesis()
+	g.CustomAllocation = []*chain.CustomAllocation{{Address: sender, Balance: 10000000}}
+	db := memdb.New()
+	defer db.Close()
+	if err := g.Load(db, nil); err != nil {
+		t.Fatal(err)
+	}
+	vm := testAcceptedVM(t, g, db, time.Now())
+	testAcceptTx(t, vm, priv, &chain.SetTx{
+		BaseTx: &chain.BaseTx{BlockID: vm.preferred, Price: 2},
+		Value:  []byte("taken"),
+	})
+	balance, err := chain.GetBalance(db, sender)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := &PublicService{vm: vm}
+
+	simulate := func(v []byte) *SimulateTxReply {
+		tx := testSignTx(t, g, priv, &chain.SetTx{
+			BaseTx: &chain.BaseTx{BlockID: vm.preferred, Price: 2},
+			Value:  v,
+		})
+		reply := new(SimulateTxReply)
+		if err := svc.SimulateTx(nil, &SimulateTxArgs{Tx: tx.Bytes()}, reply); err != nil {
+			t.Fatal(err)
+		}
+		if reply.TxID != tx.ID() {
+			t.Fatalf("unexpected txID %s, expected %s", reply.TxID, tx.ID())
+		}
+		return reply
+	}
+
+	reply := simulate([]byte("taken"))
+	if reply.Success || !strings.Contains(reply.Error, chain.ErrKeyExists.Error()) {
+		t.Fatalf("expected failure with %v, got %+v", chain.ErrKeyExists, reply)
+	}
+	reply = simulate([]byte("new"))
+	if !reply.Success || reply.Fee == 0 || reply.Balance != balance-reply.Fee {
+		t.Fatalf("unexpected simulation %+v (balance=%d)", reply, balance)
+	}
+
+	// Nothing is issued or persisted
+	if vm.mempool.Len() != 0 {
+		t.Fatalf("unexpected mempool size %d", vm.mempool.Len())
+	}
+	persisted, err := chain.GetBalance(db, sender)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if persisted != balance {
+		t.Fatalf("unexpected persisted balance %d, expected %d", persisted, balance)
+	}
+	if has, err := chain.HasKey(db, chain.ValueHash([]byte("new"))); err != nil || has {
+		t.Fatalf("simulated value persisted (err=%v)", err)
+	}
+
+	if err := svc.SimulateTx(nil, &SimulateTxArgs{Tx: []byte("invalid")}, new(SimulateTxReply)); err == nil {
+		t.Fatal("expected unparsable tx to fail")
+	}
+}
+
+func TestRecentActivityMaxResponseSize(t *testing.T) {
+	activity := make([]*chain.Activity, 10)
+	for i := range activity {
+		activity[i] = &chain.Activity{
+			Tmstmp: int64(i),
+			Typ:    chain.Set,
+			Key:    fmt.Sprintf("%064x", i),
+		}
+	}
+	itemSize := 0
+	for _, a := range activity {
+		b, err := json.Marshal(a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(b) > itemSize {
+			itemSize = len(b)
+		}
+	}
+
+	for i, tv := range []struct {
+		maxSize int
+		n       int
+	}{
+		{maxSize: 0, n: 10},              // unbounded
+		{maxSize: 100 * itemSize, n: 10}, // everything fits
+		{maxSize: 3*itemSize + 1, n: 3},  // truncated
+		{maxSize: itemSize - 1, n: 0},    // nothing fits
+		{maxSize: 10 * itemSize, n: 10},  // exactly fits
+	} {
+		vm := &VM{
+			config:        Config{ActivityCacheSize: len(activity), MaxActivityResponseSize: tv.maxSize},
+			activityCache: make([]*chain.Activity, len(activity)),
+		}
+		for _, a := range activity {
+			vm.activityCache[vm.activityCacheCursor] = a
+			vm.activityCacheCursor++
+		}
+		svc := &PublicService{vm: vm}
+		reply := new(RecentActivityReply)
+		if err := svc.RecentActivity(nil, nil, reply); err != nil {
+			t.Fatal(err)
+		}
+		if len(reply.Activity) != tv.n || reply.More != (tv.n < len(activity)) {
+			t.Fatalf("#%d: expected %d items (more=%t), got %d (more=%t)",
+				i, tv.n, tv.n < len(activity), len(reply.Activity), reply.More)
+		}
+		// Newest activity is kept
+		for j, a := range reply.Activity {
+			if a != activity[len(activity)-1-j] {
+				t.Fatalf("#%d: unexpected activity %d: %+v", i, j, a)
+			}
+		}
+	}
+}
+
+func TestGetBlock(t *testing.T) {
+	db := memdb.New()
+	defer db.Close()
+
+	vm := testVM(db, 0)
+	vm.genesis = chain.DefaultGenesis()
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blks := []*chain.StatelessBlock{}
+	parent := ids.Empty
+	for h := uint64(0); h <= 3; h++ {
+		txs := []*chain.Transaction{}
+		if h > 0 {
+			txs = append(txs, testSignedSetTx(t, vm.genesis, priv, []byte(fmt.Sprintf("value %d", h)), 1))
+		}
+		blk, err := chain.ParseStatefulBlock(&chain.StatefulBlock{Prnt: parent, Hght: h, Txs: txs}, nil, choices.Accepted, vm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := chain.SetLastAccepted(db, blk, false); err != nil {
+			t.Fatal(err)
+		}
+		blks = append(blks, blk)
+		parent = blk.ID()
+	}
+
+	svc := &PublicService{vm: vm}
+	checkBlock := func(reply *GetBlockReply, expected *chain.StatelessBlock) {
+		t.Helper()
+
+		if !reply.Exists || reply.Header.ID != expected.ID() || reply.Header.Hght != expected.Hght {
+			t.Fatalf("unexpected block %+v, expected %s at %d", reply.Header, expected.ID(), expected.Hght)
+		}
+		if len(reply.TxIDs) != len(expected.Txs) || (len(expected.Txs) > 0 && reply.TxIDs[0] != expected.Txs[0].ID()) {
+			t.Fatalf("unexpected txs %v", reply.TxIDs)
+		}
+		// Values are restored, so the block hashes to its ID
+		id, blk, err := chain.DecodeBlock(reply.Block)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id != expected.ID() {
+			t.Fatalf("block decoded as %s, expected %s", id, expected.ID())
+		}
+		if len(blk.Txs) > 0 && !bytes.Equal(blk.Txs[0].UnsignedTransaction.(*chain.SetTx).Value, expected.Txs[0].UnsignedTransaction.(*chain.SetTx).Value) {
+			t.Fatal("value not restored")
+		}
+	}
+	for _, blk := range blks {
+		reply := new(GetBlockReply)
+		if err := svc.GetBlock(nil, &GetBlockArgs{BlockID: blk.ID()}, reply); err != nil {
+			t.Fatal(err)
+		}
+		checkBlock(reply, blk)
+
+		reply = new(GetBlockReply)
+		if err := svc.GetBlockByHeight(nil, &GetBlockByHeightArgs{Height: blk.Hght}, reply); err != nil {
+			t.Fatal(err)
+		}
+		checkBlock(reply, blk)
+	}
+
+	unknown := new(GetBlockReply)
+	if err := svc.GetBlock(nil, &GetBlockArgs{BlockID: ids.GenerateTestID()}, unknown); err != nil || unknown.Exists {
+		t.Fatalf("unexpected block (exists=%t err=%v)", unknown.Exists, err)
+	}
+	missing := new(GetBlockReply)
+	if err := svc.GetBlockByHeight(nil, &GetBlockByHeightArgs{Height: 4}, missing); err != nil || missing.Exists {
+		t.Fatalf("unexpected block (exists=%t err=%v)", missing.Exists, err)
+	}
+
+	// Blocks bigger than the limit are left out
+	vm.config.MaxBlockResponseSize = 10
+	reply := new(GetBlockReply)
+	if err := svc.GetBlock(nil, &GetBlockArgs{BlockID: blks[1].ID()}, reply); err != nil {
+		t.Fatal(err)
+	}
+	if !reply.Exists || !reply.TooLarge || reply.Block != nil || reply.Header.ID != blks[1].ID() {
+		t.Fatalf("unexpected reply %+v", reply)
+	}
+	vm.config.MaxBlockResponseSize = 0
+
+	// Heights of blocks accepted before they were indexed are indexed once
+	for _, blk := range blks {
+		if err := db.Delete(chain.PrefixHeightKey(blk.Hght)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, expected := range []int{len(blks), 0} {
+		indexed, err := chain.IndexBlockHeights(db, blks[len(blks)-1].ID())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if indexed != expected {
+			t.Fatalf("indexed %d heights, expected %d", indexed, expected)
+		}
+	}
+	for _, blk := range blks {
+		reply := new(GetBlockReply)
+		if err := svc.GetBlockByHeight(nil, &GetBlockByHeightArgs{Height: blk.Hght}, reply); err != nil {
+			t.Fatal(err)
+		}
+		checkBlock(reply, blk)
+	}
+}
+
+func TestSuggestedFeeLegacySetInput(t *testing.T) {
+	g := chain.DefaultGenesis()
+	db := memdb.New()
+	defer db.Close()
+	if err := g.Load(db, nil); err != nil {
+		t.Fatal(err)
+	}
+	svc := &PublicService{vm: testAcceptedVM(t, g, db, time.Now())}
+
+	// Set inputs used to be documented as {type,key,value}, where [key] was
+	// ignored
+	args := new(SuggestedFeeArgs)
+	if err := json.Unmarshal([]byte(`{"input":{"type":"set","key":"0x01","value":"YmxvYg=="}}`), args); err != nil {
+		t.Fatal(err)
+	}
+	reply := new(SuggestedFeeReply)
+	if err := svc.SuggestedFee(nil, args, reply); err != nil {
+		t.Fatal(err)
+	}
+	utx, err := chain.ParseTypedData(reply.TypedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, ok := utx.(*chain.SetTx)
+	if !ok {
+		t.Fatalf("unexpected tx %T", utx)
+	}
+	if tx.Key != (common.Hash{}) || !bytes.Equal(tx.Value, []byte("blob")) {
+		t.Fatalf("unexpected tx %+v", tx)
+	}
+	// The tx is still signed in its legacy (pre-upgrade) form
+	if _, ok := reply.TypedData.Message["key"]; ok {
+		t.Fatalf("unexpected key in typed data %+v", reply.TypedData.Message)
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package vm
+
+import (
+	"fmt"
+
+	"github.com/ava-labs/avalanchego/database"
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/ava-labs/blobvm/chain"
+)
+
+// maxRangeDepth is the maximum number of [chain.TreeRoot] levels traversed by a
+// single range request (matches tree.DefaultMaxDepth).
+const maxRangeDepth = 8
+
+// rangeCollector gathers the chunks of a tree that cover [start, end).
+type rangeCollector struct {
+	db         database.Database
+	vc         *chain.ValueCache
+	now        uint64
+	start, end uint64
+
+	// [offset] is the position in the file of the next chunk visited
+	offset uint64
+	size   uint64
+
+	first  uint64
+	keys   []common.Hash
+	chunks [][]byte
+}
+
+// collect visits the chunks under [r] (in file order), keeping those that
+// overlap the range. Subtrees that record their [chain.TreeRoot.Size] and don't
+// overlap the range are skipped without being traversed.
+func (c *rangeCollector) collect(r *chain.TreeRoot) error {
+	// Chunk sizes don't match file offsets once compressed
+	if len(r.Compression) > 0 {
+		return ErrCompressedTree
+	}
+	for _, h := range r.Children {
+		if c.offset >= c.end {
+			return nil
+		}
+		if r.Height == 0 {
+			if err := c.collectChunk(h); err != nil {
+				return err
+			}
+			continue
+		}
+		_, live, err := chain.GetLiveValueMeta(c.db, h, c.now)
+		if err != nil {
+			return err
+		}
+		if !live {
+			return fmt.Errorf("%w: missing root %s", ErrInvalidTree, h)
+		}
+		v, exists, err := chain.GetValue(c.db, c.vc, h)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrCorruption
+		}
+		child, ok := chain.ParseTreeRoot(v)
+		if !ok || child.Height != r.Height-1 || len(child.Children) == 0 {
+			return fmt.Errorf("%w: invalid root %s", ErrInvalidTree, h)
+		}
+		if child.Size > 0 && c.offset+child.Size <= c.start {
+			c.offset += child.Size
+			continue
+		}
+		if err := c.collect(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *rangeCollector) collectChunk(h common.Hash) error {
+	vmeta, live, err := chain.GetLiveValueMeta(c.db, h, c.now)
+	if err != nil {
+		return err
+	}
+	if !live {
+		return fmt.Errorf("%w: missing chunk %s", ErrInvalidTree, h)
+	}
+	start := c.offset
+	c.offset += vmeta.Size
+	if c.offset <= c.start {
+		return nil
+	}
+	if c.size += vmeta.Size; c.size > MaxResolveRangeSize {
+		return fmt.Errorf("%w: chunks covering range exceed %d bytes", ErrRangeTooBig, MaxResolveRangeSize)
+	}
+	v, exists, err := chain.GetValue(c.db, c.vc, h)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrCorruption
+	}
+	if len(c.chunks) == 0 {
+		c.first = start
+	}
+	c.keys = append(c.keys, h)
+	c.chunks = append(c.chunks, v)
+	return nil
+}
@@ -0,0 +1,225 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package vm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/units"
+	"github.com/ethereum/go-ethereum/common"
+	log "github.com/inconshreveable/log15"
+
+	"github.com/ava-labs/blobvm/chain"
+)
+
+const (
+	// maxRepairKeys is the maximum number of keys requested from a peer at once
+	maxRepairKeys = 256
+
+	// Values are added to a [valueResponse] until the next one would make it
+	// bigger than [maxValueResponseSize] (the first value is always added)
+	maxValueResponseSize = 1 * units.MiB
+
+	repairRequestTimeout = 10 * time.Second
+)
+
+// valueRequest is an AppRequest for the values stored at [Keys].
+type valueRequest struct {
+	Keys []common.Hash `serialize:"true"`
+}
+
+// valueResponse holds the value stored at each requested key, in order (empty
+// if the peer doesn't store it). It holds fewer values than keys requested if
+// they didn't all fit in a single response.
+type valueResponse struct {
+	Values [][]byte `serialize:"true"`
+}
+
+// valueRequests tracks the [valueRequest]s awaiting a response.
+type valueRequests struct {
+	l       sync.Mutex
+	next    uint32
+	pending map[uint32]chan []byte
+}
+
+func (r *valueRequests) add() (uint32, <-chan []byte) {
+	r.l.Lock()
+	defer r.l.Unlock()
+	if r.pending == nil {
+		r.pending = map[uint32]chan []byte{}
+	}
+	requestID := r.next
+	r.next++
+	c := make(chan []byte, 1)
+	r.pending[requestID] = c
+	return requestID, c
+}
+
+// deliver passes [response] (nil if the request failed) to the sender of
+// [requestID], if it is still waiting.
+func (r *valueRequests) deliver(requestID uint32, response []byte) {
+	r.l.Lock()
+	defer r.l.Unlock()
+	c, ok := r.pending[requestID]
+	if !ok {
+		return
+	}
+	delete(r.pending, requestID)
+	c <- response
+}
+
+func (r *valueRequests) remove(requestID uint32) {
+	r.l.Lock()
+	defer r.l.Unlock()
+	delete(r.pending, requestID)
+}
+
+// serveValues responds to a [valueRequest] from [nodeID] with the values
+// stored on this node. Invalid requests are dropped.
+func (vm *VM) serveValues(ctx context.Context, nodeID ids.NodeID, requestID uint32, request []byte) error {
+	req := new(valueRequest)
+	if _, err := chain.Unmarshal(request, req); err != nil || len(req.Keys) > maxRepairKeys {
+		log.Debug("dropping invalid value request", "nodeID", nodeID, "keys", len(req.Keys), "err", err)
+		return nil
+	}
+	resp := &valueResponse{Values: make([][]byte, 0, len(req.Keys))}
+	size := 0
+	for _, k := range req.Keys {
+		v, exists, err := chain.GetValue(vm.db, vm.valueCache, k)
+		if err != nil || !exists {
+			// Values whose bytes are missing can't be served
+			v = nil
+		}
+		if len(resp.Values) > 0 && size+len(v) > maxValueResponseSize {
+			break
+		}
+		size += len(v)
+		resp.Values = append(resp.Values, v)
+	}
+	b, err := chain.Marshal(resp)
+	if err != nil {
+		log.Warn("failed to marshal value response", "err", err)
+		return nil
+	}
+	return vm.appSender.SendAppResponse(ctx, nodeID, requestID, b)
+}
+
+// requestValues requests the values stored at [keys] from [peer].
+func (vm *VM) requestValues(ctx context.Context, peer ids.NodeID, keys []common.Hash) ([][]byte, error) {
+	b, err := chain.Marshal(&valueRequest{Keys: keys})
+	if err != nil {
+		return nil, err
+	}
+	requestID, c := vm.valueRequests.add()
+	defer vm.valueRequests.remove(requestID)
+	peers := ids.NewNodeIDSet(1)
+	peers.Add(peer)
+	if err := vm.appSender.SendAppRequest(ctx, peers, requestID, b); err != nil {
+		return nil, err
+	}
+
+	t := time.NewTimer(repairRequestTimeout)
+	defer t.Stop()
+	select {
+	case b := <-c:
+		if b == nil {
+			return nil, ErrRequestFailed
+		}
+		resp := new(valueResponse)
+		if _, err := chain.Unmarshal(b, resp); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+		}
+		if len(resp.Values) > len(keys) {
+			return nil, fmt.Errorf("%w: %d values for %d keys", ErrInvalidResponse, len(resp.Values), len(keys))
+		}
+		return resp.Values, nil
+	case <-t.C:
+		return nil, ErrRequestFailed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// repair finds the values missing from disk (see [chain.CheckIntegrity]) and
+// restores those that connected peers still store. It returns the keys that
+// were restored and those that couldn't be.
+//
+// It waits for peers without holding [snowCtx.Lock] (their responses are
+// delivered under it).
+func (vm *VM) repair(ctx context.Context) (repaired []common.Hash, unrepairable []common.Hash, err error) {
+	vm.snowCtx.Lock.RLock()
+	_, inconsistent, err := chain.CheckIntegrity(vm.db)
+	vm.snowCtx.Lock.RUnlock()
+	if err != nil {
+		return nil, nil, err
+	}
+	missing := make([]common.Hash, 0, len(inconsistent))
+	for _, c := range inconsistent {
+		missing = append(missing, c.Key)
+	}
+
+	for _, peer := range vm.peerList() {
+		if len(missing) == 0 {
+			break
+		}
+		var restored []common.Hash
+		restored, missing, err = vm.repairFrom(ctx, peer, missing)
+		repaired = append(repaired, restored...)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	vm.setInconsistentCount(len(missing))
+	log.Info("repaired values", "repaired", len(repaired), "unrepairable", len(missing))
+	return repaired, missing, nil
+}
+
+// repairFrom restores the values at [keys] stored by [peer], returning those
+// that were restored and those that weren't. It only returns an error if [ctx]
+// is done.
+func (vm *VM) repairFrom(
+	ctx context.Context, peer ids.NodeID, keys []common.Hash,
+) (restored []common.Hash, missing []common.Hash, err error) {
+	for start := 0; start < len(keys); {
+		end := start + maxRepairKeys
+		if end > len(keys) {
+			end = len(keys)
+		}
+		values, err := vm.requestValues(ctx, peer, keys[start:end])
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, nil, err
+			}
+			log.Warn("value request failed", "peer", peer, "err", err)
+			return restored, append(missing, keys[start:]...), nil
+		}
+		if len(values) == 0 {
+			missing = append(missing, keys[start:end]...)
+			start = end
+			continue
+		}
+		vm.snowCtx.Lock.Lock()
+		for i, v := range values {
+			k := keys[start+i]
+			if len(v) == 0 {
+				missing = append(missing, k)
+				continue
+			}
+			if err := chain.RestoreValue(vm.db, vm.valueCache, vm.genesis, k, v); err != nil {
+				log.Warn("failed to restore value", "key", k, "peer", peer, "err", err)
+				missing = append(missing, k)
+				continue
+			}
+			restored = append(restored, k)
+		}
+		vm.snowCtx.Lock.Unlock()
+		start += len(values)
+	}
+	return restored, missing, nil
+}
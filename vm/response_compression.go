@@ -0,0 +1,95 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package vm
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+
+	log "github.com/inconshreveable/log15"
+)
+
+// compressResponses gzips the responses of [h] that are at least [threshold]
+// bytes for requests that accept gzip (0 disables). Responses are buffered
+// until [h] returns, so it must not wrap streaming handlers.
+func compressResponses(h http.Handler, threshold int) http.Handler {
+	if threshold <= 0 {
+		return h
+	}
+	return &gzipHandler{h: h, threshold: threshold}
+}
+
+type gzipHandler struct {
+	h         http.Handler
+	threshold int
+}
+
+func (g *gzipHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Vary", "Accept-Encoding")
+	if !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+		g.h.ServeHTTP(w, r)
+		return
+	}
+
+	rw := &bufferedResponseWriter{ResponseWriter: w, status: http.StatusOK}
+	g.h.ServeHTTP(rw, r)
+	b := rw.buf.Bytes()
+	if len(b) >= g.threshold && w.Header().Get("Content-Encoding") == "" {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, err := zw.Write(b)
+		if err == nil {
+			err = zw.Close()
+		}
+		if err == nil {
+			b = buf.Bytes()
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Header().Del("Content-Length")
+		} else {
+			log.Warn("could not compress response", "err", err)
+		}
+	}
+	w.WriteHeader(rw.status)
+	if _, err := w.Write(b); err != nil {
+		log.Debug("could not write response", "err", err)
+	}
+}
+
+// acceptsGzip returns true if the [Accept-Encoding] header of a request
+// allows gzip (with a non-zero quality).
+func acceptsGzip(acceptEncoding string) bool {
+	for _, enc := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(enc, ";")
+		if strings.TrimSpace(coding) != "gzip" {
+			continue
+		}
+		params = strings.TrimSpace(params)
+		if !strings.HasPrefix(params, "q=") {
+			return true
+		}
+		q, err := strconv.ParseFloat(strings.TrimPrefix(params, "q="), 64)
+		return err == nil && q > 0
+	}
+	return false
+}
+
+// bufferedResponseWriter holds the response written by a handler until it is
+// compressed.
+type bufferedResponseWriter struct {
+	http.ResponseWriter
+
+	status int
+	buf    bytes.Buffer
+}
+
+func (w *bufferedResponseWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+func (w *bufferedResponseWriter) Write(b []byte) (int, error) {
+	return w.buf.Write(b)
+}
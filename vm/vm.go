@@ -9,6 +9,7 @@ import (
 	ejson "encoding/json"
 	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/ava-labs/avalanchego/cache"
@@ -22,9 +23,13 @@ import (
 	"github.com/ava-labs/avalanchego/snow/engine/common"
 	snowmanblock "github.com/ava-labs/avalanchego/snow/engine/snowman/block"
 	"github.com/ava-labs/avalanchego/utils"
+	"github.com/ava-labs/avalanchego/utils/compression"
+	"github.com/ava-labs/avalanchego/utils/constants"
 	"github.com/ava-labs/avalanchego/utils/json"
+	"github.com/ava-labs/avalanchego/utils/timer/mockable"
 	"github.com/gorilla/rpc/v2"
 	log "github.com/inconshreveable/log15"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	avagoversion "github.com/ava-labs/avalanchego/version"
 	"github.com/ava-labs/blobvm/chain"
@@ -33,8 +38,17 @@ import (
 )
 
 const (
-	Name           = "blobvm"
-	PublicEndpoint = "/public"
+	Name            = "blobvm"
+	PublicEndpoint  = "/public"
+	AdminEndpoint   = "/admin"
+	MetricsEndpoint = "/metrics"
+
+	// AdminTokenHeader carries the [Config.AdminToken] of admin requests
+	AdminTokenHeader = "X-Admin-Token"
+
+	// ActivityStreamEndpoint serves the activity of accepted blocks as
+	// server-sent events (see [activityStream])
+	ActivityStreamEndpoint = "/activity"
 )
 
 var (
@@ -55,6 +69,9 @@ type VM struct {
 	appSender common.AppSender
 	network   *PushNetwork
 
+	// decompresses (and, if enabled, compresses) gossip messages
+	gossipCompressor compression.Compressor
+
 	// cache block objects to optimize "GetBlockStateless"
 	// only put when a block is accepted
 	// key: block ID, value: *chain.StatelessBlock
@@ -71,18 +88,43 @@ type VM struct {
 	preferred    ids.ID
 	lastAccepted *chain.StatelessBlock
 
+	// Source of the current time (see [Now]); tests can set it to advance
+	// time deterministically
+	clock mockable.Clock
+
 	// Recent activity
 	activityCacheCursor uint64
 	activityCache       []*chain.Activity
+	activityFeed        activityFeed
+
+	// Recently read values (nil caches nothing)
+	valueCache *chain.ValueCache
 
 	// Execution checks
 	targetRangeUnits uint64
 
+	// Senders whose txs are rejected by this node (see [AdminService])
+	banned *bannedSet
+
+	// Number of inconsistent values found on startup (see [checkIntegrity]),
+	// less those since restored by [repair]. It is read by [HealthCheck],
+	// which isn't called under [snowCtx.Lock].
+	inconsistentL      sync.Mutex
+	inconsistentValues int
+
+	// Peers connected to this node (see [Connected]) and the value requests
+	// sent to them by [repair]
+	peersL        sync.Mutex
+	peers         ids.NodeIDSet
+	valueRequests valueRequests
+
 	stop chan struct{}
 
 	builderStop chan struct{}
 	doneBuild   chan struct{}
 	doneGossip  chan struct{}
+
+	metrics *metrics
 }
 
 const (
@@ -114,6 +156,7 @@ func (vm *VM) Initialize(
 	vm.snowCtx = snowCtx
 	vm.db = dbManager.Current().Database
 	vm.activityCache = make([]*chain.Activity, vm.config.ActivityCacheSize)
+	vm.valueCache = chain.NewValueCache(vm.config.ValueCacheSize)
 
 	// Init channels before initializing other structs
 	vm.stop = make(chan struct{})
@@ -122,6 +165,11 @@ func (vm *VM) Initialize(
 	vm.doneGossip = make(chan struct{})
 	vm.appSender = appSender
 	vm.network = vm.NewPushNetwork()
+	gossipCompressor, err := compression.NewGzipCompressor(constants.DefaultMaxMessageSize)
+	if err != nil {
+		return err
+	}
+	vm.gossipCompressor = gossipCompressor
 
 	vm.blocks = &cache.LRU{Size: blocksLRUSize}
 	vm.verifiedBlocks = make(map[ids.ID]*chain.StatelessBlock)
@@ -129,6 +177,11 @@ func (vm *VM) Initialize(
 	vm.toEngine = toEngine
 	vm.builder = vm.NewTimeBuilder()
 
+	vm.banned, err = loadBanned(vm.db)
+	if err != nil {
+		return err
+	}
+
 	// Try to load last accepted
 	has, err := chain.HasLastAccepted(vm.db)
 	if err != nil {
@@ -146,11 +199,20 @@ func (vm *VM) Initialize(
 		log.Error("genesis is invalid")
 		return err
 	}
+	vm.genesis.ScheduleUpgrade(snowCtx.ChainID)
+	if vm.genesis.UpgradeTime != nil {
+		log.Info("network upgrade scheduled", "time", *vm.genesis.UpgradeTime)
+	}
 	targetUnitsPerSecond := vm.genesis.TargetBlockSize / uint64(vm.genesis.TargetBlockRate)
 	vm.targetRangeUnits = targetUnitsPerSecond * uint64(vm.genesis.LookbackWindow)
 	log.Debug("loaded genesis", "genesis", string(genesisBytes), "target range units", vm.targetRangeUnits)
 
 	vm.mempool = mempool.New(vm.genesis, vm.config.MempoolSize)
+	vm.metrics, err = newMetrics(vm.config.MetricsNamespace, vm.mempool, vm.valueCache)
+	if err != nil {
+		log.Error("could not create metrics", "namespace", vm.config.MetricsNamespace, "err", err)
+		return err
+	}
 
 	if has { //nolint:nestif
 		blkID, err := chain.GetLastAccepted(vm.db)
@@ -164,6 +226,16 @@ func (vm *VM) Initialize(
 			log.Error("could not load last accepted", "err", err)
 			return err
 		}
+		// Blocks accepted before heights were indexed can't be found by height
+		// until they are indexed (once)
+		indexed, err := chain.IndexBlockHeights(vm.db, blkID)
+		if err != nil {
+			log.Error("could not index block heights", "err", err)
+			return err
+		}
+		if indexed > 0 {
+			log.Info("indexed block heights", "blocks", indexed)
+		}
 
 		vm.preferred, vm.lastAccepted = blkID, blk
 		log.Info("initialized blobvm from last accepted", "block", blkID)
@@ -180,12 +252,12 @@ func (vm *VM) Initialize(
 		}
 
 		// Set Balances
-		if err := vm.genesis.Load(vm.db, vm.AirdropData); err != nil {
+		if err := vm.genesis.LoadWithWorkers(vm.db, vm.AirdropData, vm.config.GenesisLoadWorkers); err != nil {
 			log.Error("could not set genesis allocation", "err", err)
 			return err
 		}
 
-		if err := chain.SetLastAccepted(vm.db, genesisBlk); err != nil {
+		if err := chain.SetLastAccepted(vm.db, genesisBlk, vm.config.CompressBlocks); err != nil {
 			log.Error("could not set genesis as last accepted", "err", err)
 			return err
 		}
@@ -195,6 +267,24 @@ func (vm *VM) Initialize(
 	}
 	vm.AirdropData = nil
 
+	// Run once the genesis is loaded, as genesis values are stored in the
+	// codec encoding
+	migrated, err := chain.MigrateValueMetas(vm.db, vm.config.CompactValueMeta)
+	if err != nil {
+		log.Error("could not migrate value metas", "err", err)
+		return err
+	}
+	if migrated > 0 {
+		log.Info("migrated value metas", "count", migrated)
+	}
+	vm.metrics.price.Set(float64(vm.lastAccepted.Price))
+	vm.metrics.cost.Set(float64(vm.lastAccepted.Cost))
+
+	if err := vm.checkIntegrity(); err != nil {
+		log.Error("database integrity check failed", "err", err)
+		return err
+	}
+
 	go vm.builder.Build()
 	go vm.builder.Gossip()
 	return nil
@@ -270,7 +360,24 @@ func (vm *VM) CreateHandlers(ctx context.Context) (map[string]*common.HTTPHandle
 	if err != nil {
 		return nil, err
 	}
+	public.Handler = compressResponses(public.Handler, vm.config.ResponseCompressionThreshold)
 	apis[PublicEndpoint] = public
+	// Admin requests take [snowCtx.Lock] themselves, so [AdminService.Repair]
+	// can wait for peers (whose responses are delivered under the lock)
+	admin, err := newHandler(Name, &AdminService{vm: vm}, common.NoLock)
+	if err != nil {
+		return nil, err
+	}
+	admin.Handler = compressResponses(admin.Handler, vm.config.ResponseCompressionThreshold)
+	apis[AdminEndpoint] = admin
+	apis[MetricsEndpoint] = &common.HTTPHandler{
+		LockOptions: common.NoLock,
+		Handler:     promhttp.HandlerFor(vm.metrics.registry, promhttp.HandlerOpts{}),
+	}
+	apis[ActivityStreamEndpoint] = &common.HTTPHandler{
+		LockOptions: common.NoLock,
+		Handler:     &activityStream{vm: vm},
+	}
 	return apis, nil
 }
 
@@ -282,19 +389,26 @@ func (vm *VM) CreateStaticHandlers(ctx context.Context) (map[string]*common.HTTP
 
 // implements "snowmanblock.ChainVM.commom.VM.AppHandler"
 func (vm *VM) AppRequest(ctx context.Context, nodeID ids.NodeID, requestID uint32, deadline time.Time, request []byte) error {
-	// (currently) no app-specific messages
-	return nil
+	// (currently) only requests for values (see [repair])
+	if vm.appSender == nil {
+		return nil
+	}
+	return vm.serveValues(ctx, nodeID, requestID, request)
 }
 
 // implements "snowmanblock.ChainVM.commom.VM.AppHandler"
 func (vm *VM) AppRequestFailed(ctx context.Context, nodeID ids.NodeID, requestID uint32) error {
-	// (currently) no app-specific messages
+	vm.valueRequests.deliver(requestID, nil)
 	return nil
 }
 
 // implements "snowmanblock.ChainVM.commom.VM.AppHandler"
 func (vm *VM) AppResponse(ctx context.Context, nodeID ids.NodeID, requestID uint32, response []byte) error {
-	// (currently) no app-specific messages
+	if response == nil {
+		// Reserved for failed requests
+		response = []byte{}
+	}
+	vm.valueRequests.deliver(requestID, response)
 	return nil
 }
 
@@ -318,21 +432,43 @@ func (vm *VM) CrossChainAppResponse(ctx context.Context, chainID ids.ID, request
 
 // implements "snowmanblock.ChainVM.commom.VM.health.Checkable"
 func (vm *VM) HealthCheck(ctx context.Context) (interface{}, error) {
+	if n := vm.inconsistentCount(); n > 0 {
+		return http.StatusInternalServerError, fmt.Errorf(
+			"%w: %d inconsistent values not repaired", ErrCorruption, n,
+		)
+	}
 	return http.StatusOK, nil
 }
 
 // implements "snowmanblock.ChainVM.commom.VM.validators.Connector"
 func (vm *VM) Connected(ctx context.Context, id ids.NodeID, nodeVersion *avagoversion.Application) error {
-	// no-op
+	if vm.snowCtx != nil && id == vm.snowCtx.NodeID {
+		return nil
+	}
+	vm.peersL.Lock()
+	defer vm.peersL.Unlock()
+	if vm.peers == nil {
+		vm.peers = ids.NewNodeIDSet(1)
+	}
+	vm.peers.Add(id)
 	return nil
 }
 
 // implements "snowmanblock.ChainVM.commom.VM.validators.Connector"
 func (vm *VM) Disconnected(ctx context.Context, id ids.NodeID) error {
-	// no-op
+	vm.peersL.Lock()
+	defer vm.peersL.Unlock()
+	vm.peers.Remove(id)
 	return nil
 }
 
+// peerList returns the peers connected to this node.
+func (vm *VM) peerList() []ids.NodeID {
+	vm.peersL.Lock()
+	defer vm.peersL.Unlock()
+	return vm.peers.List()
+}
+
 // implements "snowmanblock.ChainVM.commom.VM.Getter"
 // replaces "core.SnowmanVM.GetBlock"
 func (vm *VM) GetBlock(ctx context.Context, id ids.ID) (snowman.Block, error) {
@@ -396,7 +532,9 @@ func (vm *VM) ParseBlock(ctx context.Context, source []byte) (snowman.Block, err
 // called via "avalanchego" node over RPC
 func (vm *VM) BuildBlock(ctx context.Context) (snowman.Block, error) {
 	log.Debug("BuildBlock triggered")
+	start := time.Now()
 	blk, err := chain.BuildBlock(vm, vm.preferred)
+	vm.metrics.built(time.Since(start))
 	vm.builder.HandleGenerateBlock()
 	if err != nil {
 		log.Debug("BuildBlock failed", "error", err)
@@ -412,12 +550,26 @@ func (vm *VM) BuildBlock(ctx context.Context) (snowman.Block, error) {
 }
 
 func (vm *VM) Submit(txs ...*chain.Transaction) (errs []error) {
-	blk, err := vm.GetStatelessBlock(vm.preferred)
-	if err != nil {
-		return []error{err}
+	verified := make([]*chain.Transaction, 0, len(txs))
+	for _, tx := range txs {
+		if err := tx.Init(vm.genesis); err != nil {
+			log.Debug("failed to init transaction",
+				"error", err,
+			)
+			errs = append(errs, err)
+			continue
+		}
+		verified = append(verified, tx)
 	}
-	now := time.Now().Unix()
-	ctx, err := vm.ExecutionContext(now, blk)
+	return append(errs, vm.submitVerified(verified...)...)
+}
+
+// submitVerified assumes each tx in [txs] has already been initialized.
+func (vm *VM) submitVerified(txs ...*chain.Transaction) (errs []error) {
+	if len(txs) == 0 {
+		return nil
+	}
+	now, ctx, err := vm.submitContext()
 	if err != nil {
 		return []error{err}
 	}
@@ -437,19 +589,104 @@ func (vm *VM) Submit(txs ...*chain.Transaction) (errs []error) {
 	return errs
 }
 
+// submitWithBalance submits [tx] (which has already been initialized) and
+// returns the fee it was charged and the balance of its sender after executing
+// it on top of the accepted state.
+func (vm *VM) submitWithBalance(tx *chain.Transaction) (uint64, uint64, error) {
+	now, ctx, err := vm.submitContext()
+	if err != nil {
+		return 0, 0, err
+	}
+	vdb := versiondb.New(vm.db)
+	defer vdb.Abort()
+
+	before, err := chain.GetBalance(vdb, tx.Sender())
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := vm.submit(tx, vdb, now, ctx); err != nil {
+		log.Debug("failed to submit transaction",
+			"tx", tx.ID(),
+			"error", err,
+		)
+		return 0, 0, err
+	}
+	return chargedFee(vdb, tx, before)
+}
+
+// simulate executes [tx] (which has already been initialized) as
+// [submitWithBalance] would, without adding it to the mempool, and returns the
+// fee it would be charged and the balance of its sender after executing it.
+func (vm *VM) simulate(tx *chain.Transaction) (uint64, uint64, error) {
+	now, ctx, err := vm.submitContext()
+	if err != nil {
+		return 0, 0, err
+	}
+	vdb := versiondb.New(vm.db)
+	defer vdb.Abort()
+
+	before, err := chain.GetBalance(vdb, tx.Sender())
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := vm.execute(tx, vdb, now, ctx); err != nil {
+		return 0, 0, err
+	}
+	return chargedFee(vdb, tx, before)
+}
+
+// chargedFee returns the fee charged to the sender of [tx] (executed against
+// [db]) given its balance [before] and the sender's balance after. Fees that
+// depend on state (ex: the storage fees of a [chain.RenewTx] or
+// [chain.PinTx]) are only known once executed, so the fee is the change in
+// balance, less any units transferred to another account.
+func chargedFee(db database.KeyValueReader, tx *chain.Transaction, before uint64) (uint64, uint64, error) {
+	after, err := chain.GetBalance(db, tx.Sender())
+	if err != nil {
+		return 0, 0, err
+	}
+	fee := before - after
+	if t, ok := tx.UnsignedTransaction.(*chain.TransferTx); ok && t.To != tx.Sender() {
+		fee -= t.Units
+	}
+	return fee, after, nil
+}
+
+// submitContext returns the time and execution context txs are submitted
+// with (on top of the preferred block).
+func (vm *VM) submitContext() (int64, *chain.Context, error) {
+	blk, err := vm.GetStatelessBlock(vm.preferred)
+	if err != nil {
+		return 0, nil, err
+	}
+	now := vm.Now().Unix()
+	ctx, err := vm.ExecutionContext(now, blk)
+	if err != nil {
+		return 0, nil, err
+	}
+	return now, ctx, nil
+}
+
 func (vm *VM) submit(tx *chain.Transaction, db database.Database, blkTime int64, ctx *chain.Context) error {
-	if err := tx.Init(vm.genesis); err != nil {
+	if err := vm.execute(tx, db, blkTime, ctx); err != nil {
+		vm.metrics.rejected()
 		return err
 	}
+	vm.mempool.Add(tx)
+	return nil
+}
+
+// execute checks that [tx] would be admitted to the mempool, executing it
+// against [db].
+func (vm *VM) execute(tx *chain.Transaction, db database.Database, blkTime int64, ctx *chain.Context) error {
 	if err := tx.ExecuteBase(vm.genesis); err != nil {
 		return err
 	}
-	dummy := chain.DummyBlock(blkTime, tx)
-	if err := tx.Execute(vm.genesis, db, dummy, ctx); err != nil {
-		return err
+	if vm.banned.has(tx.Sender()) {
+		return ErrSenderBanned
 	}
-	vm.mempool.Add(tx)
-	return nil
+	dummy := chain.DummyBlock(blkTime, tx)
+	return tx.Execute(vm.genesis, db, dummy, ctx)
 }
 
 // "SetPreference" implements "snowmanblock.ChainVM"
@@ -4,12 +4,32 @@
 package vm
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/ava-labs/avalanchego/cache"
+	"github.com/ava-labs/avalanchego/database"
+	"github.com/ava-labs/avalanchego/database/memdb"
 	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/snow/choices"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
 	"github.com/ava-labs/blobvm/chain"
+	"github.com/ava-labs/blobvm/mempool"
 )
 
 func TestBlockCache(t *testing.T) {
@@ -44,3 +64,599 @@ func TestBlockCache(t *testing.T) {
 		t.Fatalf("block expected %+v, got %+v", blk, blk2)
 	}
 }
+
+func TestSubmitAllowedSenders(t *testing.T) {
+	allowed, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	allowedAddr := crypto.PubkeyToAddress(allowed.PublicKey)
+	otherAddr := crypto.PubkeyToAddress(other.PublicKey)
+
+	g := chain.DefaultGenesis()
+	g.AllowedSenders = []common.Address{allowedAddr}
+	g.CustomAllocation = []*chain.CustomAllocation{
+		{Address: allowedAddr, Balance: 10000000},
+		{Address: otherAddr, Balance: 10000000},
+	}
+	db := memdb.New()
+	defer db.Close()
+	if err := g.Load(db, nil); err != nil {
+		t.Fatal(err)
+	}
+	vm := &VM{genesis: g, mempool: mempool.New(g, 16)}
+	ctx := &chain.Context{RecentBlockIDs: ids.Set{testBlockID: struct{}{}}, NextPrice: 1}
+
+	tx := testSignedSetTx(t, g, other, []byte("not allowed"), 1)
+	if err := vm.submit(tx, db, 1, ctx); !errors.Is(err, chain.ErrSenderNotAllowed) {
+		t.Fatalf("unexpected error %v, expected %v", err, chain.ErrSenderNotAllowed)
+	}
+	if vm.mempool.Has(tx.ID()) {
+		t.Fatal("tx from non-allowed sender added to mempool")
+	}
+
+	tx = testSignedSetTx(t, g, allowed, []byte("allowed"), 1)
+	if err := vm.submit(tx, db, 1, ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !vm.mempool.Has(tx.ID()) {
+		t.Fatal("tx from allowed sender not added to mempool")
+	}
+}
+
+// testAcceptedVM returns a VM over [db] whose clock is set to [now] and whose
+// preferred (and last accepted) block was built at [now].
+func testAcceptedVM(t *testing.T, g *chain.Genesis, db database.Database, now time.Time) *VM {
+	t.Helper()
+
+	vm := &VM{
+		db:             db,
+		genesis:        g,
+		mempool:        mempool.New(g, 16),
+		blocks:         &cache.LRU{Size: 3},
+		verifiedBlocks: make(map[ids.ID]*chain.StatelessBlock),
+	}
+	vm.clock.Set(now)
+	blk, err := chain.ParseStatefulBlock(&chain.StatefulBlock{
+		Tmstmp: now.Unix(),
+		Price:  g.MinPrice,
+		Cost:   chain.MinBlockCost,
+	}, nil, choices.Accepted, vm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vm.Accepted(blk)
+	vm.preferred = blk.ID()
+	return vm
+}
+
+// testAcceptTx builds (at the time of [vm.clock]) and accepts a block on top of
+// the preferred block holding [utx] signed by [priv].
+func testAcceptTx(t *testing.T, vm *VM, priv *ecdsa.PrivateKey, utx chain.UnsignedTransaction) *chain.Transaction {
+	t.Helper()
+
+	tx := testSignTx(t, vm.genesis, priv, utx)
+	vm.mempool.Add(tx)
+	blk, err := chain.BuildBlock(vm, vm.preferred)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := blk.Verify(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := blk.Accept(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	vm.preferred = blk.ID()
+	return tx
+}
+
+func TestSubmitWithBalance(t *testing.T) {
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := crypto.PubkeyToAddress(priv.PublicKey)
+
+	g := chain.DefaultGenesis()
+	g.CustomAllocation = []*chain.CustomAllocation{{Address: sender, Balance: 10000000}}
+	db := memdb.New()
+	defer db.Close()
+	if err := g.Load(db, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	vm := testAcceptedVM(t, g, db, time.Now())
+	testAcceptTx(t, vm, priv, &chain.SetTx{
+		BaseTx: &chain.BaseTx{BlockID: vm.preferred, Price: 2},
+		Value:  []byte("renewable"),
+		TTL:    100,
+	})
+	persistedBalance, err := chain.GetBalance(db, sender)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx := testSignTx(t, g, priv, &chain.SetTx{
+		BaseTx: &chain.BaseTx{BlockID: vm.preferred, Price: 2},
+		Value:  []byte("fee"),
+	})
+	charged, balance, err := vm.submitWithBalance(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fee := tx.FeeUnits(g) * tx.GetPrice()
+	if fee == 0 {
+		t.Fatal("expected a non-zero fee")
+	}
+	if charged != fee {
+		t.Fatalf("unexpected fee %d, expected %d", charged, fee)
+	}
+	if balance != persistedBalance-fee {
+		t.Fatalf("unexpected balance %d, expected %d", balance, persistedBalance-fee)
+	}
+	if !vm.mempool.Has(tx.ID()) {
+		t.Fatal("tx not added to mempool")
+	}
+
+	// Storage fees charged on execution are included in the fee
+	renew := &chain.RenewTx{BaseTx: &chain.BaseTx{BlockID: vm.preferred, Price: 2}, Key: g.ValueHash([]byte("renewable")), Extension: 100}
+	charged, balance, err = vm.simulate(testSignTx(t, g, priv, renew))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fee = (renew.FeeUnits(g) + renew.StorageUnits(g, uint64(len("renewable")))) * renew.Price
+	if charged != fee || balance != persistedBalance-fee {
+		t.Fatalf("unexpected fee %d (balance %d), expected %d (balance %d)", charged, balance, fee, persistedBalance-fee)
+	}
+
+	// the simulated execution must not be persisted
+	persisted, err := chain.GetBalance(db, sender)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if persisted != persistedBalance {
+		t.Fatalf("unexpected persisted balance %d", persisted)
+	}
+}
+
+func TestClockExpiry(t *testing.T) {
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := crypto.PubkeyToAddress(priv.PublicKey)
+
+	g := chain.DefaultGenesis()
+	g.CustomAllocation = []*chain.CustomAllocation{{Address: sender, Balance: 10000000}}
+	db := memdb.New()
+	defer db.Close()
+	if err := g.Load(db, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Unix(1000, 0)
+	vm := testAcceptedVM(t, g, db, start)
+
+	resolve := func(k common.Hash) bool {
+		reply := new(ResolveReply)
+		if err := (&PublicService{vm: vm}).Resolve(nil, &ResolveArgs{Key: k}, reply); err != nil {
+			t.Fatal(err)
+		}
+		return reply.Exists
+	}
+
+	v := []byte("expiring")
+	testAcceptTx(t, vm, priv, &chain.SetTx{
+		BaseTx: &chain.BaseTx{BlockID: vm.preferred, Price: 1000},
+		Value:  v,
+		TTL:    10,
+	})
+	if vm.lastAccepted.Tmstmp != start.Unix() {
+		t.Fatalf("block built at %d, expected %d", vm.lastAccepted.Tmstmp, start.Unix())
+	}
+	if !resolve(chain.ValueHash(v)) {
+		t.Fatal("value should exist before its TTL elapses")
+	}
+
+	// Advancing the clock past the TTL expires the value once a later block
+	// is accepted
+	vm.clock.Set(start.Add(10 * time.Second))
+	testAcceptTx(t, vm, priv, &chain.SetTx{
+		BaseTx: &chain.BaseTx{BlockID: vm.preferred, Price: 1000},
+		Value:  []byte("later"),
+	})
+	if resolve(chain.ValueHash(v)) {
+		t.Fatal("value should expire once its TTL elapses")
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := crypto.PubkeyToAddress(priv.PublicKey)
+
+	g := chain.DefaultGenesis()
+	g.CustomAllocation = []*chain.CustomAllocation{{Address: sender, Balance: 10000000}}
+	db := memdb.New()
+	defer db.Close()
+	if err := g.Load(db, nil); err != nil {
+		t.Fatal(err)
+	}
+	vm := testAcceptedVM(t, g, db, time.Now())
+	vm.valueCache = chain.NewValueCache(1)
+	vm.metrics, err = newMetrics("test", vm.mempool, vm.valueCache)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testAcceptTx(t, vm, priv, &chain.SetTx{
+		BaseTx: &chain.BaseTx{BlockID: vm.preferred, Price: 2},
+		Value:  []byte("taken"),
+	})
+	if _, _, err := vm.submitWithBalance(testSignTx(t, g, priv, &chain.SetTx{
+		BaseTx: &chain.BaseTx{BlockID: vm.preferred, Price: 2},
+		Value:  []byte("taken"),
+	})); !errors.Is(err, chain.ErrKeyExists) {
+		t.Fatalf("unexpected error %v, expected %v", err, chain.ErrKeyExists)
+	}
+	if _, _, err := vm.submitWithBalance(testSignTx(t, g, priv, &chain.SetTx{
+		BaseTx: &chain.BaseTx{BlockID: vm.preferred, Price: 2},
+		Value:  []byte("pending"),
+	})); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, _, err := chain.GetValue(vm.db, vm.valueCache, g.ValueHash([]byte("taken"))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	apis, err := vm.CreateHandlers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	apis[MetricsEndpoint].Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, MetricsEndpoint, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d", rec.Code)
+	}
+	body := rec.Body.String()
+	for _, m := range []string{
+		"test_txs_accepted 1",
+		"test_txs_rejected 1",
+		"test_value_bytes_stored 5",
+		"test_mempool_size 1",
+		fmt.Sprintf("test_price %d", vm.lastAccepted.Price),
+		fmt.Sprintf("test_block_cost %d", vm.lastAccepted.Cost),
+		"test_build_block_duration_seconds_count 0",
+		"test_value_cache_hits 1",
+		"test_value_cache_misses 1",
+		"test_value_cache_evictions 0",
+	} {
+		if !strings.Contains(body, m+"\n") {
+			t.Fatalf("missing %q in:\n%s", m, body)
+		}
+	}
+
+	if _, err := newMetrics("invalid-namespace", vm.mempool, nil); err == nil {
+		t.Fatal("expected invalid namespace to fail")
+	}
+}
+
+func TestHasTxHeight(t *testing.T) {
+	t.Parallel()
+
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := crypto.PubkeyToAddress(priv.PublicKey)
+
+	g := chain.DefaultGenesis()
+	g.CustomAllocation = []*chain.CustomAllocation{{Address: sender, Balance: 10000000}}
+	db := memdb.New()
+	defer db.Close()
+	if err := g.Load(db, nil); err != nil {
+		t.Fatal(err)
+	}
+	vm := testAcceptedVM(t, g, db, time.Now())
+	svc := &PublicService{vm: vm}
+
+	var txIDs []ids.ID
+	for _, v := range []string{"a", "b"} {
+		tx := testAcceptTx(t, vm, priv, &chain.SetTx{
+			BaseTx: &chain.BaseTx{BlockID: vm.preferred, Price: 100},
+			Value:  []byte(v),
+		})
+		txIDs = append(txIDs, tx.ID())
+	}
+	for i, txID := range txIDs {
+		reply := new(HasTxReply)
+		if err := svc.HasTx(nil, &HasTxArgs{TxID: txID}, reply); err != nil {
+			t.Fatal(err)
+		}
+		expected := vm.lastAccepted.Hght - uint64(len(txIDs)-1-i)
+		if !reply.Accepted || reply.Height == nil || *reply.Height != expected {
+			t.Fatalf("#%d: expected accepted at height %d, got %+v", i, expected, reply)
+		}
+	}
+
+	// Txs accepted before their block was indexed (and pending txs) have no
+	// height
+	old, pending := ids.GenerateTestID(), ids.GenerateTestID()
+	if err := db.Put(chain.PrefixTxKey(old), nil); err != nil {
+		t.Fatal(err)
+	}
+	for txID, accepted := range map[ids.ID]bool{old: true, pending: false} {
+		reply := new(HasTxReply)
+		if err := svc.HasTx(nil, &HasTxArgs{TxID: txID}, reply); err != nil {
+			t.Fatal(err)
+		}
+		if reply.Accepted != accepted || reply.Height != nil {
+			t.Fatalf("expected accepted=%t without a height, got %+v", accepted, reply)
+		}
+	}
+}
+
+func TestActivityStreamKeepalive(t *testing.T) {
+	t.Parallel()
+
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := crypto.PubkeyToAddress(priv.PublicKey)
+
+	g := chain.DefaultGenesis()
+	g.CustomAllocation = []*chain.CustomAllocation{{Address: sender, Balance: 10000000}}
+	db := memdb.New()
+	defer db.Close()
+	if err := g.Load(db, nil); err != nil {
+		t.Fatal(err)
+	}
+	vm := testAcceptedVM(t, g, db, time.Now())
+	vm.config.ActivityKeepaliveInterval = 10 * time.Millisecond
+
+	server := httptest.NewServer(&activityStream{vm: vm})
+	defer server.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	readEvent := func() []string {
+		t.Helper()
+		lines := []string{}
+		for scanner.Scan() {
+			if scanner.Text() == "" {
+				return lines
+			}
+			lines = append(lines, scanner.Text())
+		}
+		t.Fatalf("stream ended: %v", scanner.Err())
+		return nil
+	}
+
+	// The stream is idle until a block is accepted
+	for i := 0; i < 3; i++ {
+		if event := readEvent(); !reflect.DeepEqual(event, []string{": keepalive"}) {
+			t.Fatalf("unexpected event %q, expected keepalive", event)
+		}
+	}
+
+	tx := testAcceptTx(t, vm, priv, &chain.SetTx{
+		BaseTx: &chain.BaseTx{BlockID: vm.preferred, Price: 2},
+		Value:  []byte("streamed"),
+	})
+	event := readEvent()
+	for len(event) == 1 && event[0] == ": keepalive" {
+		event = readEvent()
+	}
+	if len(event) != 1 || !strings.HasPrefix(event[0], "data: ") {
+		t.Fatalf("unexpected event %q, expected activity", event)
+	}
+	activity := new(chain.Activity)
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(event[0], "data: ")), activity); err != nil {
+		t.Fatal(err)
+	}
+	if activity.TxID != tx.ID() || activity.Typ != chain.Set || activity.Sender != sender.Hex() {
+		t.Fatalf("unexpected activity %+v", activity)
+	}
+
+	// Keepalives resume once the stream is idle again
+	if event := readEvent(); !reflect.DeepEqual(event, []string{": keepalive"}) {
+		t.Fatalf("unexpected event %q, expected keepalive", event)
+	}
+}
+
+func TestBuildBlockMaxSenderTxs(t *testing.T) {
+	t.Parallel()
+
+	spammer, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	spammerAddr := crypto.PubkeyToAddress(spammer.PublicKey)
+	otherAddr := crypto.PubkeyToAddress(other.PublicKey)
+
+	g := chain.DefaultGenesis()
+	g.MaxSenderTxsPerBlock = 2
+	g.CustomAllocation = []*chain.CustomAllocation{
+		{Address: spammerAddr, Balance: 10000000},
+		{Address: otherAddr, Balance: 10000000},
+	}
+	db := memdb.New()
+	defer db.Close()
+	if err := g.Load(db, nil); err != nil {
+		t.Fatal(err)
+	}
+	vm := testAcceptedVM(t, g, db, time.Now())
+
+	// The spammer outbids the other sender, so its txs are considered first
+	for i := 0; i < 5; i++ {
+		vm.mempool.Add(testSignTx(t, g, spammer, &chain.SetTx{
+			BaseTx: &chain.BaseTx{BlockID: vm.preferred, Price: 10},
+			Value:  []byte(fmt.Sprintf("spam %d", i)),
+		}))
+	}
+	vm.mempool.Add(testSignTx(t, g, other, &chain.SetTx{
+		BaseTx: &chain.BaseTx{BlockID: vm.preferred, Price: 2},
+		Value:  []byte("not spam"),
+	}))
+
+	for _, expected := range []map[common.Address]int{
+		{spammerAddr: 2, otherAddr: 1},
+		{spammerAddr: 2},
+		{spammerAddr: 1},
+	} {
+		blk, err := chain.BuildBlock(vm, vm.preferred)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := blk.Verify(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if err := blk.Accept(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		vm.preferred = blk.ID()
+
+		senders := map[common.Address]int{}
+		for _, tx := range vm.lastAccepted.Txs {
+			senders[tx.Sender()]++
+		}
+		if !reflect.DeepEqual(senders, expected) {
+			t.Fatalf("block %d has txs from %v, expected %v", vm.lastAccepted.Hght, senders, expected)
+		}
+	}
+	if l := vm.mempool.Len(); l != 0 {
+		t.Fatalf("%d txs left in mempool", l)
+	}
+}
+
+func TestResponseCompression(t *testing.T) {
+	t.Parallel()
+
+	db := memdb.New()
+	defer db.Close()
+
+	v := []byte(strings.Repeat("compressible ", 1000))
+	k := chain.ValueHash(v)
+	txID := ids.GenerateTestID()
+	if err := db.Put(chain.PrefixTxValueKey(txID), v); err != nil {
+		t.Fatal(err)
+	}
+	if err := chain.PutKey(db, k, &chain.ValueMeta{Size: uint64(len(v)), TxID: txID}); err != nil {
+		t.Fatal(err)
+	}
+	public, err := newHandler(Name, &PublicService{vm: testVM(db, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(compressResponses(public.Handler, 1024))
+	defer server.Close()
+
+	// Responses aren't decompressed transparently if the encoding is set
+	// explicitly
+	request := func(acceptEncoding string, key common.Hash) (*http.Response, []byte) {
+		t.Helper()
+
+		body := fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"blobvm.resolve","params":{"key":%q}}`, key.Hex())
+		req, err := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp, b
+	}
+	decodeValue := func(b []byte) []byte {
+		t.Helper()
+
+		reply := new(struct {
+			Result *ResolveReply `json:"result"`
+		})
+		if err := json.Unmarshal(b, reply); err != nil {
+			t.Fatal(err)
+		}
+		if reply.Result == nil || !reply.Result.Exists {
+			t.Fatalf("value not found in %s", b)
+		}
+		dv, err := DecodeValue(reply.Result.Encoding, reply.Result.Value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return dv
+	}
+
+	resp, b := request("deflate, gzip;q=0.5", k)
+	if enc := resp.Header.Get("Content-Encoding"); enc != "gzip" {
+		t.Fatalf("content encoding expected gzip, got %q", enc)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) >= len(plain) {
+		t.Fatalf("compressed response is %d bytes (uncompressed is %d)", len(b), len(plain))
+	}
+	if dv := decodeValue(plain); !bytes.Equal(dv, v) {
+		t.Fatalf("value expected %d bytes, got %d", len(v), len(dv))
+	}
+
+	// Not compressed if gzip isn't accepted or the response is small
+	for _, tv := range []struct {
+		acceptEncoding string
+		key            common.Hash
+	}{
+		{acceptEncoding: "identity", key: k},
+		{acceptEncoding: "gzip;q=0", key: k},
+		{acceptEncoding: "gzip", key: chain.ValueHash([]byte("missing"))},
+	} {
+		resp, b := request(tv.acceptEncoding, tv.key)
+		if enc := resp.Header.Get("Content-Encoding"); enc != "" {
+			t.Fatalf("accept=%q: unexpected content encoding %q", tv.acceptEncoding, enc)
+		}
+		if !json.Valid(b) {
+			t.Fatalf("accept=%q: invalid response %q", tv.acceptEncoding, b)
+		}
+	}
+}